@@ -0,0 +1,134 @@
+// Package access enforces who may call which gamification route. The host
+// application's authentication stores the caller's role and user id on the
+// gin context; routes then require roles, and end users are limited to their
+// own user rows.
+package access
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Roles
+const (
+	// RoleAdmin manages the catalog
+	RoleAdmin = "admin"
+
+	// RoleService is a trusted backend that records activities and awards
+	RoleService = "service"
+
+	// RoleUser is an end user, who may only read their own rows
+	RoleUser = "user"
+)
+
+var (
+	// RoleKey is the gin context key holding the caller's role
+	RoleKey = "role"
+
+	// UserIdKey is the gin context key holding the caller's user id
+	UserIdKey = "user_id"
+)
+
+// Role returns the role of the caller, or "" when authentication set none
+func Role(ctx *gin.Context) string {
+	return ctx.GetString(RoleKey)
+}
+
+// UserId returns the user id of the caller
+func UserId(ctx *gin.Context) (uint, bool) {
+	value, ok := ctx.Get(UserIdKey)
+	if !ok {
+		return 0, false
+	}
+	switch v := value.(type) {
+	case uint:
+		return v, v != 0
+	case uint64:
+		return uint(v), v != 0
+	case int:
+		return uint(v), v > 0
+	case int64:
+		return uint(v), v > 0
+	case float64:
+		return uint(v), v > 0
+	case string:
+		id, err := strconv.ParseUint(v, 10, 32)
+		return uint(id), err == nil && id != 0
+	}
+	return 0, false
+}
+
+// Caller is who made a request, as carried by the request context
+type Caller struct {
+	Role   string
+	UserId uint
+}
+
+type callerKey struct{}
+
+// WithCaller returns a copy of ctx that carries caller
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller carried by ctx, if any. Background
+// jobs run without one.
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	if ctx == nil {
+		return Caller{}, false
+	}
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+// Authenticated rejects callers without a known role, and end users without
+// a user id. It stores the caller on the request context, so that services
+// called with that context know who made the request.
+func Authenticated() gin.HandlerFunc {
+	require := Require(RoleAdmin, RoleService, RoleUser)
+	return func(ctx *gin.Context) {
+		userId, _ := UserId(ctx)
+		caller := Caller{Role: Role(ctx), UserId: userId}
+		ctx.Request = ctx.Request.WithContext(WithCaller(ctx.Request.Context(), caller))
+		require(ctx)
+	}
+}
+
+// Require rejects callers whose role is not one of roles
+func Require(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		role := Role(ctx)
+		for _, allowed := range roles {
+			if role != allowed {
+				continue
+			}
+			if role == RoleUser {
+				if _, ok := UserId(ctx); !ok {
+					break
+				}
+			}
+			ctx.Next()
+			return
+		}
+		Forbid(ctx)
+	}
+}
+
+// RestrictedTo returns the caller's user id when the caller may only see
+// their own rows, that is for every role but admin and service
+func RestrictedTo(ctx *gin.Context) (uint, bool) {
+	switch Role(ctx) {
+	case RoleAdmin, RoleService:
+		return 0, false
+	}
+	userId, _ := UserId(ctx)
+	return userId, true
+}
+
+// Forbid aborts the request with 403
+func Forbid(ctx *gin.Context) {
+	ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+}
@@ -1,10 +1,12 @@
 package achievement_criteria
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"base/core/storage"
+	"base/packages/gamification/access"
 	"base/packages/gamification/models"
 
 	"github.com/gin-gonic/gin"
@@ -27,9 +29,9 @@ func (c *AchievementCriteriaController) Routes(router *gin.RouterGroup) {
 	router.GET("/achievement-criteria", c.List)        // Paginated list
 	router.GET("/achievement-criteria/all", c.ListAll) // Unpaginated list
 	router.GET("/achievement-criteria/:id", c.Get)
-	router.POST("/achievement-criteria", c.Create)
-	router.PUT("/achievement-criteria/:id", c.Update)
-	router.DELETE("/achievement-criteria/:id", c.Delete)
+	router.POST("/achievement-criteria", access.Require(access.RoleAdmin), c.Create)
+	router.PUT("/achievement-criteria/:id", access.Require(access.RoleAdmin), c.Update)
+	router.DELETE("/achievement-criteria/:id", access.Require(access.RoleAdmin), c.Delete)
 
 	// File/Image attachment endpoints
 
@@ -47,6 +49,7 @@ func (c *AchievementCriteriaController) Routes(router *gin.RouterGroup) {
 // @Param achievement-criteria body models.CreateAchievementCriteriaRequest true "Create AchievementCriteria request"
 // @Success 201 {object} models.AchievementCriteriaResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /achievement-criteria [post]
 func (c *AchievementCriteriaController) Create(ctx *gin.Context) {
@@ -56,8 +59,13 @@ func (c *AchievementCriteriaController) Create(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Create(&req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
 		return
 	}
@@ -76,6 +84,7 @@ func (c *AchievementCriteriaController) Create(ctx *gin.Context) {
 // @Param id path int true "AchievementCriteria id"
 // @Success 200 {object} models.AchievementCriteriaResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /achievement-criteria/{id} [get]
 func (c *AchievementCriteriaController) Get(ctx *gin.Context) {
@@ -85,7 +94,7 @@ func (c *AchievementCriteriaController) Get(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -106,6 +115,7 @@ func (c *AchievementCriteriaController) Get(ctx *gin.Context) {
 // @Param limit query int false "Number of items per page"
 // @Success 200 {object} types.PaginatedResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /achievement-criteria [get]
 func (c *AchievementCriteriaController) List(ctx *gin.Context) {
@@ -129,7 +139,7 @@ func (c *AchievementCriteriaController) List(ctx *gin.Context) {
 		}
 	}
 
-	paginatedResponse, err := c.Service.GetAll(page, limit)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(page, limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
 		return
@@ -147,10 +157,11 @@ func (c *AchievementCriteriaController) List(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /achievement-criteria/all [get]
 func (c *AchievementCriteriaController) ListAll(ctx *gin.Context) {
-	paginatedResponse, err := c.Service.GetAll(nil, nil)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(nil, nil)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
 		return
@@ -171,6 +182,7 @@ func (c *AchievementCriteriaController) ListAll(ctx *gin.Context) {
 // @Param achievement-criteria body models.UpdateAchievementCriteriaRequest true "Update AchievementCriteria request"
 // @Success 200 {object} models.AchievementCriteriaResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /achievement-criteria/{id} [put]
@@ -187,8 +199,13 @@ func (c *AchievementCriteriaController) Update(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Update(uint(id), &req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
 	}
@@ -207,6 +224,8 @@ func (c *AchievementCriteriaController) Update(ctx *gin.Context) {
 // @Param id path int true "AchievementCriteria id"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
 // @Failure 500 {object} ErrorResponse
 // @Router /achievement-criteria/{id} [delete]
 func (c *AchievementCriteriaController) Delete(ctx *gin.Context) {
@@ -216,7 +235,12 @@ func (c *AchievementCriteriaController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.Delete(uint(id)); err != nil {
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
 		return
 	}
@@ -41,7 +41,10 @@ func (m *Module) Routes(router *gin.RouterGroup) {
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.AchievementCriteria{})
+	if err := m.DB.AutoMigrate(&models.AchievementCriteria{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.AchievementCriteria{})
 }
 
 func (m *Module) GetModels() []interface{} {
@@ -1,6 +1,8 @@
 package achievement_criteria
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 
@@ -35,7 +37,37 @@ func NewAchievementCriteriaService(db *gorm.DB, emitter *emitter.Emitter, storag
 	}
 }
 
+func (s *AchievementCriteriaService) WithContext(ctx context.Context) *AchievementCriteriaService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
 func (s *AchievementCriteriaService) Create(req *models.CreateAchievementCriteriaRequest) (*models.AchievementCriteria, error) {
+	if req.AchievementKey != "" {
+		achievementId, err := models.ResolveFieldKey(s.DB, &models.Achievement{}, "achievement_key", req.AchievementKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve achievement key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve achievement: %w", err)
+		}
+		req.AchievementId = achievementId
+	}
+	if req.ActivityTypeKey != "" {
+		activityTypeId, err := models.ResolveFieldKey(s.DB, &models.ActivityType{}, "activity_type_key", req.ActivityTypeKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve activity type key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve activity type: %w", err)
+		}
+		req.ActivityTypeId = activityTypeId
+	}
+	if err := models.CheckReferences(s.DB).
+		Exists("achievement_id", &models.Achievement{}, req.AchievementId).
+		Exists("activity_type_id", &models.ActivityType{}, req.ActivityTypeId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check achievementcriteria references", logger.String("error", err.Error()))
+		return nil, err
+	}
+
 	item := &models.AchievementCriteria{
 		AchievementId:  req.AchievementId,
 		ActivityTypeId: req.ActivityTypeId,
@@ -62,6 +94,15 @@ func (s *AchievementCriteriaService) Update(id uint, req *models.UpdateAchieveme
 			logger.Int("id", int(id)))
 		return nil, fmt.Errorf("failed to find achievementcriteria: %w", err)
 	}
+	if err := models.CheckReferences(s.DB).
+		Exists("achievement_id", &models.Achievement{}, req.AchievementId).
+		Exists("activity_type_id", &models.ActivityType{}, req.ActivityTypeId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check achievementcriteria references",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
 
 	// Build updates map
 	updates := make(map[string]interface{})
@@ -110,7 +151,11 @@ func (s *AchievementCriteriaService) Delete(id uint) error {
 
 	// Delete file attachments if any
 
-	if err := s.DB.Delete(item).Error; err != nil {
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
 		s.Logger.Error("failed to delete achievementcriteria",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
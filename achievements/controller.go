@@ -1,10 +1,12 @@
 package achievements
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"base/core/storage"
+	"base/packages/gamification/access"
 	"base/packages/gamification/models"
 
 	"github.com/gin-gonic/gin"
@@ -27,13 +29,14 @@ func (c *AchievementController) Routes(router *gin.RouterGroup) {
 	router.GET("/achievements", c.List)        // Paginated list
 	router.GET("/achievements/all", c.ListAll) // Unpaginated list
 	router.GET("/achievements/:id", c.Get)
-	router.POST("/achievements", c.Create)
-	router.PUT("/achievements/:id", c.Update)
-	router.DELETE("/achievements/:id", c.Delete)
+	router.GET("/achievements/by-key/:key", c.GetByKey)
+	router.POST("/achievements", access.Require(access.RoleAdmin), c.Create)
+	router.PUT("/achievements/:id", access.Require(access.RoleAdmin), c.Update)
+	router.DELETE("/achievements/:id", access.Require(access.RoleAdmin), c.Delete)
 
 	// File/Image attachment endpoints
-	router.PUT("/achievements/:id/icon", c.UploadIcon)
-	router.DELETE("/achievements/:id/icon", c.DeleteIcon)
+	router.PUT("/achievements/:id/icon", access.Require(access.RoleAdmin), c.UploadIcon)
+	router.DELETE("/achievements/:id/icon", access.Require(access.RoleAdmin), c.DeleteIcon)
 
 	// HasMany relation endpoints
 }
@@ -49,6 +52,8 @@ func (c *AchievementController) Routes(router *gin.RouterGroup) {
 // @Param achievements body models.CreateAchievementRequest true "Create Achievement request"
 // @Success 201 {object} models.AchievementResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /achievements [post]
 func (c *AchievementController) Create(ctx *gin.Context) {
@@ -58,8 +63,16 @@ func (c *AchievementController) Create(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Create(&req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidKey) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, models.ErrDuplicateKey) {
+			ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
 		return
 	}
@@ -78,6 +91,7 @@ func (c *AchievementController) Create(ctx *gin.Context) {
 // @Param id path int true "Achievement id"
 // @Success 200 {object} models.AchievementResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /achievements/{id} [get]
 func (c *AchievementController) Get(ctx *gin.Context) {
@@ -87,7 +101,30 @@ func (c *AchievementController) Get(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// GetAchievementByKey godoc
+// @Summary Get a Achievement by key
+// @Description Get a Achievement by its stable key
+// @Tags Achievement
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key path string true "Achievement key"
+// @Success 200 {object} models.AchievementResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /achievements/by-key/{key} [get]
+func (c *AchievementController) GetByKey(ctx *gin.Context) {
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetByKey(ctx.Param("key"))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -108,6 +145,7 @@ func (c *AchievementController) Get(ctx *gin.Context) {
 // @Param limit query int false "Number of items per page"
 // @Success 200 {object} types.PaginatedResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /achievements [get]
 func (c *AchievementController) List(ctx *gin.Context) {
@@ -131,7 +169,7 @@ func (c *AchievementController) List(ctx *gin.Context) {
 		}
 	}
 
-	paginatedResponse, err := c.Service.GetAll(page, limit)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(page, limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
 		return
@@ -149,10 +187,11 @@ func (c *AchievementController) List(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /achievements/all [get]
 func (c *AchievementController) ListAll(ctx *gin.Context) {
-	paginatedResponse, err := c.Service.GetAll(nil, nil)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(nil, nil)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
 		return
@@ -173,6 +212,7 @@ func (c *AchievementController) ListAll(ctx *gin.Context) {
 // @Param achievements body models.UpdateAchievementRequest true "Update Achievement request"
 // @Success 200 {object} models.AchievementResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /achievements/{id} [put]
@@ -189,7 +229,7 @@ func (c *AchievementController) Update(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Update(uint(id), &req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
@@ -209,6 +249,8 @@ func (c *AchievementController) Update(ctx *gin.Context) {
 // @Param id path int true "Achievement id"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
 // @Failure 500 {object} ErrorResponse
 // @Router /achievements/{id} [delete]
 func (c *AchievementController) Delete(ctx *gin.Context) {
@@ -218,7 +260,12 @@ func (c *AchievementController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.Delete(uint(id)); err != nil {
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
 		return
 	}
@@ -238,6 +285,7 @@ func (c *AchievementController) Delete(ctx *gin.Context) {
 // @Param file formData file true "File to upload"
 // @Success 200 {object} models.AchievementResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /achievements/{id}/icon [put]
@@ -255,7 +303,7 @@ func (c *AchievementController) UploadIcon(ctx *gin.Context) {
 	}
 
 	// Get the item first
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -269,7 +317,7 @@ func (c *AchievementController) UploadIcon(ctx *gin.Context) {
 	}
 
 	// Update the item with the new attachment
-	updatedItem, err := c.Service.UploadIcon(uint(id), file)
+	updatedItem, err := c.Service.WithContext(ctx.Request.Context()).UploadIcon(uint(id), file)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
@@ -289,6 +337,7 @@ func (c *AchievementController) UploadIcon(ctx *gin.Context) {
 // @Param id path int true "Achievement id"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /achievements/{id}/icon [delete]
@@ -300,7 +349,7 @@ func (c *AchievementController) DeleteIcon(ctx *gin.Context) {
 	}
 
 	// Get the item first
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -317,7 +366,7 @@ func (c *AchievementController) DeleteIcon(ctx *gin.Context) {
 		Icon: nil,
 	}
 
-	_, err = c.Service.Update(uint(id), updateReq)
+	_, err = c.Service.WithContext(ctx.Request.Context()).Update(uint(id), updateReq)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
@@ -41,7 +41,13 @@ func (m *Module) Routes(router *gin.RouterGroup) {
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.Achievement{})
+	if err := models.MigrateKey(m.DB, &models.Achievement{}, "name"); err != nil {
+		return err
+	}
+	if err := m.DB.AutoMigrate(&models.Achievement{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.Achievement{})
 }
 
 func (m *Module) GetModels() []interface{} {
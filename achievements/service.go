@@ -1,6 +1,8 @@
 package achievements
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"mime/multipart"
@@ -36,8 +38,21 @@ func NewAchievementService(db *gorm.DB, emitter *emitter.Emitter, storage *stora
 	}
 }
 
+func (s *AchievementService) WithContext(ctx context.Context) *AchievementService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
 func (s *AchievementService) Create(req *models.CreateAchievementRequest) (*models.Achievement, error) {
+	key, err := models.NewKey(s.DB, &models.Achievement{}, req.Key, req.Name)
+	if err != nil {
+		s.Logger.Error("failed to assign achievement key", logger.String("error", err.Error()))
+		return nil, err
+	}
+
 	item := &models.Achievement{
+		Key:         key,
 		Name:        req.Name,
 		Description: req.Description,
 		// Icon attachment is handled via separate endpoint
@@ -119,17 +134,14 @@ func (s *AchievementService) Delete(id uint) error {
 		return fmt.Errorf("failed to find achievement: %w", err)
 	}
 
-	// Delete file attachments if any
-	if item.Icon != nil {
-		if err := s.Storage.Delete(item.Icon); err != nil {
-			s.Logger.Error("failed to delete icon",
-				logger.String("error", err.Error()),
-				logger.Int("id", int(id)))
-			return fmt.Errorf("failed to delete icon: %w", err)
-		}
-	}
+	// The icon stays while the item is in the trash, so that it can be
+	// restored; purging the item deletes it
 
-	if err := s.DB.Delete(item).Error; err != nil {
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
 		s.Logger.Error("failed to delete achievement",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -157,6 +169,22 @@ func (s *AchievementService) GetById(id uint) (*models.Achievement, error) {
 	return item, nil
 }
 
+// GetByKey returns the achievement identified by its key
+func (s *AchievementService) GetByKey(key string) (*models.Achievement, error) {
+	item := &models.Achievement{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.Where(&models.Achievement{Key: key}).First(item).Error; err != nil {
+		s.Logger.Error("failed to get achievement by key",
+			logger.String("error", err.Error()),
+			logger.String("key", key))
+		return nil, fmt.Errorf("failed to get achievement: %w", err)
+	}
+
+	return item, nil
+}
+
 func (s *AchievementService) GetAll(page *int, limit *int) (*types.PaginatedResponse, error) {
 	var items []*models.Achievement
 	var total int64
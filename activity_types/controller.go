@@ -1,10 +1,12 @@
 package activity_types
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"base/core/storage"
+	"base/packages/gamification/access"
 	"base/packages/gamification/models"
 
 	"github.com/gin-gonic/gin"
@@ -27,9 +29,10 @@ func (c *ActivityTypeController) Routes(router *gin.RouterGroup) {
 	router.GET("/activity-types", c.List)        // Paginated list
 	router.GET("/activity-types/all", c.ListAll) // Unpaginated list
 	router.GET("/activity-types/:id", c.Get)
-	router.POST("/activity-types", c.Create)
-	router.PUT("/activity-types/:id", c.Update)
-	router.DELETE("/activity-types/:id", c.Delete)
+	router.GET("/activity-types/by-key/:key", c.GetByKey)
+	router.POST("/activity-types", access.Require(access.RoleAdmin), c.Create)
+	router.PUT("/activity-types/:id", access.Require(access.RoleAdmin), c.Update)
+	router.DELETE("/activity-types/:id", access.Require(access.RoleAdmin), c.Delete)
 
 	// File/Image attachment endpoints
 
@@ -47,6 +50,8 @@ func (c *ActivityTypeController) Routes(router *gin.RouterGroup) {
 // @Param activity-types body models.CreateActivityTypeRequest true "Create ActivityType request"
 // @Success 201 {object} models.ActivityTypeResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /activity-types [post]
 func (c *ActivityTypeController) Create(ctx *gin.Context) {
@@ -56,8 +61,21 @@ func (c *ActivityTypeController) Create(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Create(&req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, models.ErrDuplicateKey) {
+			ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, models.ErrInvalidKey) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
 		return
 	}
@@ -76,6 +94,7 @@ func (c *ActivityTypeController) Create(ctx *gin.Context) {
 // @Param id path int true "ActivityType id"
 // @Success 200 {object} models.ActivityTypeResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /activity-types/{id} [get]
 func (c *ActivityTypeController) Get(ctx *gin.Context) {
@@ -85,7 +104,30 @@ func (c *ActivityTypeController) Get(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// GetActivityTypeByKey godoc
+// @Summary Get a ActivityType by key
+// @Description Get a ActivityType by its stable key
+// @Tags ActivityType
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key path string true "ActivityType key"
+// @Success 200 {object} models.ActivityTypeResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /activity-types/by-key/{key} [get]
+func (c *ActivityTypeController) GetByKey(ctx *gin.Context) {
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetByKey(ctx.Param("key"))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -106,6 +148,7 @@ func (c *ActivityTypeController) Get(ctx *gin.Context) {
 // @Param limit query int false "Number of items per page"
 // @Success 200 {object} types.PaginatedResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /activity-types [get]
 func (c *ActivityTypeController) List(ctx *gin.Context) {
@@ -129,7 +172,7 @@ func (c *ActivityTypeController) List(ctx *gin.Context) {
 		}
 	}
 
-	paginatedResponse, err := c.Service.GetAll(page, limit)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(page, limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
 		return
@@ -147,10 +190,11 @@ func (c *ActivityTypeController) List(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /activity-types/all [get]
 func (c *ActivityTypeController) ListAll(ctx *gin.Context) {
-	paginatedResponse, err := c.Service.GetAll(nil, nil)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(nil, nil)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
 		return
@@ -171,6 +215,7 @@ func (c *ActivityTypeController) ListAll(ctx *gin.Context) {
 // @Param activity-types body models.UpdateActivityTypeRequest true "Update ActivityType request"
 // @Success 200 {object} models.ActivityTypeResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /activity-types/{id} [put]
@@ -187,8 +232,13 @@ func (c *ActivityTypeController) Update(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Update(uint(id), &req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
 	}
@@ -207,6 +257,8 @@ func (c *ActivityTypeController) Update(ctx *gin.Context) {
 // @Param id path int true "ActivityType id"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
 // @Failure 500 {object} ErrorResponse
 // @Router /activity-types/{id} [delete]
 func (c *ActivityTypeController) Delete(ctx *gin.Context) {
@@ -216,7 +268,12 @@ func (c *ActivityTypeController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.Delete(uint(id)); err != nil {
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
 		return
 	}
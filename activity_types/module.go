@@ -41,9 +41,15 @@ func (m *Module) Routes(router *gin.RouterGroup) {
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.ActivityType{})
+	if err := models.MigrateKey(m.DB, &models.ActivityType{}, "name"); err != nil {
+		return err
+	}
+	if err := m.DB.AutoMigrate(&models.ActivityType{}, &models.ActivityTypeReward{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.ActivityType{}, &models.ActivityTypeReward{})
 }
 
 func (m *Module) GetModels() []interface{} {
-	return []interface{}{&models.ActivityType{}}
+	return []interface{}{&models.ActivityType{}, &models.ActivityTypeReward{}}
 }
@@ -1,6 +1,8 @@
 package activity_types
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 
@@ -19,6 +21,9 @@ const (
 	DeleteActivityTypeEvent = "activitytypes.delete"
 )
 
+// limitColumns are the columns of the embedded ActivityLimits
+var limitColumns = []string{"limit_burst_count", "limit_burst_window", "limit_rate_factor", "limit_metadata"}
+
 type ActivityTypeService struct {
 	DB      *gorm.DB
 	Emitter *emitter.Emitter
@@ -35,14 +40,37 @@ func NewActivityTypeService(db *gorm.DB, emitter *emitter.Emitter, storage *stor
 	}
 }
 
+func (s *ActivityTypeService) WithContext(ctx context.Context) *ActivityTypeService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
 func (s *ActivityTypeService) Create(req *models.CreateActivityTypeRequest) (*models.ActivityType, error) {
+	key, err := models.NewKey(s.DB, &models.ActivityType{}, req.Key, req.Name)
+	if err != nil {
+		s.Logger.Error("failed to assign activitytype key", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	rewards, err := s.buildRewards(req.Rewards)
+	if err != nil {
+		s.Logger.Error("failed to build activitytype rewards", logger.String("error", err.Error()))
+		return nil, err
+	}
+
 	item := &models.ActivityType{
+		Key:            key,
 		Name:           req.Name,
 		Description:    req.Description,
 		Category:       req.Category,
 		PointsValue:    req.PointsValue,
 		CooldownPeriod: req.CooldownPeriod,
 		IsActive:       req.IsActive,
+		Rewards:        rewards,
+	}
+	if req.Limits != nil {
+		item.Limits = *req.Limits
 	}
 
 	if err := s.DB.Create(item).Error; err != nil {
@@ -86,7 +114,43 @@ func (s *ActivityTypeService) Update(id uint, req *models.UpdateActivityTypeRequ
 		updates["is_active"] = req.IsActive
 	}
 
-	if err := s.DB.Model(item).Updates(updates).Error; err != nil {
+	var rewards []*models.ActivityTypeReward
+	if req.Rewards != nil {
+		var err error
+		if rewards, err = s.buildRewards(req.Rewards); err != nil {
+			s.Logger.Error("failed to build activitytype rewards",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(id)))
+			return nil, err
+		}
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(item).Updates(updates).Error; err != nil {
+			return err
+		}
+		// Limits are replaced as a whole, so zero values switch checks off
+		if req.Limits != nil {
+			if err := tx.Model(item).Select(limitColumns).Updates(&models.ActivityType{Limits: *req.Limits}).Error; err != nil {
+				return err
+			}
+		}
+		if req.Rewards == nil {
+			return nil
+		}
+		// A rewards list replaces the current rewards as a whole
+		if err := tx.Where("activity_type_id = ?", item.Id).Delete(&models.ActivityTypeReward{}).Error; err != nil {
+			return err
+		}
+		for _, reward := range rewards {
+			reward.ActivityTypeId = item.Id
+			if err := tx.Create(reward).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		s.Logger.Error("failed to update activitytype",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -118,7 +182,11 @@ func (s *ActivityTypeService) Delete(id uint) error {
 
 	// Delete file attachments if any
 
-	if err := s.DB.Delete(item).Error; err != nil {
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
 		s.Logger.Error("failed to delete activitytype",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -146,6 +214,22 @@ func (s *ActivityTypeService) GetById(id uint) (*models.ActivityType, error) {
 	return item, nil
 }
 
+// GetByKey returns the activitytype identified by its key
+func (s *ActivityTypeService) GetByKey(key string) (*models.ActivityType, error) {
+	item := &models.ActivityType{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.Where(&models.ActivityType{Key: key}).First(item).Error; err != nil {
+		s.Logger.Error("failed to get activitytype by key",
+			logger.String("error", err.Error()),
+			logger.String("key", key))
+		return nil, fmt.Errorf("failed to get activitytype: %w", err)
+	}
+
+	return item, nil
+}
+
 func (s *ActivityTypeService) GetAll(page *int, limit *int) (*types.PaginatedResponse, error) {
 	var items []*models.ActivityType
 	var total int64
@@ -205,3 +289,29 @@ func (s *ActivityTypeService) GetAll(page *int, limit *int) (*types.PaginatedRes
 		},
 	}, nil
 }
+
+// buildRewards turns reward requests into rewards, resolving point type keys
+// and checking that the point types exist
+func (s *ActivityTypeService) buildRewards(reqs []models.ActivityTypeRewardRequest) ([]*models.ActivityTypeReward, error) {
+	rewards := make([]*models.ActivityTypeReward, 0, len(reqs))
+	check := models.CheckReferences(s.DB)
+	for i, req := range reqs {
+		pointTypeId := req.PointTypeId
+		if req.PointTypeKey != "" {
+			id, err := models.ResolveFieldKey(s.DB, &models.PointType{}, fmt.Sprintf("rewards[%d].point_type_key", i), req.PointTypeKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve point type: %w", err)
+			}
+			pointTypeId = id
+		}
+		check.Exists(fmt.Sprintf("rewards[%d].point_type_id", i), &models.PointType{}, pointTypeId)
+		rewards = append(rewards, &models.ActivityTypeReward{
+			PointTypeId: pointTypeId,
+			Amount:      req.Amount,
+		})
+	}
+	if err := check.Err(); err != nil {
+		return nil, err
+	}
+	return rewards, nil
+}
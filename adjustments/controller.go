@@ -0,0 +1,244 @@
+package adjustments
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"base/core/storage"
+	"base/packages/gamification/access"
+	"base/packages/gamification/models"
+	"base/packages/gamification/user_points"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdjustmentController struct {
+	Service *AdjustmentService
+	Storage *storage.ActiveStorage
+}
+
+func NewAdjustmentController(service *AdjustmentService, storage *storage.ActiveStorage) *AdjustmentController {
+	return &AdjustmentController{
+		Service: service,
+		Storage: storage,
+	}
+}
+
+func (c *AdjustmentController) Routes(router *gin.RouterGroup) {
+	router.GET("/adjustments", access.Require(access.RoleAdmin), c.List)
+	router.GET("/adjustments/:id", access.Require(access.RoleAdmin), c.Get)
+	router.POST("/adjustments", access.Require(access.RoleAdmin), c.Create)
+	router.POST("/adjustments/:id/approve", access.Require(access.RoleAdmin), c.Approve)
+	router.POST("/adjustments/:id/reject", access.Require(access.RoleAdmin), c.Reject)
+}
+
+// CreateAdjustment godoc
+// @Summary Adjust a user
+// @Description Grant or deduct points, grant or revoke an achievement, set a level or reset a challenge for a user, with a reason. Point grants above the point type's approval threshold wait for a second admin.
+// @Tags Adjustment
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param adjustments body models.CreateAdjustmentRequest true "Create Adjustment request"
+// @Success 201 {object} models.AdjustmentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 402 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /adjustments [post]
+func (c *AdjustmentController) Create(ctx *gin.Context) {
+	var req models.CreateAdjustmentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// Every adjustment records the admin who made it
+	adminId, ok := access.UserId(ctx)
+	if !ok {
+		access.Forbid(ctx)
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req, adminId)
+	if err != nil {
+		c.fail(ctx, err, "Failed to create item: ")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, item.ToResponse())
+}
+
+// GetAdjustment godoc
+// @Summary Get an Adjustment
+// @Description Get an Adjustment by its id
+// @Tags Adjustment
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Adjustment id"
+// @Success 200 {object} models.AdjustmentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /adjustments/{id} [get]
+func (c *AdjustmentController) Get(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// ListAdjustments godoc
+// @Summary List adjustments
+// @Description Get the history of adjustments, latest first, optionally of one user
+// @Tags Adjustment
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param user_id query int false "Only adjustments of this user"
+// @Param status query string false "Only adjustments of this status"
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /adjustments [get]
+func (c *AdjustmentController) List(ctx *gin.Context) {
+	var page, limit *int
+	var userId uint
+
+	if userIdStr := ctx.Query("user_id"); userIdStr != "" {
+		id, err := strconv.ParseUint(userIdStr, 10, 32)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user_id"})
+			return
+		}
+		userId = uint(id)
+	}
+
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid page number"})
+			return
+		}
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit number"})
+			return
+		}
+	}
+
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(userId, ctx.Query("status"), page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// ApproveAdjustment godoc
+// @Summary Approve an adjustment
+// @Description Apply a pending adjustment as a second admin
+// @Tags Adjustment
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Adjustment id"
+// @Success 200 {object} models.AdjustmentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 402 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /adjustments/{id}/approve [post]
+func (c *AdjustmentController) Approve(ctx *gin.Context) {
+	c.review(ctx, (*AdjustmentService).Approve)
+}
+
+// RejectAdjustment godoc
+// @Summary Reject an adjustment
+// @Description Turn a pending adjustment down as a second admin
+// @Tags Adjustment
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Adjustment id"
+// @Success 200 {object} models.AdjustmentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /adjustments/{id}/reject [post]
+func (c *AdjustmentController) Reject(ctx *gin.Context) {
+	c.review(ctx, (*AdjustmentService).Reject)
+}
+
+// review applies a second admin's verdict to a pending adjustment
+func (c *AdjustmentController) review(ctx *gin.Context, apply func(*AdjustmentService, uint, uint) (*models.Adjustment, error)) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	adminId, ok := access.UserId(ctx)
+	if !ok {
+		access.Forbid(ctx)
+		return
+	}
+
+	item, err := apply(c.Service.WithContext(ctx.Request.Context()), uint(id), adminId)
+	if err != nil {
+		c.fail(ctx, err, "Failed to review item: ")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// fail maps an adjustment error to its status
+func (c *AdjustmentController) fail(ctx *gin.Context, err error, message string) {
+	var invalid *models.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		ctx.JSON(http.StatusBadRequest, invalid)
+	case errors.Is(err, ErrInvalidAdjustment):
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, user_points.ErrInsufficientBalance):
+		ctx.JSON(http.StatusPaymentRequired, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrSameAdmin):
+		ctx.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrAdjustmentNotPending):
+		ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: message + err.Error()})
+	}
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
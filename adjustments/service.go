@@ -0,0 +1,505 @@
+package adjustments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+	"base/core/types"
+	"base/packages/gamification/models"
+	"base/packages/gamification/user_achievements"
+	"base/packages/gamification/user_challenges"
+	"base/packages/gamification/user_levels"
+	"base/packages/gamification/user_points"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	CreateAdjustmentEvent  = "adjustments.create"
+	ApplyAdjustmentEvent   = "adjustments.apply"
+	RejectAdjustmentEvent  = "adjustments.reject"
+	ApproveAdjustmentEvent = "adjustments.approve"
+)
+
+var (
+	// ErrInvalidAdjustment is returned for an adjustment whose target is
+	// missing or does not fit the user
+	ErrInvalidAdjustment = errors.New("invalid adjustment")
+
+	// ErrAdjustmentNotPending is returned when reviewing an adjustment that
+	// does not wait for approval
+	ErrAdjustmentNotPending = errors.New("adjustment is not pending")
+
+	// ErrSameAdmin is returned when the admin who requested an adjustment
+	// tries to review it
+	ErrSameAdmin = errors.New("adjustment must be reviewed by another admin")
+)
+
+type AdjustmentService struct {
+	DB           *gorm.DB
+	Emitter      *emitter.Emitter
+	Storage      *storage.ActiveStorage
+	Logger       logger.Logger
+	Points       *user_points.UserPointService
+	Levels       *user_levels.UserLevelService
+	Achievements *user_achievements.UserAchievementService
+}
+
+func NewAdjustmentService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *AdjustmentService {
+	return &AdjustmentService{
+		DB:           db,
+		Emitter:      emitter,
+		Storage:      storage,
+		Logger:       logger,
+		Points:       user_points.NewUserPointService(db, emitter, storage, logger),
+		Levels:       user_levels.NewUserLevelService(db, emitter, storage, logger),
+		Achievements: user_achievements.NewUserAchievementService(db, emitter, storage, logger),
+	}
+}
+
+func (s *AdjustmentService) WithContext(ctx context.Context) *AdjustmentService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	clone.Points = s.Points.WithContext(ctx)
+	clone.Levels = s.Levels.WithContext(ctx)
+	clone.Achievements = s.Achievements.WithContext(ctx)
+	return &clone
+}
+
+// effect is an event to emit once an applied adjustment is committed
+type effect struct {
+	event string
+	data  interface{}
+}
+
+// Create records an adjustment requested by adminId and applies it, unless
+// it is a point grant above the point type's approval threshold, which waits
+// for a second admin
+func (s *AdjustmentService) Create(req *models.CreateAdjustmentRequest, adminId uint) (*models.Adjustment, error) {
+	if err := s.resolveKeys(req); err != nil {
+		return nil, err
+	}
+
+	item := &models.Adjustment{
+		UserId:      req.UserId,
+		Kind:        req.Kind,
+		ReasonCode:  req.ReasonCode,
+		Reason:      req.Reason,
+		Status:      models.AdjustmentApplied,
+		RequestedBy: adminId,
+	}
+	if err := s.target(req, item); err != nil {
+		return nil, err
+	}
+	if err := s.checkTarget(item); err != nil {
+		return nil, err
+	}
+
+	var effects []effect
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if item.Kind == models.AdjustmentPointGrant {
+			pointType := &models.PointType{}
+			if err := tx.First(pointType, *item.PointTypeId).Error; err != nil {
+				return fmt.Errorf("failed to find point type: %w", err)
+			}
+			if pointType.ApprovalThreshold > 0 && item.Amount > pointType.ApprovalThreshold {
+				item.Status = models.AdjustmentPending
+			}
+		}
+
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		if item.Status == models.AdjustmentPending {
+			return nil
+		}
+		var err error
+		effects, err = s.apply(tx, item)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, ErrInvalidAdjustment) && !errors.Is(err, user_points.ErrInsufficientBalance) {
+			s.Logger.Error("failed to create adjustment",
+				logger.String("error", err.Error()),
+				logger.Int("user_id", int(req.UserId)))
+		}
+		return nil, err
+	}
+
+	// Emit create event
+	s.Emitter.Emit(CreateAdjustmentEvent, item)
+	s.announce(item, effects)
+
+	return s.GetById(item.Id)
+}
+
+// resolveKeys turns the keys of a request into the ids of its targets
+func (s *AdjustmentService) resolveKeys(req *models.CreateAdjustmentRequest) error {
+	keys := []struct {
+		field string
+		key   string
+		model interface{}
+		id    **uint
+	}{
+		{"point_type_key", req.PointTypeKey, &models.PointType{}, &req.PointTypeId},
+		{"achievement_key", req.AchievementKey, &models.Achievement{}, &req.AchievementId},
+		{"level_key", req.LevelKey, &models.Level{}, &req.LevelId},
+		{"challenge_key", req.ChallengeKey, &models.Challenge{}, &req.ChallengeId},
+	}
+	for _, k := range keys {
+		if k.key == "" {
+			continue
+		}
+		id, err := models.ResolveFieldKey(s.DB, k.model, k.field, k.key)
+		if err != nil {
+			s.Logger.Error("failed to resolve adjustment key", logger.String("error", err.Error()))
+			return fmt.Errorf("failed to resolve %s: %w", k.key, err)
+		}
+		*k.id = &id
+	}
+	return nil
+}
+
+// target copies the target the kind of adjustment needs from the request
+func (s *AdjustmentService) target(req *models.CreateAdjustmentRequest, item *models.Adjustment) error {
+	switch req.Kind {
+	case models.AdjustmentPointGrant, models.AdjustmentPointDeduct:
+		if req.PointTypeId == nil || req.Amount <= 0 {
+			return fmt.Errorf("%w: %s needs a point type and a positive amount", ErrInvalidAdjustment, req.Kind)
+		}
+		item.PointTypeId, item.Amount = req.PointTypeId, req.Amount
+	case models.AdjustmentAchievementGrant, models.AdjustmentAchievementRevoke:
+		if req.AchievementId == nil {
+			return fmt.Errorf("%w: %s needs an achievement", ErrInvalidAdjustment, req.Kind)
+		}
+		item.AchievementId = req.AchievementId
+	case models.AdjustmentLevelSet:
+		if req.LevelId == nil {
+			return fmt.Errorf("%w: %s needs a level", ErrInvalidAdjustment, req.Kind)
+		}
+		item.LevelId = req.LevelId
+	case models.AdjustmentChallengeReset:
+		if req.ChallengeId == nil {
+			return fmt.Errorf("%w: %s needs a challenge", ErrInvalidAdjustment, req.Kind)
+		}
+		item.ChallengeId = req.ChallengeId
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidAdjustment, req.Kind)
+	}
+	return nil
+}
+
+// checkTarget checks that the target of an adjustment exists
+func (s *AdjustmentService) checkTarget(item *models.Adjustment) error {
+	targets := []struct {
+		field string
+		model interface{}
+		id    *uint
+	}{
+		{"point_type_id", &models.PointType{}, item.PointTypeId},
+		{"achievement_id", &models.Achievement{}, item.AchievementId},
+		{"level_id", &models.Level{}, item.LevelId},
+		{"challenge_id", &models.Challenge{}, item.ChallengeId},
+	}
+	check := models.CheckReferences(s.DB)
+	for _, t := range targets {
+		if t.id != nil {
+			check.Exists(t.field, t.model, *t.id)
+		}
+	}
+	if err := check.Err(); err != nil {
+		s.Logger.Error("failed to check adjustment references", logger.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// apply carries out an adjustment within tx and marks it applied. It returns
+// the events to emit once tx is committed.
+func (s *AdjustmentService) apply(tx *gorm.DB, item *models.Adjustment) ([]effect, error) {
+	var effects []effect
+	reference := fmt.Sprintf("adjustment:%d", item.Id)
+
+	switch item.Kind {
+	case models.AdjustmentPointGrant, models.AdjustmentPointDeduct:
+		delta := item.Amount
+		var err error
+		if item.Kind == models.AdjustmentPointGrant {
+			_, err = s.Points.Credit(tx, item.UserId, *item.PointTypeId, item.Amount, models.PointTransactionAdjust, reference)
+		} else {
+			delta = -item.Amount
+			_, err = s.Points.Debit(tx, item.UserId, *item.PointTypeId, item.Amount, models.PointTransactionAdjust, reference)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		xpPointTypeId, err := s.Points.XpPointTypeId(tx)
+		if err != nil {
+			return nil, err
+		}
+		if *item.PointTypeId == xpPointTypeId {
+			// Deducted xp may take the user down a level
+			level, changed, err := s.Levels.AddXp(tx, item.UserId, delta)
+			if err != nil {
+				return nil, err
+			}
+			if changed && delta > 0 {
+				effects = append(effects, effect{user_levels.LevelUpEvent, level})
+			} else if changed {
+				effects = append(effects, effect{user_levels.UpdateUserLevelEvent, level})
+			}
+		}
+
+	case models.AdjustmentAchievementGrant:
+		achievement, granted, err := s.Achievements.Grant(tx, item.UserId, *item.AchievementId)
+		if err != nil {
+			return nil, err
+		}
+		if !granted {
+			return nil, fmt.Errorf("%w: the user already has the achievement", ErrInvalidAdjustment)
+		}
+		effects = append(effects, effect{user_achievements.CreateUserAchievementEvent, achievement})
+
+	case models.AdjustmentAchievementRevoke:
+		achievement := &models.UserAchievement{}
+		err := tx.Where("user_id = ? AND achievement_id = ?", item.UserId, *item.AchievementId).Take(achievement).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: the user does not have the achievement", ErrInvalidAdjustment)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find userachievement: %w", err)
+		}
+		if err := tx.Delete(achievement).Error; err != nil {
+			return nil, fmt.Errorf("failed to delete userachievement: %w", err)
+		}
+		effects = append(effects, effect{user_achievements.DeleteUserAchievementEvent, achievement})
+
+	case models.AdjustmentLevelSet:
+		level, err := setLevel(tx, item.UserId, *item.LevelId)
+		if err != nil {
+			return nil, err
+		}
+		effects = append(effects, effect{user_levels.UpdateUserLevelEvent, level})
+
+	case models.AdjustmentChallengeReset:
+		challenge := &models.UserChallenge{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND challenge_id = ?", item.UserId, *item.ChallengeId).
+			Take(challenge).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: the user has not joined the challenge", ErrInvalidAdjustment)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find userchallenge: %w", err)
+		}
+		challenge.Progress, challenge.Value = 0, 0
+		challenge.CompletedAt, challenge.RewardClaimed = types.DateTime{}, false
+		if err := tx.Model(challenge).Updates(map[string]interface{}{
+			"progress":       0,
+			"value":          0,
+			"completed_at":   challenge.CompletedAt,
+			"reward_claimed": false,
+		}).Error; err != nil {
+			return nil, fmt.Errorf("failed to reset userchallenge: %w", err)
+		}
+		effects = append(effects, effect{user_challenges.UpdateUserChallengeEvent, challenge})
+	}
+
+	now := time.Now()
+	item.Status, item.AppliedAt = models.AdjustmentApplied, &now
+	if err := tx.Model(item).Updates(map[string]interface{}{"status": item.Status, "applied_at": now}).Error; err != nil {
+		return nil, err
+	}
+	return effects, nil
+}
+
+// setLevel moves a user to a level, with the xp the level requires so that
+// the next xp they earn carries on from there
+func setLevel(tx *gorm.DB, userId uint, levelId uint) (*models.UserLevel, error) {
+	level := &models.Level{}
+	if err := tx.First(level, levelId).Error; err != nil {
+		return nil, fmt.Errorf("failed to find level: %w", err)
+	}
+
+	item := &models.UserLevel{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userId).
+		First(item).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find userlevel: %w", err)
+	}
+	item.UserId = userId
+	item.CurrentLevelId = &level.Id
+	item.CurrentXp = level.XpRequired
+	item.LastLeveledUp = types.DateTime{Time: time.Now()}
+
+	if err := tx.Save(item).Error; err != nil {
+		return nil, fmt.Errorf("failed to save userlevel: %w", err)
+	}
+	return item, nil
+}
+
+// Approve applies a pending adjustment on behalf of a second admin
+func (s *AdjustmentService) Approve(id uint, adminId uint) (*models.Adjustment, error) {
+	var effects []effect
+	item, err := s.review(id, adminId, ApproveAdjustmentEvent, func(tx *gorm.DB, item *models.Adjustment) error {
+		var err error
+		effects, err = s.apply(tx, item)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.announce(item, effects)
+
+	return s.GetById(item.Id)
+}
+
+// Reject turns a pending adjustment down on behalf of a second admin
+func (s *AdjustmentService) Reject(id uint, adminId uint) (*models.Adjustment, error) {
+	item, err := s.review(id, adminId, RejectAdjustmentEvent, func(tx *gorm.DB, item *models.Adjustment) error {
+		item.Status = models.AdjustmentRejected
+		return tx.Model(item).Update("status", item.Status).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetById(item.Id)
+}
+
+// review applies change to a pending adjustment requested by another admin,
+// with the adjustment locked, and records adminId as its reviewer
+func (s *AdjustmentService) review(id uint, adminId uint, event string, change func(tx *gorm.DB, item *models.Adjustment) error) (*models.Adjustment, error) {
+	item := &models.Adjustment{}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(item, id).Error; err != nil {
+			return fmt.Errorf("failed to find adjustment: %w", err)
+		}
+		if item.Status != models.AdjustmentPending {
+			return ErrAdjustmentNotPending
+		}
+		if item.RequestedBy == adminId {
+			return ErrSameAdmin
+		}
+		item.ReviewedBy = &adminId
+		if err := tx.Model(item).Update("reviewed_by", adminId).Error; err != nil {
+			return err
+		}
+		return change(tx, item)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrAdjustmentNotPending), errors.Is(err, ErrSameAdmin),
+			errors.Is(err, ErrInvalidAdjustment), errors.Is(err, user_points.ErrInsufficientBalance):
+		default:
+			s.Logger.Error("failed to review adjustment",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(id)))
+		}
+		return nil, err
+	}
+
+	s.Emitter.Emit(event, item)
+
+	return item, nil
+}
+
+// announce emits the events of a committed adjustment once it is applied
+func (s *AdjustmentService) announce(item *models.Adjustment, effects []effect) {
+	if item.Status != models.AdjustmentApplied {
+		return
+	}
+	s.Emitter.Emit(ApplyAdjustmentEvent, item)
+	for _, e := range effects {
+		s.Emitter.Emit(e.event, e.data)
+	}
+}
+
+func (s *AdjustmentService) GetById(id uint) (*models.Adjustment, error) {
+	item := &models.Adjustment{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to get adjustment",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to get adjustment: %w", err)
+	}
+
+	return item, nil
+}
+
+// GetAll returns the adjustments, latest first, optionally of one user and
+// of one status
+func (s *AdjustmentService) GetAll(userId uint, status string, page *int, limit *int) (*types.PaginatedResponse, error) {
+	var items []*models.Adjustment
+	var total int64
+	query := s.DB.Model(&models.Adjustment{})
+	if userId != 0 {
+		query = query.Where("user_id = ?", userId)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	// Set default values if nil
+	defaultPage := 1
+	defaultLimit := 10
+	if page == nil {
+		page = &defaultPage
+	}
+	if limit == nil {
+		limit = &defaultLimit
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count adjustments",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to count adjustments: %w", err)
+	}
+
+	offset := (*page - 1) * *limit
+	query = query.Order("id DESC").Offset(offset).Limit(*limit)
+
+	// Preload relationships
+	query = (&models.Adjustment{}).Preload(query)
+
+	// Execute query
+	if err := query.Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get adjustments",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to get adjustments: %w", err)
+	}
+
+	// Convert to response type
+	responses := make([]*models.AdjustmentResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToResponse()
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(total) / float64(*limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return &types.PaginatedResponse{
+		Data: responses,
+		Pagination: types.Pagination{
+			Total:      int(total),
+			Page:       *page,
+			PageSize:   *limit,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
@@ -0,0 +1,129 @@
+package adjustments_test
+
+import (
+	"errors"
+	"testing"
+
+	"base/core/emitter"
+	"base/packages/gamification/adjustments"
+	"base/packages/gamification/internal/testdb"
+	"base/packages/gamification/models"
+	"base/packages/gamification/user_points"
+
+	"gorm.io/gorm"
+)
+
+// newAdjustments returns the database of tenant 1, a service on it and a
+// point type whose grants above 100 wait for approval
+func newAdjustments(t *testing.T) (*gorm.DB, *adjustments.AdjustmentService, *models.PointType) {
+	t.Helper()
+	base := testdb.Open(t, &models.PointType{}, &models.UserPoint{}, &models.PointLot{}, &models.PointTransaction{},
+		&models.Level{}, &models.UserLevel{}, &models.Achievement{}, &models.UserAchievement{},
+		&models.Challenge{}, &models.UserChallenge{}, &models.Adjustment{})
+	db := testdb.ForTenant(base, 1)
+	coins := &models.PointType{Key: "coins", Name: "Coins", ApprovalThreshold: 100}
+	if err := db.Create(coins).Error; err != nil {
+		t.Fatalf("failed to create point type: %v", err)
+	}
+	return db, adjustments.NewAdjustmentService(db, &emitter.Emitter{}, nil, testdb.Logger{}), coins
+}
+
+func request(kind string, pointTypeId uint, amount int) *models.CreateAdjustmentRequest {
+	return &models.CreateAdjustmentRequest{
+		UserId:      7,
+		Kind:        kind,
+		PointTypeId: &pointTypeId,
+		Amount:      amount,
+		ReasonCode:  models.AdjustmentReasonCompensation,
+		Reason:      "outage",
+	}
+}
+
+// balance returns the balance of user 7 and the sum of its transactions
+func balance(t *testing.T, db *gorm.DB, pointTypeId uint) (int, int) {
+	t.Helper()
+	var balances []int
+	if err := db.Model(&models.UserPoint{}).Where("user_id = ? AND point_type_id = ?", 7, pointTypeId).
+		Pluck("current_balance", &balances).Error; err != nil {
+		t.Fatalf("failed to find balance: %v", err)
+	}
+	var sum int
+	if err := db.Model(&models.PointTransaction{}).Select("COALESCE(SUM(amount), 0)").
+		Where("user_id = ? AND point_type_id = ?", 7, pointTypeId).Scan(&sum).Error; err != nil {
+		t.Fatalf("failed to sum transactions: %v", err)
+	}
+	if len(balances) == 0 {
+		return 0, sum
+	}
+	return balances[0], sum
+}
+
+func TestApprove(t *testing.T) {
+	db, service, coins := newAdjustments(t)
+
+	item, err := service.Create(request(models.AdjustmentPointGrant, coins.Id, 500), 1)
+	if err != nil {
+		t.Fatalf("failed to create adjustment: %v", err)
+	}
+	if item.Status != models.AdjustmentPending {
+		t.Fatalf("status = %s, want %s", item.Status, models.AdjustmentPending)
+	}
+	if current, sum := balance(t, db, coins.Id); current != 0 || sum != 0 {
+		t.Errorf("pending grant: balance = %d, transactions = %d, want 0 and 0", current, sum)
+	}
+
+	if _, err := service.Approve(item.Id, 1); !errors.Is(err, adjustments.ErrSameAdmin) {
+		t.Errorf("approving own adjustment: err = %v, want %v", err, adjustments.ErrSameAdmin)
+	}
+
+	approved, err := service.Approve(item.Id, 2)
+	if err != nil {
+		t.Fatalf("failed to approve adjustment: %v", err)
+	}
+	if approved.Status != models.AdjustmentApplied || approved.AppliedAt == nil {
+		t.Errorf("status = %s, want %s with an applied_at", approved.Status, models.AdjustmentApplied)
+	}
+	if approved.ReviewedBy == nil {
+		t.Errorf("reviewed by nobody, want 2")
+	} else if *approved.ReviewedBy != 2 {
+		t.Errorf("reviewed by %d, want 2", *approved.ReviewedBy)
+	}
+	if current, sum := balance(t, db, coins.Id); current != 500 || sum != 500 {
+		t.Errorf("balance = %d, transactions = %d, want 500 and 500", current, sum)
+	}
+
+	// An adjustment is applied once
+	if _, err := service.Approve(item.Id, 3); !errors.Is(err, adjustments.ErrAdjustmentNotPending) {
+		t.Errorf("approving again: err = %v, want %v", err, adjustments.ErrAdjustmentNotPending)
+	}
+	if current, _ := balance(t, db, coins.Id); current != 500 {
+		t.Errorf("balance = %d after approving again, want 500", current)
+	}
+}
+
+func TestCreateAppliesBelowThreshold(t *testing.T) {
+	db, service, coins := newAdjustments(t)
+
+	item, err := service.Create(request(models.AdjustmentPointGrant, coins.Id, 100), 1)
+	if err != nil {
+		t.Fatalf("failed to create adjustment: %v", err)
+	}
+	if item.Status != models.AdjustmentApplied {
+		t.Errorf("status = %s, want %s", item.Status, models.AdjustmentApplied)
+	}
+
+	// A deduction beyond the balance is not recorded
+	if _, err := service.Create(request(models.AdjustmentPointDeduct, coins.Id, 150), 1); !errors.Is(err, user_points.ErrInsufficientBalance) {
+		t.Errorf("deducting past the balance: err = %v, want %v", err, user_points.ErrInsufficientBalance)
+	}
+	var count int64
+	if err := db.Model(&models.Adjustment{}).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count adjustments: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("%d adjustments stored, want 1", count)
+	}
+	if current, sum := balance(t, db, coins.Id); current != 100 || sum != 100 {
+		t.Errorf("balance = %d, transactions = %d, want 100 and 100", current, sum)
+	}
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"base/core/storage"
+	"base/packages/gamification/access"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController struct {
+	Service *AuditService
+	Storage *storage.ActiveStorage
+}
+
+func NewAuditController(service *AuditService, storage *storage.ActiveStorage) *AuditController {
+	return &AuditController{
+		Service: service,
+		Storage: storage,
+	}
+}
+
+func (c *AuditController) Routes(router *gin.RouterGroup) {
+	router.GET("/audit", access.Require(access.RoleAdmin), c.List)
+}
+
+// ListAuditRecords godoc
+// @Summary List audit records
+// @Description Get the audit log of catalog writes, latest first, with the actor and the before and after state of every write
+// @Tags Audit
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param entity query string false "Only records of this entity, such as activity_types or achievement_criteria"
+// @Param id query int false "Only records of this row of the entity"
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /audit [get]
+func (c *AuditController) List(ctx *gin.Context) {
+	var page, limit *int
+	var entityId uint
+
+	entity := ctx.Query("entity")
+	if idStr := ctx.Query("id"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+			return
+		}
+		if entity == "" {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "id requires an entity"})
+			return
+		}
+		entityId = uint(id)
+	}
+
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid page number"})
+			return
+		}
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit number"})
+			return
+		}
+	}
+
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(entity, entityId, page, limit)
+	if err != nil {
+		if errors.Is(err, ErrUnknownEntity) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
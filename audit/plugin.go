@@ -0,0 +1,292 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"base/packages/gamification/access"
+	"base/packages/gamification/models"
+	"base/packages/gamification/tenancy"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Entities maps the tables of the audited catalog entities to the entity
+// names the audit log is queried by
+var Entities = map[string]string{
+	"activitytypes":       "activity_types",
+	"pointtypes":          "point_types",
+	"achievements":        "achievements",
+	"achievementcriteria": "achievement_criteria",
+	"levels":              "levels",
+	"challenges":          "challenges",
+	"leaderboards":        "leaderboards",
+}
+
+// beforeKey is the statement instance key holding the rows before a write
+const beforeKey = "audit:before"
+
+// Plugin records an AuditRecord for every create, update and delete of a
+// catalog entity, within the transaction of the write. Updates and deletes
+// load the rows they touch beforehand, so the record holds the row before
+// and after the write whichever code path made it, and the caller of the
+// statement context is recorded as its actor.
+type Plugin struct{}
+
+// snapshot is a row as JSON, with its tenant
+type snapshot struct {
+	tenantId uint
+	data     map[string]interface{}
+}
+
+// Name returns the plugin name
+func (Plugin) Name() string {
+	return "gamification:audit"
+}
+
+// Initialize registers the audit callbacks
+func (Plugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("audit:create", recordCreate); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("audit:before_update", captureBefore); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("audit:update", recordUpdate); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("audit:before_delete", captureBefore); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Register("audit:delete", recordDelete)
+}
+
+func recordCreate(db *gorm.DB) {
+	record(db, models.AuditCreate)
+}
+
+func recordUpdate(db *gorm.DB) {
+	record(db, models.AuditUpdate)
+}
+
+func recordDelete(db *gorm.DB) {
+	record(db, models.AuditDelete)
+}
+
+// captureBefore stores the rows an update or delete is about to write
+func captureBefore(db *gorm.DB) {
+	if _, ok := audited(db); !ok {
+		return
+	}
+	keys, err := ids(db, true)
+	if err != nil {
+		db.AddError(fmt.Errorf("failed to audit write: %w", err))
+		return
+	}
+	before, err := snapshots(db, keys)
+	if err != nil {
+		db.AddError(fmt.Errorf("failed to audit write: %w", err))
+		return
+	}
+	db.InstanceSet(beforeKey, before)
+}
+
+// record writes the audit records of a write that succeeded
+func record(db *gorm.DB, action string) {
+	entity, ok := audited(db)
+	if !ok {
+		return
+	}
+
+	var before map[uint]snapshot
+	if value, ok := db.InstanceGet(beforeKey); ok {
+		before = value.(map[uint]snapshot)
+	}
+	var keys []uint
+	if action == models.AuditCreate {
+		var err error
+		if keys, err = ids(db, false); err != nil {
+			db.AddError(fmt.Errorf("failed to audit write: %w", err))
+			return
+		}
+	} else {
+		for id := range before {
+			keys = append(keys, id)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	after := map[uint]snapshot{}
+	if action != models.AuditDelete {
+		var err error
+		if after, err = snapshots(db, keys); err != nil {
+			db.AddError(fmt.Errorf("failed to audit write: %w", err))
+			return
+		}
+	}
+
+	var actorId *uint
+	var actorRole string
+	if caller, ok := access.CallerFromContext(db.Statement.Context); ok {
+		actorRole = caller.Role
+		if caller.UserId != 0 {
+			userId := caller.UserId
+			actorId = &userId
+		}
+	}
+
+	var records []*models.AuditRecord
+	for _, id := range keys {
+		old, current := before[id], after[id]
+		changes := diff(old.data, current.data)
+		// Writes that changed nothing are not worth a record
+		if action == models.AuditUpdate && len(changes) == 0 {
+			continue
+		}
+		tenantId := current.tenantId
+		if current.data == nil {
+			tenantId = old.tenantId
+		}
+		records = append(records, &models.AuditRecord{
+			TenantId:  tenantId,
+			Entity:    entity,
+			EntityId:  id,
+			Action:    action,
+			ActorId:   actorId,
+			ActorRole: actorRole,
+			Before:    old.data,
+			After:     current.data,
+			Diff:      changes,
+		})
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(&records).Error; err != nil {
+		db.AddError(fmt.Errorf("failed to write audit records: %w", err))
+	}
+}
+
+// audited returns the entity name of the table a statement writes, if it is
+// audited
+func audited(db *gorm.DB) (string, bool) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Schema.PrioritizedPrimaryField == nil {
+		return "", false
+	}
+	entity, ok := Entities[db.Statement.Schema.Table]
+	return entity, ok
+}
+
+// ids returns the primary keys of the rows a statement writes: those set on
+// its model or, when byConditions is set and there are none, those its
+// conditions match
+func ids(db *gorm.DB, byConditions bool) ([]uint, error) {
+	stmt := db.Statement
+	field := stmt.Schema.PrioritizedPrimaryField
+
+	var keys []uint
+	collect := func(value reflect.Value) {
+		if id, zero := field.ValueOf(stmt.Context, value); !zero {
+			if key, ok := id.(uint); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Struct:
+		collect(stmt.ReflectValue)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			collect(reflect.Indirect(stmt.ReflectValue.Index(i)))
+		}
+	}
+	if len(keys) > 0 || !byConditions {
+		return keys, nil
+	}
+
+	conditions, ok := stmt.Clauses["WHERE"]
+	if !ok {
+		return nil, nil
+	}
+	where, ok := conditions.Expression.(clause.Where)
+	if !ok {
+		return nil, nil
+	}
+	model := reflect.New(stmt.Schema.ModelType).Interface()
+	if err := session(db).Model(model).Clauses(where).Pluck(field.DBName, &keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// snapshots loads the rows with the given primary keys as JSON objects
+func snapshots(db *gorm.DB, keys []uint) (map[uint]snapshot, error) {
+	result := map[uint]snapshot{}
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	schema := db.Statement.Schema
+	rows := reflect.New(reflect.SliceOf(reflect.PtrTo(schema.ModelType)))
+	if err := session(db).Find(rows.Interface(), keys).Error; err != nil {
+		return nil, err
+	}
+
+	tenantField := schema.LookUpField(tenancy.FieldName)
+	for i := 0; i < rows.Elem().Len(); i++ {
+		row := rows.Elem().Index(i)
+		id, _ := schema.PrioritizedPrimaryField.ValueOf(db.Statement.Context, row.Elem())
+		key, ok := id.(uint)
+		if !ok {
+			continue
+		}
+
+		encoded, err := json.Marshal(row.Interface())
+		if err != nil {
+			return nil, err
+		}
+		item := snapshot{data: map[string]interface{}{}}
+		if err := json.Unmarshal(encoded, &item.data); err != nil {
+			return nil, err
+		}
+		if tenantField != nil {
+			if tenantId, ok := tenantField.ReflectValueOf(db.Statement.Context, row.Elem()).Interface().(uint); ok {
+				item.tenantId = tenantId
+			}
+		}
+		result[key] = item
+	}
+	return result, nil
+}
+
+// session starts a new statement on the connection and context of db, which
+// also sees soft deleted rows
+func session(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Unscoped()
+}
+
+// diff returns the fields whose value differs between two snapshots. The
+// update time changes on every write, so it is left out.
+func diff(before map[string]interface{}, after map[string]interface{}) map[string]models.AuditChange {
+	changes := map[string]models.AuditChange{}
+	for name, value := range after {
+		if !reflect.DeepEqual(before[name], value) {
+			changes[name] = models.AuditChange{Before: before[name], After: value}
+		}
+	}
+	for name, old := range before {
+		if _, ok := after[name]; !ok && old != nil {
+			changes[name] = models.AuditChange{Before: old}
+		}
+	}
+	delete(changes, "updated_at")
+	return changes
+}
@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+	"base/core/types"
+	"base/packages/gamification/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownEntity is returned when querying the log of an entity that is not audited
+var ErrUnknownEntity = errors.New("unknown audit entity")
+
+type AuditService struct {
+	DB      *gorm.DB
+	Emitter *emitter.Emitter
+	Storage *storage.ActiveStorage
+	Logger  logger.Logger
+}
+
+func NewAuditService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *AuditService {
+	return &AuditService{
+		DB:      db,
+		Emitter: emitter,
+		Storage: storage,
+		Logger:  logger,
+	}
+}
+
+func (s *AuditService) WithContext(ctx context.Context) *AuditService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
+// GetAll returns the audit records, latest first, optionally of one entity
+// and of one row of it
+func (s *AuditService) GetAll(entity string, entityId uint, page *int, limit *int) (*types.PaginatedResponse, error) {
+	var items []*models.AuditRecord
+	var total int64
+	query := s.DB.Model(&models.AuditRecord{})
+	if entity != "" {
+		if !known(entity) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEntity, entity)
+		}
+		query = query.Where("entity = ?", entity)
+	}
+	if entityId != 0 {
+		query = query.Where("entity_id = ?", entityId)
+	}
+	// Set default values if nil
+	defaultPage := 1
+	defaultLimit := 10
+	if page == nil {
+		page = &defaultPage
+	}
+	if limit == nil {
+		limit = &defaultLimit
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count auditrecords",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to count auditrecords: %w", err)
+	}
+
+	offset := (*page - 1) * *limit
+	query = query.Order("id DESC").Offset(offset).Limit(*limit)
+
+	// Execute query
+	if err := query.Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get auditrecords",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to get auditrecords: %w", err)
+	}
+
+	// Convert to response type
+	responses := make([]*models.AuditRecordResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToResponse()
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(total) / float64(*limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return &types.PaginatedResponse{
+		Data: responses,
+		Pagination: types.Pagination{
+			Total:      int(total),
+			Page:       *page,
+			PageSize:   *limit,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// known reports whether entity is the name of an audited entity
+func known(entity string) bool {
+	for _, name := range Entities {
+		if name == entity {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"base/core/storage"
+	"base/packages/gamification/access"
+	"base/packages/gamification/models"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+type CatalogController struct {
+	Service *CatalogService
+	Storage *storage.ActiveStorage
+}
+
+func NewCatalogController(service *CatalogService, storage *storage.ActiveStorage) *CatalogController {
+	return &CatalogController{
+		Service: service,
+		Storage: storage,
+	}
+}
+
+func (c *CatalogController) Routes(router *gin.RouterGroup) {
+	// Whole catalog as a JSON or YAML bundle
+	router.GET("/catalog/export", c.Export)
+	router.POST("/catalog/import", access.Require(access.RoleAdmin), c.Import)
+
+	// Single catalog table as CSV
+	router.GET("/catalog/export/:table", c.ExportTable)
+	router.POST("/catalog/import/:table", access.Require(access.RoleAdmin), c.ImportTable)
+}
+
+// ExportCatalog godoc
+// @Summary Export the catalog
+// @Description Export every catalog definition as one JSON or YAML bundle
+// @Tags Catalog
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Produce application/x-yaml
+// @Param format query string false "json (default) or yaml"
+// @Success 200 {object} models.CatalogBundle
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /catalog/export [get]
+func (c *CatalogController) Export(ctx *gin.Context) {
+	bundle, err := c.Service.WithContext(ctx.Request.Context()).Export()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to export catalog: " + err.Error()})
+		return
+	}
+
+	if bundleFormat(ctx) == "yaml" {
+		data, err := yaml.Marshal(bundle)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to encode catalog: " + err.Error()})
+			return
+		}
+		ctx.Header("Content-Disposition", "attachment; filename=catalog.yaml")
+		ctx.Data(http.StatusOK, "application/x-yaml", data)
+		return
+	}
+
+	ctx.Header("Content-Disposition", "attachment; filename=catalog.json")
+	ctx.JSON(http.StatusOK, bundle)
+}
+
+// ImportCatalog godoc
+// @Summary Import the catalog
+// @Description Upsert catalog definitions by key from a JSON or YAML bundle. With dry_run the changes are only reported.
+// @Tags Catalog
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Accept application/x-yaml
+// @Produce json
+// @Param format query string false "json (default) or yaml"
+// @Param dry_run query bool false "Only report the changes"
+// @Param bundle body models.CatalogBundle true "Catalog bundle"
+// @Success 200 {object} models.CatalogImportResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /catalog/import [post]
+func (c *CatalogController) Import(ctx *gin.Context) {
+	dryRun, err := dryRunParam(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid dry_run value"})
+		return
+	}
+
+	data, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to read request body"})
+		return
+	}
+
+	bundle := &models.CatalogBundle{}
+	if bundleFormat(ctx) == "yaml" {
+		err = yaml.Unmarshal(data, bundle)
+	} else {
+		err = json.Unmarshal(data, bundle)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result, err := c.Service.WithContext(ctx.Request.Context()).Import(bundle, dryRun)
+	if err != nil {
+		c.importError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ExportCatalogTable godoc
+// @Summary Export a catalog table
+// @Description Export one catalog table as CSV
+// @Tags Catalog
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce text/csv
+// @Param table path string true "point-types, activity-types, activity-type-rewards, achievements, achievement-criteria, levels, challenges or leaderboards"
+// @Success 200 {string} string
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /catalog/export/{table} [get]
+func (c *CatalogController) ExportTable(ctx *gin.Context) {
+	table := ctx.Param("table")
+
+	var buf bytes.Buffer
+	if err := c.Service.WithContext(ctx.Request.Context()).ExportCSV(table, &buf); err != nil {
+		if errors.Is(err, ErrUnknownTable) {
+			ctx.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to export table: " + err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Disposition", "attachment; filename="+table+".csv")
+	ctx.Data(http.StatusOK, "text/csv", buf.Bytes())
+}
+
+// ImportCatalogTable godoc
+// @Summary Import a catalog table
+// @Description Upsert one catalog table by key from CSV, sent as the request body or as a "file" form field
+// @Tags Catalog
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept text/csv
+// @Accept multipart/form-data
+// @Produce json
+// @Param table path string true "point-types, activity-types, activity-type-rewards, achievements, achievement-criteria, levels, challenges or leaderboards"
+// @Param dry_run query bool false "Only report the changes"
+// @Success 200 {object} models.CatalogImportResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /catalog/import/{table} [post]
+func (c *CatalogController) ImportTable(ctx *gin.Context) {
+	dryRun, err := dryRunParam(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid dry_run value"})
+		return
+	}
+
+	var body io.Reader = ctx.Request.Body
+	if file, err := ctx.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to open uploaded file"})
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	result, err := c.Service.WithContext(ctx.Request.Context()).ImportCSV(ctx.Param("table"), body, dryRun)
+	if err != nil {
+		c.importError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (c *CatalogController) importError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrUnknownTable):
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrInvalidBundle):
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to import catalog: " + err.Error()})
+	}
+}
+
+// bundleFormat picks yaml when asked for by query or content type, json otherwise
+func bundleFormat(ctx *gin.Context) string {
+	if format := ctx.Query("format"); format != "" {
+		if format == "yml" {
+			return "yaml"
+		}
+		return format
+	}
+	if strings.Contains(ctx.ContentType(), "yaml") {
+		return "yaml"
+	}
+	return "json"
+}
+
+func dryRunParam(ctx *gin.Context) (bool, error) {
+	value := ctx.Query("dry_run")
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,225 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+
+	"base/packages/gamification/models"
+)
+
+// ErrUnknownTable is returned when a CSV table name is not part of the catalog
+var ErrUnknownTable = errors.New("unknown catalog table")
+
+// ExportCSV writes one catalog table as CSV
+func (s *CatalogService) ExportCSV(table string, w io.Writer) error {
+	bundle, err := s.Export()
+	if err != nil {
+		return err
+	}
+
+	section, err := bundleSection(bundle, table)
+	if err != nil {
+		return err
+	}
+
+	return writeCSV(w, section)
+}
+
+// ImportCSV imports one catalog table from CSV
+func (s *CatalogService) ImportCSV(table string, r io.Reader, dryRun bool) (*models.CatalogImportResult, error) {
+	bundle := &models.CatalogBundle{}
+	section, err := bundleSection(bundle, table)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := readCSV(r, section); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBundle, err.Error())
+	}
+
+	return s.Import(bundle, dryRun)
+}
+
+// bundleSection returns a pointer to the definition slice of a table
+func bundleSection(bundle *models.CatalogBundle, table string) (interface{}, error) {
+	switch table {
+	case "point-types":
+		return &bundle.PointTypes, nil
+	case "activity-types":
+		return &bundle.ActivityTypes, nil
+	case "activity-type-rewards":
+		return &bundle.ActivityTypeRewards, nil
+	case "achievements":
+		return &bundle.Achievements, nil
+	case "achievement-criteria":
+		return &bundle.AchievementCriteria, nil
+	case "levels":
+		return &bundle.Levels, nil
+	case "challenges":
+		return &bundle.Challenges, nil
+	case "leaderboards":
+		return &bundle.Leaderboards, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownTable, table)
+}
+
+// writeCSV writes a slice of definitions with a header made of the json field names
+func writeCSV(w io.Writer, section interface{}) error {
+	rows := reflect.ValueOf(section).Elem()
+	rowType := rows.Type().Elem()
+
+	writer := csv.NewWriter(w)
+	header := make([]string, rowType.NumField())
+	for i := range header {
+		header[i] = fieldName(rowType.Field(i))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for i := 0; i < rows.Len(); i++ {
+		record := make([]string, rowType.NumField())
+		for j := range record {
+			record[j] = formatValue(rows.Index(i).Field(j))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// readCSV appends one definition per CSV record to the slice section points to.
+// Columns are matched by header name so their order does not matter.
+func readCSV(r io.Reader, section interface{}) error {
+	rows := reflect.ValueOf(section).Elem()
+	rowType := rows.Type().Elem()
+
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+
+	fields := make(map[string]int)
+	for i := 0; i < rowType.NumField(); i++ {
+		fields[fieldName(rowType.Field(i))] = i
+	}
+	columns := make([]int, len(header))
+	for i, name := range header {
+		index, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("unknown column %q", name)
+		}
+		columns[i] = index
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		row := reflect.New(rowType).Elem()
+		for i, raw := range record {
+			if err := parseValue(row.Field(columns[i]), raw); err != nil {
+				return fmt.Errorf("line %d, column %q: %w", line, header[i], err)
+			}
+		}
+		rows.Set(reflect.Append(rows, row))
+	}
+}
+
+func formatValue(value reflect.Value) string {
+	if t, ok := value.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Struct:
+		// Nested values, such as the rewards of a leaderboard, go in one JSON cell
+		if value.IsZero() {
+			return ""
+		}
+		if (value.Kind() == reflect.Slice || value.Kind() == reflect.Map) && value.Len() == 0 {
+			return ""
+		}
+		data, _ := json.Marshal(value.Interface())
+		return string(data)
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	}
+	return value.String()
+}
+
+func parseValue(field reflect.Value, raw string) error {
+	if _, ok := field.Interface().(time.Time); ok {
+		if raw == "" {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Struct:
+		if raw == "" {
+			return nil
+		}
+		return json.Unmarshal([]byte(raw), field.Addr().Interface())
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		if raw == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		if raw == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint64:
+		if raw == "" {
+			return nil
+		}
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
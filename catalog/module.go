@@ -0,0 +1,49 @@
+package catalog
+
+import (
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/module"
+	"base/core/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Module struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Controller *CatalogController
+	Service    *CatalogService
+	Logger     *logger.Logger
+	Storage    *storage.ActiveStorage
+}
+
+func NewCatalogModule(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, storage *storage.ActiveStorage) module.Module {
+
+	service := NewCatalogService(db, emitter, storage, log)
+	controller := NewCatalogController(service, storage)
+
+	m := &Module{
+		DB:         db,
+		Service:    service,
+		Controller: controller,
+		Logger:     &log,
+		Storage:    storage,
+	}
+
+	return m
+}
+
+func (m *Module) Routes(router *gin.RouterGroup) {
+	m.Controller.Routes(router)
+}
+
+// Migrate has nothing to do, the catalog only works on the other modules' tables
+func (m *Module) Migrate() error {
+	return nil
+}
+
+func (m *Module) GetModels() []interface{} {
+	return []interface{}{}
+}
@@ -0,0 +1,954 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+	"base/core/types"
+	"base/packages/gamification/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ImportCatalogEvent = "catalog.import"
+)
+
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionDelete    = "delete"
+	ActionUnchanged = "unchanged"
+)
+
+var (
+	// ErrInvalidBundle is returned when a bundle references unknown keys or misses required values
+	ErrInvalidBundle = errors.New("invalid catalog bundle")
+
+	// errDryRun rolls back the import transaction once the diff has been computed
+	errDryRun = errors.New("dry run")
+)
+
+type CatalogService struct {
+	DB      *gorm.DB
+	Emitter *emitter.Emitter
+	Storage *storage.ActiveStorage
+	Logger  logger.Logger
+}
+
+func NewCatalogService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *CatalogService {
+	return &CatalogService{
+		DB:      db,
+		Emitter: emitter,
+		Storage: storage,
+		Logger:  logger,
+	}
+}
+
+func (s *CatalogService) WithContext(ctx context.Context) *CatalogService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
+// Export returns every catalog definition as a single bundle
+func (s *CatalogService) Export() (*models.CatalogBundle, error) {
+	bundle := &models.CatalogBundle{}
+
+	var pointTypes []*models.PointType
+	if err := s.DB.Order("id").Find(&pointTypes).Error; err != nil {
+		return nil, s.exportError("point types", err)
+	}
+	for _, item := range pointTypes {
+		bundle.PointTypes = append(bundle.PointTypes, pointTypeDefinition(item))
+	}
+
+	var activityTypes []*models.ActivityType
+	if err := s.DB.Order("id").Find(&activityTypes).Error; err != nil {
+		return nil, s.exportError("activity types", err)
+	}
+	for _, item := range activityTypes {
+		bundle.ActivityTypes = append(bundle.ActivityTypes, activityTypeDefinition(item))
+	}
+
+	var rewards []*models.ActivityTypeReward
+	if err := s.DB.Preload("PointType").Order("activity_type_id, id").Find(&rewards).Error; err != nil {
+		return nil, s.exportError("activity type rewards", err)
+	}
+	keys := typeKeys(pointTypes, activityTypes)
+	for _, item := range rewards {
+		activityKey, ok := keys.activityTypes[item.ActivityTypeId]
+		if !ok || item.PointType == nil {
+			continue
+		}
+		bundle.ActivityTypeRewards = append(bundle.ActivityTypeRewards, rewardDefinition(item, activityKey))
+	}
+
+	var achievements []*models.Achievement
+	if err := s.DB.Order("id").Find(&achievements).Error; err != nil {
+		return nil, s.exportError("achievements", err)
+	}
+	for _, item := range achievements {
+		bundle.Achievements = append(bundle.Achievements, achievementDefinition(item))
+	}
+
+	var criteria []*models.AchievementCriteria
+	if err := s.DB.Preload("Achievement").Preload("ActivityType").Order("achievement_id, id").Find(&criteria).Error; err != nil {
+		return nil, s.exportError("achievement criteria", err)
+	}
+	for _, item := range criteria {
+		// Criteria whose achievement or activity type is gone cannot be referenced by key
+		if item.Achievement == nil || item.ActivityType == nil {
+			continue
+		}
+		bundle.AchievementCriteria = append(bundle.AchievementCriteria, criteriaDefinition(item, item.Achievement.Key))
+	}
+
+	var levels []*models.Level
+	if err := s.DB.Order("level_number").Find(&levels).Error; err != nil {
+		return nil, s.exportError("levels", err)
+	}
+	for _, item := range levels {
+		bundle.Levels = append(bundle.Levels, levelDefinition(item))
+	}
+
+	// Challenges instantiated from a template are not part of the catalog
+	var challenges []*models.Challenge
+	if err := s.DB.Where("template_id IS NULL").Order("id").Find(&challenges).Error; err != nil {
+		return nil, s.exportError("challenges", err)
+	}
+	for _, item := range challenges {
+		bundle.Challenges = append(bundle.Challenges, challengeDefinition(item, keys))
+	}
+
+	var leaderboards []*models.Leaderboard
+	if err := preloadRewards(s.DB).Order("id").Find(&leaderboards).Error; err != nil {
+		return nil, s.exportError("leaderboards", err)
+	}
+	for _, item := range leaderboards {
+		bundle.Leaderboards = append(bundle.Leaderboards, leaderboardDefinition(item))
+	}
+
+	return bundle, nil
+}
+
+// Import upserts every definition of the bundle by key. Entries missing from
+// the bundle are left untouched, except for the rewards of an activity type
+// and the criteria of an achievement listed in the bundle, which are replaced
+// as a whole, as are the rewards of a leaderboard. With dryRun the import
+// runs inside a transaction that is rolled back, so the returned diff is
+// exactly what a real import would do.
+func (s *CatalogService) Import(bundle *models.CatalogBundle, dryRun bool) (*models.CatalogImportResult, error) {
+	result := &models.CatalogImportResult{
+		DryRun:  dryRun,
+		Changes: []models.CatalogChange{},
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.importBundle(tx, bundle, result); err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		s.Logger.Error("failed to import catalog", logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to import catalog: %w", err)
+	}
+
+	if !dryRun {
+		// Emit import event
+		s.Emitter.Emit(ImportCatalogEvent, result)
+	}
+
+	return result, nil
+}
+
+func (s *CatalogService) importBundle(tx *gorm.DB, bundle *models.CatalogBundle, result *models.CatalogImportResult) error {
+	for i, def := range bundle.PointTypes {
+		if err := requireKey("point_types", i, def.Key); err != nil {
+			return err
+		}
+		item := &models.PointType{}
+		found, err := findByKey(tx, item, &models.PointType{Key: def.Key})
+		if err != nil {
+			return err
+		}
+		var before interface{}
+		if found {
+			before = pointTypeDefinition(item)
+		}
+		if !recordChange(result, "point_types", def.Key, before, def) {
+			continue
+		}
+		item.Key = def.Key
+		item.Name = def.Name
+		item.Description = def.Description
+		item.Icon = def.Icon
+		item.IsXpSource = def.IsXpSource
+		item.ExpiresAfterDays = def.ExpiresAfterDays
+		item.DecayPercent = def.DecayPercent
+		item.DecayPeriodDays = def.DecayPeriodDays
+		item.IsTransferable = def.IsTransferable
+		item.DailyTransferLimit = def.DailyTransferLimit
+		item.OverdraftLimit = def.OverdraftLimit
+		item.ApprovalThreshold = def.ApprovalThreshold
+		if item.IsXpSource {
+			// Only one point type feeds user levels
+			if err := tx.Model(&models.PointType{}).Where("is_xp_source = ? AND id <> ?", true, item.Id).
+				Update("is_xp_source", false).Error; err != nil {
+				return err
+			}
+		}
+		if err := save(tx, item); err != nil {
+			return err
+		}
+		// Balances carry the overdraft limit for their check constraint
+		var overdrawn int64
+		if err := tx.Model(&models.UserPoint{}).
+			Where("point_type_id = ? AND current_balance < ?", item.Id, -item.OverdraftLimit).
+			Count(&overdrawn).Error; err != nil {
+			return err
+		}
+		if overdrawn > 0 {
+			return fmt.Errorf("%w: point_types %q: balances are overdrawn beyond overdraft_limit", ErrInvalidBundle, def.Key)
+		}
+		if err := tx.Model(&models.UserPoint{}).Where("point_type_id = ?", item.Id).
+			Update("overdraft_limit", item.OverdraftLimit).Error; err != nil {
+			return err
+		}
+	}
+
+	for i, def := range bundle.ActivityTypes {
+		if err := requireKey("activity_types", i, def.Key); err != nil {
+			return err
+		}
+		item := &models.ActivityType{}
+		found, err := findByKey(tx, item, &models.ActivityType{Key: def.Key})
+		if err != nil {
+			return err
+		}
+		var before interface{}
+		if found {
+			before = activityTypeDefinition(item)
+		}
+		if !recordChange(result, "activity_types", def.Key, before, def) {
+			continue
+		}
+		item.Key = def.Key
+		item.Name = def.Name
+		item.Description = def.Description
+		item.Category = def.Category
+		item.PointsValue = def.PointsValue
+		item.CooldownPeriod = def.CooldownPeriod
+		item.IsActive = def.IsActive
+		item.Limits = models.ActivityLimits{}
+		if def.Limits != nil {
+			item.Limits = *def.Limits
+		}
+		if err := save(tx, item); err != nil {
+			return err
+		}
+	}
+
+	if err := importRewards(tx, bundle.ActivityTypeRewards, result); err != nil {
+		return err
+	}
+
+	for i, def := range bundle.Achievements {
+		if err := requireKey("achievements", i, def.Key); err != nil {
+			return err
+		}
+		item := &models.Achievement{}
+		found, err := findByKey(tx, item, &models.Achievement{Key: def.Key})
+		if err != nil {
+			return err
+		}
+		var before interface{}
+		if found {
+			before = achievementDefinition(item)
+		}
+		if !recordChange(result, "achievements", def.Key, before, def) {
+			continue
+		}
+		item.Key = def.Key
+		item.Name = def.Name
+		item.Description = def.Description
+		item.Category = def.Category
+		item.DifficultyLevel = def.DifficultyLevel
+		item.IsHidden = def.IsHidden
+		item.IsActive = def.IsActive
+		if err := save(tx, item); err != nil {
+			return err
+		}
+	}
+
+	if err := importCriteria(tx, bundle.AchievementCriteria, result); err != nil {
+		return err
+	}
+
+	for i, def := range bundle.Levels {
+		if err := requireKey("levels", i, def.Key); err != nil {
+			return err
+		}
+		item := &models.Level{}
+		found, err := findByKey(tx, item, &models.Level{Key: def.Key})
+		if err != nil {
+			return err
+		}
+		var before interface{}
+		if found {
+			before = levelDefinition(item)
+		}
+		if !recordChange(result, "levels", def.Key, before, def) {
+			continue
+		}
+		item.Key = def.Key
+		item.LevelNumber = def.LevelNumber
+		item.XpRequired = def.XpRequired
+		item.Title = def.Title
+		item.Rewards = def.Rewards
+		if err := save(tx, item); err != nil {
+			return err
+		}
+	}
+
+	var keys catalogKeys
+	if len(bundle.Challenges) > 0 {
+		var pointTypes []*models.PointType
+		if err := tx.Find(&pointTypes).Error; err != nil {
+			return err
+		}
+		var activityTypes []*models.ActivityType
+		if err := tx.Find(&activityTypes).Error; err != nil {
+			return err
+		}
+		keys = typeKeys(pointTypes, activityTypes)
+	}
+	for i, def := range bundle.Challenges {
+		if err := requireKey("challenges", i, def.Key); err != nil {
+			return err
+		}
+		goal, err := challengeGoal(tx, def)
+		if err != nil {
+			return err
+		}
+		item := &models.Challenge{}
+		found, err := findByKey(tx, item, &models.Challenge{Key: def.Key})
+		if err != nil {
+			return err
+		}
+		var before interface{}
+		if found {
+			before = challengeDefinition(item, keys)
+		}
+		if !recordChange(result, "challenges", def.Key, before, def) {
+			continue
+		}
+		item.Key = def.Key
+		item.Name = def.Name
+		item.Description = def.Description
+		item.StartDate = types.DateTime{Time: def.StartDate}
+		item.EndDate = types.DateTime{Time: def.EndDate}
+		item.RewardType = def.RewardType
+		item.RewardValue = def.RewardValue
+		item.IsActive = def.IsActive
+		item.IsTeam = def.IsTeam
+		item.Goal = goal
+		if err := save(tx, item); err != nil {
+			return err
+		}
+	}
+
+	for i, def := range bundle.Leaderboards {
+		if err := requireKey("leaderboards", i, def.Key); err != nil {
+			return err
+		}
+		rewards, err := leaderboardRewards(tx, def)
+		if err != nil {
+			return err
+		}
+		item := &models.Leaderboard{}
+		found, err := findByKey(preloadRewards(tx), item, &models.Leaderboard{Key: def.Key})
+		if err != nil {
+			return err
+		}
+		var before interface{}
+		if found {
+			before = leaderboardDefinition(item)
+		}
+		if !recordChange(result, "leaderboards", def.Key, before, def) {
+			continue
+		}
+		item.Key = def.Key
+		item.Name = def.Name
+		item.Type = def.Type
+		item.Period = def.Period
+		item.ResetFrequency = def.ResetFrequency
+		item.IsActive = def.IsActive
+		item.Scope = def.Scope
+		if item.Scope == "" {
+			item.Scope = models.LeaderboardScopeUser
+		}
+		item.SegmentBy = def.SegmentBy
+		item.Rewards = nil
+		if err := save(tx, item); err != nil {
+			return err
+		}
+		// The rewards of a leaderboard are replaced as a whole
+		if err := tx.Where("leaderboard_id = ?", item.Id).Delete(&models.LeaderboardReward{}).Error; err != nil {
+			return err
+		}
+		for _, reward := range rewards {
+			reward.LeaderboardId = item.Id
+			if err := tx.Create(reward).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// challengeGoal resolves the activity and point type keys of the goal of a
+// challenge definition and checks that the goal is complete
+func challengeGoal(tx *gorm.DB, def models.ChallengeDefinition) (models.ChallengeGoal, error) {
+	goal := models.ChallengeGoal{}
+	if def.Goal == nil {
+		return goal, nil
+	}
+	switch def.Goal.Kind {
+	case models.ChallengeGoalActivityCount, models.ChallengeGoalPointsSum, models.ChallengeGoalActiveDays, models.ChallengeGoalStreak:
+	default:
+		return goal, fmt.Errorf("%w: challenges %q: unknown goal kind %q", ErrInvalidBundle, def.Key, def.Goal.Kind)
+	}
+	if def.Goal.Target < 1 {
+		return goal, fmt.Errorf("%w: challenges %q: goal target must be at least 1", ErrInvalidBundle, def.Key)
+	}
+	if def.Goal.Kind == models.ChallengeGoalActivityCount && def.Goal.ActivityType == "" {
+		return goal, fmt.Errorf("%w: challenges %q: %s needs an activity type", ErrInvalidBundle, def.Key, def.Goal.Kind)
+	}
+	if def.Goal.PointType != "" && def.Goal.Kind != models.ChallengeGoalPointsSum {
+		return goal, fmt.Errorf("%w: challenges %q: only %s takes a point type", ErrInvalidBundle, def.Key, models.ChallengeGoalPointsSum)
+	}
+
+	goal.Kind = def.Goal.Kind
+	goal.Target = def.Goal.Target
+	goal.Filters = def.Goal.Filters
+	if def.Goal.ActivityType != "" {
+		activityType := &models.ActivityType{}
+		if err := findKey(tx, activityType, &models.ActivityType{Key: def.Goal.ActivityType}, "activity type", def.Goal.ActivityType); err != nil {
+			return goal, err
+		}
+		goal.ActivityTypeId = &activityType.Id
+	}
+	if def.Goal.PointType != "" {
+		pointType := &models.PointType{}
+		if err := findKey(tx, pointType, &models.PointType{Key: def.Goal.PointType}, "point type", def.Goal.PointType); err != nil {
+			return goal, err
+		}
+		goal.PointTypeId = &pointType.Id
+	}
+	return goal, nil
+}
+
+// leaderboardRewards resolves the point type and achievement keys of the
+// rewards of a leaderboard definition
+func leaderboardRewards(tx *gorm.DB, def models.LeaderboardDefinition) ([]*models.LeaderboardReward, error) {
+	rewards := make([]*models.LeaderboardReward, 0, len(def.Rewards))
+	for i, rewardDef := range def.Rewards {
+		if rewardDef.MaxRank < 1 || (rewardDef.PointType != "") != (rewardDef.Amount > 0) ||
+			(rewardDef.PointType == "" && rewardDef.Achievement == "") {
+			return nil, fmt.Errorf("%w: leaderboards %q: rewards[%d] needs a max_rank and a point_type with an amount or an achievement", ErrInvalidBundle, def.Key, i)
+		}
+		reward := &models.LeaderboardReward{MaxRank: rewardDef.MaxRank, Amount: rewardDef.Amount}
+		if rewardDef.PointType != "" {
+			pointType := &models.PointType{}
+			if err := findKey(tx, pointType, &models.PointType{Key: rewardDef.PointType}, "point type", rewardDef.PointType); err != nil {
+				return nil, err
+			}
+			reward.PointTypeId = &pointType.Id
+		}
+		if rewardDef.Achievement != "" {
+			achievement := &models.Achievement{}
+			if err := findKey(tx, achievement, &models.Achievement{Key: rewardDef.Achievement}, "achievement", rewardDef.Achievement); err != nil {
+				return nil, err
+			}
+			reward.AchievementId = &achievement.Id
+		}
+		rewards = append(rewards, reward)
+	}
+	return rewards, nil
+}
+
+// importCriteria replaces the criteria of every achievement referenced in defs
+func importCriteria(tx *gorm.DB, defs []models.AchievementCriteriaDefinition, result *models.CatalogImportResult) error {
+	var achievementKeys []string
+	grouped := make(map[string][]models.AchievementCriteriaDefinition)
+	for i, def := range defs {
+		if def.Achievement == "" || def.ActivityType == "" {
+			return fmt.Errorf("%w: achievement_criteria[%d]: achievement and activity_type are required", ErrInvalidBundle, i)
+		}
+		if _, ok := grouped[def.Achievement]; !ok {
+			achievementKeys = append(achievementKeys, def.Achievement)
+		}
+		grouped[def.Achievement] = append(grouped[def.Achievement], def)
+	}
+
+	for _, achievementKey := range achievementKeys {
+		achievement := &models.Achievement{}
+		if err := tx.Where(&models.Achievement{Key: achievementKey}).First(achievement).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: unknown achievement %q", ErrInvalidBundle, achievementKey)
+			}
+			return err
+		}
+
+		var existing []*models.AchievementCriteria
+		if err := tx.Preload("ActivityType").Where("achievement_id = ?", achievement.Id).Find(&existing).Error; err != nil {
+			return err
+		}
+		stored := make(map[string]*models.AchievementCriteria)
+		for _, item := range existing {
+			activityKey := ""
+			if item.ActivityType != nil {
+				activityKey = item.ActivityType.Key
+			}
+			stored[activityKey] = item
+		}
+
+		for _, def := range grouped[achievementKey] {
+			activityType := &models.ActivityType{}
+			if err := tx.Where(&models.ActivityType{Key: def.ActivityType}).First(activityType).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: unknown activity type %q", ErrInvalidBundle, def.ActivityType)
+				}
+				return err
+			}
+
+			item, found := stored[def.ActivityType]
+			delete(stored, def.ActivityType)
+			var before interface{}
+			if found {
+				before = criteriaDefinition(item, achievementKey)
+			} else {
+				item = &models.AchievementCriteria{AchievementId: achievement.Id}
+			}
+			if !recordChange(result, "achievement_criteria", achievementKey+"/"+def.ActivityType, before, def) {
+				continue
+			}
+			item.ActivityTypeId = activityType.Id
+			item.ActivityType = nil
+			item.RequiredCount = def.RequiredCount
+			item.TimeFrame = def.TimeFrame
+			if err := tx.Save(item).Error; err != nil {
+				return err
+			}
+		}
+
+		// Whatever is left is no longer part of the achievement
+		leftover := make([]string, 0, len(stored))
+		for activityKey := range stored {
+			leftover = append(leftover, activityKey)
+		}
+		sort.Strings(leftover)
+		for _, activityKey := range leftover {
+			result.Deleted++
+			result.Changes = append(result.Changes, models.CatalogChange{
+				Table:  "achievement_criteria",
+				Key:    achievementKey + "/" + activityKey,
+				Action: ActionDelete,
+			})
+			if err := tx.Delete(stored[activityKey]).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// importRewards replaces the rewards of every activity type referenced in defs
+func importRewards(tx *gorm.DB, defs []models.ActivityTypeRewardDefinition, result *models.CatalogImportResult) error {
+	var activityKeys []string
+	grouped := make(map[string][]models.ActivityTypeRewardDefinition)
+	for i, def := range defs {
+		if def.ActivityType == "" || def.PointType == "" {
+			return fmt.Errorf("%w: activity_type_rewards[%d]: activity_type and point_type are required", ErrInvalidBundle, i)
+		}
+		if _, ok := grouped[def.ActivityType]; !ok {
+			activityKeys = append(activityKeys, def.ActivityType)
+		}
+		grouped[def.ActivityType] = append(grouped[def.ActivityType], def)
+	}
+
+	for _, activityKey := range activityKeys {
+		activityType := &models.ActivityType{}
+		if err := tx.Where(&models.ActivityType{Key: activityKey}).First(activityType).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: unknown activity type %q", ErrInvalidBundle, activityKey)
+			}
+			return err
+		}
+
+		var existing []*models.ActivityTypeReward
+		if err := tx.Preload("PointType").Where("activity_type_id = ?", activityType.Id).Find(&existing).Error; err != nil {
+			return err
+		}
+		stored := make(map[string]*models.ActivityTypeReward)
+		for _, item := range existing {
+			pointKey := ""
+			if item.PointType != nil {
+				pointKey = item.PointType.Key
+			}
+			stored[pointKey] = item
+		}
+
+		for _, def := range grouped[activityKey] {
+			pointType := &models.PointType{}
+			if err := tx.Where(&models.PointType{Key: def.PointType}).First(pointType).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: unknown point type %q", ErrInvalidBundle, def.PointType)
+				}
+				return err
+			}
+
+			item, found := stored[def.PointType]
+			delete(stored, def.PointType)
+			var before interface{}
+			if found {
+				before = rewardDefinition(item, activityKey)
+			} else {
+				item = &models.ActivityTypeReward{ActivityTypeId: activityType.Id}
+			}
+			if !recordChange(result, "activity_type_rewards", activityKey+"/"+def.PointType, before, def) {
+				continue
+			}
+			item.PointTypeId = pointType.Id
+			item.PointType = nil
+			item.Amount = def.Amount
+			if err := tx.Save(item).Error; err != nil {
+				return err
+			}
+		}
+
+		leftover := make([]string, 0, len(stored))
+		for pointKey := range stored {
+			leftover = append(leftover, pointKey)
+		}
+		sort.Strings(leftover)
+		for _, pointKey := range leftover {
+			result.Deleted++
+			result.Changes = append(result.Changes, models.CatalogChange{
+				Table:  "activity_type_rewards",
+				Key:    activityKey + "/" + pointKey,
+				Action: ActionDelete,
+			})
+			if err := tx.Delete(stored[pointKey]).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *CatalogService) exportError(what string, err error) error {
+	s.Logger.Error("failed to export "+what, logger.String("error", err.Error()))
+	return fmt.Errorf("failed to export %s: %w", what, err)
+}
+
+func requireKey(table string, index int, key string) error {
+	if key == "" {
+		return fmt.Errorf("%w: %s[%d]: key is required", ErrInvalidBundle, table, index)
+	}
+	return nil
+}
+
+// findByKey loads the row matching cond into item. It prefers the live row
+// and falls back to the latest deleted one, so that importing a deleted key
+// revives it instead of leaving the deleted rows to pile up.
+func findByKey(tx *gorm.DB, item interface{}, cond interface{}) (bool, error) {
+	err := tx.Where(cond).First(item).Error
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	err = tx.Unscoped().Where(cond).Last(item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
+// catalogKeys maps the ids of point and activity types to their keys
+type catalogKeys struct {
+	pointTypes    map[uint]string
+	activityTypes map[uint]string
+}
+
+func typeKeys(pointTypes []*models.PointType, activityTypes []*models.ActivityType) catalogKeys {
+	keys := catalogKeys{pointTypes: make(map[uint]string), activityTypes: make(map[uint]string)}
+	for _, item := range pointTypes {
+		keys.pointTypes[item.Id] = item.Key
+	}
+	for _, item := range activityTypes {
+		keys.activityTypes[item.Id] = item.Key
+	}
+	return keys
+}
+
+// findKey loads the live row matching cond into item, which a definition
+// references by key
+func findKey(tx *gorm.DB, item interface{}, cond interface{}, what, key string) error {
+	err := tx.Where(cond).First(item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: unknown %s %q", ErrInvalidBundle, what, key)
+	}
+	return err
+}
+
+// preloadRewards loads the rewards of leaderboards with the point types and
+// achievements they reference
+func preloadRewards(db *gorm.DB) *gorm.DB {
+	return db.Preload("Rewards", func(db *gorm.DB) *gorm.DB {
+		return db.Order("max_rank, id")
+	}).Preload("Rewards.PointType").Preload("Rewards.Achievement")
+}
+
+func save(tx *gorm.DB, item interface{}) error {
+	reflect.ValueOf(item).Elem().FieldByName("DeletedAt").Set(reflect.ValueOf(gorm.DeletedAt{}))
+	return tx.Unscoped().Save(item).Error
+}
+
+// recordChange adds the diff between before and after to the result and
+// reports whether the entry needs to be written. A nil before means the
+// entry does not exist yet.
+func recordChange(result *models.CatalogImportResult, table, key string, before, after interface{}) bool {
+	change := models.CatalogChange{Table: table, Key: key}
+	change.Fields = diffDefinitions(before, after)
+
+	switch {
+	case before == nil:
+		change.Action = ActionCreate
+		result.Created++
+	case len(change.Fields) == 0:
+		change.Action = ActionUnchanged
+		result.Unchanged++
+	default:
+		change.Action = ActionUpdate
+		result.Updated++
+	}
+
+	result.Changes = append(result.Changes, change)
+	return change.Action != ActionUnchanged
+}
+
+// diffDefinitions lists the fields whose values differ between two
+// definitions of the same type. A nil before is compared as the zero value.
+func diffDefinitions(before, after interface{}) []models.CatalogFieldChange {
+	afterValue := reflect.ValueOf(after)
+	beforeValue := reflect.Zero(afterValue.Type())
+	if before != nil {
+		beforeValue = reflect.ValueOf(before)
+	}
+
+	var changes []models.CatalogFieldChange
+	for i := 0; i < afterValue.NumField(); i++ {
+		oldField := beforeValue.Field(i).Interface()
+		newField := afterValue.Field(i).Interface()
+		if equalValues(oldField, newField) {
+			continue
+		}
+		var old interface{}
+		if before != nil {
+			old = oldField
+		}
+		changes = append(changes, models.CatalogFieldChange{
+			Field:  fieldName(afterValue.Type().Field(i)),
+			Before: old,
+			After:  newField,
+		})
+	}
+	return changes
+}
+
+func equalValues(a, b interface{}) bool {
+	if at, ok := a.(time.Time); ok {
+		return at.Equal(b.(time.Time))
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// fieldName returns the json name of a definition field
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func pointTypeDefinition(item *models.PointType) models.PointTypeDefinition {
+	return models.PointTypeDefinition{
+		Key:                item.Key,
+		Name:               item.Name,
+		Description:        item.Description,
+		Icon:               item.Icon,
+		IsXpSource:         item.IsXpSource,
+		ExpiresAfterDays:   item.ExpiresAfterDays,
+		DecayPercent:       item.DecayPercent,
+		DecayPeriodDays:    item.DecayPeriodDays,
+		IsTransferable:     item.IsTransferable,
+		DailyTransferLimit: item.DailyTransferLimit,
+		OverdraftLimit:     item.OverdraftLimit,
+		ApprovalThreshold:  item.ApprovalThreshold,
+	}
+}
+
+func activityTypeDefinition(item *models.ActivityType) models.ActivityTypeDefinition {
+	def := models.ActivityTypeDefinition{
+		Key:            item.Key,
+		Name:           item.Name,
+		Description:    item.Description,
+		Category:       item.Category,
+		PointsValue:    item.PointsValue,
+		CooldownPeriod: item.CooldownPeriod,
+		IsActive:       item.IsActive,
+	}
+	if item.Limits.IsSet() {
+		limits := item.Limits
+		if len(limits.Metadata) == 0 {
+			limits.Metadata = nil
+		}
+		def.Limits = &limits
+	}
+	return def
+}
+
+func rewardDefinition(item *models.ActivityTypeReward, activityKey string) models.ActivityTypeRewardDefinition {
+	pointKey := ""
+	if item.PointType != nil {
+		pointKey = item.PointType.Key
+	}
+	return models.ActivityTypeRewardDefinition{
+		ActivityType: activityKey,
+		PointType:    pointKey,
+		Amount:       item.Amount,
+	}
+}
+
+func achievementDefinition(item *models.Achievement) models.AchievementDefinition {
+	return models.AchievementDefinition{
+		Key:             item.Key,
+		Name:            item.Name,
+		Description:     item.Description,
+		Category:        item.Category,
+		DifficultyLevel: item.DifficultyLevel,
+		IsHidden:        item.IsHidden,
+		IsActive:        item.IsActive,
+	}
+}
+
+func criteriaDefinition(item *models.AchievementCriteria, achievementKey string) models.AchievementCriteriaDefinition {
+	activityKey := ""
+	if item.ActivityType != nil {
+		activityKey = item.ActivityType.Key
+	}
+	return models.AchievementCriteriaDefinition{
+		Achievement:   achievementKey,
+		ActivityType:  activityKey,
+		RequiredCount: item.RequiredCount,
+		TimeFrame:     item.TimeFrame,
+	}
+}
+
+func levelDefinition(item *models.Level) models.LevelDefinition {
+	return models.LevelDefinition{
+		Key:         item.Key,
+		LevelNumber: item.LevelNumber,
+		XpRequired:  item.XpRequired,
+		Title:       item.Title,
+		Rewards:     item.Rewards,
+	}
+}
+
+func challengeDefinition(item *models.Challenge, keys catalogKeys) models.ChallengeDefinition {
+	def := models.ChallengeDefinition{
+		Key:         item.Key,
+		Name:        item.Name,
+		Description: item.Description,
+		StartDate:   item.StartDate.Time,
+		EndDate:     item.EndDate.Time,
+		RewardType:  item.RewardType,
+		RewardValue: item.RewardValue,
+		IsActive:    item.IsActive,
+		IsTeam:      item.IsTeam,
+	}
+	if item.Goal.IsSet() {
+		def.Goal = &models.ChallengeGoalDefinition{
+			Kind:   item.Goal.Kind,
+			Target: item.Goal.Target,
+		}
+		if item.Goal.ActivityTypeId != nil {
+			def.Goal.ActivityType = keys.activityTypes[*item.Goal.ActivityTypeId]
+		}
+		if item.Goal.PointTypeId != nil {
+			def.Goal.PointType = keys.pointTypes[*item.Goal.PointTypeId]
+		}
+		if len(item.Goal.Filters) > 0 {
+			def.Goal.Filters = item.Goal.Filters
+		}
+	}
+	return def
+}
+
+func leaderboardDefinition(item *models.Leaderboard) models.LeaderboardDefinition {
+	return models.LeaderboardDefinition{
+		Key:            item.Key,
+		Name:           item.Name,
+		Type:           item.Type,
+		Period:         item.Period,
+		ResetFrequency: item.ResetFrequency,
+		IsActive:       item.IsActive,
+		Scope:          item.Scope,
+		SegmentBy:      item.SegmentBy,
+		Rewards:        rewardDefinitions(item.Rewards),
+	}
+}
+
+// rewardDefinitions returns the portable form of the rewards of a
+// leaderboard, leaving out those whose point type or achievement is gone
+func rewardDefinitions(rewards []*models.LeaderboardReward) []models.LeaderboardRewardDefinition {
+	var defs []models.LeaderboardRewardDefinition
+	for _, item := range rewards {
+		def := models.LeaderboardRewardDefinition{MaxRank: item.MaxRank, Amount: item.Amount}
+		if item.PointTypeId != nil {
+			if item.PointType == nil {
+				continue
+			}
+			def.PointType = item.PointType.Key
+		}
+		if item.AchievementId != nil {
+			if item.Achievement == nil {
+				continue
+			}
+			def.Achievement = item.Achievement.Key
+		}
+		defs = append(defs, def)
+	}
+	return defs
+}
@@ -0,0 +1,224 @@
+package catalog_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"base/core/emitter"
+	"base/packages/gamification/catalog"
+	"base/packages/gamification/internal/testdb"
+	"base/packages/gamification/models"
+)
+
+func newCatalog(t *testing.T) *catalog.CatalogService {
+	t.Helper()
+	db := testdb.Open(t, &models.PointType{}, &models.UserPoint{}, &models.ActivityType{}, &models.ActivityTypeReward{},
+		&models.Achievement{}, &models.AchievementCriteria{}, &models.Level{}, &models.Challenge{},
+		&models.Leaderboard{}, &models.LeaderboardReward{})
+	return catalog.NewCatalogService(testdb.ForTenant(db, 1), &emitter.Emitter{}, nil, testdb.Logger{})
+}
+
+func importBundle(t *testing.T, service *catalog.CatalogService, bundle *models.CatalogBundle) *models.CatalogImportResult {
+	t.Helper()
+	result, err := service.Import(bundle, false)
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	return result
+}
+
+// assertUnchanged checks that importing bundle again changes nothing
+func assertUnchanged(t *testing.T, service *catalog.CatalogService, bundle *models.CatalogBundle) {
+	t.Helper()
+	result, err := service.Import(bundle, true)
+	if err != nil {
+		t.Fatalf("failed to import again: %v", err)
+	}
+	if result.Created+result.Updated+result.Deleted != 0 {
+		t.Errorf("importing again changes %+v, want nothing", result.Changes)
+	}
+}
+
+func TestLeaderboardRewardsRoundTrip(t *testing.T) {
+	service := newCatalog(t)
+	bundle := &models.CatalogBundle{
+		PointTypes:   []models.PointTypeDefinition{{Key: "coins", Name: "Coins"}},
+		Achievements: []models.AchievementDefinition{{Key: "champion", Name: "Champion"}},
+		Leaderboards: []models.LeaderboardDefinition{{
+			Key:   "weekly",
+			Name:  "Weekly",
+			Scope: models.LeaderboardScopeUser,
+			Rewards: []models.LeaderboardRewardDefinition{
+				{MaxRank: 1, PointType: "coins", Amount: 100, Achievement: "champion"},
+				{MaxRank: 3, PointType: "coins", Amount: 20},
+			},
+		}},
+	}
+	importBundle(t, service, bundle)
+
+	exported, err := service.Export()
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if len(exported.Leaderboards) != 1 || !reflect.DeepEqual(exported.Leaderboards[0].Rewards, bundle.Leaderboards[0].Rewards) {
+		t.Errorf("exported leaderboards = %+v, want the rewards %+v", exported.Leaderboards, bundle.Leaderboards[0].Rewards)
+	}
+	assertUnchanged(t, service, exported)
+
+	// The rewards are replaced as a whole
+	bundle.Leaderboards[0].Rewards = bundle.Leaderboards[0].Rewards[1:]
+	importBundle(t, service, bundle)
+	if exported, err = service.Export(); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if got := exported.Leaderboards[0].Rewards; !reflect.DeepEqual(got, bundle.Leaderboards[0].Rewards) {
+		t.Errorf("rewards = %+v, want %+v", got, bundle.Leaderboards[0].Rewards)
+	}
+
+	// A CSV cell holds the rewards as JSON
+	var csv bytes.Buffer
+	if err := service.ExportCSV("leaderboards", &csv); err != nil {
+		t.Fatalf("failed to export csv: %v", err)
+	}
+	result, err := service.ImportCSV("leaderboards", &csv, true)
+	if err != nil {
+		t.Fatalf("failed to import csv: %v", err)
+	}
+	if result.Unchanged != 1 {
+		t.Errorf("csv import changes %+v, want nothing", result.Changes)
+	}
+}
+
+func TestLeaderboardRewardsUnknownKey(t *testing.T) {
+	service := newCatalog(t)
+	_, err := service.Import(&models.CatalogBundle{
+		Leaderboards: []models.LeaderboardDefinition{{
+			Key:     "weekly",
+			Name:    "Weekly",
+			Rewards: []models.LeaderboardRewardDefinition{{MaxRank: 1, PointType: "coins", Amount: 100}},
+		}},
+	}, false)
+	if !errors.Is(err, catalog.ErrInvalidBundle) {
+		t.Errorf("err = %v, want %v", err, catalog.ErrInvalidBundle)
+	}
+}
+
+func TestChallengeGoalRoundTrip(t *testing.T) {
+	service := newCatalog(t)
+	startDate := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	bundle := &models.CatalogBundle{
+		PointTypes:    []models.PointTypeDefinition{{Key: "coins", Name: "Coins"}},
+		ActivityTypes: []models.ActivityTypeDefinition{{Key: "run-logged", Name: "Run logged", IsActive: true}},
+		Challenges: []models.ChallengeDefinition{
+			{
+				Key:       "ten-runs",
+				Name:      "Ten runs",
+				StartDate: startDate,
+				EndDate:   startDate.AddDate(0, 1, 0),
+				Goal: &models.ChallengeGoalDefinition{
+					Kind:         models.ChallengeGoalActivityCount,
+					ActivityType: "run-logged",
+					Target:       10,
+					Filters:      map[string]string{"distance_km": "5"},
+				},
+			},
+			{
+				Key:       "coin-rush",
+				Name:      "Coin rush",
+				StartDate: startDate,
+				EndDate:   startDate.AddDate(0, 1, 0),
+				Goal:      &models.ChallengeGoalDefinition{Kind: models.ChallengeGoalPointsSum, PointType: "coins", Target: 500},
+			},
+		},
+	}
+	importBundle(t, service, bundle)
+
+	exported, err := service.Export()
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if len(exported.Challenges) != 2 {
+		t.Fatalf("exported %d challenges, want 2", len(exported.Challenges))
+	}
+	for i, def := range exported.Challenges {
+		if !reflect.DeepEqual(def.Goal, bundle.Challenges[i].Goal) {
+			t.Errorf("goal of %s = %+v, want %+v", def.Key, def.Goal, bundle.Challenges[i].Goal)
+		}
+	}
+	assertUnchanged(t, service, exported)
+
+	// A definition without a goal clears it
+	bundle.Challenges[1].Goal = nil
+	importBundle(t, service, bundle)
+	if exported, err = service.Export(); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if goal := exported.Challenges[1].Goal; goal != nil {
+		t.Errorf("goal of %s = %+v, want none", exported.Challenges[1].Key, goal)
+	}
+}
+
+func TestChallengeGoalNeedsActivityType(t *testing.T) {
+	service := newCatalog(t)
+	_, err := service.Import(&models.CatalogBundle{
+		Challenges: []models.ChallengeDefinition{{
+			Key:  "ten-runs",
+			Name: "Ten runs",
+			Goal: &models.ChallengeGoalDefinition{Kind: models.ChallengeGoalActivityCount, Target: 10},
+		}},
+	}, false)
+	if !errors.Is(err, catalog.ErrInvalidBundle) {
+		t.Errorf("err = %v, want %v", err, catalog.ErrInvalidBundle)
+	}
+}
+
+func TestActivityTypeLimitsRoundTrip(t *testing.T) {
+	service := newCatalog(t)
+	max := 50.0
+	bundle := &models.CatalogBundle{
+		ActivityTypes: []models.ActivityTypeDefinition{
+			{
+				Key:      "run-logged",
+				Name:     "Run logged",
+				IsActive: true,
+				Limits: &models.ActivityLimits{
+					BurstCount:  5,
+					BurstWindow: 60,
+					RateFactor:  3,
+					Metadata:    map[string]models.MetadataLimit{"distance_km": {Max: &max}},
+				},
+			},
+			{Key: "lesson-completed", Name: "Lesson completed", IsActive: true},
+		},
+	}
+	importBundle(t, service, bundle)
+
+	exported, err := service.Export()
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if len(exported.ActivityTypes) != 2 {
+		t.Fatalf("exported %d activity types, want 2", len(exported.ActivityTypes))
+	}
+	for i, def := range exported.ActivityTypes {
+		if !reflect.DeepEqual(def.Limits, bundle.ActivityTypes[i].Limits) {
+			t.Errorf("limits of %s = %+v, want %+v", def.Key, def.Limits, bundle.ActivityTypes[i].Limits)
+		}
+	}
+	assertUnchanged(t, service, exported)
+
+	var csv bytes.Buffer
+	if err := service.ExportCSV("activity-types", &csv); err != nil {
+		t.Fatalf("failed to export csv: %v", err)
+	}
+	result, err := service.ImportCSV("activity-types", &csv, true)
+	if err != nil {
+		t.Fatalf("failed to import csv: %v", err)
+	}
+	if result.Unchanged != 2 {
+		t.Errorf("csv import changes %+v, want nothing", result.Changes)
+	}
+}
@@ -0,0 +1,295 @@
+package challenge_templates
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"base/core/storage"
+	"base/packages/gamification/access"
+	"base/packages/gamification/challenges"
+	"base/packages/gamification/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChallengeTemplateController struct {
+	Service *ChallengeTemplateService
+	Storage *storage.ActiveStorage
+}
+
+func NewChallengeTemplateController(service *ChallengeTemplateService, storage *storage.ActiveStorage) *ChallengeTemplateController {
+	return &ChallengeTemplateController{
+		Service: service,
+		Storage: storage,
+	}
+}
+
+func (c *ChallengeTemplateController) Routes(router *gin.RouterGroup) {
+	// Main CRUD endpoints
+	router.GET("/challenge-templates", c.List)        // Paginated list
+	router.GET("/challenge-templates/all", c.ListAll) // Unpaginated list
+	router.GET("/challenge-templates/:id", c.Get)
+	router.GET("/challenge-templates/by-key/:key", c.GetByKey)
+	router.POST("/challenge-templates", access.Require(access.RoleAdmin), c.Create)
+	router.PUT("/challenge-templates/:id", access.Require(access.RoleAdmin), c.Update)
+	router.DELETE("/challenge-templates/:id", access.Require(access.RoleAdmin), c.Delete)
+
+	// File/Image attachment endpoints
+
+	// HasMany relation endpoints
+}
+
+// CreateChallengeTemplate godoc
+// @Summary Create a new ChallengeTemplate
+// @Description Create a new ChallengeTemplate with the input payload
+// @Tags ChallengeTemplate
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param challenge-templates body models.CreateChallengeTemplateRequest true "Create ChallengeTemplate request"
+// @Success 201 {object} models.ChallengeTemplateResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /challenge-templates [post]
+func (c *ChallengeTemplateController) Create(ctx *gin.Context) {
+	var req models.CreateChallengeTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
+	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, models.ErrDuplicateKey) {
+			ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, ErrInvalidRecurrence) || errors.Is(err, challenges.ErrInvalidGoal) || errors.Is(err, models.ErrInvalidKey) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, item.ToResponse())
+}
+
+// GetChallengeTemplate godoc
+// @Summary Get a ChallengeTemplate
+// @Description Get a ChallengeTemplate by its id
+// @Tags ChallengeTemplate
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Challenge template id"
+// @Success 200 {object} models.ChallengeTemplateResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /challenge-templates/{id} [get]
+func (c *ChallengeTemplateController) Get(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// GetChallengeTemplateByKey godoc
+// @Summary Get a ChallengeTemplate by key
+// @Description Get a ChallengeTemplate by its stable key
+// @Tags ChallengeTemplate
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key path string true "Challenge template key"
+// @Success 200 {object} models.ChallengeTemplateResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /challenge-templates/by-key/{key} [get]
+func (c *ChallengeTemplateController) GetByKey(ctx *gin.Context) {
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetByKey(ctx.Param("key"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// ListChallengeTemplates godoc
+// @Summary List challenge-templates
+// @Description Get a list of challenge-templates
+// @Tags ChallengeTemplate
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /challenge-templates [get]
+func (c *ChallengeTemplateController) List(ctx *gin.Context) {
+	var page, limit *int
+
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid page number"})
+			return
+		}
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit number"})
+			return
+		}
+	}
+
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// ListAllChallengeTemplates godoc
+// @Summary List all challenge-templates without pagination
+// @Description Get a list of all challenge-templates without pagination
+// @Tags ChallengeTemplate
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /challenge-templates/all [get]
+func (c *ChallengeTemplateController) ListAll(ctx *gin.Context) {
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(nil, nil)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// UpdateChallengeTemplate godoc
+// @Summary Update a ChallengeTemplate
+// @Description Update a ChallengeTemplate by its id
+// @Tags ChallengeTemplate
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Challenge template id"
+// @Param challenge-templates body models.UpdateChallengeTemplateRequest true "Update ChallengeTemplate request"
+// @Success 200 {object} models.ChallengeTemplateResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /challenge-templates/{id} [put]
+func (c *ChallengeTemplateController) Update(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	var req models.UpdateChallengeTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
+	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, ErrInvalidRecurrence) || errors.Is(err, challenges.ErrInvalidGoal) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// DeleteChallengeTemplate godoc
+// @Summary Delete a ChallengeTemplate
+// @Description Delete a ChallengeTemplate by its id
+// @Tags ChallengeTemplate
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Challenge template id"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
+// @Failure 500 {object} ErrorResponse
+// @Router /challenge-templates/{id} [delete]
+func (c *ChallengeTemplateController) Delete(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, SuccessResponse{Message: "Item deleted successfully"})
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
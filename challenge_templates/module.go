@@ -0,0 +1,62 @@
+package challenge_templates
+
+import (
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/module"
+	"base/core/storage"
+	"base/packages/gamification/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Module struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Controller *ChallengeTemplateController
+	Service    *ChallengeTemplateService
+	Logger     *logger.Logger
+	Storage    *storage.ActiveStorage
+}
+
+func NewChallengeTemplateModule(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, storage *storage.ActiveStorage) module.Module {
+
+	service := NewChallengeTemplateService(db, emitter, storage, log)
+	controller := NewChallengeTemplateController(service, storage)
+
+	m := &Module{
+		DB:         db,
+		Service:    service,
+		Controller: controller,
+		Logger:     &log,
+		Storage:    storage,
+	}
+
+	return m
+}
+
+// Init starts the background job that instantiates templates and closes
+// expired instances
+func (m *Module) Init() error {
+	go m.Service.RunScheduler(ScheduleInterval)
+	return nil
+}
+
+func (m *Module) Routes(router *gin.RouterGroup) {
+	m.Controller.Routes(router)
+}
+
+func (m *Module) Migrate() error {
+	if err := models.MigrateKey(m.DB, &models.ChallengeTemplate{}, "name"); err != nil {
+		return err
+	}
+	if err := m.DB.AutoMigrate(&models.ChallengeTemplate{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.ChallengeTemplate{})
+}
+
+func (m *Module) GetModels() []interface{} {
+	return []interface{}{&models.ChallengeTemplate{}}
+}
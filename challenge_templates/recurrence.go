@@ -0,0 +1,160 @@
+package challenge_templates
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRecurrence is returned for a recurrence that is neither daily,
+// weekly nor a supported RRULE
+var ErrInvalidRecurrence = errors.New("invalid recurrence")
+
+// Recurrence frequencies
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Rule is a parsed recurrence: "daily", "weekly", or the RRULE subset
+// FREQ=DAILY|WEEKLY|MONTHLY with optional INTERVAL and, weekly, BYDAY, e.g.
+// "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH". Windows start at the time of day of
+// the template's StartsAt, in UTC.
+type Rule struct {
+	Freq     string
+	Interval int
+	ByDay    map[time.Weekday]bool
+}
+
+// ParseRule parses a template recurrence
+func ParseRule(recurrence string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	switch strings.ToLower(strings.TrimSpace(recurrence)) {
+	case "daily":
+		rule.Freq = FreqDaily
+		return rule, nil
+	case "weekly":
+		rule.Freq = FreqWeekly
+		return rule, nil
+	}
+
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(recurrence), "RRULE:"), ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return rule, fmt.Errorf("%w: %q", ErrInvalidRecurrence, part)
+		}
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(value)
+			if rule.Freq != FreqDaily && rule.Freq != FreqWeekly && rule.Freq != FreqMonthly {
+				return rule, fmt.Errorf("%w: unsupported FREQ %q", ErrInvalidRecurrence, value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return rule, fmt.Errorf("%w: INTERVAL must be a positive integer", ErrInvalidRecurrence)
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			rule.ByDay = make(map[time.Weekday]bool)
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := weekdays[strings.ToUpper(day)]
+				if !ok {
+					return rule, fmt.Errorf("%w: unsupported BYDAY %q", ErrInvalidRecurrence, day)
+				}
+				rule.ByDay[weekday] = true
+			}
+		default:
+			return rule, fmt.Errorf("%w: unsupported %s", ErrInvalidRecurrence, name)
+		}
+	}
+	if rule.Freq == "" {
+		return rule, fmt.Errorf("%w: FREQ is required", ErrInvalidRecurrence)
+	}
+	if rule.ByDay != nil && rule.Freq != FreqWeekly {
+		return rule, fmt.Errorf("%w: BYDAY needs FREQ=WEEKLY", ErrInvalidRecurrence)
+	}
+	return rule, nil
+}
+
+// Latest returns the start of the last window that started at or before t,
+// the first one starting at anchor. It reports false before anchor.
+func (r Rule) Latest(anchor time.Time, t time.Time) (time.Time, bool) {
+	anchor, t = anchor.UTC(), t.UTC()
+	if t.Before(anchor) {
+		return time.Time{}, false
+	}
+
+	switch {
+	case r.Freq == FreqMonthly:
+		months := (t.Year()-anchor.Year())*12 + int(t.Month()-anchor.Month())
+		// Months too short for the anchor's day have no window
+		for k := months / r.Interval * r.Interval; k >= 0; k -= r.Interval {
+			start := anchor.AddDate(0, k, 0)
+			if start.Day() == anchor.Day() && !start.After(t) {
+				return start, true
+			}
+		}
+		return time.Time{}, false
+
+	case r.Freq == FreqWeekly && r.ByDay != nil:
+		anchorWeek := monday(anchor)
+		day := time.Date(t.Year(), t.Month(), t.Day(), anchor.Hour(), anchor.Minute(), anchor.Second(), 0, time.UTC)
+		for d := 0; d < 7*r.Interval+1; d, day = d+1, day.AddDate(0, 0, -1) {
+			if day.After(t) {
+				continue
+			}
+			if day.Before(anchor) {
+				return time.Time{}, false
+			}
+			weeks := int(monday(day).Sub(anchorWeek).Hours()) / (24 * 7)
+			if weeks%r.Interval == 0 && r.ByDay[day.Weekday()] {
+				return day, true
+			}
+		}
+		return time.Time{}, false
+	}
+
+	days := 1
+	if r.Freq == FreqWeekly {
+		days = 7
+	}
+	step := days * r.Interval
+	elapsed := int(t.Sub(anchor).Hours()) / 24
+	return anchor.AddDate(0, 0, elapsed/step*step), true
+}
+
+// End returns the end of the window starting at start: after hours when set,
+// otherwise a day for daily rules and for weekly rules by day, a week for
+// other weekly rules and a month for monthly rules
+func (r Rule) End(start time.Time, hours int) time.Time {
+	if hours > 0 {
+		return start.Add(time.Duration(hours) * time.Hour)
+	}
+	switch {
+	case r.Freq == FreqMonthly:
+		return start.AddDate(0, 1, 0)
+	case r.Freq == FreqWeekly && r.ByDay == nil:
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// monday returns midnight of the Monday starting the week of t
+func monday(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
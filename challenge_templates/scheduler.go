@@ -0,0 +1,202 @@
+package challenge_templates
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"base/core/logger"
+	"base/core/types"
+	"base/packages/gamification/models"
+	"base/packages/gamification/tenancy"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	InstantiateChallengeEvent = "challengetemplates.instantiate"
+	CloseChallengeEvent       = "challengetemplates.close"
+)
+
+// ScheduleInterval is how often templates are instantiated and expired
+// instances closed
+const ScheduleInterval = 5 * time.Minute
+
+// RunScheduler instantiates the running window of every active template and
+// closes expired instances every interval. It blocks, so callers run it in
+// its own goroutine.
+func (s *ChallengeTemplateService) RunScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if err := s.Schedule(now); err != nil {
+			s.Logger.Error("failed to schedule challenge templates", logger.String("error", err.Error()))
+		}
+	}
+}
+
+// Schedule instantiates the window running at now of every active template,
+// across all tenants, and closes the instances that ended at now. A template
+// that fails to instantiate does not hold up the others.
+func (s *ChallengeTemplateService) Schedule(now time.Time) error {
+	var templates []*models.ChallengeTemplate
+	if err := s.DB.Where("is_active = ?", true).Order("id").Find(&templates).Error; err != nil {
+		return fmt.Errorf("failed to find challenge templates: %w", err)
+	}
+
+	for _, template := range templates {
+		if _, err := s.forTenant(template.TenantId).Instantiate(template, now); err != nil {
+			s.Logger.Error("failed to instantiate challenge template",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(template.Id)))
+		}
+	}
+
+	_, err := s.CloseExpired(now)
+	return err
+}
+
+// Instantiate creates the challenge of the template's window running at now
+// and, for auto-enrolling templates, enrolls its participants. It returns nil
+// when no window is running or its challenge already exists.
+func (s *ChallengeTemplateService) Instantiate(template *models.ChallengeTemplate, now time.Time) (*models.Challenge, error) {
+	rule, err := ParseRule(template.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	start, ok := rule.Latest(template.StartsAt, now)
+	if !ok || (template.EndsAt != nil && !start.Before(*template.EndsAt)) {
+		return nil, nil
+	}
+	end := rule.End(start, template.DurationHours)
+	if !end.After(now) {
+		return nil, nil
+	}
+
+	item := &models.Challenge{
+		Key:         fmt.Sprintf("%s-%s", template.Key, start.Format("2006-01-02")),
+		Name:        template.Name,
+		Description: template.Description,
+		StartDate:   types.DateTime{Time: start},
+		EndDate:     types.DateTime{Time: end},
+		RewardType:  template.RewardType,
+		RewardValue: template.RewardValue,
+		IsActive:    true,
+		IsTeam:      template.IsTeam,
+		TemplateId:  &template.Id,
+		Goal:        template.Goal,
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// The window's instance may exist already, e.g. from another replica
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(item)
+		if result.Error != nil {
+			return fmt.Errorf("failed to create challenge: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			item = nil
+			return nil
+		}
+		if !template.AutoEnroll {
+			return nil
+		}
+		return enroll(tx, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, nil
+	}
+
+	s.Emitter.Emit(InstantiateChallengeEvent, item)
+
+	return item, nil
+}
+
+// CloseExpired deactivates every active template instance that ended at now.
+// It returns the number of instances closed.
+func (s *ChallengeTemplateService) CloseExpired(now time.Time) (int, error) {
+	var expired []*models.Challenge
+	if err := s.DB.Where("template_id IS NOT NULL AND is_active = ? AND end_date <= ?", true, now).
+		Find(&expired).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired challenges: %w", err)
+	}
+
+	closed := 0
+	for _, item := range expired {
+		result := s.forTenant(item.TenantId).DB.Model(&models.Challenge{}).
+			Where("id = ? AND is_active = ?", item.Id, true).
+			Update("is_active", false)
+		if result.Error != nil {
+			return closed, fmt.Errorf("failed to close challenge: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		closed++
+		item.IsActive = false
+		s.Emitter.Emit(CloseChallengeEvent, item)
+	}
+
+	return closed, nil
+}
+
+// forTenant scopes a clone of the service to a template's tenant, since the
+// scheduler itself runs across all tenants
+func (s *ChallengeTemplateService) forTenant(tenantId uint) *ChallengeTemplateService {
+	return s.WithContext(tenancy.WithTenant(context.Background(), tenantId))
+}
+
+// enroll enrolls the participants of the template's previous instance and the
+// users who recorded an activity in the window-long stretch before item
+// starts
+func enroll(tx *gorm.DB, item *models.Challenge) error {
+	since := item.StartDate.Add(-item.EndDate.Sub(item.StartDate.Time))
+
+	var participants []uint
+	previous := &models.Challenge{}
+	err := tx.Where("template_id = ? AND id <> ?", *item.TemplateId, item.Id).
+		Order("start_date DESC").
+		Limit(1).
+		Find(previous).Error
+	if err != nil {
+		return fmt.Errorf("failed to find previous challenge: %w", err)
+	}
+	if previous.Id != 0 {
+		if err := tx.Model(&models.UserChallenge{}).
+			Where("challenge_id = ?", previous.Id).
+			Distinct().
+			Pluck("user_id", &participants).Error; err != nil {
+			return fmt.Errorf("failed to find previous participants: %w", err)
+		}
+	}
+
+	var active []uint
+	if err := tx.Model(&models.UserActivity{}).
+		Where("created_at >= ? AND created_at < ? AND status = ?", since, item.StartDate.Time, models.UserActivityAccepted).
+		Distinct().
+		Pluck("user_id", &active).Error; err != nil {
+		return fmt.Errorf("failed to find active users: %w", err)
+	}
+
+	seen := make(map[uint]bool, len(participants)+len(active))
+	var enrollments []*models.UserChallenge
+	for _, userId := range append(participants, active...) {
+		if seen[userId] {
+			continue
+		}
+		seen[userId] = true
+		enrollments = append(enrollments, &models.UserChallenge{UserId: userId, ChallengeId: item.Id})
+	}
+	if len(enrollments) == 0 {
+		return nil
+	}
+
+	if err := tx.CreateInBatches(enrollments, 500).Error; err != nil {
+		return fmt.Errorf("failed to enroll users: %w", err)
+	}
+	return nil
+}
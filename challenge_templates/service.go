@@ -0,0 +1,320 @@
+package challenge_templates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+	"base/core/types"
+	"base/packages/gamification/challenges"
+	"base/packages/gamification/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CreateChallengeTemplateEvent = "challengetemplates.create"
+	UpdateChallengeTemplateEvent = "challengetemplates.update"
+	DeleteChallengeTemplateEvent = "challengetemplates.delete"
+)
+
+type ChallengeTemplateService struct {
+	DB      *gorm.DB
+	Emitter *emitter.Emitter
+	Storage *storage.ActiveStorage
+	Logger  logger.Logger
+}
+
+func NewChallengeTemplateService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *ChallengeTemplateService {
+	return &ChallengeTemplateService{
+		DB:      db,
+		Emitter: emitter,
+		Storage: storage,
+		Logger:  logger,
+	}
+}
+
+func (s *ChallengeTemplateService) WithContext(ctx context.Context) *ChallengeTemplateService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
+func (s *ChallengeTemplateService) Create(req *models.CreateChallengeTemplateRequest) (*models.ChallengeTemplate, error) {
+	if err := validate(req.Recurrence, req.StartsAt, req.EndsAt); err != nil {
+		return nil, err
+	}
+	var goal models.ChallengeGoal
+	if req.Goal != nil {
+		var err error
+		if goal, err = challenges.ResolveGoal(s.DB, req.Goal); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := models.NewKey(s.DB, &models.ChallengeTemplate{}, req.Key, req.Name)
+	if err != nil {
+		s.Logger.Error("failed to assign challengetemplate key", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	item := &models.ChallengeTemplate{
+		Key:           key,
+		Name:          req.Name,
+		Description:   req.Description,
+		Recurrence:    req.Recurrence,
+		DurationHours: req.DurationHours,
+		StartsAt:      req.StartsAt,
+		EndsAt:        req.EndsAt,
+		RewardType:    req.RewardType,
+		RewardValue:   req.RewardValue,
+		IsTeam:        req.IsTeam,
+		AutoEnroll:    req.AutoEnroll,
+		IsActive:      req.IsActive,
+		Goal:          goal,
+	}
+
+	if err := s.DB.Create(item).Error; err != nil {
+		s.Logger.Error("failed to create challenge template", logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to create challenge template: %w", err)
+	}
+
+	// Emit create event
+	s.Emitter.Emit(CreateChallengeTemplateEvent, item)
+
+	return s.GetById(item.Id)
+}
+
+func (s *ChallengeTemplateService) Update(id uint, req *models.UpdateChallengeTemplateRequest) (*models.ChallengeTemplate, error) {
+	item := &models.ChallengeTemplate{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to find challenge template for update",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to find challenge template: %w", err)
+	}
+
+	// Validate the schedule as it will be after the update
+	recurrence, startsAt, endsAt := item.Recurrence, item.StartsAt, item.EndsAt
+	if req.Recurrence != "" {
+		recurrence = req.Recurrence
+	}
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		endsAt = req.EndsAt
+	}
+	if err := validate(recurrence, startsAt, endsAt); err != nil {
+		return nil, err
+	}
+	var goal models.ChallengeGoal
+	if req.Goal != nil {
+		var err error
+		if goal, err = challenges.ResolveGoal(s.DB, req.Goal); err != nil {
+			return nil, err
+		}
+	}
+
+	// Build updates map
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != "" {
+		updates["description"] = req.Description
+	}
+	if req.Recurrence != "" {
+		updates["recurrence"] = req.Recurrence
+	}
+	if req.DurationHours != nil {
+		updates["duration_hours"] = *req.DurationHours
+	}
+	if req.StartsAt != nil {
+		updates["starts_at"] = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		updates["ends_at"] = *req.EndsAt
+	}
+	if req.RewardType != "" {
+		updates["reward_type"] = req.RewardType
+	}
+	if req.RewardValue != "" {
+		updates["reward_value"] = req.RewardValue
+	}
+	if req.IsTeam != nil {
+		updates["is_team"] = *req.IsTeam
+	}
+	if req.AutoEnroll != nil {
+		updates["auto_enroll"] = *req.AutoEnroll
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(item).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if req.Goal == nil {
+			return nil
+		}
+		return tx.Model(item).Select(challenges.GoalColumns).Updates(&models.ChallengeTemplate{Goal: goal}).Error
+	})
+	if err != nil {
+		s.Logger.Error("failed to update challenge template",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to update challenge template: %w", err)
+	}
+
+	result, err := s.GetById(item.Id)
+	if err != nil {
+		s.Logger.Error("failed to get updated challenge template",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to get updated challenge template: %w", err)
+	}
+
+	// Emit update event
+	s.Emitter.Emit(UpdateChallengeTemplateEvent, result)
+
+	return result, nil
+}
+
+// validate checks that a template has a supported recurrence and ends after it starts
+func validate(recurrence string, startsAt time.Time, endsAt *time.Time) error {
+	if _, err := ParseRule(recurrence); err != nil {
+		return err
+	}
+	if endsAt != nil && !endsAt.After(startsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidRecurrence)
+	}
+	return nil
+}
+
+func (s *ChallengeTemplateService) Delete(id uint) error {
+	item := &models.ChallengeTemplate{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to find challenge template for deletion",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return fmt.Errorf("failed to find challenge template: %w", err)
+	}
+
+	// Delete file attachments if any
+
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
+		s.Logger.Error("failed to delete challenge template",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return fmt.Errorf("failed to delete challenge template: %w", err)
+	}
+
+	// Emit delete event
+	s.Emitter.Emit(DeleteChallengeTemplateEvent, item)
+
+	return nil
+}
+
+func (s *ChallengeTemplateService) GetById(id uint) (*models.ChallengeTemplate, error) {
+	item := &models.ChallengeTemplate{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to get challenge template",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to get challenge template: %w", err)
+	}
+
+	return item, nil
+}
+
+// GetByKey returns the challenge template identified by its key
+func (s *ChallengeTemplateService) GetByKey(key string) (*models.ChallengeTemplate, error) {
+	item := &models.ChallengeTemplate{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.Where(&models.ChallengeTemplate{Key: key}).First(item).Error; err != nil {
+		s.Logger.Error("failed to get challenge template by key",
+			logger.String("error", err.Error()),
+			logger.String("key", key))
+		return nil, fmt.Errorf("failed to get challenge template: %w", err)
+	}
+
+	return item, nil
+}
+
+func (s *ChallengeTemplateService) GetAll(page *int, limit *int) (*types.PaginatedResponse, error) {
+	var items []*models.ChallengeTemplate
+	var total int64
+	query := s.DB.Model(&models.ChallengeTemplate{})
+	// Set default values if nil
+	defaultPage := 1
+	defaultLimit := 10
+	if page == nil {
+		page = &defaultPage
+	}
+	if limit == nil {
+		limit = &defaultLimit
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count challenge template templates",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to count challenge template templates: %w", err)
+	}
+
+	// Apply pagination if provided
+	if page != nil && limit != nil {
+		offset := (*page - 1) * *limit
+		query = query.Offset(offset).Limit(*limit)
+	}
+
+	// Preload relationships
+	query = (&models.ChallengeTemplate{}).Preload(query)
+
+	// Execute query
+	if err := query.Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get challenge template templates",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to get challenge template templates: %w", err)
+	}
+
+	// Convert to response type
+	responses := make([]*models.ChallengeTemplateListResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToListResponse()
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(total) / float64(*limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return &types.PaginatedResponse{
+		Data: responses,
+		Pagination: types.Pagination{
+			Total:      int(total),
+			Page:       *page,
+			PageSize:   *limit,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
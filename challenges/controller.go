@@ -1,10 +1,12 @@
 package challenges
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"base/core/storage"
+	"base/packages/gamification/access"
 	"base/packages/gamification/models"
 
 	"github.com/gin-gonic/gin"
@@ -27,9 +29,10 @@ func (c *ChallengeController) Routes(router *gin.RouterGroup) {
 	router.GET("/challenges", c.List)        // Paginated list
 	router.GET("/challenges/all", c.ListAll) // Unpaginated list
 	router.GET("/challenges/:id", c.Get)
-	router.POST("/challenges", c.Create)
-	router.PUT("/challenges/:id", c.Update)
-	router.DELETE("/challenges/:id", c.Delete)
+	router.GET("/challenges/by-key/:key", c.GetByKey)
+	router.POST("/challenges", access.Require(access.RoleAdmin), c.Create)
+	router.PUT("/challenges/:id", access.Require(access.RoleAdmin), c.Update)
+	router.DELETE("/challenges/:id", access.Require(access.RoleAdmin), c.Delete)
 
 	// File/Image attachment endpoints
 
@@ -47,6 +50,8 @@ func (c *ChallengeController) Routes(router *gin.RouterGroup) {
 // @Param challenges body models.CreateChallengeRequest true "Create Challenge request"
 // @Success 201 {object} models.ChallengeResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /challenges [post]
 func (c *ChallengeController) Create(ctx *gin.Context) {
@@ -56,8 +61,21 @@ func (c *ChallengeController) Create(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Create(&req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, models.ErrDuplicateKey) {
+			ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, ErrInvalidGoal) || errors.Is(err, models.ErrInvalidKey) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
 		return
 	}
@@ -76,6 +94,7 @@ func (c *ChallengeController) Create(ctx *gin.Context) {
 // @Param id path int true "Challenge id"
 // @Success 200 {object} models.ChallengeResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /challenges/{id} [get]
 func (c *ChallengeController) Get(ctx *gin.Context) {
@@ -85,7 +104,30 @@ func (c *ChallengeController) Get(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// GetChallengeByKey godoc
+// @Summary Get a Challenge by key
+// @Description Get a Challenge by its stable key
+// @Tags Challenge
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key path string true "Challenge key"
+// @Success 200 {object} models.ChallengeResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /challenges/by-key/{key} [get]
+func (c *ChallengeController) GetByKey(ctx *gin.Context) {
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetByKey(ctx.Param("key"))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -106,6 +148,7 @@ func (c *ChallengeController) Get(ctx *gin.Context) {
 // @Param limit query int false "Number of items per page"
 // @Success 200 {object} types.PaginatedResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /challenges [get]
 func (c *ChallengeController) List(ctx *gin.Context) {
@@ -129,7 +172,7 @@ func (c *ChallengeController) List(ctx *gin.Context) {
 		}
 	}
 
-	paginatedResponse, err := c.Service.GetAll(page, limit)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(page, limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
 		return
@@ -147,10 +190,11 @@ func (c *ChallengeController) List(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /challenges/all [get]
 func (c *ChallengeController) ListAll(ctx *gin.Context) {
-	paginatedResponse, err := c.Service.GetAll(nil, nil)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(nil, nil)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
 		return
@@ -171,6 +215,7 @@ func (c *ChallengeController) ListAll(ctx *gin.Context) {
 // @Param challenges body models.UpdateChallengeRequest true "Update Challenge request"
 // @Success 200 {object} models.ChallengeResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /challenges/{id} [put]
@@ -187,8 +232,17 @@ func (c *ChallengeController) Update(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Update(uint(id), &req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, ErrInvalidGoal) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
 	}
@@ -207,6 +261,8 @@ func (c *ChallengeController) Update(ctx *gin.Context) {
 // @Param id path int true "Challenge id"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
 // @Failure 500 {object} ErrorResponse
 // @Router /challenges/{id} [delete]
 func (c *ChallengeController) Delete(ctx *gin.Context) {
@@ -216,7 +272,12 @@ func (c *ChallengeController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.Delete(uint(id)); err != nil {
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
 		return
 	}
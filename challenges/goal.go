@@ -0,0 +1,63 @@
+package challenges
+
+import (
+	"errors"
+	"fmt"
+
+	"base/packages/gamification/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidGoal is returned for a goal that lacks what its kind measures
+var ErrInvalidGoal = errors.New("invalid challenge goal")
+
+// GoalColumns are the columns of an embedded ChallengeGoal, for updates that
+// replace a goal with Select
+var GoalColumns = []string{"goal_kind", "goal_activity_type_id", "goal_point_type_id", "goal_target", "goal_filters"}
+
+// ResolveGoal resolves the activity and point type keys of a goal request and
+// checks that they exist and that the goal is complete
+func ResolveGoal(db *gorm.DB, req *models.ChallengeGoalRequest) (models.ChallengeGoal, error) {
+	goal := models.ChallengeGoal{
+		Kind:           req.Kind,
+		ActivityTypeId: req.ActivityTypeId,
+		PointTypeId:    req.PointTypeId,
+		Target:         req.Target,
+		Filters:        req.Filters,
+	}
+
+	if req.ActivityTypeKey != "" {
+		activityTypeId, err := models.ResolveFieldKey(db, &models.ActivityType{}, "goal.activity_type_key", req.ActivityTypeKey)
+		if err != nil {
+			return goal, fmt.Errorf("failed to resolve activity type: %w", err)
+		}
+		goal.ActivityTypeId = &activityTypeId
+	}
+	if req.PointTypeKey != "" {
+		pointTypeId, err := models.ResolveFieldKey(db, &models.PointType{}, "goal.point_type_key", req.PointTypeKey)
+		if err != nil {
+			return goal, fmt.Errorf("failed to resolve point type: %w", err)
+		}
+		goal.PointTypeId = &pointTypeId
+	}
+
+	check := models.CheckReferences(db)
+	if goal.ActivityTypeId != nil {
+		check.Exists("goal.activity_type_id", &models.ActivityType{}, *goal.ActivityTypeId)
+	}
+	if goal.PointTypeId != nil {
+		check.Exists("goal.point_type_id", &models.PointType{}, *goal.PointTypeId)
+	}
+	if err := check.Err(); err != nil {
+		return goal, err
+	}
+
+	if goal.Kind == models.ChallengeGoalActivityCount && goal.ActivityTypeId == nil {
+		return goal, fmt.Errorf("%w: %s needs an activity type", ErrInvalidGoal, goal.Kind)
+	}
+	if goal.PointTypeId != nil && goal.Kind != models.ChallengeGoalPointsSum {
+		return goal, fmt.Errorf("%w: only %s takes a point type", ErrInvalidGoal, models.ChallengeGoalPointsSum)
+	}
+	return goal, nil
+}
@@ -41,7 +41,13 @@ func (m *Module) Routes(router *gin.RouterGroup) {
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.Challenge{})
+	if err := models.MigrateKey(m.DB, &models.Challenge{}, "name"); err != nil {
+		return err
+	}
+	if err := m.DB.AutoMigrate(&models.Challenge{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.Challenge{})
 }
 
 func (m *Module) GetModels() []interface{} {
@@ -1,6 +1,8 @@
 package challenges
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 
@@ -35,8 +37,29 @@ func NewChallengeService(db *gorm.DB, emitter *emitter.Emitter, storage *storage
 	}
 }
 
+func (s *ChallengeService) WithContext(ctx context.Context) *ChallengeService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
 func (s *ChallengeService) Create(req *models.CreateChallengeRequest) (*models.Challenge, error) {
+	key, err := models.NewKey(s.DB, &models.Challenge{}, req.Key, req.Name)
+	if err != nil {
+		s.Logger.Error("failed to assign challenge key", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	var goal models.ChallengeGoal
+	if req.Goal != nil {
+		var err error
+		if goal, err = ResolveGoal(s.DB, req.Goal); err != nil {
+			return nil, err
+		}
+	}
+
 	item := &models.Challenge{
+		Key:         key,
 		Name:        req.Name,
 		Description: req.Description,
 		StartDate:   req.StartDate,
@@ -44,6 +67,8 @@ func (s *ChallengeService) Create(req *models.CreateChallengeRequest) (*models.C
 		RewardType:  req.RewardType,
 		RewardValue: req.RewardValue,
 		IsActive:    req.IsActive,
+		IsTeam:      req.IsTeam,
+		Goal:        goal,
 	}
 
 	if err := s.DB.Create(item).Error; err != nil {
@@ -66,6 +91,14 @@ func (s *ChallengeService) Update(id uint, req *models.UpdateChallengeRequest) (
 		return nil, fmt.Errorf("failed to find challenge: %w", err)
 	}
 
+	var goal models.ChallengeGoal
+	if req.Goal != nil {
+		var err error
+		if goal, err = ResolveGoal(s.DB, req.Goal); err != nil {
+			return nil, err
+		}
+	}
+
 	// Build updates map
 	updates := make(map[string]interface{})
 	if req.Name != "" {
@@ -89,8 +122,22 @@ func (s *ChallengeService) Update(id uint, req *models.UpdateChallengeRequest) (
 	if req.IsActive != "" {
 		updates["is_active"] = req.IsActive
 	}
+	if req.IsTeam != nil {
+		updates["is_team"] = *req.IsTeam
+	}
 
-	if err := s.DB.Model(item).Updates(updates).Error; err != nil {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(item).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if req.Goal == nil {
+			return nil
+		}
+		return tx.Model(item).Select(GoalColumns).Updates(&models.Challenge{Goal: goal}).Error
+	})
+	if err != nil {
 		s.Logger.Error("failed to update challenge",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -122,7 +169,11 @@ func (s *ChallengeService) Delete(id uint) error {
 
 	// Delete file attachments if any
 
-	if err := s.DB.Delete(item).Error; err != nil {
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
 		s.Logger.Error("failed to delete challenge",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -150,6 +201,22 @@ func (s *ChallengeService) GetById(id uint) (*models.Challenge, error) {
 	return item, nil
 }
 
+// GetByKey returns the challenge identified by its key
+func (s *ChallengeService) GetByKey(key string) (*models.Challenge, error) {
+	item := &models.Challenge{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.Where(&models.Challenge{Key: key}).First(item).Error; err != nil {
+		s.Logger.Error("failed to get challenge by key",
+			logger.String("error", err.Error()),
+			logger.String("key", key))
+		return nil, fmt.Errorf("failed to get challenge: %w", err)
+	}
+
+	return item, nil
+}
+
 func (s *ChallengeService) GetAll(page *int, limit *int) (*types.PaginatedResponse, error) {
 	var items []*models.Challenge
 	var total int64
@@ -0,0 +1,278 @@
+package duels
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"base/core/storage"
+	"base/packages/gamification/access"
+	"base/packages/gamification/models"
+	"base/packages/gamification/user_points"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DuelController struct {
+	Service *DuelService
+	Storage *storage.ActiveStorage
+}
+
+func NewDuelController(service *DuelService, storage *storage.ActiveStorage) *DuelController {
+	return &DuelController{
+		Service: service,
+		Storage: storage,
+	}
+}
+
+func (c *DuelController) Routes(router *gin.RouterGroup) {
+	router.GET("/duels", c.List)
+	router.GET("/duels/:id", c.Get)
+	router.POST("/duels", access.Require(access.RoleService, access.RoleUser), c.Create)
+	router.POST("/duels/:id/accept", access.Require(access.RoleService, access.RoleUser), c.Accept)
+	router.POST("/duels/:id/decline", access.Require(access.RoleService, access.RoleUser), c.Decline)
+	router.POST("/duels/:id/cancel", access.Require(access.RoleService, access.RoleUser), c.Cancel)
+}
+
+// CreateDuel godoc
+// @Summary Invite a user to a duel
+// @Description Invite the opponent to a duel and take the challenger's stake into escrow
+// @Tags Duel
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param duels body models.CreateDuelRequest true "Create Duel request"
+// @Success 201 {object} models.DuelResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 402 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /duels [post]
+func (c *DuelController) Create(ctx *gin.Context) {
+	var req models.CreateDuelRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// End users may only challenge others themselves
+	if own, restricted := access.RestrictedTo(ctx); restricted && own != req.ChallengerId {
+		access.Forbid(ctx)
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
+	if err != nil {
+		c.fail(ctx, err, "Failed to create item: ")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, item.ToResponse())
+}
+
+// GetDuel godoc
+// @Summary Get a Duel
+// @Description Get a Duel by its id, with live scores while it is active
+// @Tags Duel
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Duel id"
+// @Success 200 {object} models.DuelResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /duels/{id} [get]
+func (c *DuelController) Get(ctx *gin.Context) {
+	item, ok := c.find(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// ListDuels godoc
+// @Summary List duels
+// @Description Get a list of duels, latest first; end users see their own
+// @Tags Duel
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param status query string false "Only duels of this status"
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /duels [get]
+func (c *DuelController) List(ctx *gin.Context) {
+	var page, limit *int
+
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid page number"})
+			return
+		}
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit number"})
+			return
+		}
+	}
+
+	paginatedResponse, err := c.service(ctx).GetAll(ctx.Query("status"), page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// AcceptDuel godoc
+// @Summary Accept a duel
+// @Description Accept a duel invite as its opponent, taking the opponent's stake into escrow
+// @Tags Duel
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Duel id"
+// @Success 200 {object} models.DuelResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 402 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /duels/{id}/accept [post]
+func (c *DuelController) Accept(ctx *gin.Context) {
+	c.answer(ctx, false, (*DuelService).Accept)
+}
+
+// DeclineDuel godoc
+// @Summary Decline a duel
+// @Description Decline a duel invite as its opponent, refunding the challenger
+// @Tags Duel
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Duel id"
+// @Success 200 {object} models.DuelResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /duels/{id}/decline [post]
+func (c *DuelController) Decline(ctx *gin.Context) {
+	c.answer(ctx, false, (*DuelService).Decline)
+}
+
+// CancelDuel godoc
+// @Summary Cancel a duel
+// @Description Withdraw an unanswered duel invite as its challenger, refunding the stake
+// @Tags Duel
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Duel id"
+// @Success 200 {object} models.DuelResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /duels/{id}/cancel [post]
+func (c *DuelController) Cancel(ctx *gin.Context) {
+	c.answer(ctx, true, (*DuelService).Cancel)
+}
+
+// answer applies an answer to a duel invite. End users answer as the
+// opponent, or cancel as the challenger.
+func (c *DuelController) answer(ctx *gin.Context, challenger bool, apply func(*DuelService, uint) (*models.Duel, error)) {
+	item, ok := c.find(ctx)
+	if !ok {
+		return
+	}
+	if own, restricted := access.RestrictedTo(ctx); restricted {
+		side := item.OpponentId
+		if challenger {
+			side = item.ChallengerId
+		}
+		if own != side {
+			access.Forbid(ctx)
+			return
+		}
+	}
+
+	result, err := apply(c.Service.WithContext(ctx.Request.Context()), item.Id)
+	if err != nil {
+		c.fail(ctx, err, "Failed to answer duel: ")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result.ToResponse())
+}
+
+// find loads the duel of the id path parameter, answering 400 or 404 when it
+// cannot
+func (c *DuelController) find(ctx *gin.Context) (*models.Duel, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return nil, false
+	}
+
+	item, err := c.service(ctx).GetById(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return nil, false
+	}
+	return item, true
+}
+
+// fail maps a duel error to its status
+func (c *DuelController) fail(ctx *gin.Context, err error, message string) {
+	var invalid *models.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		ctx.JSON(http.StatusBadRequest, invalid)
+	case errors.Is(err, ErrInvalidDuel):
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, user_points.ErrInsufficientBalance):
+		ctx.JSON(http.StatusPaymentRequired, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, user_points.ErrNotTransferable):
+		ctx.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrDuelNotPending):
+		ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: message + err.Error()})
+	}
+}
+
+// service scopes the service to the request's tenant and, for end users, to their own duels
+func (c *DuelController) service(ctx *gin.Context) *DuelService {
+	service := c.Service.WithContext(ctx.Request.Context())
+	if userId, restricted := access.RestrictedTo(ctx); restricted {
+		service = service.ForUser(userId)
+	}
+	return service
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,58 @@
+package duels
+
+import (
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/module"
+	"base/core/storage"
+	"base/packages/gamification/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Module struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Controller *DuelController
+	Service    *DuelService
+	Logger     *logger.Logger
+	Storage    *storage.ActiveStorage
+}
+
+func NewDuelModule(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, storage *storage.ActiveStorage) module.Module {
+
+	service := NewDuelService(db, emitter, storage, log)
+	controller := NewDuelController(service, storage)
+
+	m := &Module{
+		DB:         db,
+		Service:    service,
+		Controller: controller,
+		Logger:     &log,
+		Storage:    storage,
+	}
+
+	return m
+}
+
+// Init starts the background job that times out invites and resolves duels
+func (m *Module) Init() error {
+	go m.Service.RunScheduler(SchedulerInterval)
+	return nil
+}
+
+func (m *Module) Routes(router *gin.RouterGroup) {
+	m.Controller.Routes(router)
+}
+
+func (m *Module) Migrate() error {
+	if err := m.DB.AutoMigrate(&models.Duel{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.Duel{})
+}
+
+func (m *Module) GetModels() []interface{} {
+	return []interface{}{&models.Duel{}}
+}
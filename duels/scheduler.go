@@ -0,0 +1,244 @@
+package duels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"base/core/logger"
+	"base/packages/gamification/models"
+	"base/packages/gamification/tenancy"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SchedulerInterval is how often unanswered invites time out and ended duels
+// are resolved
+const SchedulerInterval = time.Minute
+
+// RunScheduler times out invites and resolves duels every interval. It
+// blocks, so callers run it in its own goroutine.
+func (s *DuelService) RunScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if _, err := s.ExpireInvites(now); err != nil {
+			s.Logger.Error("failed to expire duel invites", logger.String("error", err.Error()))
+		}
+		if _, err := s.ResolveEnded(now); err != nil {
+			s.Logger.Error("failed to resolve duels", logger.String("error", err.Error()))
+		}
+	}
+}
+
+// ExpireInvites closes every pending duel whose invite was not answered by
+// now and refunds its challenger. A duel that fails does not hold up the
+// others; their errors are returned together. It returns the number of
+// invites expired.
+func (s *DuelService) ExpireInvites(now time.Time) (int, error) {
+	var items []*models.Duel
+	if err := s.DB.Where("status = ? AND respond_by < ?", models.DuelPending, now).
+		Find(&items).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired duel invites: %w", err)
+	}
+
+	expired := 0
+	var failures []error
+	for _, item := range items {
+		tenant := s.forTenant(item.TenantId)
+		changed := false
+		err := tenant.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(item, item.Id).Error; err != nil {
+				return fmt.Errorf("failed to lock duel: %w", err)
+			}
+			if item.Status != models.DuelPending {
+				return nil
+			}
+			changed = true
+			return tenant.close(tx, item, models.DuelExpired)
+		})
+		if err != nil {
+			s.Logger.Error("failed to expire duel",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(item.Id)))
+			failures = append(failures, fmt.Errorf("duel %d: %w", item.Id, err))
+			continue
+		}
+		if changed {
+			expired++
+			s.Emitter.Emit(ExpireDuelEvent, item)
+		}
+	}
+
+	return expired, errors.Join(failures...)
+}
+
+// ResolveEnded resolves every active duel that ended at now: the user with
+// the higher score wins both stakes, and a draw refunds them. A duel that
+// fails does not hold up the others; their errors are returned together. It
+// returns the number of duels resolved.
+func (s *DuelService) ResolveEnded(now time.Time) (int, error) {
+	var items []*models.Duel
+	if err := s.DB.Where("status = ? AND ends_at <= ?", models.DuelActive, now).
+		Find(&items).Error; err != nil {
+		return 0, fmt.Errorf("failed to find ended duels: %w", err)
+	}
+
+	resolved := 0
+	var failures []error
+	for _, item := range items {
+		tenant := s.forTenant(item.TenantId)
+		changed := false
+		err := tenant.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(item, item.Id).Error; err != nil {
+				return fmt.Errorf("failed to lock duel: %w", err)
+			}
+			if item.Status != models.DuelActive {
+				return nil
+			}
+			changed = true
+			return tenant.resolve(tx, item, now)
+		})
+		if err != nil {
+			s.Logger.Error("failed to resolve duel",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(item.Id)))
+			failures = append(failures, fmt.Errorf("duel %d: %w", item.Id, err))
+			continue
+		}
+		if changed {
+			resolved++
+			s.Emitter.Emit(ResolveDuelEvent, item)
+		}
+	}
+
+	return resolved, errors.Join(failures...)
+}
+
+// resolve stores the final scores of a locked duel and pays out its stakes
+func (s *DuelService) resolve(tx *gorm.DB, item *models.Duel, now time.Time) error {
+	challengerScore, opponentScore, err := scores(tx, item)
+	if err != nil {
+		return err
+	}
+
+	var winnerId *uint
+	switch {
+	case challengerScore > opponentScore:
+		winnerId = &item.ChallengerId
+	case opponentScore > challengerScore:
+		winnerId = &item.OpponentId
+	}
+
+	if item.Stake > 0 {
+		if winnerId != nil {
+			if _, err := s.Points.Credit(tx, *winnerId, *item.PointTypeId, 2*item.Stake, models.PointTransactionPayout, reference(item)); err != nil {
+				return err
+			}
+		} else {
+			for _, userId := range []uint{item.ChallengerId, item.OpponentId} {
+				if _, err := s.Points.Credit(tx, userId, *item.PointTypeId, item.Stake, models.PointTransactionRefund, reference(item)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	item.ChallengerScore, item.OpponentScore = challengerScore, opponentScore
+	item.Status, item.WinnerId, item.ResolvedAt = models.DuelCompleted, winnerId, &now
+	return tx.Model(item).Updates(map[string]interface{}{
+		"challenger_score": challengerScore,
+		"opponent_score":   opponentScore,
+		"status":           item.Status,
+		"winner_id":        winnerId,
+		"resolved_at":      now,
+	}).Error
+}
+
+// forTenant scopes a clone of the service to a duel's tenant, since the
+// scheduler itself runs across all tenants
+func (s *DuelService) forTenant(tenantId uint) *DuelService {
+	return s.WithContext(tenancy.WithTenant(context.Background(), tenantId))
+}
+
+// scores measures both sides of a duel from their activities in its window
+func scores(db *gorm.DB, item *models.Duel) (int, int, error) {
+	challengerScore, err := score(db, item, item.ChallengerId)
+	if err != nil {
+		return 0, 0, err
+	}
+	opponentScore, err := score(db, item, item.OpponentId)
+	if err != nil {
+		return 0, 0, err
+	}
+	return challengerScore, opponentScore, nil
+}
+
+// score measures one side of a duel by its metric. points_sum counts the
+// points of the staked point type, or xp when nothing is staked.
+func score(db *gorm.DB, item *models.Duel, userId uint) (int, error) {
+	query := db.Model(&models.UserActivity{}).
+		Where("user_id = ? AND created_at >= ? AND created_at < ?", userId, item.StartsAt, item.EndsAt).
+		Where("status = ?", models.UserActivityAccepted)
+	if item.ActivityTypeId != nil {
+		query = query.Where("activity_type_id = ?", *item.ActivityTypeId)
+	}
+
+	switch item.Metric {
+	case models.DuelMetricActivityCount:
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return 0, fmt.Errorf("failed to count duel activities: %w", err)
+		}
+		return int(count), nil
+
+	case models.DuelMetricPointsSum:
+		var sum int
+		if item.PointTypeId != nil {
+			query = db.Model(&models.UserActivityPoint{}).
+				Select("COALESCE(SUM(amount), 0)").
+				Where("user_activity_id IN (?) AND point_type_id = ?", query.Select("id"), *item.PointTypeId)
+		} else {
+			query = query.Select("COALESCE(SUM(points_earned), 0)")
+		}
+		if err := query.Scan(&sum).Error; err != nil {
+			return 0, fmt.Errorf("failed to sum duel points: %w", err)
+		}
+		return sum, nil
+
+	case models.DuelMetricMetadataSum:
+		var metadata []string
+		if err := query.Pluck("metadata", &metadata).Error; err != nil {
+			return 0, fmt.Errorf("failed to find duel activities: %w", err)
+		}
+		sum := 0
+		for _, raw := range metadata {
+			sum += metadataValue(raw, item.MetricField)
+		}
+		return sum, nil
+	}
+
+	return 0, nil
+}
+
+// metadataValue returns the whole number at field of activity metadata, or 0
+// when it is missing or not a number
+func metadataValue(raw string, field string) int {
+	values := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return 0
+	}
+	switch value := values[field].(type) {
+	case float64:
+		return int(value)
+	case string:
+		number, _ := strconv.ParseFloat(value, 64)
+		return int(number)
+	}
+	return 0
+}
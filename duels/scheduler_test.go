@@ -0,0 +1,151 @@
+package duels_test
+
+import (
+	"testing"
+	"time"
+
+	"base/core/emitter"
+	"base/packages/gamification/duels"
+	"base/packages/gamification/internal/testdb"
+	"base/packages/gamification/models"
+
+	"gorm.io/gorm"
+)
+
+// newDuel funds users 1 and 2 with 20 coins in tenant 1 and has user 2
+// accept user 1's invite to a duel staking 10 of them over the next hour. It
+// returns the unscoped database, the database of tenant 1, the unscoped
+// service the scheduler runs on and the duel.
+func newDuel(t *testing.T, metric string) (*gorm.DB, *gorm.DB, *duels.DuelService, *models.Duel) {
+	t.Helper()
+	base := testdb.Open(t, &models.ActivityType{}, &models.UserActivity{}, &models.UserActivityPoint{},
+		&models.PointType{}, &models.UserPoint{}, &models.PointLot{}, &models.PointTransaction{}, &models.Duel{})
+	db := testdb.ForTenant(base, 1)
+	service := duels.NewDuelService(base, &emitter.Emitter{}, nil, testdb.Logger{})
+	tenant := service.WithContext(db.Statement.Context)
+
+	coins := &models.PointType{Key: "coins", Name: "Coins", IsTransferable: true}
+	if err := db.Create(coins).Error; err != nil {
+		t.Fatalf("failed to create point type: %v", err)
+	}
+	for _, userId := range []uint{1, 2} {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			_, err := tenant.Points.Credit(tx, userId, coins.Id, 20, models.PointTransactionEarn, "test")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("failed to credit: %v", err)
+		}
+	}
+
+	startsAt := time.Now().Add(time.Minute)
+	item, err := tenant.Create(&models.CreateDuelRequest{
+		ChallengerId: 1,
+		OpponentId:   2,
+		Metric:       metric,
+		PointTypeId:  &coins.Id,
+		Stake:        10,
+		StartsAt:     startsAt,
+		EndsAt:       startsAt.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to create duel: %v", err)
+	}
+	if item, err = tenant.Accept(item.Id); err != nil {
+		t.Fatalf("failed to accept duel: %v", err)
+	}
+	return base, db, service, item
+}
+
+// record stores an accepted activity of a user in the middle of the duel
+// that earned xp and coins
+func record(t *testing.T, db *gorm.DB, item *models.Duel, userId uint, xp, coins int) {
+	t.Helper()
+	activity := &models.UserActivity{
+		CreatedAt:    item.StartsAt.Add(time.Minute),
+		UserId:       userId,
+		PointsEarned: xp,
+		Status:       models.UserActivityAccepted,
+		Points:       []*models.UserActivityPoint{{PointTypeId: *item.PointTypeId, Amount: coins}},
+	}
+	if err := db.Create(activity).Error; err != nil {
+		t.Fatalf("failed to create activity: %v", err)
+	}
+}
+
+func balance(t *testing.T, db *gorm.DB, userId uint) int {
+	t.Helper()
+	item := &models.UserPoint{}
+	if err := db.Where("user_id = ?", userId).Take(item).Error; err != nil {
+		t.Fatalf("failed to find balance: %v", err)
+	}
+	return item.CurrentBalance
+}
+
+func TestResolveEndedPaysTheWinner(t *testing.T) {
+	base, db, service, item := newDuel(t, models.DuelMetricPointsSum)
+	// The challenger earns more coins, the opponent more xp
+	record(t, db, item, 1, 10, 5)
+	record(t, db, item, 2, 100, 3)
+	// Activities of the same user in another tenant do not count
+	record(t, testdb.ForTenant(base, 2), item, 2, 0, 50)
+
+	resolved, err := service.ResolveEnded(item.EndsAt)
+	if err != nil {
+		t.Fatalf("failed to resolve duels: %v", err)
+	}
+	if resolved != 1 {
+		t.Errorf("resolved = %d, want 1", resolved)
+	}
+
+	stored := &models.Duel{}
+	if err := db.First(stored, item.Id).Error; err != nil {
+		t.Fatalf("failed to find duel: %v", err)
+	}
+	if stored.Status != models.DuelCompleted {
+		t.Errorf("status = %s, want %s", stored.Status, models.DuelCompleted)
+	}
+	if stored.ChallengerScore != 5 || stored.OpponentScore != 3 {
+		t.Errorf("scores = %d and %d, want 5 and 3", stored.ChallengerScore, stored.OpponentScore)
+	}
+	if stored.WinnerId == nil {
+		t.Errorf("winner = none, want 1")
+	} else if *stored.WinnerId != 1 {
+		t.Errorf("winner = %d, want 1", *stored.WinnerId)
+	}
+	// The winner takes both stakes
+	if got := balance(t, db, 1); got != 30 {
+		t.Errorf("winner's balance = %d, want 30", got)
+	}
+	if got := balance(t, db, 2); got != 10 {
+		t.Errorf("loser's balance = %d, want 10", got)
+	}
+
+	// A resolved duel is not paid out twice
+	if resolved, err := service.ResolveEnded(item.EndsAt); err != nil || resolved != 0 {
+		t.Errorf("resolving again = %d, %v, want 0, nil", resolved, err)
+	}
+}
+
+func TestResolveEndedRefundsADraw(t *testing.T) {
+	_, db, service, item := newDuel(t, models.DuelMetricActivityCount)
+	record(t, db, item, 1, 10, 5)
+	record(t, db, item, 2, 10, 5)
+
+	if _, err := service.ResolveEnded(item.EndsAt); err != nil {
+		t.Fatalf("failed to resolve duels: %v", err)
+	}
+
+	stored := &models.Duel{}
+	if err := db.First(stored, item.Id).Error; err != nil {
+		t.Fatalf("failed to find duel: %v", err)
+	}
+	if stored.WinnerId != nil {
+		t.Errorf("winner = %d, want none", *stored.WinnerId)
+	}
+	for _, userId := range []uint{1, 2} {
+		if got := balance(t, db, userId); got != 20 {
+			t.Errorf("balance of %d = %d, want 20", userId, got)
+		}
+	}
+}
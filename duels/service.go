@@ -0,0 +1,349 @@
+package duels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+	"base/core/types"
+	"base/packages/gamification/models"
+	"base/packages/gamification/user_points"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	CreateDuelEvent  = "duels.create"
+	AcceptDuelEvent  = "duels.accept"
+	DeclineDuelEvent = "duels.decline"
+	CancelDuelEvent  = "duels.cancel"
+	ExpireDuelEvent  = "duels.expire"
+	ResolveDuelEvent = "duels.resolve"
+)
+
+var (
+	// ErrInvalidDuel is returned for a duel whose stake, metric or window does not add up
+	ErrInvalidDuel = errors.New("invalid duel")
+
+	// ErrDuelNotPending is returned when answering or cancelling an invite
+	// that was already answered, cancelled or timed out
+	ErrDuelNotPending = errors.New("duel is no longer pending")
+)
+
+type DuelService struct {
+	DB      *gorm.DB
+	Emitter *emitter.Emitter
+	Storage *storage.ActiveStorage
+	Logger  logger.Logger
+	Points  *user_points.UserPointService
+}
+
+func NewDuelService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *DuelService {
+	return &DuelService{
+		DB:      db,
+		Emitter: emitter,
+		Storage: storage,
+		Logger:  logger,
+		Points:  user_points.NewUserPointService(db, emitter, storage, logger),
+	}
+}
+
+func (s *DuelService) WithContext(ctx context.Context) *DuelService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	clone.Points = s.Points.WithContext(ctx)
+	return &clone
+}
+
+// ForUser returns a copy of the service that only sees the duels of userId
+func (s *DuelService) ForUser(userId uint) *DuelService {
+	clone := *s
+	clone.DB = s.DB.Where("(challenger_id = ? OR opponent_id = ?)", userId, userId).Session(&gorm.Session{})
+	return &clone
+}
+
+// Create invites the opponent to a duel and takes the challenger's stake
+// into escrow
+func (s *DuelService) Create(req *models.CreateDuelRequest) (*models.Duel, error) {
+	if req.ActivityTypeKey != "" {
+		activityTypeId, err := models.ResolveFieldKey(s.DB, &models.ActivityType{}, "activity_type_key", req.ActivityTypeKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve activity type key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve activity type: %w", err)
+		}
+		req.ActivityTypeId = &activityTypeId
+	}
+	if req.PointTypeKey != "" {
+		pointTypeId, err := models.ResolveFieldKey(s.DB, &models.PointType{}, "point_type_key", req.PointTypeKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve point type key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve point type: %w", err)
+		}
+		req.PointTypeId = &pointTypeId
+	}
+
+	respondBy := req.StartsAt
+	if req.RespondBy != nil {
+		respondBy = *req.RespondBy
+	}
+	if err := s.validate(req, respondBy); err != nil {
+		return nil, err
+	}
+	check := models.CheckReferences(s.DB)
+	if req.ActivityTypeId != nil {
+		check.Active("activity_type_id", &models.ActivityType{}, *req.ActivityTypeId)
+	}
+	if req.PointTypeId != nil {
+		check.Exists("point_type_id", &models.PointType{}, *req.PointTypeId)
+	}
+	if err := check.Err(); err != nil {
+		s.Logger.Error("failed to check duel references", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	item := &models.Duel{
+		ChallengerId:   req.ChallengerId,
+		OpponentId:     req.OpponentId,
+		ActivityTypeId: req.ActivityTypeId,
+		Metric:         req.Metric,
+		MetricField:    req.MetricField,
+		PointTypeId:    req.PointTypeId,
+		Stake:          req.Stake,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		RespondBy:      respondBy,
+		Status:         models.DuelPending,
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		return s.escrow(tx, item, item.ChallengerId)
+	})
+	if err != nil {
+		s.Logger.Error("failed to create duel",
+			logger.String("error", err.Error()),
+			logger.Int("challenger_id", int(req.ChallengerId)),
+			logger.Int("opponent_id", int(req.OpponentId)))
+		return nil, fmt.Errorf("failed to create duel: %w", err)
+	}
+
+	// Emit create event
+	s.Emitter.Emit(CreateDuelEvent, item)
+
+	return s.GetById(item.Id)
+}
+
+// validate checks a duel invite before anything is escrowed
+func (s *DuelService) validate(req *models.CreateDuelRequest, respondBy time.Time) error {
+	if req.Metric == models.DuelMetricMetadataSum && req.MetricField == "" {
+		return fmt.Errorf("%w: %s needs a metric_field", ErrInvalidDuel, req.Metric)
+	}
+	if !req.StartsAt.After(time.Now()) {
+		return fmt.Errorf("%w: starts_at must be in the future", ErrInvalidDuel)
+	}
+	if respondBy.After(req.StartsAt) {
+		return fmt.Errorf("%w: respond_by must not be after starts_at", ErrInvalidDuel)
+	}
+	if req.Stake == 0 {
+		req.PointTypeId = nil
+		return nil
+	}
+	if req.PointTypeId == nil {
+		return fmt.Errorf("%w: a stake needs a point type", ErrInvalidDuel)
+	}
+
+	pointType := &models.PointType{}
+	if err := s.DB.First(pointType, *req.PointTypeId).Error; err != nil {
+		return fmt.Errorf("failed to find point type: %w", err)
+	}
+	// Stakes change hands, so only points that may be transferred are at stake
+	if !pointType.IsTransferable {
+		return user_points.ErrNotTransferable
+	}
+	return nil
+}
+
+// Accept takes the opponent's stake into escrow and starts the duel
+func (s *DuelService) Accept(id uint) (*models.Duel, error) {
+	item, err := s.answer(id, AcceptDuelEvent, func(tx *gorm.DB, item *models.Duel) error {
+		if err := s.escrow(tx, item, item.OpponentId); err != nil {
+			return err
+		}
+		item.Status = models.DuelActive
+		return tx.Model(item).Update("status", item.Status).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetById(item.Id)
+}
+
+// Decline turns the invite down and refunds the challenger
+func (s *DuelService) Decline(id uint) (*models.Duel, error) {
+	item, err := s.answer(id, DeclineDuelEvent, func(tx *gorm.DB, item *models.Duel) error {
+		return s.close(tx, item, models.DuelDeclined)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetById(item.Id)
+}
+
+// Cancel withdraws an unanswered invite and refunds the challenger
+func (s *DuelService) Cancel(id uint) (*models.Duel, error) {
+	item, err := s.answer(id, CancelDuelEvent, func(tx *gorm.DB, item *models.Duel) error {
+		return s.close(tx, item, models.DuelCancelled)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetById(item.Id)
+}
+
+// answer applies change to a pending duel whose invite has not timed out,
+// with the duel locked
+func (s *DuelService) answer(id uint, event string, change func(tx *gorm.DB, item *models.Duel) error) (*models.Duel, error) {
+	item := &models.Duel{}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(item, id).Error; err != nil {
+			return fmt.Errorf("failed to find duel: %w", err)
+		}
+		if item.Status != models.DuelPending || time.Now().After(item.RespondBy) {
+			return ErrDuelNotPending
+		}
+		return change(tx, item)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrDuelNotPending) {
+			s.Logger.Error("failed to answer duel",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(id)))
+		}
+		return nil, err
+	}
+
+	s.Emitter.Emit(event, item)
+
+	return item, nil
+}
+
+// escrow takes a user's stake for a duel
+func (s *DuelService) escrow(tx *gorm.DB, item *models.Duel, userId uint) error {
+	if item.Stake == 0 {
+		return nil
+	}
+	_, err := s.Points.Debit(tx, userId, *item.PointTypeId, item.Stake, models.PointTransactionStake, reference(item))
+	return err
+}
+
+// close ends a duel that never started with status and refunds the challenger
+func (s *DuelService) close(tx *gorm.DB, item *models.Duel, status string) error {
+	if item.Stake > 0 {
+		if _, err := s.Points.Credit(tx, item.ChallengerId, *item.PointTypeId, item.Stake, models.PointTransactionRefund, reference(item)); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	item.Status, item.ResolvedAt = status, &now
+	return tx.Model(item).Updates(map[string]interface{}{"status": status, "resolved_at": now}).Error
+}
+
+// reference is the ledger reference of a duel's stakes
+func reference(item *models.Duel) string {
+	return fmt.Sprintf("duel:%d", item.Id)
+}
+
+// GetById returns a duel. The scores of an active duel are measured live.
+func (s *DuelService) GetById(id uint) (*models.Duel, error) {
+	item := &models.Duel{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to get duel",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to get duel: %w", err)
+	}
+
+	if item.Status == models.DuelActive {
+		var err error
+		if item.ChallengerScore, item.OpponentScore, err = scores(s.DB, item); err != nil {
+			s.Logger.Error("failed to measure duel",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(id)))
+			return nil, err
+		}
+	}
+
+	return item, nil
+}
+
+// GetAll returns the duels, latest first, optionally of one status
+func (s *DuelService) GetAll(status string, page *int, limit *int) (*types.PaginatedResponse, error) {
+	var items []*models.Duel
+	var total int64
+	query := s.DB.Model(&models.Duel{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	// Set default values if nil
+	defaultPage := 1
+	defaultLimit := 10
+	if page == nil {
+		page = &defaultPage
+	}
+	if limit == nil {
+		limit = &defaultLimit
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count duels",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to count duels: %w", err)
+	}
+
+	offset := (*page - 1) * *limit
+	query = query.Order("id DESC").Offset(offset).Limit(*limit)
+
+	// Preload relationships
+	query = (&models.Duel{}).Preload(query)
+
+	// Execute query
+	if err := query.Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get duels",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to get duels: %w", err)
+	}
+
+	// Convert to response type
+	responses := make([]*models.DuelResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToResponse()
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(total) / float64(*limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return &types.PaginatedResponse{
+		Data: responses,
+		Pagination: types.Pagination{
+			Total:      int(total),
+			Page:       *page,
+			PageSize:   *limit,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
@@ -3,20 +3,35 @@ package gamification
 import (
 	// MODULE_IMPORT_MARKER - Do not remove this comment because it's used by the CLI to add new module imports
 
+	"errors"
+
 	"base/core/config"
 	"base/core/database"
 	"base/core/emitter"
 	"base/core/logger"
 	"base/core/module"
 	"base/core/storage"
+	"base/packages/gamification/access"
 	"base/packages/gamification/achievement_criteria"
 	"base/packages/gamification/achievements"
 	"base/packages/gamification/activity_types"
+	"base/packages/gamification/adjustments"
+	"base/packages/gamification/audit"
+	"base/packages/gamification/catalog"
+	"base/packages/gamification/challenge_templates"
 	"base/packages/gamification/challenges"
+	"base/packages/gamification/duels"
 	"base/packages/gamification/leaderboard_entries"
 	"base/packages/gamification/leaderboards"
 	"base/packages/gamification/levels"
+	"base/packages/gamification/me"
+	"base/packages/gamification/models"
 	"base/packages/gamification/point_types"
+	"base/packages/gamification/quest_steps"
+	"base/packages/gamification/quests"
+	"base/packages/gamification/teams"
+	"base/packages/gamification/tenancy"
+	"base/packages/gamification/trash"
 	"base/packages/gamification/user_achievements"
 	"base/packages/gamification/user_activities"
 	"base/packages/gamification/user_challenges"
@@ -57,6 +72,14 @@ func NewApp(cfg *config.Config) (*Gamification, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Scope every query made with a request context to the request's tenant
+	if err := db.Use(tenancy.Plugin{}); err != nil {
+		return nil, err
+	}
+	// Record every catalog write in the audit log
+	if err := db.Use(audit.Plugin{}); err != nil {
+		return nil, err
+	}
 	// Initialize storage
 	storageConfig := storage.Config{
 		Provider:  cfg.StorageProvider,
@@ -83,12 +106,16 @@ func NewApp(cfg *config.Config) (*Gamification, error) {
 	// Initialize modules
 	moduleInitializer := &GamificationModuleInitializer{
 		DB:      db,
-		Router:  router.Group("/api"),
+		Router:  router.Group("/api", tenancy.Middleware(), access.Authenticated()),
 		Logger:  log,
 		Emitter: emitter,
 		Storage: activeStorage,
 	}
 	app.Modules = moduleInitializer.InitializeModules(db)
+	// Seed the catalog once every table is migrated
+	if err := app.seedCatalog(); err != nil {
+		return nil, err
+	}
 	return app, nil
 }
 
@@ -122,12 +149,19 @@ func (a *GamificationModuleInitializer) InitializeModules(db *gorm.DB) []module.
 				logger.String("error", err.Error()))
 			continue
 		}
-		// Migrate the module
+		// Migrate the module. Dangling references only leave out foreign
+		// keys, so the module is still served.
 		if err := mod.Migrate(); err != nil {
-			a.Logger.Error("Failed to migrate module",
+			var dangling *models.DanglingError
+			if !errors.As(err, &dangling) {
+				a.Logger.Error("Failed to migrate module",
+					logger.String("module", name),
+					logger.String("error", err.Error()))
+				continue
+			}
+			a.Logger.Warn("Migrated module without some foreign keys",
 				logger.String("module", name),
 				logger.String("error", err.Error()))
-			continue
 		}
 		// Set up routes for the module
 		if routeModule, ok := mod.(interface{ Routes(*gin.RouterGroup) }); ok {
@@ -196,6 +230,46 @@ func (a *GamificationModuleInitializer) getModules(db *gorm.DB) map[string]modul
 			return leaderboard_entries.NewLeaderboardEntryModule(db, router, log, emitter, activeStorage)
 		},
 
+		"catalog": func(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage) module.Module {
+			return catalog.NewCatalogModule(db, router, log, emitter, activeStorage)
+		},
+
+		"teams": func(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage) module.Module {
+			return teams.NewTeamModule(db, router, log, emitter, activeStorage)
+		},
+
+		"me": func(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage) module.Module {
+			return me.NewMeModule(db, router, log, emitter, activeStorage)
+		},
+
+		"quests": func(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage) module.Module {
+			return quests.NewQuestModule(db, router, log, emitter, activeStorage)
+		},
+
+		"quest_steps": func(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage) module.Module {
+			return quest_steps.NewQuestStepModule(db, router, log, emitter, activeStorage)
+		},
+
+		"challenge_templates": func(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage) module.Module {
+			return challenge_templates.NewChallengeTemplateModule(db, router, log, emitter, activeStorage)
+		},
+
+		"duels": func(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage) module.Module {
+			return duels.NewDuelModule(db, router, log, emitter, activeStorage)
+		},
+
+		"adjustments": func(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage) module.Module {
+			return adjustments.NewAdjustmentModule(db, router, log, emitter, activeStorage)
+		},
+
+		"audit": func(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage) module.Module {
+			return audit.NewAuditModule(db, router, log, emitter, activeStorage)
+		},
+
+		"trash": func(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage) module.Module {
+			return trash.NewTrashModule(db, router, log, emitter, activeStorage)
+		},
+
 		// MODULE_INITIALIZER_MARKER - Do not remove this comment because it's used by the CLI to add new module initializers
 	}
 
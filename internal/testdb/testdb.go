@@ -0,0 +1,54 @@
+// Package testdb opens the databases the package tests run against
+package testdb
+
+import (
+	"context"
+	"testing"
+
+	"base/core/logger"
+	"base/packages/gamification/tenancy"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Open returns a new in-memory SQLite database with the tenancy plugin,
+// migrated for the given models
+func Open(t testing.TB, values ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	// Every connection to an in-memory database gets a database of its own
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get connection pool: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.Use(tenancy.Plugin{}); err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+	if err := db.AutoMigrate(values...); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+// ForTenant scopes db to a tenant
+func ForTenant(db *gorm.DB, tenantId uint) *gorm.DB {
+	return db.WithContext(tenancy.WithTenant(context.Background(), tenantId))
+}
+
+// Logger drops every message, such as the errors services log for the
+// calls that are meant to fail
+type Logger struct {
+	logger.Logger
+}
+
+func (Logger) Debug(string, ...logger.Field) {}
+func (Logger) Info(string, ...logger.Field)  {}
+func (Logger) Warn(string, ...logger.Field)  {}
+func (Logger) Error(string, ...logger.Field) {}
@@ -1,10 +1,12 @@
 package leaderboard_entries
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"base/core/storage"
+	"base/packages/gamification/access"
 	"base/packages/gamification/models"
 
 	"github.com/gin-gonic/gin"
@@ -27,9 +29,9 @@ func (c *LeaderboardEntryController) Routes(router *gin.RouterGroup) {
 	router.GET("/leaderboard-entries", c.List)        // Paginated list
 	router.GET("/leaderboard-entries/all", c.ListAll) // Unpaginated list
 	router.GET("/leaderboard-entries/:id", c.Get)
-	router.POST("/leaderboard-entries", c.Create)
-	router.PUT("/leaderboard-entries/:id", c.Update)
-	router.DELETE("/leaderboard-entries/:id", c.Delete)
+	router.POST("/leaderboard-entries", access.Require(access.RoleService), c.Create)
+	router.PUT("/leaderboard-entries/:id", access.Require(access.RoleService), c.Update)
+	router.DELETE("/leaderboard-entries/:id", access.Require(access.RoleService), c.Delete)
 
 	// File/Image attachment endpoints
 
@@ -47,6 +49,7 @@ func (c *LeaderboardEntryController) Routes(router *gin.RouterGroup) {
 // @Param leaderboard-entries body models.CreateLeaderboardEntryRequest true "Create LeaderboardEntry request"
 // @Success 201 {object} models.LeaderboardEntryResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /leaderboard-entries [post]
 func (c *LeaderboardEntryController) Create(ctx *gin.Context) {
@@ -56,8 +59,17 @@ func (c *LeaderboardEntryController) Create(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Create(&req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, ErrSubjectMismatch) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
 		return
 	}
@@ -76,6 +88,7 @@ func (c *LeaderboardEntryController) Create(ctx *gin.Context) {
 // @Param id path int true "LeaderboardEntry id"
 // @Success 200 {object} models.LeaderboardEntryResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /leaderboard-entries/{id} [get]
 func (c *LeaderboardEntryController) Get(ctx *gin.Context) {
@@ -85,7 +98,7 @@ func (c *LeaderboardEntryController) Get(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -106,6 +119,7 @@ func (c *LeaderboardEntryController) Get(ctx *gin.Context) {
 // @Param limit query int false "Number of items per page"
 // @Success 200 {object} types.PaginatedResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /leaderboard-entries [get]
 func (c *LeaderboardEntryController) List(ctx *gin.Context) {
@@ -129,7 +143,7 @@ func (c *LeaderboardEntryController) List(ctx *gin.Context) {
 		}
 	}
 
-	paginatedResponse, err := c.Service.GetAll(page, limit)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(page, limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
 		return
@@ -147,10 +161,11 @@ func (c *LeaderboardEntryController) List(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /leaderboard-entries/all [get]
 func (c *LeaderboardEntryController) ListAll(ctx *gin.Context) {
-	paginatedResponse, err := c.Service.GetAll(nil, nil)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(nil, nil)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
 		return
@@ -171,6 +186,7 @@ func (c *LeaderboardEntryController) ListAll(ctx *gin.Context) {
 // @Param leaderboard-entries body models.UpdateLeaderboardEntryRequest true "Update LeaderboardEntry request"
 // @Success 200 {object} models.LeaderboardEntryResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /leaderboard-entries/{id} [put]
@@ -187,8 +203,17 @@ func (c *LeaderboardEntryController) Update(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Update(uint(id), &req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, ErrSubjectMismatch) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
 	}
@@ -207,6 +232,8 @@ func (c *LeaderboardEntryController) Update(ctx *gin.Context) {
 // @Param id path int true "LeaderboardEntry id"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
 // @Failure 500 {object} ErrorResponse
 // @Router /leaderboard-entries/{id} [delete]
 func (c *LeaderboardEntryController) Delete(ctx *gin.Context) {
@@ -216,7 +243,12 @@ func (c *LeaderboardEntryController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.Delete(uint(id)); err != nil {
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
 		return
 	}
@@ -41,7 +41,10 @@ func (m *Module) Routes(router *gin.RouterGroup) {
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.LeaderboardEntry{})
+	if err := m.DB.AutoMigrate(&models.LeaderboardEntry{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.LeaderboardEntry{})
 }
 
 func (m *Module) GetModels() []interface{} {
@@ -1,6 +1,8 @@
 package leaderboard_entries
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 
@@ -19,6 +21,10 @@ const (
 	DeleteLeaderboardEntryEvent = "leaderboardentries.delete"
 )
 
+// ErrSubjectMismatch is returned when an entry ranks a user on a team
+// leaderboard or a team on a user leaderboard
+var ErrSubjectMismatch = errors.New("entry must reference exactly one of user_id or team_id, matching the leaderboard scope")
+
 type LeaderboardEntryService struct {
 	DB      *gorm.DB
 	Emitter *emitter.Emitter
@@ -35,14 +41,45 @@ func NewLeaderboardEntryService(db *gorm.DB, emitter *emitter.Emitter, storage *
 	}
 }
 
+func (s *LeaderboardEntryService) WithContext(ctx context.Context) *LeaderboardEntryService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
 func (s *LeaderboardEntryService) Create(req *models.CreateLeaderboardEntryRequest) (*models.LeaderboardEntry, error) {
+	if req.LeaderboardKey != "" {
+		leaderboardId, err := models.ResolveFieldKey(s.DB, &models.Leaderboard{}, "leaderboard_key", req.LeaderboardKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve leaderboard key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve leaderboard: %w", err)
+		}
+		req.LeaderboardId = leaderboardId
+	}
+	if err := models.CheckReferences(s.DB).
+		Active("leaderboard_id", &models.Leaderboard{}, req.LeaderboardId).
+		Exists("team_id", &models.Team{}, req.TeamId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check leaderboardentry references", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	if err := s.checkSubject(req.LeaderboardId, req.UserId, req.TeamId); err != nil {
+		return nil, err
+	}
+
 	item := &models.LeaderboardEntry{
 		LeaderboardId: req.LeaderboardId,
-		UserId:        req.UserId,
 		Score:         req.Score,
 		Rank:          req.Rank,
 		PeriodStart:   req.PeriodStart,
 		PeriodEnd:     req.PeriodEnd,
+		Segment:       req.Segment,
+	}
+	if req.TeamId != 0 {
+		item.TeamId = &req.TeamId
+	} else {
+		item.UserId = &req.UserId
 	}
 
 	if err := s.DB.Create(item).Error; err != nil {
@@ -64,6 +101,15 @@ func (s *LeaderboardEntryService) Update(id uint, req *models.UpdateLeaderboardE
 			logger.Int("id", int(id)))
 		return nil, fmt.Errorf("failed to find leaderboardentry: %w", err)
 	}
+	if err := models.CheckReferences(s.DB).
+		Active("leaderboard_id", &models.Leaderboard{}, req.LeaderboardId).
+		Exists("team_id", &models.Team{}, req.TeamId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check leaderboardentry references",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
 
 	// Build updates map
 	updates := make(map[string]interface{})
@@ -73,6 +119,24 @@ func (s *LeaderboardEntryService) Update(id uint, req *models.UpdateLeaderboardE
 	if req.UserId != 0 {
 		updates["user_id"] = req.UserId
 	}
+	if req.TeamId != 0 {
+		updates["team_id"] = req.TeamId
+	}
+	if req.LeaderboardId != 0 || req.UserId != 0 || req.TeamId != 0 {
+		leaderboardId, userId, teamId := item.LeaderboardId, req.UserId, req.TeamId
+		if req.LeaderboardId != 0 {
+			leaderboardId = req.LeaderboardId
+		}
+		if userId == 0 && item.UserId != nil {
+			userId = *item.UserId
+		}
+		if teamId == 0 && item.TeamId != nil {
+			teamId = *item.TeamId
+		}
+		if err := s.checkSubject(leaderboardId, userId, teamId); err != nil {
+			return nil, err
+		}
+	}
 	if req.Score != "" {
 		updates["score"] = req.Score
 	}
@@ -107,6 +171,29 @@ func (s *LeaderboardEntryService) Update(id uint, req *models.UpdateLeaderboardE
 	return result, nil
 }
 
+// checkSubject verifies that an entry references a user on a user leaderboard
+// and a team on a team leaderboard
+func (s *LeaderboardEntryService) checkSubject(leaderboardId uint, userId uint, teamId uint) error {
+	leaderboard := &models.Leaderboard{}
+	if err := s.DB.First(leaderboard, leaderboardId).Error; err != nil {
+		s.Logger.Error("failed to find leaderboard for entry",
+			logger.String("error", err.Error()),
+			logger.Int("leaderboard_id", int(leaderboardId)))
+		return fmt.Errorf("failed to find leaderboard: %w", err)
+	}
+
+	if leaderboard.Scope == models.LeaderboardScopeTeam {
+		if teamId == 0 || userId != 0 {
+			return ErrSubjectMismatch
+		}
+		return nil
+	}
+	if userId == 0 || teamId != 0 {
+		return ErrSubjectMismatch
+	}
+	return nil
+}
+
 func (s *LeaderboardEntryService) Delete(id uint) error {
 	item := &models.LeaderboardEntry{}
 	if err := s.DB.First(item, id).Error; err != nil {
@@ -118,7 +205,11 @@ func (s *LeaderboardEntryService) Delete(id uint) error {
 
 	// Delete file attachments if any
 
-	if err := s.DB.Delete(item).Error; err != nil {
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
 		s.Logger.Error("failed to delete leaderboardentry",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
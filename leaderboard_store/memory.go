@@ -0,0 +1,319 @@
+package leaderboard_store
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore keeps every board in an indexable skip list in process
+// memory. Boards are lost on restart, so it suits a single instance whose
+// boards can be restored from snapshots.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	boards   map[string]*skipList
+	restored map[string]bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{boards: make(map[string]*skipList), restored: make(map[string]bool)}
+}
+
+func (s *MemoryStore) Set(ctx context.Context, board string, member uint, score int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list(board).set(member, score)
+	return nil
+}
+
+func (s *MemoryStore) Incr(ctx context.Context, board string, member uint, delta int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.list(board)
+	score := list.scores[member] + delta
+	list.set(member, score)
+	return score, nil
+}
+
+func (s *MemoryStore) Score(ctx context.Context, board string, member uint) (int, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list, ok := s.boards[board]
+	if !ok {
+		return 0, false, nil
+	}
+	score, ok := list.scores[member]
+	return score, ok, nil
+}
+
+func (s *MemoryStore) Rank(ctx context.Context, board string, score int) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list, ok := s.boards[board]
+	if !ok {
+		return 1, nil
+	}
+	// Member 0 sorts before every member with the same score
+	return list.countBefore(score, 0) + 1, nil
+}
+
+func (s *MemoryStore) Position(ctx context.Context, board string, member uint) (int, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list, ok := s.boards[board]
+	if !ok {
+		return 0, false, nil
+	}
+	score, ok := list.scores[member]
+	if !ok {
+		return 0, false, nil
+	}
+	return list.countBefore(score, member), true, nil
+}
+
+func (s *MemoryStore) Range(ctx context.Context, board string, offset int, limit int) ([]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list, ok := s.boards[board]
+	if !ok {
+		return []Entry{}, nil
+	}
+	entries := make([]Entry, 0, limit)
+	for x := list.at(offset); x != nil && len(entries) < limit; x = x.next[0] {
+		entries = append(entries, Entry{Member: x.member, Score: x.score})
+	}
+	return entries, nil
+}
+
+func (s *MemoryStore) Count(ctx context.Context, board string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if list, ok := s.boards[board]; ok {
+		return list.length, nil
+	}
+	return 0, nil
+}
+
+func (s *MemoryStore) Clear(ctx context.Context, board string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.boards, board)
+	delete(s.restored, board)
+	return nil
+}
+
+func (s *MemoryStore) Boards(ctx context.Context, prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	boards := []string{}
+	for board := range s.boards {
+		if strings.HasPrefix(board, prefix) {
+			boards = append(boards, board)
+		}
+	}
+	sort.Strings(boards)
+	return boards, nil
+}
+
+func (s *MemoryStore) Restore(ctx context.Context, board string, load func() ([]Entry, error)) error {
+	s.mu.RLock()
+	restored := s.restored[board]
+	s.mu.RUnlock()
+	if restored {
+		return nil
+	}
+
+	entries, err := load()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.list(board)
+	for _, entry := range entries {
+		if _, ok := list.scores[entry.Member]; !ok {
+			list.set(entry.Member, entry.Score)
+		}
+	}
+	s.restored[board] = true
+	return nil
+}
+
+// list returns the skip list of a board, creating it. Callers hold the write lock.
+func (s *MemoryStore) list(board string) *skipList {
+	list, ok := s.boards[board]
+	if !ok {
+		list = newSkipList()
+		s.boards[board] = list
+	}
+	return list
+}
+
+const (
+	skipListMaxLevel = 32
+	skipListP        = 0.25
+)
+
+// skipNode is a member of a skip list. span[i] is the number of nodes
+// next[i] skips over, which lets positions be found in O(log n).
+type skipNode struct {
+	member uint
+	score  int
+	next   []*skipNode
+	span   []int
+}
+
+// skipList orders members by score, highest first, then by member id
+type skipList struct {
+	head   *skipNode
+	level  int
+	length int
+	scores map[uint]int
+	random *rand.Rand
+}
+
+func newSkipList() *skipList {
+	return &skipList{
+		head: &skipNode{
+			next: make([]*skipNode, skipListMaxLevel),
+			span: make([]int, skipListMaxLevel),
+		},
+		level:  1,
+		scores: make(map[uint]int),
+		random: rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+// before reports whether x sorts before the member with the given score
+func (x *skipNode) before(score int, member uint) bool {
+	return x.score > score || (x.score == score && x.member < member)
+}
+
+func (l *skipList) randomLevel() int {
+	level := 1
+	for level < skipListMaxLevel && l.random.Float64() < skipListP {
+		level++
+	}
+	return level
+}
+
+// set inserts a member or moves it to its new score
+func (l *skipList) set(member uint, score int) {
+	if old, ok := l.scores[member]; ok {
+		if old == score {
+			return
+		}
+		l.remove(member, old)
+	}
+	l.insert(member, score)
+	l.scores[member] = score
+}
+
+func (l *skipList) insert(member uint, score int) {
+	var update [skipListMaxLevel]*skipNode
+	var rank [skipListMaxLevel]int
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		if i < l.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.next[i] != nil && x.next[i].before(score, member) {
+			rank[i] += x.span[i]
+			x = x.next[i]
+		}
+		update[i] = x
+	}
+
+	level := l.randomLevel()
+	if level > l.level {
+		for i := l.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = l.head
+			update[i].span[i] = l.length
+		}
+		l.level = level
+	}
+
+	node := &skipNode{
+		member: member,
+		score:  score,
+		next:   make([]*skipNode, level),
+		span:   make([]int, level),
+	}
+	for i := 0; i < level; i++ {
+		node.next[i] = update[i].next[i]
+		update[i].next[i] = node
+		node.span[i] = update[i].span[i] - (rank[0] - rank[i])
+		update[i].span[i] = rank[0] - rank[i] + 1
+	}
+	for i := level; i < l.level; i++ {
+		update[i].span[i]++
+	}
+	l.length++
+}
+
+func (l *skipList) remove(member uint, score int) {
+	var update [skipListMaxLevel]*skipNode
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].before(score, member) {
+			x = x.next[i]
+		}
+		update[i] = x
+	}
+
+	x = x.next[0]
+	if x == nil || x.member != member || x.score != score {
+		return
+	}
+	for i := 0; i < l.level; i++ {
+		if update[i].next[i] == x {
+			update[i].span[i] += x.span[i] - 1
+			update[i].next[i] = x.next[i]
+		} else {
+			update[i].span[i]--
+		}
+	}
+	for l.level > 1 && l.head.next[l.level-1] == nil {
+		l.level--
+	}
+	l.length--
+	delete(l.scores, member)
+}
+
+// countBefore returns the number of members that sort before the given one
+func (l *skipList) countBefore(score int, member uint) int {
+	count := 0
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].before(score, member) {
+			count += x.span[i]
+			x = x.next[i]
+		}
+	}
+	return count
+}
+
+// at returns the node at a 0-based position, nil past the end
+func (l *skipList) at(position int) *skipNode {
+	if position < 0 || position >= l.length {
+		return nil
+	}
+	traversed := 0
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && traversed+x.span[i] <= position+1 {
+			traversed += x.span[i]
+			x = x.next[i]
+		}
+		if traversed == position+1 {
+			return x
+		}
+	}
+	return nil
+}
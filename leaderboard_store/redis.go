@@ -0,0 +1,168 @@
+package leaderboard_store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// RedisKeyPrefix namespaces the sorted sets of the redis store
+	RedisKeyPrefix = "gamification:leaderboard:"
+	// RedisRestoredPrefix namespaces the markers of the restored boards.
+	// Markers live next to the boards, so that a flush loses both.
+	RedisRestoredPrefix = "gamification:leaderboard-restored:"
+)
+
+// RedisStore keeps every board in a redis sorted set. Scores are stored
+// negated and members zero padded, so that the ascending order of the set is
+// highest score first, then lowest member id.
+type RedisStore struct {
+	Client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{Client: client}
+}
+
+func (s *RedisStore) key(board string) string {
+	return RedisKeyPrefix + board
+}
+
+// member encodes an id so that sorted sets order members numerically
+func member(id uint) string {
+	return fmt.Sprintf("%020d", id)
+}
+
+func (s *RedisStore) Set(ctx context.Context, board string, id uint, score int) error {
+	if err := s.Client.ZAdd(ctx, s.key(board), redis.Z{Score: -float64(score), Member: member(id)}).Err(); err != nil {
+		return fmt.Errorf("failed to set leaderboard score: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Incr(ctx context.Context, board string, id uint, delta int) (int, error) {
+	score, err := s.Client.ZIncrBy(ctx, s.key(board), -float64(delta), member(id)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment leaderboard score: %w", err)
+	}
+	return int(-score), nil
+}
+
+func (s *RedisStore) Score(ctx context.Context, board string, id uint) (int, bool, error) {
+	score, err := s.Client.ZScore(ctx, s.key(board), member(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get leaderboard score: %w", err)
+	}
+	return int(-score), true, nil
+}
+
+func (s *RedisStore) Rank(ctx context.Context, board string, score int) (int, error) {
+	better, err := s.Client.ZCount(ctx, s.key(board), "-inf", "("+strconv.Itoa(-score)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count better leaderboard scores: %w", err)
+	}
+	return int(better) + 1, nil
+}
+
+func (s *RedisStore) Position(ctx context.Context, board string, id uint) (int, bool, error) {
+	position, err := s.Client.ZRank(ctx, s.key(board), member(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get leaderboard position: %w", err)
+	}
+	return int(position), true, nil
+}
+
+func (s *RedisStore) Range(ctx context.Context, board string, offset int, limit int) ([]Entry, error) {
+	items, err := s.Client.ZRangeWithScores(ctx, s.key(board), int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard scores: %w", err)
+	}
+	entries := make([]Entry, 0, len(items))
+	for _, item := range items {
+		raw, _ := item.Member.(string)
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid leaderboard member %q: %w", raw, err)
+		}
+		entries = append(entries, Entry{Member: uint(id), Score: int(-item.Score)})
+	}
+	return entries, nil
+}
+
+func (s *RedisStore) Count(ctx context.Context, board string) (int, error) {
+	total, err := s.Client.ZCard(ctx, s.key(board)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count leaderboard scores: %w", err)
+	}
+	return int(total), nil
+}
+
+func (s *RedisStore) Clear(ctx context.Context, board string) error {
+	if err := s.Client.Del(ctx, s.key(board), RedisRestoredPrefix+board).Err(); err != nil {
+		return fmt.Errorf("failed to clear leaderboard scores: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Boards(ctx context.Context, prefix string) ([]string, error) {
+	boards := []string{}
+	iter := s.Client.Scan(ctx, 0, s.key(escapeGlob(prefix))+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		boards = append(boards, strings.TrimPrefix(iter.Val(), RedisKeyPrefix))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list leaderboard boards: %w", err)
+	}
+	sort.Strings(boards)
+	return boards, nil
+}
+
+// Restore adds the entries of a board that has no marker and sets the marker
+// in one transaction. Entries are added with NX, so that a restore running
+// late keeps the scores written since the first one.
+func (s *RedisStore) Restore(ctx context.Context, board string, load func() ([]Entry, error)) error {
+	restored, err := s.Client.Exists(ctx, RedisRestoredPrefix+board).Result()
+	if err != nil {
+		return fmt.Errorf("failed to find leaderboard restore marker: %w", err)
+	}
+	if restored > 0 {
+		return nil
+	}
+
+	entries, err := load()
+	if err != nil {
+		return err
+	}
+	members := make([]redis.Z, len(entries))
+	for i, entry := range entries {
+		members[i] = redis.Z{Score: -float64(entry.Score), Member: member(entry.Member)}
+	}
+
+	if _, err := s.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(members) > 0 {
+			pipe.ZAddNX(ctx, s.key(board), members...)
+		}
+		pipe.Set(ctx, RedisRestoredPrefix+board, 1, 0)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to restore leaderboard scores: %w", err)
+	}
+	return nil
+}
+
+// escapeGlob escapes the glob characters of a literal SCAN pattern
+func escapeGlob(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(value)
+}
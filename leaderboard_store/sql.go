@@ -0,0 +1,171 @@
+package leaderboard_store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"base/packages/gamification/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SQLStore keeps boards in the leaderboardscores table. Rank and position
+// are counts over the (board_key, score) index. Boards only lose their data
+// with the database, so the boards found restored are remembered in process.
+type SQLStore struct {
+	DB       *gorm.DB
+	restored sync.Map
+}
+
+func NewSQLStore(db *gorm.DB) *SQLStore {
+	return &SQLStore{DB: db}
+}
+
+func (s *SQLStore) board(ctx context.Context, board string) *gorm.DB {
+	return s.DB.WithContext(ctx).Model(&models.LeaderboardScore{}).Where("board_key = ?", board)
+}
+
+func (s *SQLStore) Set(ctx context.Context, board string, member uint, score int) error {
+	item := &models.LeaderboardScore{BoardKey: board, SubjectId: member, Score: score}
+	if err := s.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "board_key"}, {Name: "subject_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"score"}),
+	}).Create(item).Error; err != nil {
+		return fmt.Errorf("failed to set leaderboard score: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) Incr(ctx context.Context, board string, member uint, delta int) (int, error) {
+	item := &models.LeaderboardScore{BoardKey: board, SubjectId: member, Score: delta}
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "board_key"}, {Name: "subject_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"score": gorm.Expr("leaderboardscores.score + ?", delta),
+			}),
+		}).Create(item).Error; err != nil {
+			return err
+		}
+		return tx.Where("board_key = ? AND subject_id = ?", board, member).Take(item).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment leaderboard score: %w", err)
+	}
+	return item.Score, nil
+}
+
+func (s *SQLStore) Score(ctx context.Context, board string, member uint) (int, bool, error) {
+	item := &models.LeaderboardScore{}
+	err := s.board(ctx, board).Where("subject_id = ?", member).Take(item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get leaderboard score: %w", err)
+	}
+	return item.Score, true, nil
+}
+
+func (s *SQLStore) Rank(ctx context.Context, board string, score int) (int, error) {
+	var better int64
+	if err := s.board(ctx, board).Where("score > ?", score).Count(&better).Error; err != nil {
+		return 0, fmt.Errorf("failed to count better leaderboard scores: %w", err)
+	}
+	return int(better) + 1, nil
+}
+
+func (s *SQLStore) Position(ctx context.Context, board string, member uint) (int, bool, error) {
+	score, ok, err := s.Score(ctx, board, member)
+	if err != nil || !ok {
+		return 0, ok, err
+	}
+	var ahead int64
+	if err := s.board(ctx, board).
+		Where("score > ? OR (score = ? AND subject_id < ?)", score, score, member).
+		Count(&ahead).Error; err != nil {
+		return 0, false, fmt.Errorf("failed to count leaderboard position: %w", err)
+	}
+	return int(ahead), true, nil
+}
+
+func (s *SQLStore) Range(ctx context.Context, board string, offset int, limit int) ([]Entry, error) {
+	var items []*models.LeaderboardScore
+	if err := s.board(ctx, board).
+		Order("score DESC").Order("subject_id ASC").
+		Offset(offset).Limit(limit).
+		Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard scores: %w", err)
+	}
+	entries := make([]Entry, len(items))
+	for i, item := range items {
+		entries[i] = Entry{Member: item.SubjectId, Score: item.Score}
+	}
+	return entries, nil
+}
+
+func (s *SQLStore) Count(ctx context.Context, board string) (int, error) {
+	var total int64
+	if err := s.board(ctx, board).Count(&total).Error; err != nil {
+		return 0, fmt.Errorf("failed to count leaderboard scores: %w", err)
+	}
+	return int(total), nil
+}
+
+func (s *SQLStore) Clear(ctx context.Context, board string) error {
+	if err := s.DB.WithContext(ctx).Where("board_key = ?", board).Delete(&models.LeaderboardScore{}).Error; err != nil {
+		return fmt.Errorf("failed to clear leaderboard scores: %w", err)
+	}
+	s.restored.Delete(board)
+	return nil
+}
+
+func (s *SQLStore) Restore(ctx context.Context, board string, load func() ([]Entry, error)) error {
+	if _, ok := s.restored.Load(board); ok {
+		return nil
+	}
+
+	var found []uint
+	if err := s.board(ctx, board).Limit(1).Pluck("subject_id", &found).Error; err != nil {
+		return fmt.Errorf("failed to find leaderboard scores: %w", err)
+	}
+	if len(found) == 0 {
+		entries, err := load()
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			items := make([]*models.LeaderboardScore, len(entries))
+			for i, entry := range entries {
+				items[i] = &models.LeaderboardScore{BoardKey: board, SubjectId: entry.Member, Score: entry.Score}
+			}
+			if err := s.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&items).Error; err != nil {
+				return fmt.Errorf("failed to restore leaderboard scores: %w", err)
+			}
+		}
+	}
+
+	s.restored.Store(board, true)
+	return nil
+}
+
+func (s *SQLStore) Boards(ctx context.Context, prefix string) ([]string, error) {
+	boards := []string{}
+	if err := s.DB.WithContext(ctx).Model(&models.LeaderboardScore{}).
+		Distinct("board_key").
+		Where("board_key LIKE ?", escapeLike(prefix)+"%").
+		Order("board_key").
+		Pluck("board_key", &boards).Error; err != nil {
+		return nil, fmt.Errorf("failed to list leaderboard boards: %w", err)
+	}
+	return boards, nil
+}
+
+// escapeLike escapes the LIKE wildcards of a literal prefix
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
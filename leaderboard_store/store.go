@@ -0,0 +1,96 @@
+// Package leaderboard_store keeps the live scores of leaderboards in a sorted
+// set, so that score and rank queries don't have to scan leaderboard entries.
+package leaderboard_store
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"base/core/logger"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// StoreEnv selects the store backend: sql (default), memory or redis
+	StoreEnv = "GAMIFICATION_LEADERBOARD_STORE"
+	// RedisURLEnv is the redis:// URL used by the redis backend
+	RedisURLEnv = "GAMIFICATION_REDIS_URL"
+)
+
+// Store backends
+const (
+	BackendSQL    = "sql"
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// Entry is the score of one member of a board
+type Entry struct {
+	Member uint
+	Score  int
+}
+
+// LeaderboardStore is a set of boards, each one ordering its members by
+// score, highest first, then by member id. Rank follows competition ranking:
+// members with the same score share a rank, one more than the number of
+// members with a higher score.
+type LeaderboardStore interface {
+	// Set replaces the score of a member
+	Set(ctx context.Context, board string, member uint, score int) error
+	// Incr adds delta to the score of a member and returns the new score
+	Incr(ctx context.Context, board string, member uint, delta int) (int, error)
+	// Score returns the score of a member and whether it is on the board
+	Score(ctx context.Context, board string, member uint) (int, bool, error)
+	// Rank returns the rank a score has on the board
+	Rank(ctx context.Context, board string, score int) (int, error)
+	// Position returns the 0-based place of a member in the board's order
+	Position(ctx context.Context, board string, member uint) (int, bool, error)
+	// Range returns up to limit entries in order starting at offset
+	Range(ctx context.Context, board string, offset int, limit int) ([]Entry, error)
+	// Count returns the number of members of the board
+	Count(ctx context.Context, board string) (int, error)
+	// Clear removes the board
+	Clear(ctx context.Context, board string) error
+	// Boards returns the names of the boards that start with prefix
+	Boards(ctx context.Context, prefix string) ([]string, error)
+	// Restore fills a board that has not been filled yet, or that lost its
+	// data, with the entries load returns. Members already on the board keep
+	// their scores, so concurrent restores and writes don't overwrite each
+	// other. Stores remember restored boards, and load only runs once.
+	Restore(ctx context.Context, board string, load func() ([]Entry, error)) error
+}
+
+var (
+	shared     LeaderboardStore
+	sharedOnce sync.Once
+)
+
+// Shared returns the store of the process, built on first use from StoreEnv.
+// An unusable redis configuration falls back to the SQL store.
+func Shared(db *gorm.DB, log logger.Logger) LeaderboardStore {
+	sharedOnce.Do(func() {
+		shared = NewSQLStore(db)
+		switch backend := os.Getenv(StoreEnv); backend {
+		case "", BackendSQL:
+		case BackendMemory:
+			shared = NewMemoryStore()
+		case BackendRedis:
+			options, err := redis.ParseURL(os.Getenv(RedisURLEnv))
+			if err != nil {
+				log.Error("invalid leaderboard redis url, using the sql store",
+					logger.String("env", RedisURLEnv),
+					logger.String("error", err.Error()))
+				return
+			}
+			shared = NewRedisStore(redis.NewClient(options))
+		default:
+			log.Error("unknown leaderboard store, using the sql store",
+				logger.String("env", StoreEnv),
+				logger.String("backend", backend))
+		}
+	})
+	return shared
+}
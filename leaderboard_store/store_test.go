@@ -0,0 +1,229 @@
+package leaderboard_store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"base/packages/gamification/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// eachStore runs test against a new board set of every backend, redis
+// being served by miniredis
+func eachStore(t *testing.T, test func(t *testing.T, store LeaderboardStore)) {
+	backends := map[string]func(t *testing.T) LeaderboardStore{
+		BackendMemory: func(t *testing.T) LeaderboardStore {
+			return NewMemoryStore()
+		},
+		BackendSQL: func(t *testing.T) LeaderboardStore {
+			db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormlogger.Discard})
+			if err != nil {
+				t.Fatalf("failed to open database: %v", err)
+			}
+			sqlDB, err := db.DB()
+			if err != nil {
+				t.Fatalf("failed to get connection pool: %v", err)
+			}
+			sqlDB.SetMaxOpenConns(1)
+			if err := db.AutoMigrate(&models.LeaderboardScore{}); err != nil {
+				t.Fatalf("failed to migrate: %v", err)
+			}
+			return NewSQLStore(db)
+		},
+		BackendRedis: func(t *testing.T) LeaderboardStore {
+			server := miniredis.RunT(t)
+			return NewRedisStore(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+		},
+	}
+	for name, newStore := range backends {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStore(t))
+		})
+	}
+}
+
+func TestStoreScores(t *testing.T) {
+	eachStore(t, func(t *testing.T, store LeaderboardStore) {
+		ctx := context.Background()
+		if err := store.Set(ctx, "b", 1, 10); err != nil {
+			t.Fatalf("failed to set: %v", err)
+		}
+		score, err := store.Incr(ctx, "b", 1, 5)
+		if err != nil {
+			t.Fatalf("failed to increment: %v", err)
+		}
+		if score != 15 {
+			t.Errorf("incremented score = %d, want 15", score)
+		}
+		if score, err := store.Incr(ctx, "b", 2, 3); err != nil || score != 3 {
+			t.Errorf("first increment = %d, %v, want 3", score, err)
+		}
+
+		if score, ok, err := store.Score(ctx, "b", 1); err != nil || !ok || score != 15 {
+			t.Errorf("score = %d, %t, %v, want 15", score, ok, err)
+		}
+		if _, ok, err := store.Score(ctx, "b", 9); err != nil || ok {
+			t.Errorf("score of a missing member found = %t, %v", ok, err)
+		}
+	})
+}
+
+func TestStoreOrder(t *testing.T) {
+	eachStore(t, func(t *testing.T, store LeaderboardStore) {
+		ctx := context.Background()
+		for member, score := range map[uint]int{1: 50, 2: 70, 3: 50, 4: 20} {
+			if err := store.Set(ctx, "b", member, score); err != nil {
+				t.Fatalf("failed to set: %v", err)
+			}
+		}
+
+		entries, err := store.Range(ctx, "b", 0, 10)
+		if err != nil {
+			t.Fatalf("failed to range: %v", err)
+		}
+		want := []Entry{{2, 70}, {1, 50}, {3, 50}, {4, 20}}
+		if !reflect.DeepEqual(entries, want) {
+			t.Errorf("range = %v, want %v", entries, want)
+		}
+		if entries, err := store.Range(ctx, "b", 1, 2); err != nil || !reflect.DeepEqual(entries, want[1:3]) {
+			t.Errorf("range from 1 = %v, %v, want %v", entries, err, want[1:3])
+		}
+
+		// Equal scores share a rank, and positions break ties by member id
+		for score, want := range map[int]int{70: 1, 50: 2, 20: 4, 10: 5, 90: 1} {
+			if rank, err := store.Rank(ctx, "b", score); err != nil || rank != want {
+				t.Errorf("rank of %d = %d, %v, want %d", score, rank, err, want)
+			}
+		}
+		for member, want := range map[uint]int{2: 0, 1: 1, 3: 2, 4: 3} {
+			if position, ok, err := store.Position(ctx, "b", member); err != nil || !ok || position != want {
+				t.Errorf("position of %d = %d, %t, %v, want %d", member, position, ok, err, want)
+			}
+		}
+
+		if count, err := store.Count(ctx, "b"); err != nil || count != 4 {
+			t.Errorf("count = %d, %v, want 4", count, err)
+		}
+	})
+}
+
+func TestStoreBoards(t *testing.T) {
+	eachStore(t, func(t *testing.T, store LeaderboardStore) {
+		ctx := context.Background()
+		for _, board := range []string{"1:7:100:", "1:7:100:country=de", "1:7:200:", "1:8:100:"} {
+			if err := store.Set(ctx, board, 1, 1); err != nil {
+				t.Fatalf("failed to set: %v", err)
+			}
+		}
+
+		boards, err := store.Boards(ctx, "1:7:100:")
+		if err != nil {
+			t.Fatalf("failed to list boards: %v", err)
+		}
+		if want := []string{"1:7:100:", "1:7:100:country=de"}; !reflect.DeepEqual(boards, want) {
+			t.Errorf("boards = %v, want %v", boards, want)
+		}
+
+		if err := store.Clear(ctx, "1:7:100:"); err != nil {
+			t.Fatalf("failed to clear: %v", err)
+		}
+		if count, err := store.Count(ctx, "1:7:100:"); err != nil || count != 0 {
+			t.Errorf("count after clear = %d, %v, want 0", count, err)
+		}
+		if count, err := store.Count(ctx, "1:7:100:country=de"); err != nil || count != 1 {
+			t.Errorf("count of another board = %d, %v, want 1", count, err)
+		}
+	})
+}
+
+func TestStoreRestore(t *testing.T) {
+	eachStore(t, func(t *testing.T, store LeaderboardStore) {
+		ctx := context.Background()
+		loads := 0
+		load := func() ([]Entry, error) {
+			loads++
+			return []Entry{{1, 40}, {2, 30}}, nil
+		}
+
+		if err := store.Restore(ctx, "b", load); err != nil {
+			t.Fatalf("failed to restore: %v", err)
+		}
+		if _, err := store.Incr(ctx, "b", 1, 5); err != nil {
+			t.Fatalf("failed to increment: %v", err)
+		}
+		// Later restores neither load again nor reset the scores
+		if err := store.Restore(ctx, "b", load); err != nil {
+			t.Fatalf("failed to restore: %v", err)
+		}
+		if loads != 1 {
+			t.Errorf("snapshot loaded %d times, want once", loads)
+		}
+		if score, _, err := store.Score(ctx, "b", 1); err != nil || score != 45 {
+			t.Errorf("score = %d, %v, want 45", score, err)
+		}
+
+		// A cleared board is restored again
+		if err := store.Clear(ctx, "b"); err != nil {
+			t.Fatalf("failed to clear: %v", err)
+		}
+		if err := store.Restore(ctx, "b", load); err != nil {
+			t.Fatalf("failed to restore: %v", err)
+		}
+		if loads != 2 {
+			t.Errorf("snapshot loaded %d times, want twice", loads)
+		}
+		if score, _, err := store.Score(ctx, "b", 1); err != nil || score != 40 {
+			t.Errorf("restored score = %d, %v, want 40", score, err)
+		}
+	})
+}
+
+func TestStoreRestoreKeepsWrittenScores(t *testing.T) {
+	eachStore(t, func(t *testing.T, store LeaderboardStore) {
+		ctx := context.Background()
+		// Another writer restored the board and scored before this restore ran
+		err := store.Restore(ctx, "b", func() ([]Entry, error) {
+			if _, err := store.Incr(ctx, "b", 1, 45); err != nil {
+				return nil, err
+			}
+			return []Entry{{1, 40}, {2, 30}}, nil
+		})
+		if err != nil {
+			t.Fatalf("failed to restore: %v", err)
+		}
+
+		entries, err := store.Range(ctx, "b", 0, 10)
+		if err != nil {
+			t.Fatalf("failed to range: %v", err)
+		}
+		if want := []Entry{{1, 45}, {2, 30}}; !reflect.DeepEqual(entries, want) {
+			t.Errorf("range = %v, want %v", entries, want)
+		}
+	})
+}
+
+func TestStoreRestoreFailure(t *testing.T) {
+	eachStore(t, func(t *testing.T, store LeaderboardStore) {
+		ctx := context.Background()
+		failure := errors.New("snapshot unavailable")
+		if err := store.Restore(ctx, "b", func() ([]Entry, error) { return nil, failure }); !errors.Is(err, failure) {
+			t.Fatalf("restore err = %v, want %v", err, failure)
+		}
+
+		// A failed restore is tried again
+		loaded := false
+		if err := store.Restore(ctx, "b", func() ([]Entry, error) { loaded = true; return nil, nil }); err != nil {
+			t.Fatalf("failed to restore: %v", err)
+		}
+		if !loaded {
+			t.Error("restore after a failure did not load the snapshot")
+		}
+	})
+}
@@ -1,13 +1,19 @@
 package leaderboards
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"base/core/storage"
+	"base/packages/gamification/access"
+	"base/packages/gamification/leaderboard_entries"
 	"base/packages/gamification/models"
+	"base/packages/gamification/segments"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type LeaderboardController struct {
@@ -27,9 +33,21 @@ func (c *LeaderboardController) Routes(router *gin.RouterGroup) {
 	router.GET("/leaderboards", c.List)        // Paginated list
 	router.GET("/leaderboards/all", c.ListAll) // Unpaginated list
 	router.GET("/leaderboards/:id", c.Get)
-	router.POST("/leaderboards", c.Create)
-	router.PUT("/leaderboards/:id", c.Update)
-	router.DELETE("/leaderboards/:id", c.Delete)
+	router.GET("/leaderboards/by-key/:key", c.GetByKey)
+	router.POST("/leaderboards", access.Require(access.RoleAdmin), c.Create)
+	router.PUT("/leaderboards/:id", access.Require(access.RoleAdmin), c.Update)
+	router.DELETE("/leaderboards/:id", access.Require(access.RoleAdmin), c.Delete)
+
+	// Standings of the current or a given period
+	router.GET("/leaderboards/:id/top", c.Top)
+	router.GET("/leaderboards/:id/around/:user_id", c.Around)
+	router.GET("/leaderboards/:id/users/:user_id/rank", c.UserRank)
+	router.POST("/leaderboards/:id/scores", access.Require(access.RoleService), c.SubmitScore)
+
+	// Period history and final standings
+	router.GET("/leaderboards/:id/periods", c.Periods)
+	router.GET("/leaderboards/:id/periods/:period_id/standings", c.PeriodStandings)
+	router.POST("/leaderboards/:id/periods/:period_id/close", access.Require(access.RoleAdmin), c.ClosePeriod)
 
 	// File/Image attachment endpoints
 
@@ -47,6 +65,8 @@ func (c *LeaderboardController) Routes(router *gin.RouterGroup) {
 // @Param leaderboards body models.CreateLeaderboardRequest true "Create Leaderboard request"
 // @Success 201 {object} models.LeaderboardResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /leaderboards [post]
 func (c *LeaderboardController) Create(ctx *gin.Context) {
@@ -56,8 +76,21 @@ func (c *LeaderboardController) Create(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Create(&req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, models.ErrDuplicateKey) {
+			ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, ErrInvalidReward) || errors.Is(err, models.ErrInvalidKey) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
 		return
 	}
@@ -76,6 +109,7 @@ func (c *LeaderboardController) Create(ctx *gin.Context) {
 // @Param id path int true "Leaderboard id"
 // @Success 200 {object} models.LeaderboardResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /leaderboards/{id} [get]
 func (c *LeaderboardController) Get(ctx *gin.Context) {
@@ -85,7 +119,30 @@ func (c *LeaderboardController) Get(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// GetLeaderboardByKey godoc
+// @Summary Get a Leaderboard by key
+// @Description Get a Leaderboard by its stable key
+// @Tags Leaderboard
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key path string true "Leaderboard key"
+// @Success 200 {object} models.LeaderboardResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /leaderboards/by-key/{key} [get]
+func (c *LeaderboardController) GetByKey(ctx *gin.Context) {
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetByKey(ctx.Param("key"))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -106,6 +163,7 @@ func (c *LeaderboardController) Get(ctx *gin.Context) {
 // @Param limit query int false "Number of items per page"
 // @Success 200 {object} types.PaginatedResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /leaderboards [get]
 func (c *LeaderboardController) List(ctx *gin.Context) {
@@ -129,7 +187,7 @@ func (c *LeaderboardController) List(ctx *gin.Context) {
 		}
 	}
 
-	paginatedResponse, err := c.Service.GetAll(page, limit)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(page, limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
 		return
@@ -147,10 +205,11 @@ func (c *LeaderboardController) List(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /leaderboards/all [get]
 func (c *LeaderboardController) ListAll(ctx *gin.Context) {
-	paginatedResponse, err := c.Service.GetAll(nil, nil)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(nil, nil)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
 		return
@@ -171,6 +230,7 @@ func (c *LeaderboardController) ListAll(ctx *gin.Context) {
 // @Param leaderboards body models.UpdateLeaderboardRequest true "Update Leaderboard request"
 // @Success 200 {object} models.LeaderboardResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /leaderboards/{id} [put]
@@ -187,8 +247,17 @@ func (c *LeaderboardController) Update(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Update(uint(id), &req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, ErrInvalidReward) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
 	}
@@ -207,6 +276,8 @@ func (c *LeaderboardController) Update(ctx *gin.Context) {
 // @Param id path int true "Leaderboard id"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
 // @Failure 500 {object} ErrorResponse
 // @Router /leaderboards/{id} [delete]
 func (c *LeaderboardController) Delete(ctx *gin.Context) {
@@ -216,7 +287,12 @@ func (c *LeaderboardController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.Delete(uint(id)); err != nil {
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
 		return
 	}
@@ -224,6 +300,435 @@ func (c *LeaderboardController) Delete(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, SuccessResponse{Message: "Item deleted successfully"})
 }
 
+// TopLeaderboard godoc
+// @Summary Get the top of a Leaderboard
+// @Description Get the n best standings of the leaderboard period running at the given time, now by default
+// @Tags Leaderboard
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Leaderboard id"
+// @Param n query int false "Number of standings, 10 by default"
+// @Param segment query string false "Segment to rank within, e.g. country:DE or friends:42"
+// @Param at query string false "Time within the period, RFC3339 or YYYY-MM-DD"
+// @Success 200 {object} models.LeaderboardStandingsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /leaderboards/{id}/top [get]
+func (c *LeaderboardController) Top(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	n, ok := boundedQuery(ctx, "n", DefaultTopSize, MaxTopSize)
+	if !ok {
+		return
+	}
+	at, ok := PeriodAt(ctx)
+	if !ok {
+		return
+	}
+
+	if !AllowSegment(ctx, ctx.Query("segment"), 0) {
+		return
+	}
+
+	result, err := c.Service.WithContext(ctx.Request.Context()).Top(uint(id), ctx.Query("segment"), at, n)
+	if err != nil {
+		standingsError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// AroundLeaderboard godoc
+// @Summary Get the standings around a user
+// @Description Get the standings within radius places of a user, or of their team on a team leaderboard
+// @Tags Leaderboard
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Leaderboard id"
+// @Param user_id path int true "User id"
+// @Param radius query int false "Places above and below the user, 5 by default"
+// @Param segment query string false "Segment to rank within, e.g. country:DE or friends"
+// @Param at query string false "Time within the period, RFC3339 or YYYY-MM-DD"
+// @Success 200 {object} models.LeaderboardStandingsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /leaderboards/{id}/around/{user_id} [get]
+func (c *LeaderboardController) Around(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+	userId, err := strconv.ParseUint(ctx.Param("user_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user id format"})
+		return
+	}
+
+	radius, ok := boundedQuery(ctx, "radius", DefaultAroundRadius, MaxAroundRadius)
+	if !ok {
+		return
+	}
+	at, ok := PeriodAt(ctx)
+	if !ok {
+		return
+	}
+
+	if !AllowSegment(ctx, ctx.Query("segment"), uint(userId)) {
+		return
+	}
+
+	result, err := c.Service.WithContext(ctx.Request.Context()).Around(uint(id), ctx.Query("segment"), uint(userId), at, radius)
+	if err != nil {
+		standingsError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// UserRankLeaderboard godoc
+// @Summary Get the rank of a user
+// @Description Get the standing of a user, or of their team on a team leaderboard
+// @Tags Leaderboard
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Leaderboard id"
+// @Param user_id path int true "User id"
+// @Param segment query string false "Segment to rank within, e.g. country:DE or friends"
+// @Param at query string false "Time within the period, RFC3339 or YYYY-MM-DD"
+// @Success 200 {object} models.LeaderboardRankResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /leaderboards/{id}/users/{user_id}/rank [get]
+func (c *LeaderboardController) UserRank(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+	userId, err := strconv.ParseUint(ctx.Param("user_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user id format"})
+		return
+	}
+
+	at, ok := PeriodAt(ctx)
+	if !ok {
+		return
+	}
+
+	if !AllowSegment(ctx, ctx.Query("segment"), uint(userId)) {
+		return
+	}
+
+	result, err := c.Service.WithContext(ctx.Request.Context()).UserRank(uint(id), ctx.Query("segment"), uint(userId), at)
+	if err != nil {
+		standingsError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// SubmitLeaderboardScore godoc
+// @Summary Submit a live score
+// @Description Set or increment the score of a user, or of a team on a team leaderboard, for the current period
+// @Tags Leaderboard
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Leaderboard id"
+// @Param score body models.SubmitLeaderboardScoreRequest true "Submit score request"
+// @Success 200 {object} models.LeaderboardRankResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /leaderboards/{id}/scores [post]
+func (c *LeaderboardController) SubmitScore(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	var req models.SubmitLeaderboardScoreRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result, err := c.Service.WithContext(ctx.Request.Context()).SubmitScore(uint(id), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, leaderboard_entries.ErrSubjectMismatch):
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrLeaderboardInactive), errors.Is(err, ErrPeriodClosed):
+			ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to submit score: " + err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ListLeaderboardPeriods godoc
+// @Summary List the periods of a Leaderboard
+// @Description Get the periods a leaderboard took scores in, latest first, with their status
+// @Tags Leaderboard
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Leaderboard id"
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /leaderboards/{id}/periods [get]
+func (c *LeaderboardController) Periods(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	var page, limit *int
+
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid page number"})
+			return
+		}
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit number"})
+			return
+		}
+	}
+
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).Periods(uint(id), page, limit)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch periods: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// LeaderboardPeriodStandings godoc
+// @Summary Get the standings of a Leaderboard period
+// @Description Get the standings of one period, live while it is open and final once it is closed
+// @Tags Leaderboard
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Leaderboard id"
+// @Param period_id path int true "Period id"
+// @Param offset query int false "Number of standings to skip"
+// @Param n query int false "Number of standings, 10 by default"
+// @Param segment query string false "Segment to rank within, e.g. country:DE or friends:42"
+// @Success 200 {object} models.LeaderboardStandingsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /leaderboards/{id}/periods/{period_id}/standings [get]
+func (c *LeaderboardController) PeriodStandings(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+	periodId, err := strconv.ParseUint(ctx.Param("period_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid period id format"})
+		return
+	}
+
+	offset := 0
+	if raw := ctx.Query("offset"); raw != "" {
+		if offset, err = strconv.Atoi(raw); err != nil || offset < 0 {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid offset"})
+			return
+		}
+	}
+	n, ok := boundedQuery(ctx, "n", DefaultTopSize, MaxTopSize)
+	if !ok {
+		return
+	}
+
+	if !AllowSegment(ctx, ctx.Query("segment"), 0) {
+		return
+	}
+
+	result, err := c.Service.WithContext(ctx.Request.Context()).PeriodStandings(uint(id), uint(periodId), ctx.Query("segment"), offset, n)
+	if err != nil {
+		standingsError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// CloseLeaderboardPeriod godoc
+// @Summary Close a Leaderboard period
+// @Description Freeze the final standings of a period and award the leaderboard's rewards to its top finishers
+// @Tags Leaderboard
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Leaderboard id"
+// @Param period_id path int true "Period id"
+// @Success 200 {object} models.LeaderboardPeriodResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /leaderboards/{id}/periods/{period_id}/close [post]
+func (c *LeaderboardController) ClosePeriod(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+	periodId, err := strconv.ParseUint(ctx.Param("period_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid period id format"})
+		return
+	}
+
+	period, err := c.Service.WithContext(ctx.Request.Context()).ClosePeriod(uint(id), uint(periodId))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPeriodClosed):
+			ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to close period: " + err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, period.ToResponse())
+}
+
+// standingsError answers 400 for bad segments and 404 for unknown
+// leaderboards and unranked users
+func standingsError(ctx *gin.Context, err error) {
+	if errors.Is(err, segments.ErrInvalidSegment) {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrNotRanked) {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch standings: " + err.Error()})
+}
+
+// boundedQuery parses a positive integer query parameter, capped at max
+func boundedQuery(ctx *gin.Context, name string, fallback int, max int) (int, bool) {
+	raw := ctx.Query(name)
+	if raw == "" {
+		return fallback, true
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid " + name})
+		return 0, false
+	}
+	if value > max {
+		value = max
+	}
+	return value, true
+}
+
+// AllowSegment answers 403 when an end user asks for the friends of another
+// user, which would disclose who they are friends with. userId is the user
+// being ranked, whom a friends segment without a value refers to. Admins and
+// services may rank anyone's friends, and malformed segments are left for the
+// service to reject.
+func AllowSegment(ctx *gin.Context, segment string, userId uint) bool {
+	caller, restricted := access.RestrictedTo(ctx)
+	if !restricted {
+		return true
+	}
+	seg, err := segments.Parse(segment)
+	if err != nil || seg.Dimension != segments.DimensionFriends {
+		return true
+	}
+	owner := uint64(userId)
+	if seg.Value != "" {
+		if owner, err = strconv.ParseUint(seg.Value, 10, 32); err != nil {
+			return true
+		}
+	}
+	if owner != 0 && uint(owner) != caller {
+		access.Forbid(ctx)
+		return false
+	}
+	return true
+}
+
+// PeriodAt parses the optional at query parameter that selects a leaderboard
+// period, answering 400 when it is malformed. It defaults to now.
+func PeriodAt(ctx *gin.Context) (time.Time, bool) {
+	raw := ctx.Query("at")
+	if raw == "" {
+		return time.Now(), true
+	}
+	if at, err := time.Parse(time.RFC3339, raw); err == nil {
+		return at, true
+	}
+	if at, err := time.Parse("2006-01-02", raw); err == nil {
+		return at, true
+	}
+	ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid at, expected RFC3339 or YYYY-MM-DD"})
+	return time.Time{}, false
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
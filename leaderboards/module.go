@@ -36,14 +36,27 @@ func NewLeaderboardModule(db *gorm.DB, router *gin.RouterGroup, log logger.Logge
 	return m
 }
 
+// Init starts the background job that snapshots live scores and closes
+// ended periods
+func (m *Module) Init() error {
+	go m.Service.RunScheduler(SnapshotInterval)
+	return nil
+}
+
 func (m *Module) Routes(router *gin.RouterGroup) {
 	m.Controller.Routes(router)
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.Leaderboard{})
+	if err := models.MigrateKey(m.DB, &models.Leaderboard{}, "name"); err != nil {
+		return err
+	}
+	if err := m.DB.AutoMigrate(&models.Leaderboard{}, &models.LeaderboardScore{}, &models.LeaderboardPeriod{}, &models.LeaderboardReward{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.Leaderboard{}, &models.LeaderboardPeriod{}, &models.LeaderboardReward{})
 }
 
 func (m *Module) GetModels() []interface{} {
-	return []interface{}{&models.Leaderboard{}}
+	return []interface{}{&models.Leaderboard{}, &models.LeaderboardScore{}, &models.LeaderboardPeriod{}, &models.LeaderboardReward{}}
 }
@@ -0,0 +1,358 @@
+package leaderboards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"base/core/logger"
+	"base/core/types"
+	"base/packages/gamification/models"
+	"base/packages/gamification/user_achievements"
+	"base/packages/gamification/user_levels"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const ClosePeriodEvent = "leaderboardperiods.close"
+
+var (
+	// ErrPeriodClosed is returned when scores are submitted to, or a close is
+	// requested for, a period that is already closed
+	ErrPeriodClosed = errors.New("leaderboard period is closed")
+	// ErrInvalidReward is returned when a reward gives a point type without an
+	// amount, or an amount without a point type
+	ErrInvalidReward = errors.New("reward amount and point type go together")
+)
+
+// openPeriod returns the period record of a leaderboard starting at start,
+// creating it open on first use
+func (s *LeaderboardService) openPeriod(leaderboard *models.Leaderboard, start time.Time, end time.Time) (*models.LeaderboardPeriod, error) {
+	period := &models.LeaderboardPeriod{}
+	err := s.DB.Where("leaderboard_id = ? AND period_start = ?", leaderboard.Id, start).Take(period).Error
+	if err == nil {
+		return period, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find leaderboard period: %w", err)
+	}
+
+	period = &models.LeaderboardPeriod{
+		LeaderboardId: leaderboard.Id,
+		PeriodStart:   types.DateTime{Time: start},
+		PeriodEnd:     types.DateTime{Time: end},
+		Status:        models.LeaderboardPeriodOpen,
+	}
+	// A concurrent submit may have opened it first
+	if err := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(period).Error; err != nil {
+		return nil, fmt.Errorf("failed to open leaderboard period: %w", err)
+	}
+	if period.Id != 0 {
+		return period, nil
+	}
+	if err := s.DB.Where("leaderboard_id = ? AND period_start = ?", leaderboard.Id, start).Take(period).Error; err != nil {
+		return nil, fmt.Errorf("failed to find leaderboard period: %w", err)
+	}
+	return period, nil
+}
+
+// Periods returns the periods of a leaderboard, latest first
+func (s *LeaderboardService) Periods(leaderboardId uint, page *int, limit *int) (*types.PaginatedResponse, error) {
+	if err := s.DB.Select("id").First(&models.Leaderboard{}, leaderboardId).Error; err != nil {
+		s.Logger.Error("failed to find leaderboard for periods",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboardId)))
+		return nil, fmt.Errorf("failed to find leaderboard: %w", err)
+	}
+
+	var items []*models.LeaderboardPeriod
+	var total int64
+	query := s.DB.Model(&models.LeaderboardPeriod{}).Where("leaderboard_id = ?", leaderboardId)
+	// Set default values if nil
+	defaultPage := 1
+	defaultLimit := 10
+	if page == nil {
+		page = &defaultPage
+	}
+	if limit == nil {
+		limit = &defaultLimit
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count leaderboard periods",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboardId)))
+		return nil, fmt.Errorf("failed to count leaderboard periods: %w", err)
+	}
+
+	offset := (*page - 1) * *limit
+	if err := query.Order("period_start DESC").Offset(offset).Limit(*limit).Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get leaderboard periods",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboardId)))
+		return nil, fmt.Errorf("failed to get leaderboard periods: %w", err)
+	}
+
+	responses := make([]*models.LeaderboardPeriodResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToResponse()
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(*limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return &types.PaginatedResponse{
+		Data: responses,
+		Pagination: types.Pagination{
+			Total:      int(total),
+			Page:       *page,
+			PageSize:   *limit,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// periodOf returns one period record of a leaderboard
+func (s *LeaderboardService) periodOf(leaderboardId uint, periodId uint) (*models.LeaderboardPeriod, error) {
+	period := &models.LeaderboardPeriod{}
+	if err := s.DB.Where("leaderboard_id = ?", leaderboardId).First(period, periodId).Error; err != nil {
+		s.Logger.Error("failed to find leaderboard period",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboardId)),
+			logger.Int("period_id", int(periodId)))
+		return nil, fmt.Errorf("failed to find leaderboard period: %w", err)
+	}
+	return period, nil
+}
+
+// PeriodStandings lists the standings of one period of a leaderboard: the
+// live scores while it is open, the frozen final standings once closed
+func (s *LeaderboardService) PeriodStandings(leaderboardId uint, periodId uint, segment string, offset int, limit int) (*models.LeaderboardStandingsResponse, error) {
+	period, err := s.periodOf(leaderboardId, periodId)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := s.board(leaderboardId, period.PeriodStart.Time, segment, 0)
+	if err != nil {
+		return nil, err
+	}
+	// Without scores of its own the board fell back to an earlier period
+	if !b.start.Time.Equal(period.PeriodStart.Time) {
+		b.found = false
+	}
+	b.start, b.end = period.PeriodStart, period.PeriodEnd
+
+	return s.standings(b, offset, limit)
+}
+
+// ClosePeriod freezes the final standings of a leaderboard period and awards
+// its rewards
+func (s *LeaderboardService) ClosePeriod(leaderboardId uint, periodId uint) (*models.LeaderboardPeriod, error) {
+	leaderboard := &models.Leaderboard{}
+	if err := s.DB.First(leaderboard, leaderboardId).Error; err != nil {
+		s.Logger.Error("failed to find leaderboard for close",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboardId)))
+		return nil, fmt.Errorf("failed to find leaderboard: %w", err)
+	}
+
+	period, err := s.periodOf(leaderboardId, periodId)
+	if err != nil {
+		return nil, err
+	}
+	if period.Status == models.LeaderboardPeriodClosed {
+		return nil, ErrPeriodClosed
+	}
+
+	if err := s.closePeriod(leaderboard, period); err != nil {
+		return nil, err
+	}
+	return period, nil
+}
+
+// closePeriod marks a period closing under a lock, which waits for the
+// scores being submitted to it and turns away later ones. It then snapshots
+// the final standings, marks the period closed and awards the rewards of the
+// leaderboard to its top finishers in one transaction, and drops its boards
+// from the store. Closing a period left closing by a failed close finishes it.
+func (s *LeaderboardService) closePeriod(leaderboard *models.Leaderboard, period *models.LeaderboardPeriod) error {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		locked := &models.LeaderboardPeriod{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(locked, period.Id).Error; err != nil {
+			return err
+		}
+		if locked.Status == models.LeaderboardPeriodClosed {
+			return ErrPeriodClosed
+		}
+		if err := tx.Model(locked).Update("status", models.LeaderboardPeriodClosing).Error; err != nil {
+			return err
+		}
+		period.Status = models.LeaderboardPeriodClosing
+		return nil
+	})
+	if err != nil {
+		s.Logger.Error("failed to start closing leaderboard period",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboard.Id)),
+			logger.Int("period_id", int(period.Id)))
+		if errors.Is(err, ErrPeriodClosed) {
+			return err
+		}
+		return fmt.Errorf("failed to close leaderboard period: %w", err)
+	}
+
+	start, end := period.PeriodStart.Time, period.PeriodEnd.Time
+	if _, err := s.snapshotPeriod(leaderboard, start, end); err != nil {
+		s.Logger.Error("failed to snapshot closing leaderboard period",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboard.Id)),
+			logger.Int("period_id", int(period.Id)))
+		return fmt.Errorf("failed to snapshot leaderboard period: %w", err)
+	}
+
+	var awarded *awards
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		locked := &models.LeaderboardPeriod{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(locked, period.Id).Error; err != nil {
+			return err
+		}
+		if locked.Status == models.LeaderboardPeriodClosed {
+			return ErrPeriodClosed
+		}
+
+		now := time.Now()
+		if err := tx.Model(locked).Updates(map[string]interface{}{
+			"status":    models.LeaderboardPeriodClosed,
+			"closed_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		period.Status, period.ClosedAt = models.LeaderboardPeriodClosed, &now
+
+		var err error
+		awarded, err = s.award(tx, leaderboard, period)
+		return err
+	})
+	if err != nil {
+		s.Logger.Error("failed to close leaderboard period",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboard.Id)),
+			logger.Int("period_id", int(period.Id)))
+		if errors.Is(err, ErrPeriodClosed) {
+			return err
+		}
+		return fmt.Errorf("failed to close leaderboard period: %w", err)
+	}
+
+	boards, err := s.Store.Boards(context.Background(), boardPrefix(leaderboard, start))
+	if err != nil {
+		return err
+	}
+	for _, key := range boards {
+		if err := s.Store.Clear(context.Background(), key); err != nil {
+			return err
+		}
+	}
+
+	// Emit close event
+	s.Emitter.Emit(ClosePeriodEvent, period.ToResponse())
+	for _, level := range awarded.levels {
+		s.Emitter.Emit(user_levels.LevelUpEvent, level)
+	}
+	for _, achievement := range awarded.achievements {
+		s.Emitter.Emit(user_achievements.CreateUserAchievementEvent, achievement)
+	}
+
+	return nil
+}
+
+// awards collects what closing a period changed, to be announced once it is
+// committed
+type awards struct {
+	levels       []*models.UserLevel
+	achievements []*models.UserAchievement
+}
+
+// award gives the rewards of a leaderboard to the finishers of a closed
+// period, every member of a team on a team leaderboard. Achievements a user
+// already completed are not given twice.
+func (s *LeaderboardService) award(tx *gorm.DB, leaderboard *models.Leaderboard, period *models.LeaderboardPeriod) (*awards, error) {
+	result := &awards{}
+	var rewards []*models.LeaderboardReward
+	if err := tx.Where("leaderboard_id = ?", leaderboard.Id).Find(&rewards).Error; err != nil {
+		return nil, err
+	}
+	if len(rewards) == 0 {
+		return result, nil
+	}
+	maxRank := 0
+	for _, reward := range rewards {
+		if reward.MaxRank > maxRank {
+			maxRank = reward.MaxRank
+		}
+	}
+
+	b := newBoard(leaderboard)
+	b.start, b.end = period.PeriodStart, period.PeriodEnd
+	var finishers []*models.LeaderboardEntry
+	if err := b.ordered(tx).Where(clause.Lte{Column: clause.Column{Name: "rank"}, Value: maxRank}).
+		Find(&finishers).Error; err != nil {
+		return nil, err
+	}
+
+	xpPointTypeId, err := s.Points.XpPointTypeId(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	reference := fmt.Sprintf("leaderboardperiod:%d", period.Id)
+	for _, finisher := range finishers {
+		recipients := []uint{}
+		if b.column == "team_id" {
+			if err := tx.Model(&models.TeamMember{}).Where("team_id = ?", *finisher.TeamId).
+				Order("user_id").Pluck("user_id", &recipients).Error; err != nil {
+				return nil, err
+			}
+		} else {
+			recipients = append(recipients, *finisher.UserId)
+		}
+
+		for _, reward := range rewards {
+			if finisher.Rank > reward.MaxRank {
+				continue
+			}
+			for _, userId := range recipients {
+				if reward.PointTypeId != nil && reward.Amount > 0 {
+					if _, err := s.Points.Credit(tx, userId, *reward.PointTypeId, reward.Amount, models.PointTransactionEarn, reference); err != nil {
+						return nil, err
+					}
+					if *reward.PointTypeId == xpPointTypeId {
+						level, changed, err := s.Levels.AddXp(tx, userId, reward.Amount)
+						if err != nil {
+							return nil, err
+						}
+						if changed {
+							result.levels = append(result.levels, level)
+						}
+					}
+				}
+				if reward.AchievementId != nil {
+					achievement, granted, err := s.Achievements.Grant(tx, userId, *reward.AchievementId)
+					if err != nil {
+						return nil, err
+					}
+					if granted {
+						result.achievements = append(result.achievements, achievement)
+					}
+				}
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,630 @@
+package leaderboards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"base/core/app/users"
+	"base/core/logger"
+	"base/core/types"
+	"base/packages/gamification/leaderboard_store"
+	"base/packages/gamification/models"
+	"base/packages/gamification/segments"
+
+	"gorm.io/gorm"
+)
+
+// Limits of the top and around queries
+const (
+	DefaultTopSize      = 10
+	MaxTopSize          = 1000
+	DefaultAroundRadius = 5
+	MaxAroundRadius     = 100
+)
+
+// ErrNotRanked is returned when a user has no entry in the requested period
+var ErrNotRanked = errors.New("user is not ranked on this leaderboard period")
+
+// board is one period of a leaderboard, or of one of its segments. Standings
+// are ordered by score, highest first, then by user or team id so that ties
+// always list the same way. A live board is served from the store, any other
+// from the entry snapshots. A friends board is ranked from list, the scores of
+// the friends read from the whole leaderboard.
+type board struct {
+	leaderboard *models.Leaderboard
+	column      string
+	segment     string
+	label       string
+	key         string
+	start       types.DateTime
+	end         types.DateTime
+	found       bool
+	friends     bool
+	list        []leaderboard_store.Entry
+}
+
+func newBoard(leaderboard *models.Leaderboard) *board {
+	b := &board{leaderboard: leaderboard, column: "user_id"}
+	if leaderboard.Scope == models.LeaderboardScopeTeam {
+		b.column = "team_id"
+	}
+	return b
+}
+
+func (b *board) live() bool {
+	return b.key != ""
+}
+
+// boardPrefix starts the names of the store boards of a leaderboard period
+func boardPrefix(leaderboard *models.Leaderboard, start time.Time) string {
+	return fmt.Sprintf("%d:%d:%d:", leaderboard.TenantId, leaderboard.Id, start.Unix())
+}
+
+// boardKey names the store board of a leaderboard period segment, "" being
+// the whole leaderboard
+func boardKey(leaderboard *models.Leaderboard, start time.Time, segment string) string {
+	return boardPrefix(leaderboard, start) + segment
+}
+
+func (s *LeaderboardService) context() context.Context {
+	return s.DB.Statement.Context
+}
+
+// board loads a leaderboard segment and picks the period running at at. The
+// store serves it when it holds scores for that period; otherwise the latest
+// snapshot period that had started at at is used. A friends segment without
+// a value ranks the friends of userId.
+func (s *LeaderboardService) board(leaderboardId uint, at time.Time, segment string, userId uint) (*board, error) {
+	leaderboard := &models.Leaderboard{}
+	if err := s.DB.First(leaderboard, leaderboardId).Error; err != nil {
+		s.Logger.Error("failed to find leaderboard for standings",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboardId)))
+		return nil, fmt.Errorf("failed to find leaderboard: %w", err)
+	}
+
+	b := newBoard(leaderboard)
+	seg, err := segments.Parse(segment)
+	if err != nil {
+		return nil, err
+	}
+	if !seg.IsZero() {
+		if seg.Dimension != leaderboard.SegmentBy {
+			return nil, segments.ErrInvalidSegment
+		}
+		if seg.Dimension != segments.DimensionFriends {
+			b.segment = seg.String()
+		} else {
+			if b.column == "team_id" {
+				return nil, segments.ErrInvalidSegment
+			}
+			if seg.Value == "" {
+				if userId == 0 {
+					return nil, segments.ErrInvalidSegment
+				}
+				seg.Value = strconv.FormatUint(uint64(userId), 10)
+			}
+			owner, err := strconv.ParseUint(seg.Value, 10, 32)
+			if err != nil {
+				return nil, segments.ErrInvalidSegment
+			}
+			userId, b.friends = uint(owner), true
+		}
+		b.label = seg.String()
+	}
+
+	if err := s.period(b, at); err != nil {
+		return nil, err
+	}
+	if b.friends && b.found {
+		if err := s.rankFriends(b, userId); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// period picks the live or snapshot period of a board running at at
+func (s *LeaderboardService) period(b *board, at time.Time) error {
+	leaderboard := b.leaderboard
+	start, end := leaderboard.PeriodAt(at)
+	key := boardKey(leaderboard, start, b.segment)
+	count, err := s.Store.Count(s.context(), key)
+	if err != nil {
+		s.Logger.Error("failed to count live leaderboard scores",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboard.Id)))
+		return err
+	}
+	if count > 0 {
+		b.key, b.found = key, true
+		b.start, b.end = types.DateTime{Time: start}, types.DateTime{Time: end}
+		return nil
+	}
+
+	latest := &models.LeaderboardEntry{}
+	err = s.DB.Select("period_start", "period_end").
+		Where("leaderboard_id = ? AND segment = ? AND period_start <= ?", leaderboard.Id, b.segment, at).
+		Order("period_start DESC").
+		Take(latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		s.Logger.Error("failed to find leaderboard period",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboard.Id)))
+		return fmt.Errorf("failed to find leaderboard period: %w", err)
+	}
+
+	b.start, b.end, b.found = latest.PeriodStart, latest.PeriodEnd, true
+	return nil
+}
+
+// rankFriends reads the scores of userId and their friends into the board's
+// list, in standing order. Friends without a score are left out.
+func (s *LeaderboardService) rankFriends(b *board, userId uint) error {
+	friends, err := segments.Friends(s.context(), userId)
+	if err != nil {
+		s.Logger.Error("failed to get friends of user",
+			logger.String("error", err.Error()),
+			logger.Int("user_id", int(userId)))
+		return fmt.Errorf("failed to get friends: %w", err)
+	}
+
+	ids := []uint{userId}
+	seen := map[uint]bool{userId: true}
+	for _, id := range friends {
+		if len(ids) > segments.MaxFriends {
+			break
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	b.list = []leaderboard_store.Entry{}
+	if b.live() {
+		for _, id := range ids {
+			score, ok, err := s.Store.Score(s.context(), b.key, id)
+			if err != nil {
+				s.Logger.Error("failed to get live leaderboard score",
+					logger.String("error", err.Error()),
+					logger.Int("id", int(b.leaderboard.Id)))
+				return err
+			}
+			if ok {
+				b.list = append(b.list, leaderboard_store.Entry{Member: id, Score: score})
+			}
+		}
+	} else {
+		var items []*models.LeaderboardEntry
+		if err := b.entries(s.DB).Where("user_id IN ?", ids).Find(&items).Error; err != nil {
+			s.Logger.Error("failed to get leaderboard entries of friends",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(b.leaderboard.Id)))
+			return fmt.Errorf("failed to get leaderboard entries of friends: %w", err)
+		}
+		for _, item := range items {
+			b.list = append(b.list, leaderboard_store.Entry{Member: *item.UserId, Score: item.Score})
+		}
+	}
+
+	sort.Slice(b.list, func(i, j int) bool {
+		if b.list[i].Score != b.list[j].Score {
+			return b.list[i].Score > b.list[j].Score
+		}
+		return b.list[i].Member < b.list[j].Member
+	})
+	return nil
+}
+
+// entries scopes a query to the entries of the board's period
+func (b *board) entries(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.LeaderboardEntry{}).
+		Where("leaderboard_id = ? AND period_start = ? AND segment = ?", b.leaderboard.Id, b.start, b.segment).
+		Where(b.column + " IS NOT NULL")
+}
+
+// ordered sorts the board's entries in standing order
+func (b *board) ordered(db *gorm.DB) *gorm.DB {
+	return b.entries(db).Order("score DESC").Order(b.column + " ASC").Order("id ASC")
+}
+
+// subject returns the id an entry ranks, the user or the team
+func (b *board) subject(entry *models.LeaderboardEntry) uint {
+	if b.column == "team_id" {
+		return *entry.TeamId
+	}
+	return *entry.UserId
+}
+
+// entry turns a store entry into an unsaved leaderboard entry
+func (b *board) entry(e leaderboard_store.Entry) *models.LeaderboardEntry {
+	subject := e.Member
+	item := &models.LeaderboardEntry{
+		LeaderboardId: b.leaderboard.Id,
+		Score:         e.Score,
+		PeriodStart:   b.start,
+		PeriodEnd:     b.end,
+		Segment:       b.segment,
+	}
+	if b.column == "team_id" {
+		item.TeamId = &subject
+	} else {
+		item.UserId = &subject
+	}
+	return item
+}
+
+// Top returns the n best standings of a leaderboard period
+func (s *LeaderboardService) Top(leaderboardId uint, segment string, at time.Time, n int) (*models.LeaderboardStandingsResponse, error) {
+	b, err := s.board(leaderboardId, at, segment, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.standings(b, 0, n)
+}
+
+// Around returns the standings within radius places of userId. On a team
+// leaderboard it centres on the best placed team the user belongs to.
+func (s *LeaderboardService) Around(leaderboardId uint, segment string, userId uint, at time.Time, radius int) (*models.LeaderboardStandingsResponse, error) {
+	b, err := s.board(leaderboardId, at, segment, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.entryOf(b, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	position, err := s.positionOf(b, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := position - radius
+	if offset < 0 {
+		offset = 0
+	}
+	return s.standings(b, offset, position-offset+radius+1)
+}
+
+// UserRank returns the standing of userId in a leaderboard period. On a team
+// leaderboard it is the standing of the best placed team the user belongs to.
+func (s *LeaderboardService) UserRank(leaderboardId uint, segment string, userId uint, at time.Time) (*models.LeaderboardRankResponse, error) {
+	b, err := s.board(leaderboardId, at, segment, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.entryOf(b, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.rankResponse(b, entry)
+}
+
+// rankResponse wraps the standing of one entry
+func (s *LeaderboardService) rankResponse(b *board, entry *models.LeaderboardEntry) (*models.LeaderboardRankResponse, error) {
+	rank, err := s.rankOf(b, entry.Score)
+	if err != nil {
+		return nil, err
+	}
+
+	participants, err := s.participants(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LeaderboardRankResponse{
+		LeaderboardId: b.leaderboard.Id,
+		Segment:       b.label,
+		PeriodStart:   b.start,
+		PeriodEnd:     b.end,
+		Participants:  participants,
+		Standing:      standing(entry, rank),
+	}, nil
+}
+
+// standings lists limit standings of a board starting at offset
+func (s *LeaderboardService) standings(b *board, offset int, limit int) (*models.LeaderboardStandingsResponse, error) {
+	result := &models.LeaderboardStandingsResponse{
+		LeaderboardId: b.leaderboard.Id,
+		Segment:       b.label,
+		PeriodStart:   b.start,
+		PeriodEnd:     b.end,
+		Standings:     []*models.LeaderboardStanding{},
+	}
+	if !b.found {
+		return result, nil
+	}
+
+	participants, err := s.participants(b)
+	if err != nil {
+		return nil, err
+	}
+	result.Participants = participants
+
+	items, err := s.page(b, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return result, nil
+	}
+
+	// Only the first rank needs a query, the others follow from the order
+	rank, err := s.rankOf(b, items[0].Score)
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		if i > 0 && item.Score != items[i-1].Score {
+			rank = offset + i + 1
+		}
+		result.Standings = append(result.Standings, standing(item, rank))
+	}
+
+	return result, nil
+}
+
+// page returns limit entries of a board in standing order starting at offset
+func (s *LeaderboardService) page(b *board, offset int, limit int) ([]*models.LeaderboardEntry, error) {
+	if b.friends {
+		items := []*models.LeaderboardEntry{}
+		for i := offset; i < len(b.list) && len(items) < limit; i++ {
+			items = append(items, b.entry(b.list[i]))
+		}
+		return items, s.hydrate(b, items)
+	}
+	if b.live() {
+		entries, err := s.Store.Range(s.context(), b.key, offset, limit)
+		if err != nil {
+			s.Logger.Error("failed to get live leaderboard standings",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(b.leaderboard.Id)))
+			return nil, err
+		}
+		items := make([]*models.LeaderboardEntry, len(entries))
+		for i, e := range entries {
+			items[i] = b.entry(e)
+		}
+		return items, s.hydrate(b, items)
+	}
+
+	var items []*models.LeaderboardEntry
+	if err := b.ordered(s.DB).Preload("User").Preload("Team").
+		Offset(offset).Limit(limit).Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get leaderboard standings",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(b.leaderboard.Id)))
+		return nil, fmt.Errorf("failed to get leaderboard standings: %w", err)
+	}
+	return items, nil
+}
+
+// hydrate loads the users or teams of entries built from the store
+func (s *LeaderboardService) hydrate(b *board, items []*models.LeaderboardEntry) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(items))
+	for i, item := range items {
+		ids[i] = b.subject(item)
+	}
+
+	if b.column == "team_id" {
+		var teams []*models.Team
+		if err := s.DB.Where("id IN ?", ids).Find(&teams).Error; err != nil {
+			return fmt.Errorf("failed to get leaderboard teams: %w", err)
+		}
+		byId := make(map[uint]*models.Team, len(teams))
+		for _, team := range teams {
+			byId[team.Id] = team
+		}
+		for _, item := range items {
+			item.Team = byId[*item.TeamId]
+		}
+		return nil
+	}
+
+	var members []*users.User
+	if err := s.DB.Where("id IN ?", ids).Find(&members).Error; err != nil {
+		return fmt.Errorf("failed to get leaderboard users: %w", err)
+	}
+	byId := make(map[uint]*users.User, len(members))
+	for _, member := range members {
+		byId[member.Id] = member
+	}
+	for _, item := range items {
+		item.User = byId[*item.UserId]
+	}
+	return nil
+}
+
+// entryOf returns the entry that ranks userId on a board
+func (s *LeaderboardService) entryOf(b *board, userId uint) (*models.LeaderboardEntry, error) {
+	if !b.found {
+		return nil, ErrNotRanked
+	}
+	if b.friends {
+		for _, e := range b.list {
+			if e.Member == userId {
+				entry := b.entry(e)
+				return entry, s.hydrate(b, []*models.LeaderboardEntry{entry})
+			}
+		}
+		return nil, ErrNotRanked
+	}
+	if b.live() {
+		return s.liveEntryOf(b, userId)
+	}
+
+	query := b.ordered(s.DB).Preload("User").Preload("Team")
+	if b.column == "team_id" {
+		query = query.Where("team_id IN (?)", s.teamsOf(userId))
+	} else {
+		query = query.Where("user_id = ?", userId)
+	}
+
+	entry := &models.LeaderboardEntry{}
+	if err := query.Take(entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotRanked
+		}
+		s.Logger.Error("failed to get leaderboard entry of user",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(b.leaderboard.Id)),
+			logger.Int("user_id", int(userId)))
+		return nil, fmt.Errorf("failed to get leaderboard entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+// liveEntryOf reads the score of userId, or of their best placed team, from the store
+func (s *LeaderboardService) liveEntryOf(b *board, userId uint) (*models.LeaderboardEntry, error) {
+	subjects := []uint{userId}
+	if b.column == "team_id" {
+		subjects = nil
+		if err := s.teamsOf(userId).Order("team_id").Pluck("team_id", &subjects).Error; err != nil {
+			s.Logger.Error("failed to get teams of user",
+				logger.String("error", err.Error()),
+				logger.Int("user_id", int(userId)))
+			return nil, fmt.Errorf("failed to get teams of user: %w", err)
+		}
+	}
+
+	var best *leaderboard_store.Entry
+	for _, subject := range subjects {
+		score, ok, err := s.Store.Score(s.context(), b.key, subject)
+		if err != nil {
+			s.Logger.Error("failed to get live leaderboard score",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(b.leaderboard.Id)))
+			return nil, err
+		}
+		if ok && (best == nil || score > best.Score) {
+			best = &leaderboard_store.Entry{Member: subject, Score: score}
+		}
+	}
+	if best == nil {
+		return nil, ErrNotRanked
+	}
+
+	entry := b.entry(*best)
+	return entry, s.hydrate(b, []*models.LeaderboardEntry{entry})
+}
+
+// teamsOf selects the ids of the teams userId belongs to
+func (s *LeaderboardService) teamsOf(userId uint) *gorm.DB {
+	return s.DB.Model(&models.TeamMember{}).Select("team_id").Where("user_id = ?", userId)
+}
+
+// positionOf returns the 0-based place of an entry in the board's order
+func (s *LeaderboardService) positionOf(b *board, entry *models.LeaderboardEntry) (int, error) {
+	if b.friends {
+		for i, e := range b.list {
+			if e.Member == b.subject(entry) {
+				return i, nil
+			}
+		}
+		return 0, ErrNotRanked
+	}
+	if b.live() {
+		position, _, err := s.Store.Position(s.context(), b.key, b.subject(entry))
+		if err != nil {
+			s.Logger.Error("failed to get live leaderboard position",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(b.leaderboard.Id)))
+		}
+		return position, err
+	}
+
+	var ahead int64
+	if err := b.entries(s.DB).
+		Where("score > ? OR (score = ? AND "+b.column+" < ?)", entry.Score, entry.Score, b.subject(entry)).
+		Count(&ahead).Error; err != nil {
+		s.Logger.Error("failed to count leaderboard position",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(b.leaderboard.Id)))
+		return 0, fmt.Errorf("failed to count leaderboard position: %w", err)
+	}
+	return int(ahead), nil
+}
+
+// rankOf returns the rank of a score, one more than the number of better scores
+func (s *LeaderboardService) rankOf(b *board, score int) (int, error) {
+	if b.friends {
+		rank := 1
+		for _, e := range b.list {
+			if e.Score > score {
+				rank++
+			}
+		}
+		return rank, nil
+	}
+	if b.live() {
+		rank, err := s.Store.Rank(s.context(), b.key, score)
+		if err != nil {
+			s.Logger.Error("failed to get live leaderboard rank",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(b.leaderboard.Id)))
+		}
+		return rank, err
+	}
+
+	var better int64
+	if err := b.entries(s.DB).Where("score > ?", score).Count(&better).Error; err != nil {
+		s.Logger.Error("failed to count better scores",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(b.leaderboard.Id)))
+		return 0, fmt.Errorf("failed to count better scores: %w", err)
+	}
+	return int(better) + 1, nil
+}
+
+// participants counts the users or teams ranked on a board
+func (s *LeaderboardService) participants(b *board) (int, error) {
+	if b.friends {
+		return len(b.list), nil
+	}
+	if b.live() {
+		total, err := s.Store.Count(s.context(), b.key)
+		if err != nil {
+			s.Logger.Error("failed to count live leaderboard participants",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(b.leaderboard.Id)))
+		}
+		return total, err
+	}
+
+	var total int64
+	if err := b.entries(s.DB).Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count leaderboard participants",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(b.leaderboard.Id)))
+		return 0, fmt.Errorf("failed to count leaderboard participants: %w", err)
+	}
+	return int(total), nil
+}
+
+func standing(entry *models.LeaderboardEntry, rank int) *models.LeaderboardStanding {
+	return &models.LeaderboardStanding{
+		Rank:   rank,
+		Score:  entry.Score,
+		UserId: entry.UserId,
+		User:   entry.User,
+		TeamId: entry.TeamId,
+		Team:   entry.Team,
+	}
+}
@@ -0,0 +1,194 @@
+package leaderboards
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"base/core/logger"
+	"base/packages/gamification/leaderboard_entries"
+	"base/packages/gamification/leaderboard_store"
+	"base/packages/gamification/models"
+	"base/packages/gamification/segments"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const SubmitScoreEvent = "leaderboards.score"
+
+// ErrLeaderboardInactive is returned when scores are submitted to an inactive leaderboard
+var ErrLeaderboardInactive = errors.New("leaderboard is not active")
+
+// SubmitScore sets or increments the live score of a user, or of a team on a
+// team leaderboard, for the current period and returns its new standing. On a
+// segmented leaderboard the score is also written to the subject's segment.
+// The period is recorded on its first score and takes none once closed.
+func (s *LeaderboardService) SubmitScore(leaderboardId uint, req *models.SubmitLeaderboardScoreRequest) (*models.LeaderboardRankResponse, error) {
+	leaderboard := &models.Leaderboard{}
+	if err := s.DB.First(leaderboard, leaderboardId).Error; err != nil {
+		s.Logger.Error("failed to find leaderboard for score",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboardId)))
+		return nil, fmt.Errorf("failed to find leaderboard: %w", err)
+	}
+	if !leaderboard.IsActive {
+		return nil, ErrLeaderboardInactive
+	}
+
+	subject := req.UserId
+	if leaderboard.Scope == models.LeaderboardScopeTeam {
+		if req.TeamId == 0 || req.UserId != 0 {
+			return nil, leaderboard_entries.ErrSubjectMismatch
+		}
+		subject = req.TeamId
+	} else if req.UserId == 0 || req.TeamId != 0 {
+		return nil, leaderboard_entries.ErrSubjectMismatch
+	}
+
+	segment, err := s.segmentOf(leaderboard, subject, req.Segment)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	start, end := leaderboard.PeriodAt(now)
+	period, err := s.openPeriod(leaderboard, start, end)
+	if err != nil {
+		s.Logger.Error("failed to open leaderboard period",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboard.Id)))
+		return nil, err
+	}
+
+	// The shared lock on the period keeps a close from snapshotting it before
+	// the score is written, and a closing period takes no more scores
+	key := boardKey(leaderboard, start, "")
+	var score int
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		locked := &models.LeaderboardPeriod{}
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(locked, period.Id).Error; err != nil {
+			s.Logger.Error("failed to lock leaderboard period",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(leaderboard.Id)),
+				logger.Int("period_id", int(period.Id)))
+			return fmt.Errorf("failed to lock leaderboard period: %w", err)
+		}
+		if locked.Status != models.LeaderboardPeriodOpen {
+			return ErrPeriodClosed
+		}
+
+		var err error
+		if score, err = s.writeScore(leaderboard, start, "", subject, req); err != nil {
+			return err
+		}
+		if segment != "" {
+			if _, err := s.writeScore(leaderboard, start, segment, subject, req); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b := newBoard(leaderboard)
+	b.key, b.found = key, true
+	b.start.Time, b.end.Time = start, end
+
+	entry := b.entry(leaderboard_store.Entry{Member: subject, Score: score})
+	if err := s.hydrate(b, []*models.LeaderboardEntry{entry}); err != nil {
+		return nil, err
+	}
+	result, err := s.rankResponse(b, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	// Emit score event
+	s.Emitter.Emit(SubmitScoreEvent, result)
+
+	return result, nil
+}
+
+// segmentOf returns the segment a score is also written to: the given value
+// or the resolver's of the leaderboard's dimension. Friends aren't stored
+// apart, they are ranked from the whole leaderboard.
+func (s *LeaderboardService) segmentOf(leaderboard *models.Leaderboard, subject uint, value string) (string, error) {
+	if leaderboard.SegmentBy == "" || leaderboard.SegmentBy == segments.DimensionFriends {
+		return "", nil
+	}
+	if value == "" {
+		var err error
+		value, err = segments.Value(s.context(), leaderboard.SegmentBy, subject)
+		if err != nil {
+			s.Logger.Error("failed to resolve leaderboard segment",
+				logger.String("error", err.Error()),
+				logger.String("dimension", leaderboard.SegmentBy),
+				logger.Int("subject_id", int(subject)))
+			return "", fmt.Errorf("failed to resolve segment: %w", err)
+		}
+		if value == "" {
+			return "", nil
+		}
+	}
+	return segments.Segment{Dimension: leaderboard.SegmentBy, Value: value}.String(), nil
+}
+
+// writeScore sets or increments a score on the store board of a period segment
+func (s *LeaderboardService) writeScore(leaderboard *models.Leaderboard, start time.Time, segment string, subject uint, req *models.SubmitLeaderboardScoreRequest) (int, error) {
+	key := boardKey(leaderboard, start, segment)
+	if err := s.warm(leaderboard, start, segment, key); err != nil {
+		return 0, err
+	}
+
+	score := req.Score
+	var err error
+	if req.Increment {
+		score, err = s.Store.Incr(s.context(), key, subject, req.Score)
+	} else {
+		err = s.Store.Set(s.context(), key, subject, req.Score)
+	}
+	if err != nil {
+		s.Logger.Error("failed to submit leaderboard score",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboard.Id)),
+			logger.Int("subject_id", int(subject)))
+		return 0, err
+	}
+	return score, nil
+}
+
+// warm fills a store board from the snapshot of its period the first time
+// it is written to, so that increments continue from the last snapshot after
+// the store lost its data. The store only loads the snapshot once per board.
+func (s *LeaderboardService) warm(leaderboard *models.Leaderboard, start time.Time, segment string, key string) error {
+	err := s.Store.Restore(s.context(), key, func() ([]leaderboard_store.Entry, error) {
+		var items []*models.LeaderboardEntry
+		if err := s.DB.Where("leaderboard_id = ? AND period_start = ? AND segment = ?", leaderboard.Id, start, segment).
+			Find(&items).Error; err != nil {
+			return nil, fmt.Errorf("failed to get leaderboard snapshot: %w", err)
+		}
+
+		entries := make([]leaderboard_store.Entry, 0, len(items))
+		for _, item := range items {
+			subject := item.UserId
+			if leaderboard.Scope == models.LeaderboardScopeTeam {
+				subject = item.TeamId
+			}
+			if subject == nil {
+				continue
+			}
+			entries = append(entries, leaderboard_store.Entry{Member: *subject, Score: item.Score})
+		}
+		return entries, nil
+	})
+	if err != nil {
+		s.Logger.Error("failed to restore leaderboard scores",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(leaderboard.Id)))
+		return err
+	}
+	return nil
+}
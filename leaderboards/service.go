@@ -1,6 +1,8 @@
 package leaderboards
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 
@@ -8,7 +10,11 @@ import (
 	"base/core/logger"
 	"base/core/storage"
 	"base/core/types"
+	"base/packages/gamification/leaderboard_store"
 	"base/packages/gamification/models"
+	"base/packages/gamification/user_achievements"
+	"base/packages/gamification/user_levels"
+	"base/packages/gamification/user_points"
 
 	"gorm.io/gorm"
 )
@@ -20,28 +26,65 @@ const (
 )
 
 type LeaderboardService struct {
-	DB      *gorm.DB
-	Emitter *emitter.Emitter
-	Storage *storage.ActiveStorage
-	Logger  logger.Logger
+	DB           *gorm.DB
+	Emitter      *emitter.Emitter
+	Storage      *storage.ActiveStorage
+	Logger       logger.Logger
+	Store        leaderboard_store.LeaderboardStore
+	Points       *user_points.UserPointService
+	Levels       *user_levels.UserLevelService
+	Achievements *user_achievements.UserAchievementService
 }
 
 func NewLeaderboardService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *LeaderboardService {
 	return &LeaderboardService{
-		DB:      db,
-		Emitter: emitter,
-		Storage: storage,
-		Logger:  logger,
+		DB:           db,
+		Emitter:      emitter,
+		Storage:      storage,
+		Logger:       logger,
+		Store:        leaderboard_store.Shared(db, logger),
+		Points:       user_points.NewUserPointService(db, emitter, storage, logger),
+		Levels:       user_levels.NewUserLevelService(db, emitter, storage, logger),
+		Achievements: user_achievements.NewUserAchievementService(db, emitter, storage, logger),
 	}
 }
 
+func (s *LeaderboardService) WithContext(ctx context.Context) *LeaderboardService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	clone.Points = s.Points.WithContext(ctx)
+	clone.Levels = s.Levels.WithContext(ctx)
+	clone.Achievements = s.Achievements.WithContext(ctx)
+	return &clone
+}
+
 func (s *LeaderboardService) Create(req *models.CreateLeaderboardRequest) (*models.Leaderboard, error) {
+	key, err := models.NewKey(s.DB, &models.Leaderboard{}, req.Key, req.Name)
+	if err != nil {
+		s.Logger.Error("failed to assign leaderboard key", logger.String("error", err.Error()))
+		return nil, err
+	}
+	scope := req.Scope
+	if scope == "" {
+		scope = models.LeaderboardScopeUser
+	}
+
+	rewards, err := s.buildRewards(req.Rewards)
+	if err != nil {
+		s.Logger.Error("failed to build leaderboard rewards", logger.String("error", err.Error()))
+		return nil, err
+	}
+
 	item := &models.Leaderboard{
+		Key:            key,
 		Name:           req.Name,
 		Type:           req.Type,
 		Period:         req.Period,
 		ResetFrequency: req.ResetFrequency,
 		IsActive:       req.IsActive,
+		Scope:          scope,
+		SegmentBy:      req.SegmentBy,
+		Rewards:        rewards,
 	}
 
 	if err := s.DB.Create(item).Error; err != nil {
@@ -81,8 +124,44 @@ func (s *LeaderboardService) Update(id uint, req *models.UpdateLeaderboardReques
 	if req.IsActive != "" {
 		updates["is_active"] = req.IsActive
 	}
+	if req.Scope != "" {
+		updates["scope"] = req.Scope
+	}
+	if req.SegmentBy != "" {
+		updates["segment_by"] = req.SegmentBy
+	}
 
-	if err := s.DB.Model(item).Updates(updates).Error; err != nil {
+	var rewards []*models.LeaderboardReward
+	if req.Rewards != nil {
+		var err error
+		if rewards, err = s.buildRewards(req.Rewards); err != nil {
+			s.Logger.Error("failed to build leaderboard rewards",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(id)))
+			return nil, err
+		}
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(item).Updates(updates).Error; err != nil {
+			return err
+		}
+		if req.Rewards == nil {
+			return nil
+		}
+		// A rewards list replaces the current rewards as a whole
+		if err := tx.Where("leaderboard_id = ?", item.Id).Delete(&models.LeaderboardReward{}).Error; err != nil {
+			return err
+		}
+		for _, reward := range rewards {
+			reward.LeaderboardId = item.Id
+			if err := tx.Create(reward).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		s.Logger.Error("failed to update leaderboard",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -114,7 +193,11 @@ func (s *LeaderboardService) Delete(id uint) error {
 
 	// Delete file attachments if any
 
-	if err := s.DB.Delete(item).Error; err != nil {
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
 		s.Logger.Error("failed to delete leaderboard",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -142,6 +225,22 @@ func (s *LeaderboardService) GetById(id uint) (*models.Leaderboard, error) {
 	return item, nil
 }
 
+// GetByKey returns the leaderboard identified by its key
+func (s *LeaderboardService) GetByKey(key string) (*models.Leaderboard, error) {
+	item := &models.Leaderboard{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.Where(&models.Leaderboard{Key: key}).First(item).Error; err != nil {
+		s.Logger.Error("failed to get leaderboard by key",
+			logger.String("error", err.Error()),
+			logger.String("key", key))
+		return nil, fmt.Errorf("failed to get leaderboard: %w", err)
+	}
+
+	return item, nil
+}
+
 func (s *LeaderboardService) GetAll(page *int, limit *int) (*types.PaginatedResponse, error) {
 	var items []*models.Leaderboard
 	var total int64
@@ -201,3 +300,48 @@ func (s *LeaderboardService) GetAll(page *int, limit *int) (*types.PaginatedResp
 		},
 	}, nil
 }
+
+// buildRewards turns reward requests into rewards, resolving point type and
+// achievement keys and checking that they exist
+func (s *LeaderboardService) buildRewards(reqs []models.LeaderboardRewardRequest) ([]*models.LeaderboardReward, error) {
+	rewards := make([]*models.LeaderboardReward, 0, len(reqs))
+	check := models.CheckReferences(s.DB)
+	for i, req := range reqs {
+		reward := &models.LeaderboardReward{MaxRank: req.MaxRank, Amount: req.Amount}
+
+		pointTypeId := req.PointTypeId
+		if req.PointTypeKey != "" {
+			id, err := models.ResolveFieldKey(s.DB, &models.PointType{}, fmt.Sprintf("rewards[%d].point_type_key", i), req.PointTypeKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve point type: %w", err)
+			}
+			pointTypeId = id
+		}
+		if (pointTypeId != 0) != (req.Amount > 0) {
+			return nil, ErrInvalidReward
+		}
+		if pointTypeId != 0 {
+			reward.PointTypeId = &pointTypeId
+		}
+
+		achievementId := req.AchievementId
+		if req.AchievementKey != "" {
+			id, err := models.ResolveFieldKey(s.DB, &models.Achievement{}, fmt.Sprintf("rewards[%d].achievement_key", i), req.AchievementKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve achievement: %w", err)
+			}
+			achievementId = id
+		}
+		if achievementId != 0 {
+			reward.AchievementId = &achievementId
+		}
+
+		check.Exists(fmt.Sprintf("rewards[%d].point_type_id", i), &models.PointType{}, pointTypeId).
+			Exists(fmt.Sprintf("rewards[%d].achievement_id", i), &models.Achievement{}, achievementId)
+		rewards = append(rewards, reward)
+	}
+	if err := check.Err(); err != nil {
+		return nil, err
+	}
+	return rewards, nil
+}
@@ -0,0 +1,191 @@
+package leaderboards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"base/core/logger"
+	"base/packages/gamification/models"
+	"base/packages/gamification/tenancy"
+
+	"gorm.io/gorm"
+)
+
+// SnapshotInterval is how often live scores are written to leaderboard entries
+const SnapshotInterval = 5 * time.Minute
+
+// snapshotBatchSize bounds how many scores are written per transaction
+const snapshotBatchSize = 500
+
+// RunScheduler snapshots the live scores and closes ended periods every
+// interval. It blocks, so callers run it in its own goroutine.
+func (s *LeaderboardService) RunScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if _, err := s.Snapshot(now, interval); err != nil {
+			s.Logger.Error("failed to snapshot leaderboards", logger.String("error", err.Error()))
+		}
+	}
+}
+
+// Snapshot writes the live scores of every open leaderboard period and its
+// segments to its entries, with their rank. A period that ended more than
+// grace ago is closed instead, freezing its final standings and awarding the
+// leaderboard's rewards. A leaderboard that fails does not hold up the others;
+// their errors are returned together. It returns the number of entries written.
+func (s *LeaderboardService) Snapshot(now time.Time, grace time.Duration) (int, error) {
+	var leaderboards []*models.Leaderboard
+	if err := s.DB.Find(&leaderboards).Error; err != nil {
+		return 0, fmt.Errorf("failed to find leaderboards: %w", err)
+	}
+
+	written := 0
+	var failures []error
+	for _, leaderboard := range leaderboards {
+		count, err := s.snapshotLeaderboard(leaderboard, now, grace)
+		written += count
+		if err != nil {
+			s.Logger.Error("failed to snapshot leaderboard",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(leaderboard.Id)))
+			failures = append(failures, fmt.Errorf("leaderboard %d: %w", leaderboard.Id, err))
+		}
+	}
+	return written, errors.Join(failures...)
+}
+
+// snapshotLeaderboard snapshots the open periods of a leaderboard, closing
+// those that ended more than grace ago and finishing those left closing
+func (s *LeaderboardService) snapshotLeaderboard(leaderboard *models.Leaderboard, now time.Time, grace time.Duration) (int, error) {
+	scoped := s.WithContext(tenancy.WithTenant(context.Background(), leaderboard.TenantId))
+
+	var periods []*models.LeaderboardPeriod
+	if err := scoped.DB.Where("leaderboard_id = ? AND status IN ?", leaderboard.Id, []string{models.LeaderboardPeriodOpen, models.LeaderboardPeriodClosing}).
+		Order("period_start").Find(&periods).Error; err != nil {
+		return 0, fmt.Errorf("failed to find open leaderboard periods: %w", err)
+	}
+
+	written := 0
+	for _, period := range periods {
+		if period.Status == models.LeaderboardPeriodClosing || now.Sub(period.PeriodEnd.Time) > grace {
+			// A period closed by hand in the meantime is left as it is
+			if err := scoped.closePeriod(leaderboard, period); err != nil && !errors.Is(err, ErrPeriodClosed) {
+				return written, err
+			}
+			continue
+		}
+		count, err := scoped.snapshotPeriod(leaderboard, period.PeriodStart.Time, period.PeriodEnd.Time)
+		written += count
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// snapshotPeriod snapshots every store board of a period
+func (s *LeaderboardService) snapshotPeriod(leaderboard *models.Leaderboard, start time.Time, end time.Time) (int, error) {
+	prefix := boardPrefix(leaderboard, start)
+	boards, err := s.Store.Boards(context.Background(), prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for _, key := range boards {
+		count, err := s.snapshotBoard(leaderboard, start, end, strings.TrimPrefix(key, prefix))
+		written += count
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// snapshotBoard upserts one entry per member of a period segment's store board
+func (s *LeaderboardService) snapshotBoard(leaderboard *models.Leaderboard, start time.Time, end time.Time, segment string) (int, error) {
+	ctx := tenancy.WithTenant(context.Background(), leaderboard.TenantId)
+	key := boardKey(leaderboard, start, segment)
+	b := newBoard(leaderboard)
+	b.segment = segment
+	b.start.Time, b.end.Time = start, end
+
+	written, rank, last := 0, 0, 0
+	for offset := 0; ; offset += snapshotBatchSize {
+		entries, err := s.Store.Range(ctx, key, offset, snapshotBatchSize)
+		if err != nil {
+			return written, err
+		}
+		if len(entries) == 0 {
+			return written, nil
+		}
+
+		items := make([]*models.LeaderboardEntry, len(entries))
+		for i, e := range entries {
+			if position := offset + i; position == 0 || e.Score != last {
+				rank = position + 1
+			}
+			last = e.Score
+			items[i] = b.entry(e)
+			items[i].Rank = rank
+		}
+
+		err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return upsertSnapshot(tx, b, items)
+		})
+		if err != nil {
+			return written, fmt.Errorf("failed to snapshot leaderboard %d: %w", leaderboard.Id, err)
+		}
+		written += len(items)
+
+		if len(entries) < snapshotBatchSize {
+			return written, nil
+		}
+	}
+}
+
+// upsertSnapshot updates the entries of the period that changed and creates
+// the missing ones
+func upsertSnapshot(tx *gorm.DB, b *board, items []*models.LeaderboardEntry) error {
+	subjects := make([]uint, len(items))
+	for i, item := range items {
+		subjects[i] = b.subject(item)
+	}
+
+	var existing []*models.LeaderboardEntry
+	if err := b.entries(tx).Where(b.column+" IN ?", subjects).Find(&existing).Error; err != nil {
+		return err
+	}
+	bySubject := make(map[uint]*models.LeaderboardEntry, len(existing))
+	for _, entry := range existing {
+		bySubject[b.subject(entry)] = entry
+	}
+
+	var created []*models.LeaderboardEntry
+	for _, item := range items {
+		entry, ok := bySubject[b.subject(item)]
+		if !ok {
+			created = append(created, item)
+			continue
+		}
+		if entry.Score == item.Score && entry.Rank == item.Rank {
+			continue
+		}
+		if err := tx.Model(entry).Updates(map[string]interface{}{
+			"score":      item.Score,
+			"rank":       item.Rank,
+			"period_end": b.end,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	if len(created) == 0 {
+		return nil
+	}
+	return tx.Create(&created).Error
+}
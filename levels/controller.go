@@ -1,10 +1,12 @@
 package levels
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"base/core/storage"
+	"base/packages/gamification/access"
 	"base/packages/gamification/models"
 
 	"github.com/gin-gonic/gin"
@@ -27,13 +29,14 @@ func (c *LevelController) Routes(router *gin.RouterGroup) {
 	router.GET("/levels", c.List)        // Paginated list
 	router.GET("/levels/all", c.ListAll) // Unpaginated list
 	router.GET("/levels/:id", c.Get)
-	router.POST("/levels", c.Create)
-	router.PUT("/levels/:id", c.Update)
-	router.DELETE("/levels/:id", c.Delete)
+	router.GET("/levels/by-key/:key", c.GetByKey)
+	router.POST("/levels", access.Require(access.RoleAdmin), c.Create)
+	router.PUT("/levels/:id", access.Require(access.RoleAdmin), c.Update)
+	router.DELETE("/levels/:id", access.Require(access.RoleAdmin), c.Delete)
 
 	// File/Image attachment endpoints
-	router.PUT("/levels/:id/icon", c.UploadIcon)
-	router.DELETE("/levels/:id/icon", c.DeleteIcon)
+	router.PUT("/levels/:id/icon", access.Require(access.RoleAdmin), c.UploadIcon)
+	router.DELETE("/levels/:id/icon", access.Require(access.RoleAdmin), c.DeleteIcon)
 
 	// HasMany relation endpoints
 }
@@ -49,6 +52,8 @@ func (c *LevelController) Routes(router *gin.RouterGroup) {
 // @Param levels body models.CreateLevelRequest true "Create Level request"
 // @Success 201 {object} models.LevelResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /levels [post]
 func (c *LevelController) Create(ctx *gin.Context) {
@@ -58,8 +63,16 @@ func (c *LevelController) Create(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Create(&req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidKey) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, models.ErrDuplicateKey) {
+			ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
 		return
 	}
@@ -78,6 +91,7 @@ func (c *LevelController) Create(ctx *gin.Context) {
 // @Param id path int true "Level id"
 // @Success 200 {object} models.LevelResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /levels/{id} [get]
 func (c *LevelController) Get(ctx *gin.Context) {
@@ -87,7 +101,30 @@ func (c *LevelController) Get(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// GetLevelByKey godoc
+// @Summary Get a Level by key
+// @Description Get a Level by its stable key
+// @Tags Level
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key path string true "Level key"
+// @Success 200 {object} models.LevelResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /levels/by-key/{key} [get]
+func (c *LevelController) GetByKey(ctx *gin.Context) {
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetByKey(ctx.Param("key"))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -108,6 +145,7 @@ func (c *LevelController) Get(ctx *gin.Context) {
 // @Param limit query int false "Number of items per page"
 // @Success 200 {object} types.PaginatedResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /levels [get]
 func (c *LevelController) List(ctx *gin.Context) {
@@ -131,7 +169,7 @@ func (c *LevelController) List(ctx *gin.Context) {
 		}
 	}
 
-	paginatedResponse, err := c.Service.GetAll(page, limit)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(page, limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
 		return
@@ -149,10 +187,11 @@ func (c *LevelController) List(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /levels/all [get]
 func (c *LevelController) ListAll(ctx *gin.Context) {
-	paginatedResponse, err := c.Service.GetAll(nil, nil)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(nil, nil)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
 		return
@@ -173,6 +212,7 @@ func (c *LevelController) ListAll(ctx *gin.Context) {
 // @Param levels body models.UpdateLevelRequest true "Update Level request"
 // @Success 200 {object} models.LevelResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /levels/{id} [put]
@@ -189,7 +229,7 @@ func (c *LevelController) Update(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Update(uint(id), &req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
@@ -209,6 +249,8 @@ func (c *LevelController) Update(ctx *gin.Context) {
 // @Param id path int true "Level id"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
 // @Failure 500 {object} ErrorResponse
 // @Router /levels/{id} [delete]
 func (c *LevelController) Delete(ctx *gin.Context) {
@@ -218,7 +260,12 @@ func (c *LevelController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.Delete(uint(id)); err != nil {
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
 		return
 	}
@@ -238,6 +285,7 @@ func (c *LevelController) Delete(ctx *gin.Context) {
 // @Param file formData file true "File to upload"
 // @Success 200 {object} models.LevelResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /levels/{id}/icon [put]
@@ -255,7 +303,7 @@ func (c *LevelController) UploadIcon(ctx *gin.Context) {
 	}
 
 	// Get the item first
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -269,7 +317,7 @@ func (c *LevelController) UploadIcon(ctx *gin.Context) {
 	}
 
 	// Update the item with the new attachment
-	updatedItem, err := c.Service.UploadIcon(uint(id), file)
+	updatedItem, err := c.Service.WithContext(ctx.Request.Context()).UploadIcon(uint(id), file)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
@@ -289,6 +337,7 @@ func (c *LevelController) UploadIcon(ctx *gin.Context) {
 // @Param id path int true "Level id"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /levels/{id}/icon [delete]
@@ -300,7 +349,7 @@ func (c *LevelController) DeleteIcon(ctx *gin.Context) {
 	}
 
 	// Get the item first
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -317,7 +366,7 @@ func (c *LevelController) DeleteIcon(ctx *gin.Context) {
 		Icon: nil,
 	}
 
-	_, err = c.Service.Update(uint(id), updateReq)
+	_, err = c.Service.WithContext(ctx.Request.Context()).Update(uint(id), updateReq)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
@@ -41,7 +41,13 @@ func (m *Module) Routes(router *gin.RouterGroup) {
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.Level{})
+	if err := models.MigrateKey(m.DB, &models.Level{}, "title"); err != nil {
+		return err
+	}
+	if err := m.DB.AutoMigrate(&models.Level{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.Level{})
 }
 
 func (m *Module) GetModels() []interface{} {
@@ -1,6 +1,8 @@
 package levels
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"mime/multipart"
@@ -36,8 +38,21 @@ func NewLevelService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.Act
 	}
 }
 
+func (s *LevelService) WithContext(ctx context.Context) *LevelService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
 func (s *LevelService) Create(req *models.CreateLevelRequest) (*models.Level, error) {
+	key, err := models.NewKey(s.DB, &models.Level{}, req.Key, req.Title)
+	if err != nil {
+		s.Logger.Error("failed to assign level key", logger.String("error", err.Error()))
+		return nil, err
+	}
+
 	item := &models.Level{
+		Key:         key,
 		LevelNumber: req.LevelNumber,
 		XpRequired:  req.XpRequired,
 		Title:       req.Title,
@@ -111,17 +126,14 @@ func (s *LevelService) Delete(id uint) error {
 		return fmt.Errorf("failed to find level: %w", err)
 	}
 
-	// Delete file attachments if any
-	if item.Icon != nil {
-		if err := s.Storage.Delete(item.Icon); err != nil {
-			s.Logger.Error("failed to delete icon",
-				logger.String("error", err.Error()),
-				logger.Int("id", int(id)))
-			return fmt.Errorf("failed to delete icon: %w", err)
-		}
-	}
+	// The icon stays while the item is in the trash, so that it can be
+	// restored; purging the item deletes it
 
-	if err := s.DB.Delete(item).Error; err != nil {
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
 		s.Logger.Error("failed to delete level",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -149,6 +161,22 @@ func (s *LevelService) GetById(id uint) (*models.Level, error) {
 	return item, nil
 }
 
+// GetByKey returns the level identified by its key
+func (s *LevelService) GetByKey(key string) (*models.Level, error) {
+	item := &models.Level{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.Where(&models.Level{Key: key}).First(item).Error; err != nil {
+		s.Logger.Error("failed to get level by key",
+			logger.String("error", err.Error()),
+			logger.String("key", key))
+		return nil, fmt.Errorf("failed to get level: %w", err)
+	}
+
+	return item, nil
+}
+
 func (s *LevelService) GetAll(page *int, limit *int) (*types.PaginatedResponse, error) {
 	var items []*models.Level
 	var total int64
@@ -0,0 +1,361 @@
+package me
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"base/core/storage"
+	"base/packages/gamification/access"
+	"base/packages/gamification/leaderboards"
+	"base/packages/gamification/quests"
+	"base/packages/gamification/segments"
+	"base/packages/gamification/user_achievements"
+	"base/packages/gamification/user_activities"
+	"base/packages/gamification/user_challenges"
+	"base/packages/gamification/user_levels"
+	"base/packages/gamification/user_points"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MeController serves the authenticated user's own data. The user is always
+// taken from the auth context, never from the request.
+type MeController struct {
+	Points       *user_points.UserPointService
+	Levels       *user_levels.UserLevelService
+	Achievements *user_achievements.UserAchievementService
+	Challenges   *user_challenges.UserChallengeService
+	Activities   *user_activities.UserActivityService
+	Leaderboards *leaderboards.LeaderboardService
+	Quests       *quests.QuestService
+	Storage      *storage.ActiveStorage
+}
+
+func NewMeController(
+	points *user_points.UserPointService,
+	levels *user_levels.UserLevelService,
+	achievements *user_achievements.UserAchievementService,
+	challenges *user_challenges.UserChallengeService,
+	activities *user_activities.UserActivityService,
+	leaderboardService *leaderboards.LeaderboardService,
+	questService *quests.QuestService,
+	storage *storage.ActiveStorage,
+) *MeController {
+	return &MeController{
+		Points:       points,
+		Levels:       levels,
+		Achievements: achievements,
+		Challenges:   challenges,
+		Activities:   activities,
+		Leaderboards: leaderboardService,
+		Quests:       questService,
+		Storage:      storage,
+	}
+}
+
+func (c *MeController) Routes(router *gin.RouterGroup) {
+	router.GET("/me/points", c.GetPoints)
+	router.GET("/me/level", c.GetLevel)
+	router.GET("/me/achievements", c.GetAchievements)
+	router.GET("/me/challenges", c.GetChallenges)
+	router.GET("/me/activities", c.GetActivities)
+	router.GET("/me/leaderboards/:id/rank", c.GetRank)
+	router.GET("/me/quests", c.GetQuests)
+}
+
+// GetMyPoints godoc
+// @Summary List my points
+// @Description Get the point balances of the authenticated user
+// @Tags Me
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/points [get]
+func (c *MeController) GetPoints(ctx *gin.Context) {
+	userId, ok := c.user(ctx)
+	if !ok {
+		return
+	}
+	page, limit, ok := pagination(ctx)
+	if !ok {
+		return
+	}
+
+	paginatedResponse, err := c.Points.WithContext(ctx.Request.Context()).ForUser(userId).GetAll(page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// GetMyLevel godoc
+// @Summary Get my level
+// @Description Get the level and xp of the authenticated user
+// @Tags Me
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.UserLevelResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/level [get]
+func (c *MeController) GetLevel(ctx *gin.Context) {
+	userId, ok := c.user(ctx)
+	if !ok {
+		return
+	}
+
+	item, err := c.Levels.WithContext(ctx.Request.Context()).ForUser(userId).Current()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// GetMyAchievements godoc
+// @Summary List my achievements
+// @Description Get the achievements of the authenticated user
+// @Tags Me
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/achievements [get]
+func (c *MeController) GetAchievements(ctx *gin.Context) {
+	userId, ok := c.user(ctx)
+	if !ok {
+		return
+	}
+	page, limit, ok := pagination(ctx)
+	if !ok {
+		return
+	}
+
+	paginatedResponse, err := c.Achievements.WithContext(ctx.Request.Context()).ForUser(userId).GetAll(page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// GetMyChallenges godoc
+// @Summary List my challenges
+// @Description Get the challenges joined by the authenticated user and their progress
+// @Tags Me
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/challenges [get]
+func (c *MeController) GetChallenges(ctx *gin.Context) {
+	userId, ok := c.user(ctx)
+	if !ok {
+		return
+	}
+	page, limit, ok := pagination(ctx)
+	if !ok {
+		return
+	}
+
+	paginatedResponse, err := c.Challenges.WithContext(ctx.Request.Context()).ForUser(userId).GetAll(page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// GetMyActivities godoc
+// @Summary List my activities
+// @Description Get the activities recorded for the authenticated user
+// @Tags Me
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/activities [get]
+func (c *MeController) GetActivities(ctx *gin.Context) {
+	userId, ok := c.user(ctx)
+	if !ok {
+		return
+	}
+	page, limit, ok := pagination(ctx)
+	if !ok {
+		return
+	}
+
+	paginatedResponse, err := c.Activities.WithContext(ctx.Request.Context()).ForUser(userId).GetAll(page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// GetMyRank godoc
+// @Summary Get my rank on a leaderboard
+// @Description Get the standing of the authenticated user, or of their team on a team leaderboard
+// @Tags Me
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Leaderboard id"
+// @Param segment query string false "Segment to rank within, e.g. country:DE or friends"
+// @Param at query string false "Time within the period, RFC3339 or YYYY-MM-DD"
+// @Success 200 {object} models.LeaderboardRankResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/leaderboards/{id}/rank [get]
+func (c *MeController) GetRank(ctx *gin.Context) {
+	userId, ok := c.user(ctx)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	at, ok := leaderboards.PeriodAt(ctx)
+	if !ok {
+		return
+	}
+	if !leaderboards.AllowSegment(ctx, ctx.Query("segment"), userId) {
+		return
+	}
+
+	result, err := c.Leaderboards.WithContext(ctx.Request.Context()).UserRank(uint(id), ctx.Query("segment"), userId, at)
+	if err != nil {
+		if errors.Is(err, segments.ErrInvalidSegment) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, leaderboards.ErrNotRanked) {
+			ctx.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch standings: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// GetMyQuests godoc
+// @Summary List my quests
+// @Description Get the quests started by the authenticated user and the state of their steps
+// @Tags Me
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/quests [get]
+func (c *MeController) GetQuests(ctx *gin.Context) {
+	userId, ok := c.user(ctx)
+	if !ok {
+		return
+	}
+	page, limit, ok := pagination(ctx)
+	if !ok {
+		return
+	}
+
+	paginatedResponse, err := c.Quests.WithContext(ctx.Request.Context()).UserQuests(userId, page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// user returns the authenticated user, answering 403 when the caller
+// is not acting as a user
+func (c *MeController) user(ctx *gin.Context) (uint, bool) {
+	userId, ok := access.UserId(ctx)
+	if !ok {
+		access.Forbid(ctx)
+		return 0, false
+	}
+	return userId, true
+}
+
+// pagination parses the optional page and limit query parameters
+func pagination(ctx *gin.Context) (*int, *int, bool) {
+	var page, limit *int
+
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid page number"})
+			return nil, nil, false
+		}
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit number"})
+			return nil, nil, false
+		}
+	}
+
+	return page, limit, true
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
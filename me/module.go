@@ -0,0 +1,62 @@
+package me
+
+import (
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/module"
+	"base/core/storage"
+	"base/packages/gamification/leaderboards"
+	"base/packages/gamification/quests"
+	"base/packages/gamification/user_achievements"
+	"base/packages/gamification/user_activities"
+	"base/packages/gamification/user_challenges"
+	"base/packages/gamification/user_levels"
+	"base/packages/gamification/user_points"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Module struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Controller *MeController
+	Logger     *logger.Logger
+	Storage    *storage.ActiveStorage
+}
+
+func NewMeModule(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, storage *storage.ActiveStorage) module.Module {
+
+	controller := NewMeController(
+		user_points.NewUserPointService(db, emitter, storage, log),
+		user_levels.NewUserLevelService(db, emitter, storage, log),
+		user_achievements.NewUserAchievementService(db, emitter, storage, log),
+		user_challenges.NewUserChallengeService(db, emitter, storage, log),
+		user_activities.NewUserActivityService(db, emitter, storage, log),
+		leaderboards.NewLeaderboardService(db, emitter, storage, log),
+		quests.NewQuestService(db, emitter, storage, log),
+		storage,
+	)
+
+	m := &Module{
+		DB:         db,
+		Controller: controller,
+		Logger:     &log,
+		Storage:    storage,
+	}
+
+	return m
+}
+
+func (m *Module) Routes(router *gin.RouterGroup) {
+	m.Controller.Routes(router)
+}
+
+// Migrate has nothing to do, the me endpoints only read the other modules' tables
+func (m *Module) Migrate() error {
+	return nil
+}
+
+func (m *Module) GetModels() []interface{} {
+	return []interface{}{}
+}
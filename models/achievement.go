@@ -13,6 +13,8 @@ type Achievement struct {
 	CreatedAt       time.Time           `json:"created_at"`
 	UpdatedAt       time.Time           `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt      `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId        uint                `json:"-" gorm:"uniqueIndex:idx_achievements_tenant_live_key,where:deleted_at IS NULL;not null;default:0"`
+	Key             string              `json:"key" gorm:"uniqueIndex:idx_achievements_tenant_live_key,where:deleted_at IS NULL;size:191"`
 	Name            string              `json:"name"`
 	Description     string              `json:"description"`
 	Icon            *storage.Attachment `json:"icon,omitempty" gorm:"polymorphic:Model"`
@@ -42,6 +44,7 @@ type AchievementListResponse struct {
 	Id              uint                `json:"id"`
 	CreatedAt       time.Time           `json:"created_at"`
 	UpdatedAt       time.Time           `json:"updated_at"`
+	Key             string              `json:"key"`
 	Name            string              `json:"name"`
 	Description     string              `json:"description"`
 	Icon            *storage.Attachment `json:"icon,omitempty"`
@@ -57,6 +60,7 @@ type AchievementResponse struct {
 	CreatedAt       time.Time           `json:"created_at"`
 	UpdatedAt       time.Time           `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt      `json:"deleted_at,omitempty"`
+	Key             string              `json:"key"`
 	Name            string              `json:"name"`
 	Description     string              `json:"description"`
 	Icon            *storage.Attachment `json:"icon,omitempty"`
@@ -68,6 +72,7 @@ type AchievementResponse struct {
 
 // CreateAchievementRequest represents the request payload for creating a Achievement
 type CreateAchievementRequest struct {
+	Key             string              `json:"key,omitempty"`
 	Name            string              `json:"name" binding:"required"`
 	Description     string              `json:"description" binding:"required"`
 	Icon            *storage.Attachment `json:"icon,omitempty"`
@@ -97,6 +102,7 @@ func (item *Achievement) ToListResponse() *AchievementListResponse {
 		Id:              item.Id,
 		CreatedAt:       item.CreatedAt,
 		UpdatedAt:       item.UpdatedAt,
+		Key:             item.Key,
 		Name:            item.Name,
 		Description:     item.Description,
 		Icon:            item.Icon,
@@ -117,6 +123,7 @@ func (item *Achievement) ToResponse() *AchievementResponse {
 		CreatedAt:       item.CreatedAt,
 		UpdatedAt:       item.UpdatedAt,
 		DeletedAt:       item.DeletedAt,
+		Key:             item.Key,
 		Name:            item.Name,
 		Description:     item.Description,
 		Icon:            item.Icon,
@@ -12,6 +12,7 @@ type AchievementCriteria struct {
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId       uint           `json:"-" gorm:"index;not null;default:0"`
 	AchievementId  uint           `json:"achievement_id"`
 	Achievement    *Achievement   `json:"achievement,omitempty"`
 	ActivityTypeId uint           `json:"activity_type_id"`
@@ -62,10 +63,12 @@ type AchievementCriteriaResponse struct {
 
 // CreateAchievementCriteriaRequest represents the request payload for creating a AchievementCriteria
 type CreateAchievementCriteriaRequest struct {
-	AchievementId  uint `json:"achievement_id" binding:"required"`
-	ActivityTypeId uint `json:"activity_type_id" binding:"required"`
-	RequiredCount  int  `json:"required_count" binding:"required"`
-	TimeFrame      int  `json:"time_frame" binding:"required"`
+	AchievementId   uint   `json:"achievement_id" binding:"required_without=AchievementKey"`
+	AchievementKey  string `json:"achievement_key,omitempty"`
+	ActivityTypeId  uint   `json:"activity_type_id" binding:"required_without=ActivityTypeKey"`
+	ActivityTypeKey string `json:"activity_type_key,omitempty"`
+	RequiredCount   int    `json:"required_count" binding:"required"`
+	TimeFrame       int    `json:"time_frame" binding:"required"`
 }
 
 // UpdateAchievementCriteriaRequest represents the request payload for updating a AchievementCriteria
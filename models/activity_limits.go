@@ -0,0 +1,24 @@
+package models
+
+// ActivityLimits are the thresholds the anomaly detector holds the activities
+// of a type to; a zero value disables its check. A user may record at most
+// BurstCount activities within BurstWindow seconds, and at most RateFactor
+// times their usual hourly rate within an hour. Metadata bounds the numeric
+// values of named metadata fields.
+type ActivityLimits struct {
+	BurstCount  int                      `json:"burst_count" yaml:"burst_count"`
+	BurstWindow int                      `json:"burst_window" yaml:"burst_window"`
+	RateFactor  int                      `json:"rate_factor" yaml:"rate_factor"`
+	Metadata    map[string]MetadataLimit `json:"metadata,omitempty" yaml:"metadata,omitempty" gorm:"serializer:json"`
+}
+
+// IsSet reports whether any limit is given
+func (limits ActivityLimits) IsSet() bool {
+	return limits.BurstCount > 0 || limits.BurstWindow > 0 || limits.RateFactor > 0 || len(limits.Metadata) > 0
+}
+
+// MetadataLimit bounds a numeric metadata value; either bound may be omitted
+type MetadataLimit struct {
+	Min *float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max *float64 `json:"max,omitempty" yaml:"max,omitempty"`
+}
@@ -8,16 +8,20 @@ import (
 
 // ActivityType represents a activitytype entity
 type ActivityType struct {
-	Id             uint           `json:"id" gorm:"primarykey"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
-	Name           string         `json:"name"`
-	Description    string         `json:"description"`
-	Category       string         `json:"category"`
-	PointsValue    int            `json:"points_value"`
-	CooldownPeriod int            `json:"cooldown_period"`
-	IsActive       bool           `json:"is_active"`
+	Id             uint                  `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt        `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId       uint                  `json:"-" gorm:"uniqueIndex:idx_activitytypes_tenant_live_key,where:deleted_at IS NULL;not null;default:0"`
+	Key            string                `json:"key" gorm:"uniqueIndex:idx_activitytypes_tenant_live_key,where:deleted_at IS NULL;size:191"`
+	Name           string                `json:"name"`
+	Description    string                `json:"description"`
+	Category       string                `json:"category"`
+	PointsValue    int                   `json:"points_value"`
+	CooldownPeriod int                   `json:"cooldown_period"`
+	IsActive       bool                  `json:"is_active"`
+	Rewards        []*ActivityTypeReward `json:"rewards,omitempty" gorm:"foreignKey:ActivityTypeId"`
+	Limits         ActivityLimits        `json:"limits" gorm:"embedded;embeddedPrefix:limit_"`
 }
 
 // TableName returns the table name for the ActivityType model
@@ -37,49 +41,60 @@ func (item *ActivityType) GetModelName() string {
 
 // ActivityTypeListResponse represents the list view response
 type ActivityTypeListResponse struct {
-	Id             uint      `json:"id"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	Name           string    `json:"name"`
-	Description    string    `json:"description"`
-	Category       string    `json:"category"`
-	PointsValue    int       `json:"points_value"`
-	CooldownPeriod int       `json:"cooldown_period"`
-	IsActive       bool      `json:"is_active"`
+	Id             uint                  `json:"id"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+	Key            string                `json:"key"`
+	Name           string                `json:"name"`
+	Description    string                `json:"description"`
+	Category       string                `json:"category"`
+	PointsValue    int                   `json:"points_value"`
+	CooldownPeriod int                   `json:"cooldown_period"`
+	IsActive       bool                  `json:"is_active"`
+	Rewards        []*ActivityTypeReward `json:"rewards,omitempty"`
+	Limits         ActivityLimits        `json:"limits"`
 }
 
 // ActivityTypeResponse represents the detailed view response
 type ActivityTypeResponse struct {
-	Id             uint           `json:"id"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty"`
-	Name           string         `json:"name"`
-	Description    string         `json:"description"`
-	Category       string         `json:"category"`
-	PointsValue    int            `json:"points_value"`
-	CooldownPeriod int            `json:"cooldown_period"`
-	IsActive       bool           `json:"is_active"`
+	Id             uint                  `json:"id"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt        `json:"deleted_at,omitempty"`
+	Key            string                `json:"key"`
+	Name           string                `json:"name"`
+	Description    string                `json:"description"`
+	Category       string                `json:"category"`
+	PointsValue    int                   `json:"points_value"`
+	CooldownPeriod int                   `json:"cooldown_period"`
+	IsActive       bool                  `json:"is_active"`
+	Rewards        []*ActivityTypeReward `json:"rewards,omitempty"`
+	Limits         ActivityLimits        `json:"limits"`
 }
 
 // CreateActivityTypeRequest represents the request payload for creating a ActivityType
 type CreateActivityTypeRequest struct {
-	Name           string `json:"name" binding:"required"`
-	Description    string `json:"description" binding:"required"`
-	Category       string `json:"category" binding:"required"`
-	PointsValue    int    `json:"points_value" binding:"required"`
-	CooldownPeriod int    `json:"cooldown_period" binding:"required"`
-	IsActive       bool   `json:"is_active" binding:"required"`
+	Key            string                      `json:"key,omitempty"`
+	Name           string                      `json:"name" binding:"required"`
+	Description    string                      `json:"description" binding:"required"`
+	Category       string                      `json:"category" binding:"required"`
+	PointsValue    int                         `json:"points_value" binding:"required"`
+	CooldownPeriod int                         `json:"cooldown_period" binding:"required"`
+	IsActive       bool                        `json:"is_active" binding:"required"`
+	Rewards        []ActivityTypeRewardRequest `json:"rewards,omitempty" binding:"omitempty,dive"`
+	Limits         *ActivityLimits             `json:"limits,omitempty"`
 }
 
 // UpdateActivityTypeRequest represents the request payload for updating a ActivityType
 type UpdateActivityTypeRequest struct {
-	Name           string `json:"name,omitempty"`
-	Description    string `json:"description,omitempty"`
-	Category       string `json:"category,omitempty"`
-	PointsValue    string `json:"points_value,omitempty"`
-	CooldownPeriod string `json:"cooldown_period,omitempty"`
-	IsActive       string `json:"is_active,omitempty"`
+	Name           string                      `json:"name,omitempty"`
+	Description    string                      `json:"description,omitempty"`
+	Category       string                      `json:"category,omitempty"`
+	PointsValue    string                      `json:"points_value,omitempty"`
+	CooldownPeriod string                      `json:"cooldown_period,omitempty"`
+	IsActive       string                      `json:"is_active,omitempty"`
+	Rewards        []ActivityTypeRewardRequest `json:"rewards,omitempty" binding:"omitempty,dive"`
+	Limits         *ActivityLimits             `json:"limits,omitempty"`
 }
 
 // ToListResponse converts the model to a list response
@@ -91,12 +106,15 @@ func (item *ActivityType) ToListResponse() *ActivityTypeListResponse {
 		Id:             item.Id,
 		CreatedAt:      item.CreatedAt,
 		UpdatedAt:      item.UpdatedAt,
+		Key:            item.Key,
 		Name:           item.Name,
 		Description:    item.Description,
 		Category:       item.Category,
 		PointsValue:    item.PointsValue,
 		CooldownPeriod: item.CooldownPeriod,
 		IsActive:       item.IsActive,
+		Rewards:        item.Rewards,
+		Limits:         item.Limits,
 	}
 }
 
@@ -110,17 +128,21 @@ func (item *ActivityType) ToResponse() *ActivityTypeResponse {
 		CreatedAt:      item.CreatedAt,
 		UpdatedAt:      item.UpdatedAt,
 		DeletedAt:      item.DeletedAt,
+		Key:            item.Key,
 		Name:           item.Name,
 		Description:    item.Description,
 		Category:       item.Category,
 		PointsValue:    item.PointsValue,
 		CooldownPeriod: item.CooldownPeriod,
 		IsActive:       item.IsActive,
+		Rewards:        item.Rewards,
+		Limits:         item.Limits,
 	}
 }
 
 // Preload preloads all the model's relationships
 func (item *ActivityType) Preload(db *gorm.DB) *gorm.DB {
 	query := db
+	query = query.Preload("Rewards.PointType")
 	return query
 }
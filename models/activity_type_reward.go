@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ActivityTypeReward represents the amount of one point type an activity type awards
+type ActivityTypeReward struct {
+	Id             uint           `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId       uint           `json:"-" gorm:"index;not null;default:0"`
+	ActivityTypeId uint           `json:"activity_type_id" gorm:"index"`
+	PointTypeId    uint           `json:"point_type_id"`
+	PointType      *PointType     `json:"point_type,omitempty"`
+	Amount         int            `json:"amount"`
+}
+
+// TableName returns the table name for the ActivityTypeReward model
+func (item *ActivityTypeReward) TableName() string {
+	return "activitytyperewards"
+}
+
+// GetId returns the Id of the model
+func (item *ActivityTypeReward) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *ActivityTypeReward) GetModelName() string {
+	return "activitytypereward"
+}
+
+// ActivityTypeRewardRequest represents one reward in an ActivityType create or update payload
+type ActivityTypeRewardRequest struct {
+	PointTypeId  uint   `json:"point_type_id" binding:"required_without=PointTypeKey"`
+	PointTypeKey string `json:"point_type_key,omitempty"`
+	Amount       int    `json:"amount" binding:"required"`
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Adjustment kinds
+const (
+	AdjustmentPointGrant        = "point_grant"
+	AdjustmentPointDeduct       = "point_deduct"
+	AdjustmentAchievementGrant  = "achievement_grant"
+	AdjustmentAchievementRevoke = "achievement_revoke"
+	AdjustmentLevelSet          = "level_set"
+	AdjustmentChallengeReset    = "challenge_reset"
+)
+
+// Adjustment statuses. A point grant above its point type's
+// ApprovalThreshold stays pending until a second admin approves or rejects
+// it; every other adjustment is applied at once.
+const (
+	AdjustmentPending  = "pending"
+	AdjustmentApplied  = "applied"
+	AdjustmentRejected = "rejected"
+)
+
+// Adjustment reason codes
+const (
+	AdjustmentReasonCorrection   = "correction"
+	AdjustmentReasonCompensation = "compensation"
+	AdjustmentReasonGoodwill     = "goodwill"
+	AdjustmentReasonFraud        = "fraud"
+	AdjustmentReasonTesting      = "testing"
+	AdjustmentReasonOther        = "other"
+)
+
+// Adjustment represents a manual change an admin made to a user's points,
+// achievements, level or challenge progress, with the reason for it.
+// RequestedBy is the admin who made it and ReviewedBy the second admin who
+// approved or rejected it, if it needed one. Ledger entries of point
+// adjustments reference "adjustment:<id>".
+type Adjustment struct {
+	Id            uint           `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId      uint           `json:"-" gorm:"index;not null;default:0"`
+	UserId        uint           `json:"user_id" gorm:"index"`
+	Kind          string         `json:"kind" gorm:"size:32"`
+	PointTypeId   *uint          `json:"point_type_id"`
+	PointType     *PointType     `json:"point_type,omitempty"`
+	Amount        int            `json:"amount"`
+	AchievementId *uint          `json:"achievement_id"`
+	Achievement   *Achievement   `json:"achievement,omitempty"`
+	LevelId       *uint          `json:"level_id"`
+	Level         *Level         `json:"level,omitempty"`
+	ChallengeId   *uint          `json:"challenge_id"`
+	Challenge     *Challenge     `json:"challenge,omitempty"`
+	ReasonCode    string         `json:"reason_code" gorm:"size:32;index"`
+	Reason        string         `json:"reason"`
+	Status        string         `json:"status" gorm:"size:16;index"`
+	RequestedBy   uint           `json:"requested_by" gorm:"index"`
+	ReviewedBy    *uint          `json:"reviewed_by"`
+	AppliedAt     *time.Time     `json:"applied_at"`
+}
+
+// TableName returns the table name for the Adjustment model
+func (item *Adjustment) TableName() string {
+	return "adjustments"
+}
+
+// GetId returns the Id of the model
+func (item *Adjustment) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *Adjustment) GetModelName() string {
+	return "adjustment"
+}
+
+// AdjustmentResponse represents the adjustment view response
+type AdjustmentResponse struct {
+	Id            uint         `json:"id"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+	UserId        uint         `json:"user_id"`
+	Kind          string       `json:"kind"`
+	PointTypeId   *uint        `json:"point_type_id,omitempty"`
+	PointType     *PointType   `json:"point_type,omitempty"`
+	Amount        int          `json:"amount,omitempty"`
+	AchievementId *uint        `json:"achievement_id,omitempty"`
+	Achievement   *Achievement `json:"achievement,omitempty"`
+	LevelId       *uint        `json:"level_id,omitempty"`
+	Level         *Level       `json:"level,omitempty"`
+	ChallengeId   *uint        `json:"challenge_id,omitempty"`
+	Challenge     *Challenge   `json:"challenge,omitempty"`
+	ReasonCode    string       `json:"reason_code"`
+	Reason        string       `json:"reason"`
+	Status        string       `json:"status"`
+	RequestedBy   uint         `json:"requested_by"`
+	ReviewedBy    *uint        `json:"reviewed_by"`
+	AppliedAt     *time.Time   `json:"applied_at"`
+}
+
+// CreateAdjustmentRequest represents the request payload for adjusting a
+// user. Point kinds need a point type and an amount, achievement kinds an
+// achievement, level_set a level and challenge_reset a challenge; each may be
+// given by id or key.
+type CreateAdjustmentRequest struct {
+	UserId         uint   `json:"user_id" binding:"required"`
+	Kind           string `json:"kind" binding:"required,oneof=point_grant point_deduct achievement_grant achievement_revoke level_set challenge_reset"`
+	PointTypeId    *uint  `json:"point_type_id,omitempty"`
+	PointTypeKey   string `json:"point_type_key,omitempty"`
+	Amount         int    `json:"amount" binding:"min=0"`
+	AchievementId  *uint  `json:"achievement_id,omitempty"`
+	AchievementKey string `json:"achievement_key,omitempty"`
+	LevelId        *uint  `json:"level_id,omitempty"`
+	LevelKey       string `json:"level_key,omitempty"`
+	ChallengeId    *uint  `json:"challenge_id,omitempty"`
+	ChallengeKey   string `json:"challenge_key,omitempty"`
+	ReasonCode     string `json:"reason_code" binding:"required,oneof=correction compensation goodwill fraud testing other"`
+	Reason         string `json:"reason" binding:"required,max=1000"`
+}
+
+// ToResponse converts the model to a response
+func (item *Adjustment) ToResponse() *AdjustmentResponse {
+	if item == nil {
+		return nil
+	}
+	return &AdjustmentResponse{
+		Id:            item.Id,
+		CreatedAt:     item.CreatedAt,
+		UpdatedAt:     item.UpdatedAt,
+		UserId:        item.UserId,
+		Kind:          item.Kind,
+		PointTypeId:   item.PointTypeId,
+		PointType:     item.PointType,
+		Amount:        item.Amount,
+		AchievementId: item.AchievementId,
+		Achievement:   item.Achievement,
+		LevelId:       item.LevelId,
+		Level:         item.Level,
+		ChallengeId:   item.ChallengeId,
+		Challenge:     item.Challenge,
+		ReasonCode:    item.ReasonCode,
+		Reason:        item.Reason,
+		Status:        item.Status,
+		RequestedBy:   item.RequestedBy,
+		ReviewedBy:    item.ReviewedBy,
+		AppliedAt:     item.AppliedAt,
+	}
+}
+
+// Preload preloads all the model's relationships
+func (item *Adjustment) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	query = query.Preload("PointType", Archived)
+	query = query.Preload("Achievement", Archived)
+	query = query.Preload("Level", Archived)
+	query = query.Preload("Challenge", Archived)
+	return query
+}
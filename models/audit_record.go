@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+)
+
+// Audit actions
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditChange is the value of one field before and after a write
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditRecord represents one write to a catalog entity. Before and After are
+// the JSON snapshots of the row around the write, nil for creates and
+// deletes respectively, and Diff holds the fields that changed. ActorId and
+// ActorRole are empty for writes made by background jobs and seeding.
+// Records are never updated or deleted.
+type AuditRecord struct {
+	Id        uint                   `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time              `json:"created_at" gorm:"index"`
+	TenantId  uint                   `json:"-" gorm:"index:idx_auditrecords_entity;not null;default:0"`
+	Entity    string                 `json:"entity" gorm:"index:idx_auditrecords_entity;size:64"`
+	EntityId  uint                   `json:"entity_id" gorm:"index:idx_auditrecords_entity"`
+	Action    string                 `json:"action" gorm:"size:16"`
+	ActorId   *uint                  `json:"actor_id"`
+	ActorRole string                 `json:"actor_role" gorm:"size:32"`
+	Before    map[string]interface{} `json:"before" gorm:"serializer:json"`
+	After     map[string]interface{} `json:"after" gorm:"serializer:json"`
+	Diff      map[string]AuditChange `json:"diff" gorm:"serializer:json"`
+}
+
+// TableName returns the table name for the AuditRecord model
+func (item *AuditRecord) TableName() string {
+	return "auditrecords"
+}
+
+// GetId returns the Id of the model
+func (item *AuditRecord) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *AuditRecord) GetModelName() string {
+	return "auditrecord"
+}
+
+// AuditRecordResponse represents the audit record view response
+type AuditRecordResponse struct {
+	Id        uint                   `json:"id"`
+	CreatedAt time.Time              `json:"created_at"`
+	Entity    string                 `json:"entity"`
+	EntityId  uint                   `json:"entity_id"`
+	Action    string                 `json:"action"`
+	ActorId   *uint                  `json:"actor_id"`
+	ActorRole string                 `json:"actor_role,omitempty"`
+	Before    map[string]interface{} `json:"before"`
+	After     map[string]interface{} `json:"after"`
+	Diff      map[string]AuditChange `json:"diff"`
+}
+
+// ToResponse converts the model to a response
+func (item *AuditRecord) ToResponse() *AuditRecordResponse {
+	if item == nil {
+		return nil
+	}
+	return &AuditRecordResponse{
+		Id:        item.Id,
+		CreatedAt: item.CreatedAt,
+		Entity:    item.Entity,
+		EntityId:  item.EntityId,
+		Action:    item.Action,
+		ActorId:   item.ActorId,
+		ActorRole: item.ActorRole,
+		Before:    item.Before,
+		After:     item.After,
+		Diff:      item.Diff,
+	}
+}
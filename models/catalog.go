@@ -0,0 +1,155 @@
+package models
+
+import (
+	"time"
+)
+
+// CatalogBundle holds every catalog definition of a gamification setup.
+// Definitions reference each other by key so a bundle can be moved between
+// environments whose auto-increment ids differ.
+type CatalogBundle struct {
+	PointTypes          []PointTypeDefinition           `json:"point_types" yaml:"point_types"`
+	ActivityTypes       []ActivityTypeDefinition        `json:"activity_types" yaml:"activity_types"`
+	ActivityTypeRewards []ActivityTypeRewardDefinition  `json:"activity_type_rewards" yaml:"activity_type_rewards"`
+	Achievements        []AchievementDefinition         `json:"achievements" yaml:"achievements"`
+	AchievementCriteria []AchievementCriteriaDefinition `json:"achievement_criteria" yaml:"achievement_criteria"`
+	Levels              []LevelDefinition               `json:"levels" yaml:"levels"`
+	Challenges          []ChallengeDefinition           `json:"challenges" yaml:"challenges"`
+	Leaderboards        []LeaderboardDefinition         `json:"leaderboards" yaml:"leaderboards"`
+}
+
+// PointTypeDefinition is the portable form of a PointType
+type PointTypeDefinition struct {
+	Key                string `json:"key" yaml:"key"`
+	Name               string `json:"name" yaml:"name"`
+	Description        string `json:"description" yaml:"description"`
+	Icon               string `json:"icon" yaml:"icon"`
+	IsXpSource         bool   `json:"is_xp_source" yaml:"is_xp_source"`
+	ExpiresAfterDays   int    `json:"expires_after_days" yaml:"expires_after_days"`
+	DecayPercent       int    `json:"decay_percent" yaml:"decay_percent"`
+	DecayPeriodDays    int    `json:"decay_period_days" yaml:"decay_period_days"`
+	IsTransferable     bool   `json:"is_transferable" yaml:"is_transferable"`
+	DailyTransferLimit int    `json:"daily_transfer_limit" yaml:"daily_transfer_limit"`
+	OverdraftLimit     int    `json:"overdraft_limit" yaml:"overdraft_limit"`
+	ApprovalThreshold  int    `json:"approval_threshold" yaml:"approval_threshold"`
+}
+
+// ActivityTypeDefinition is the portable form of an ActivityType
+type ActivityTypeDefinition struct {
+	Key            string          `json:"key" yaml:"key"`
+	Name           string          `json:"name" yaml:"name"`
+	Description    string          `json:"description" yaml:"description"`
+	Category       string          `json:"category" yaml:"category"`
+	PointsValue    int             `json:"points_value" yaml:"points_value"`
+	CooldownPeriod int             `json:"cooldown_period" yaml:"cooldown_period"`
+	IsActive       bool            `json:"is_active" yaml:"is_active"`
+	Limits         *ActivityLimits `json:"limits,omitempty" yaml:"limits,omitempty"`
+}
+
+// ActivityTypeRewardDefinition is the portable form of an ActivityTypeReward.
+// A reward is identified by its activity type and point type keys.
+type ActivityTypeRewardDefinition struct {
+	ActivityType string `json:"activity_type" yaml:"activity_type"`
+	PointType    string `json:"point_type" yaml:"point_type"`
+	Amount       int    `json:"amount" yaml:"amount"`
+}
+
+// AchievementDefinition is the portable form of an Achievement
+type AchievementDefinition struct {
+	Key             string `json:"key" yaml:"key"`
+	Name            string `json:"name" yaml:"name"`
+	Description     string `json:"description" yaml:"description"`
+	Category        string `json:"category" yaml:"category"`
+	DifficultyLevel int    `json:"difficulty_level" yaml:"difficulty_level"`
+	IsHidden        bool   `json:"is_hidden" yaml:"is_hidden"`
+	IsActive        bool   `json:"is_active" yaml:"is_active"`
+}
+
+// AchievementCriteriaDefinition is the portable form of an AchievementCriteria.
+// A criteria is identified by its achievement and activity type keys.
+type AchievementCriteriaDefinition struct {
+	Achievement   string `json:"achievement" yaml:"achievement"`
+	ActivityType  string `json:"activity_type" yaml:"activity_type"`
+	RequiredCount int    `json:"required_count" yaml:"required_count"`
+	TimeFrame     int    `json:"time_frame" yaml:"time_frame"`
+}
+
+// LevelDefinition is the portable form of a Level
+type LevelDefinition struct {
+	Key         string `json:"key" yaml:"key"`
+	LevelNumber int    `json:"level_number" yaml:"level_number"`
+	XpRequired  int    `json:"xp_required" yaml:"xp_required"`
+	Title       string `json:"title" yaml:"title"`
+	Rewards     string `json:"rewards" yaml:"rewards"`
+}
+
+// ChallengeDefinition is the portable form of a Challenge
+type ChallengeDefinition struct {
+	Key         string                   `json:"key" yaml:"key"`
+	Name        string                   `json:"name" yaml:"name"`
+	Description string                   `json:"description" yaml:"description"`
+	StartDate   time.Time                `json:"start_date" yaml:"start_date"`
+	EndDate     time.Time                `json:"end_date" yaml:"end_date"`
+	RewardType  string                   `json:"reward_type" yaml:"reward_type"`
+	RewardValue string                   `json:"reward_value" yaml:"reward_value"`
+	IsActive    bool                     `json:"is_active" yaml:"is_active"`
+	IsTeam      bool                     `json:"is_team" yaml:"is_team"`
+	Goal        *ChallengeGoalDefinition `json:"goal,omitempty" yaml:"goal,omitempty"`
+}
+
+// ChallengeGoalDefinition is the portable form of a ChallengeGoal. The
+// activity and point types are referenced by key.
+type ChallengeGoalDefinition struct {
+	Kind         string            `json:"kind" yaml:"kind"`
+	ActivityType string            `json:"activity_type,omitempty" yaml:"activity_type,omitempty"`
+	PointType    string            `json:"point_type,omitempty" yaml:"point_type,omitempty"`
+	Target       int               `json:"target" yaml:"target"`
+	Filters      map[string]string `json:"filters,omitempty" yaml:"filters,omitempty"`
+}
+
+// LeaderboardDefinition is the portable form of a Leaderboard
+type LeaderboardDefinition struct {
+	Key            string                        `json:"key" yaml:"key"`
+	Name           string                        `json:"name" yaml:"name"`
+	Type           string                        `json:"type" yaml:"type"`
+	Period         string                        `json:"period" yaml:"period"`
+	ResetFrequency string                        `json:"reset_frequency" yaml:"reset_frequency"`
+	IsActive       bool                          `json:"is_active" yaml:"is_active"`
+	Scope          string                        `json:"scope" yaml:"scope"`
+	SegmentBy      string                        `json:"segment_by,omitempty" yaml:"segment_by,omitempty"`
+	Rewards        []LeaderboardRewardDefinition `json:"rewards,omitempty" yaml:"rewards,omitempty"`
+}
+
+// LeaderboardRewardDefinition is the portable form of a LeaderboardReward.
+// The point type and achievement are referenced by key.
+type LeaderboardRewardDefinition struct {
+	MaxRank     int    `json:"max_rank" yaml:"max_rank"`
+	PointType   string `json:"point_type,omitempty" yaml:"point_type,omitempty"`
+	Amount      int    `json:"amount,omitempty" yaml:"amount,omitempty"`
+	Achievement string `json:"achievement,omitempty" yaml:"achievement,omitempty"`
+}
+
+// CatalogFieldChange describes a single field that an import changes
+type CatalogFieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// CatalogChange describes what an import does to one catalog entry
+type CatalogChange struct {
+	Table  string               `json:"table"`
+	Key    string               `json:"key"`
+	Action string               `json:"action"`
+	Fields []CatalogFieldChange `json:"fields,omitempty"`
+}
+
+// CatalogImportResult represents the response of a catalog import
+type CatalogImportResult struct {
+	DryRun    bool            `json:"dry_run"`
+	Created   int             `json:"created"`
+	Updated   int             `json:"updated"`
+	Deleted   int             `json:"deleted"`
+	Unchanged int             `json:"unchanged"`
+	Changes   []CatalogChange `json:"changes"`
+}
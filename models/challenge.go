@@ -7,19 +7,27 @@ import (
 	"gorm.io/gorm"
 )
 
-// Challenge represents a challenge entity
+// Challenge represents a challenge entity. Progress on a team challenge is
+// the sum of its members' progress. Challenges instantiated from a template
+// carry its id, one per window start. A challenge with a goal measures the
+// progress of its users itself.
 type Challenge struct {
 	Id          uint           `json:"id" gorm:"primarykey"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId    uint           `json:"-" gorm:"uniqueIndex:idx_challenges_tenant_live_key,where:deleted_at IS NULL;not null;default:0"`
+	Key         string         `json:"key" gorm:"uniqueIndex:idx_challenges_tenant_live_key,where:deleted_at IS NULL;size:191"`
 	Name        string         `json:"name"`
 	Description string         `json:"description"`
-	StartDate   types.DateTime `json:"start_date"`
+	StartDate   types.DateTime `json:"start_date" gorm:"uniqueIndex:idx_challenge_template_window"`
 	EndDate     types.DateTime `json:"end_date"`
 	RewardType  string         `json:"reward_type"`
 	RewardValue string         `json:"reward_value"`
 	IsActive    bool           `json:"is_active"`
+	IsTeam      bool           `json:"is_team"`
+	TemplateId  *uint          `json:"template_id" gorm:"uniqueIndex:idx_challenge_template_window"`
+	Goal        ChallengeGoal  `json:"goal" gorm:"embedded;embeddedPrefix:goal_"`
 }
 
 // TableName returns the table name for the Challenge model
@@ -42,6 +50,7 @@ type ChallengeListResponse struct {
 	Id          uint           `json:"id"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
+	Key         string         `json:"key"`
 	Name        string         `json:"name"`
 	Description string         `json:"description"`
 	StartDate   types.DateTime `json:"start_date"`
@@ -49,6 +58,9 @@ type ChallengeListResponse struct {
 	RewardType  string         `json:"reward_type"`
 	RewardValue string         `json:"reward_value"`
 	IsActive    bool           `json:"is_active"`
+	IsTeam      bool           `json:"is_team"`
+	TemplateId  *uint          `json:"template_id"`
+	Goal        ChallengeGoal  `json:"goal"`
 }
 
 // ChallengeResponse represents the detailed view response
@@ -57,6 +69,7 @@ type ChallengeResponse struct {
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty"`
+	Key         string         `json:"key"`
 	Name        string         `json:"name"`
 	Description string         `json:"description"`
 	StartDate   types.DateTime `json:"start_date"`
@@ -64,28 +77,36 @@ type ChallengeResponse struct {
 	RewardType  string         `json:"reward_type"`
 	RewardValue string         `json:"reward_value"`
 	IsActive    bool           `json:"is_active"`
+	IsTeam      bool           `json:"is_team"`
+	TemplateId  *uint          `json:"template_id"`
+	Goal        ChallengeGoal  `json:"goal"`
 }
 
 // CreateChallengeRequest represents the request payload for creating a Challenge
 type CreateChallengeRequest struct {
-	Name        string         `json:"name" binding:"required"`
-	Description string         `json:"description" binding:"required"`
-	StartDate   types.DateTime `json:"start_date" binding:"required"`
-	EndDate     types.DateTime `json:"end_date" binding:"required"`
-	RewardType  string         `json:"reward_type" binding:"required"`
-	RewardValue string         `json:"reward_value" binding:"required"`
-	IsActive    bool           `json:"is_active" binding:"required"`
+	Key         string                `json:"key,omitempty"`
+	Name        string                `json:"name" binding:"required"`
+	Description string                `json:"description" binding:"required"`
+	StartDate   types.DateTime        `json:"start_date" binding:"required"`
+	EndDate     types.DateTime        `json:"end_date" binding:"required"`
+	RewardType  string                `json:"reward_type" binding:"required"`
+	RewardValue string                `json:"reward_value" binding:"required"`
+	IsActive    bool                  `json:"is_active" binding:"required"`
+	IsTeam      bool                  `json:"is_team"`
+	Goal        *ChallengeGoalRequest `json:"goal,omitempty"`
 }
 
 // UpdateChallengeRequest represents the request payload for updating a Challenge
 type UpdateChallengeRequest struct {
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
-	StartDate   string `json:"start_date,omitempty"`
-	EndDate     string `json:"end_date,omitempty"`
-	RewardType  string `json:"reward_type,omitempty"`
-	RewardValue string `json:"reward_value,omitempty"`
-	IsActive    string `json:"is_active,omitempty"`
+	Name        string                `json:"name,omitempty"`
+	Description string                `json:"description,omitempty"`
+	StartDate   string                `json:"start_date,omitempty"`
+	EndDate     string                `json:"end_date,omitempty"`
+	RewardType  string                `json:"reward_type,omitempty"`
+	RewardValue string                `json:"reward_value,omitempty"`
+	IsActive    string                `json:"is_active,omitempty"`
+	IsTeam      *bool                 `json:"is_team,omitempty"`
+	Goal        *ChallengeGoalRequest `json:"goal,omitempty"`
 }
 
 // ToListResponse converts the model to a list response
@@ -97,6 +118,7 @@ func (item *Challenge) ToListResponse() *ChallengeListResponse {
 		Id:          item.Id,
 		CreatedAt:   item.CreatedAt,
 		UpdatedAt:   item.UpdatedAt,
+		Key:         item.Key,
 		Name:        item.Name,
 		Description: item.Description,
 		StartDate:   item.StartDate,
@@ -104,6 +126,9 @@ func (item *Challenge) ToListResponse() *ChallengeListResponse {
 		RewardType:  item.RewardType,
 		RewardValue: item.RewardValue,
 		IsActive:    item.IsActive,
+		IsTeam:      item.IsTeam,
+		TemplateId:  item.TemplateId,
+		Goal:        item.Goal,
 	}
 }
 
@@ -117,6 +142,7 @@ func (item *Challenge) ToResponse() *ChallengeResponse {
 		CreatedAt:   item.CreatedAt,
 		UpdatedAt:   item.UpdatedAt,
 		DeletedAt:   item.DeletedAt,
+		Key:         item.Key,
 		Name:        item.Name,
 		Description: item.Description,
 		StartDate:   item.StartDate,
@@ -124,6 +150,9 @@ func (item *Challenge) ToResponse() *ChallengeResponse {
 		RewardType:  item.RewardType,
 		RewardValue: item.RewardValue,
 		IsActive:    item.IsActive,
+		IsTeam:      item.IsTeam,
+		TemplateId:  item.TemplateId,
+		Goal:        item.Goal,
 	}
 }
 
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Challenge goal kinds
+const (
+	ChallengeGoalActivityCount = "activity_count"
+	ChallengeGoalPointsSum     = "points_sum"
+	ChallengeGoalActiveDays    = "active_days"
+	ChallengeGoalStreak        = "streak"
+)
+
+// ChallengeMilestones are the progress percentages announced as users reach them
+var ChallengeMilestones = []int{25, 50, 75, 100}
+
+// ChallengeGoal is what a challenge measures during its window and the value
+// that completes it. ActivityTypeId narrows the activities counted, and is
+// required for activity counts; PointTypeId narrows a points sum to one point
+// type. Filters keep only the activities whose metadata has every given value.
+type ChallengeGoal struct {
+	Kind           string            `json:"kind" gorm:"size:32"`
+	ActivityTypeId *uint             `json:"activity_type_id"`
+	PointTypeId    *uint             `json:"point_type_id"`
+	Target         int               `json:"target"`
+	Filters        map[string]string `json:"filters,omitempty" gorm:"serializer:json"`
+}
+
+// IsSet reports whether the challenge has a goal
+func (goal ChallengeGoal) IsSet() bool {
+	return goal.Kind != "" && goal.Target > 0
+}
+
+// Percent returns how much of the target value is, from 0 to 100
+func (goal ChallengeGoal) Percent(value int) int {
+	if goal.Target <= 0 || value <= 0 {
+		return 0
+	}
+	if value >= goal.Target {
+		return 100
+	}
+	return value * 100 / goal.Target
+}
+
+// Matches reports whether activity metadata has every value of the filters
+func (goal ChallengeGoal) Matches(metadata string) bool {
+	if len(goal.Filters) == 0 {
+		return true
+	}
+	values := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(metadata), &values); err != nil {
+		return false
+	}
+	for name, want := range goal.Filters {
+		value, ok := values[name]
+		if !ok || fmt.Sprint(value) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// ChallengeGoalRequest represents the goal of a challenge in a create or update payload
+type ChallengeGoalRequest struct {
+	Kind            string            `json:"kind" binding:"required,oneof=activity_count points_sum active_days streak"`
+	ActivityTypeId  *uint             `json:"activity_type_id,omitempty"`
+	ActivityTypeKey string            `json:"activity_type_key,omitempty"`
+	PointTypeId     *uint             `json:"point_type_id,omitempty"`
+	PointTypeKey    string            `json:"point_type_key,omitempty"`
+	Target          int               `json:"target" binding:"required,min=1"`
+	Filters         map[string]string `json:"filters,omitempty"`
+}
+
+// ChallengeMilestone is announced when a user's progress on a challenge
+// reaches one of the ChallengeMilestones
+type ChallengeMilestone struct {
+	UserChallenge *UserChallenge `json:"user_challenge"`
+	Milestone     int            `json:"milestone"`
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ChallengeTemplate represents a challengetemplate entity: a challenge that
+// recurs. The scheduler instantiates one Challenge per window of its
+// recurrence between StartsAt and EndsAt.
+type ChallengeTemplate struct {
+	Id            uint           `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId      uint           `json:"-" gorm:"uniqueIndex:idx_challengetemplates_tenant_live_key,where:deleted_at IS NULL;not null;default:0"`
+	Key           string         `json:"key" gorm:"uniqueIndex:idx_challengetemplates_tenant_live_key,where:deleted_at IS NULL;size:191"`
+	Name          string         `json:"name"`
+	Description   string         `json:"description"`
+	Recurrence    string         `json:"recurrence" gorm:"size:191"`
+	DurationHours int            `json:"duration_hours"`
+	StartsAt      time.Time      `json:"starts_at"`
+	EndsAt        *time.Time     `json:"ends_at"`
+	RewardType    string         `json:"reward_type"`
+	RewardValue   string         `json:"reward_value"`
+	IsTeam        bool           `json:"is_team"`
+	AutoEnroll    bool           `json:"auto_enroll"`
+	IsActive      bool           `json:"is_active"`
+	Goal          ChallengeGoal  `json:"goal" gorm:"embedded;embeddedPrefix:goal_"`
+}
+
+// TableName returns the table name for the ChallengeTemplate model
+func (item *ChallengeTemplate) TableName() string {
+	return "challengetemplates"
+}
+
+// GetId returns the Id of the model
+func (item *ChallengeTemplate) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *ChallengeTemplate) GetModelName() string {
+	return "challengetemplate"
+}
+
+// ChallengeTemplateListResponse represents the list view response
+type ChallengeTemplateListResponse struct {
+	Id            uint       `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Key           string     `json:"key"`
+	Name          string     `json:"name"`
+	Recurrence    string     `json:"recurrence"`
+	DurationHours int        `json:"duration_hours"`
+	StartsAt      time.Time  `json:"starts_at"`
+	EndsAt        *time.Time `json:"ends_at"`
+	IsTeam        bool       `json:"is_team"`
+	AutoEnroll    bool       `json:"auto_enroll"`
+	IsActive      bool       `json:"is_active"`
+}
+
+// ChallengeTemplateResponse represents the detailed view response
+type ChallengeTemplateResponse struct {
+	Id            uint           `json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty"`
+	Key           string         `json:"key"`
+	Name          string         `json:"name"`
+	Description   string         `json:"description"`
+	Recurrence    string         `json:"recurrence"`
+	DurationHours int            `json:"duration_hours"`
+	StartsAt      time.Time      `json:"starts_at"`
+	EndsAt        *time.Time     `json:"ends_at"`
+	RewardType    string         `json:"reward_type"`
+	RewardValue   string         `json:"reward_value"`
+	IsTeam        bool           `json:"is_team"`
+	AutoEnroll    bool           `json:"auto_enroll"`
+	IsActive      bool           `json:"is_active"`
+	Goal          ChallengeGoal  `json:"goal"`
+}
+
+// CreateChallengeTemplateRequest represents the request payload for creating a ChallengeTemplate
+type CreateChallengeTemplateRequest struct {
+	Key           string                `json:"key,omitempty"`
+	Name          string                `json:"name" binding:"required"`
+	Description   string                `json:"description"`
+	Recurrence    string                `json:"recurrence" binding:"required,max=191"`
+	DurationHours int                   `json:"duration_hours,omitempty" binding:"omitempty,min=1"`
+	StartsAt      time.Time             `json:"starts_at" binding:"required"`
+	EndsAt        *time.Time            `json:"ends_at,omitempty"`
+	RewardType    string                `json:"reward_type"`
+	RewardValue   string                `json:"reward_value"`
+	IsTeam        bool                  `json:"is_team"`
+	AutoEnroll    bool                  `json:"auto_enroll"`
+	IsActive      bool                  `json:"is_active"`
+	Goal          *ChallengeGoalRequest `json:"goal,omitempty"`
+}
+
+// UpdateChallengeTemplateRequest represents the request payload for updating a
+// ChallengeTemplate. Changes apply to the windows instantiated afterwards.
+type UpdateChallengeTemplateRequest struct {
+	Name          string                `json:"name,omitempty"`
+	Description   string                `json:"description,omitempty"`
+	Recurrence    string                `json:"recurrence,omitempty" binding:"omitempty,max=191"`
+	DurationHours *int                  `json:"duration_hours,omitempty" binding:"omitempty,min=0"`
+	StartsAt      *time.Time            `json:"starts_at,omitempty"`
+	EndsAt        *time.Time            `json:"ends_at,omitempty"`
+	RewardType    string                `json:"reward_type,omitempty"`
+	RewardValue   string                `json:"reward_value,omitempty"`
+	IsTeam        *bool                 `json:"is_team,omitempty"`
+	AutoEnroll    *bool                 `json:"auto_enroll,omitempty"`
+	IsActive      *bool                 `json:"is_active,omitempty"`
+	Goal          *ChallengeGoalRequest `json:"goal,omitempty"`
+}
+
+// ToListResponse converts the model to a list response
+func (item *ChallengeTemplate) ToListResponse() *ChallengeTemplateListResponse {
+	if item == nil {
+		return nil
+	}
+	return &ChallengeTemplateListResponse{
+		Id:            item.Id,
+		CreatedAt:     item.CreatedAt,
+		UpdatedAt:     item.UpdatedAt,
+		Key:           item.Key,
+		Name:          item.Name,
+		Recurrence:    item.Recurrence,
+		DurationHours: item.DurationHours,
+		StartsAt:      item.StartsAt,
+		EndsAt:        item.EndsAt,
+		IsTeam:        item.IsTeam,
+		AutoEnroll:    item.AutoEnroll,
+		IsActive:      item.IsActive,
+	}
+}
+
+// ToResponse converts the model to a detailed response
+func (item *ChallengeTemplate) ToResponse() *ChallengeTemplateResponse {
+	if item == nil {
+		return nil
+	}
+	return &ChallengeTemplateResponse{
+		Id:            item.Id,
+		CreatedAt:     item.CreatedAt,
+		UpdatedAt:     item.UpdatedAt,
+		DeletedAt:     item.DeletedAt,
+		Key:           item.Key,
+		Name:          item.Name,
+		Description:   item.Description,
+		Recurrence:    item.Recurrence,
+		DurationHours: item.DurationHours,
+		StartsAt:      item.StartsAt,
+		EndsAt:        item.EndsAt,
+		RewardType:    item.RewardType,
+		RewardValue:   item.RewardValue,
+		IsTeam:        item.IsTeam,
+		AutoEnroll:    item.AutoEnroll,
+		IsActive:      item.IsActive,
+		Goal:          item.Goal,
+	}
+}
+
+// Preload preloads all the model's relationships
+func (item *ChallengeTemplate) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	return query
+}
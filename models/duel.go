@@ -0,0 +1,154 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Duel statuses. A pending duel waits for the opponent until RespondBy; an
+// accepted duel is active until its window ends and it is completed.
+const (
+	DuelPending   = "pending"
+	DuelActive    = "active"
+	DuelDeclined  = "declined"
+	DuelCancelled = "cancelled"
+	DuelExpired   = "expired"
+	DuelCompleted = "completed"
+)
+
+// Duel metrics
+const (
+	DuelMetricActivityCount = "activity_count"
+	DuelMetricPointsSum     = "points_sum"
+	DuelMetricMetadataSum   = "metadata_sum"
+)
+
+// Duel represents a head-to-head contest between two users over a window.
+// Both put Stake of a point type in escrow, referenced as "duel:<id>", and
+// the user with the higher score takes both stakes; a draw refunds them.
+// Scores count the activities of ActivityTypeId, or all activities, by
+// Metric: their number, the points of PointTypeId they earned (xp without a
+// stake), or the sum of the MetricField value of their metadata.
+type Duel struct {
+	Id              uint           `json:"id" gorm:"primarykey"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId        uint           `json:"-" gorm:"index;not null;default:0"`
+	ChallengerId    uint           `json:"challenger_id" gorm:"index"`
+	OpponentId      uint           `json:"opponent_id" gorm:"index"`
+	ActivityTypeId  *uint          `json:"activity_type_id"`
+	ActivityType    *ActivityType  `json:"activity_type,omitempty"`
+	Metric          string         `json:"metric" gorm:"size:32"`
+	MetricField     string         `json:"metric_field" gorm:"size:191"`
+	PointTypeId     *uint          `json:"point_type_id"`
+	PointType       *PointType     `json:"point_type,omitempty"`
+	Stake           int            `json:"stake"`
+	StartsAt        time.Time      `json:"starts_at"`
+	EndsAt          time.Time      `json:"ends_at" gorm:"index"`
+	RespondBy       time.Time      `json:"respond_by" gorm:"index"`
+	Status          string         `json:"status" gorm:"size:16;index"`
+	ChallengerScore int            `json:"challenger_score"`
+	OpponentScore   int            `json:"opponent_score"`
+	WinnerId        *uint          `json:"winner_id"`
+	ResolvedAt      *time.Time     `json:"resolved_at"`
+}
+
+// TableName returns the table name for the Duel model
+func (item *Duel) TableName() string {
+	return "duels"
+}
+
+// GetId returns the Id of the model
+func (item *Duel) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *Duel) GetModelName() string {
+	return "duel"
+}
+
+// Involves reports whether the user is one of the two sides of the duel
+func (item *Duel) Involves(userId uint) bool {
+	return item.ChallengerId == userId || item.OpponentId == userId
+}
+
+// DuelResponse represents the duel view response
+type DuelResponse struct {
+	Id              uint          `json:"id"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+	ChallengerId    uint          `json:"challenger_id"`
+	OpponentId      uint          `json:"opponent_id"`
+	ActivityTypeId  *uint         `json:"activity_type_id"`
+	ActivityType    *ActivityType `json:"activity_type,omitempty"`
+	Metric          string        `json:"metric"`
+	MetricField     string        `json:"metric_field,omitempty"`
+	PointTypeId     *uint         `json:"point_type_id"`
+	PointType       *PointType    `json:"point_type,omitempty"`
+	Stake           int           `json:"stake"`
+	StartsAt        time.Time     `json:"starts_at"`
+	EndsAt          time.Time     `json:"ends_at"`
+	RespondBy       time.Time     `json:"respond_by"`
+	Status          string        `json:"status"`
+	ChallengerScore int           `json:"challenger_score"`
+	OpponentScore   int           `json:"opponent_score"`
+	WinnerId        *uint         `json:"winner_id"`
+	ResolvedAt      *time.Time    `json:"resolved_at"`
+}
+
+// CreateDuelRequest represents the request payload for inviting a user to a
+// duel. RespondBy defaults to StartsAt.
+type CreateDuelRequest struct {
+	ChallengerId    uint       `json:"challenger_id" binding:"required"`
+	OpponentId      uint       `json:"opponent_id" binding:"required,nefield=ChallengerId"`
+	Metric          string     `json:"metric" binding:"required,oneof=activity_count points_sum metadata_sum"`
+	ActivityTypeId  *uint      `json:"activity_type_id,omitempty"`
+	ActivityTypeKey string     `json:"activity_type_key,omitempty"`
+	MetricField     string     `json:"metric_field,omitempty" binding:"max=191"`
+	PointTypeId     *uint      `json:"point_type_id,omitempty"`
+	PointTypeKey    string     `json:"point_type_key,omitempty"`
+	Stake           int        `json:"stake" binding:"min=0"`
+	StartsAt        time.Time  `json:"starts_at" binding:"required"`
+	EndsAt          time.Time  `json:"ends_at" binding:"required,gtfield=StartsAt"`
+	RespondBy       *time.Time `json:"respond_by,omitempty"`
+}
+
+// ToResponse converts the model to a response
+func (item *Duel) ToResponse() *DuelResponse {
+	if item == nil {
+		return nil
+	}
+	return &DuelResponse{
+		Id:              item.Id,
+		CreatedAt:       item.CreatedAt,
+		UpdatedAt:       item.UpdatedAt,
+		ChallengerId:    item.ChallengerId,
+		OpponentId:      item.OpponentId,
+		ActivityTypeId:  item.ActivityTypeId,
+		ActivityType:    item.ActivityType,
+		Metric:          item.Metric,
+		MetricField:     item.MetricField,
+		PointTypeId:     item.PointTypeId,
+		PointType:       item.PointType,
+		Stake:           item.Stake,
+		StartsAt:        item.StartsAt,
+		EndsAt:          item.EndsAt,
+		RespondBy:       item.RespondBy,
+		Status:          item.Status,
+		ChallengerScore: item.ChallengerScore,
+		OpponentScore:   item.OpponentScore,
+		WinnerId:        item.WinnerId,
+		ResolvedAt:      item.ResolvedAt,
+	}
+}
+
+// Preload preloads all the model's relationships
+func (item *Duel) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	query = query.Preload("ActivityType", Archived)
+	query = query.Preload("PointType", Archived)
+	return query
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slugify turns a display name into a lowercase, dash separated key
+func Slugify(value string) string {
+	slug := nonSlugChars.ReplaceAllString(strings.ToLower(value), "-")
+	return strings.Trim(slug, "-")
+}
+
+// MigrateKey makes sure the key column of a catalog table exists and that rows
+// created before keys were introduced get one derived from the source column.
+// It must run before AutoMigrate so the key index is built on filled values.
+func MigrateKey(db *gorm.DB, model interface{}, source string) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(model) {
+		return nil
+	}
+	if !migrator.HasColumn(model, "Key") {
+		if err := migrator.AddColumn(model, "Key"); err != nil {
+			return fmt.Errorf("failed to add key column: %w", err)
+		}
+	}
+
+	// Keys used to be unique across the table, then per tenant including
+	// deleted rows; they are unique among the live rows of a tenant now
+	if table, ok := model.(interface{ TableName() string }); ok {
+		for _, name := range []string{"idx_" + table.TableName() + "_key", "idx_" + table.TableName() + "_tenant_key"} {
+			if !migrator.HasIndex(model, name) {
+				continue
+			}
+			if err := migrator.DropIndex(model, name); err != nil {
+				return fmt.Errorf("failed to drop key index: %w", err)
+			}
+		}
+	}
+
+	db = db.Unscoped()
+	keyColumn := clause.Column{Name: "key"}
+
+	var rows []struct {
+		Id     uint
+		Source string
+	}
+	if err := db.Model(model).
+		Select(fmt.Sprintf("id, %s AS source", source)).
+		Where("? IS NULL OR ? = ''", keyColumn, keyColumn).
+		Scan(&rows).Error; err != nil {
+		return fmt.Errorf("failed to find rows without key: %w", err)
+	}
+
+	for _, row := range rows {
+		key := fmt.Sprintf("%s-%d", Slugify(row.Source), row.Id)
+		if err := db.Model(model).Where("id = ?", row.Id).Update("key", key).Error; err != nil {
+			return fmt.Errorf("failed to backfill key: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ErrInvalidKey is returned when a row would get an empty key, such as the slug
+// of a name without letters or digits
+var ErrInvalidKey = errors.New("key must contain letters or digits")
+
+// ErrDuplicateKey is returned when a key is already used by a live row
+var ErrDuplicateKey = errors.New("key is already in use")
+
+// NewKey returns the key of a new row of model: key, or the slug of name when
+// key is empty. The key must not be empty nor used by a live row of model.
+func NewKey(db *gorm.DB, model interface{}, key string, name string) (string, error) {
+	if key == "" {
+		key = Slugify(name)
+	}
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+
+	_, err := ResolveKey(db, model, key)
+	if err == nil {
+		return "", fmt.Errorf("%w: %s", ErrDuplicateKey, key)
+	}
+	if !errors.Is(err, ErrUnknownKey) {
+		return "", err
+	}
+	return key, nil
+}
+
+// ErrUnknownKey is returned when a key does not match any catalog row
+var ErrUnknownKey = errors.New("unknown key")
+
+// ResolveKey returns the id of the row of model identified by key
+func ResolveKey(db *gorm.DB, model interface{}, key string) (uint, error) {
+	var ids []uint
+	if err := db.Model(model).
+		Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: key}).
+		Limit(1).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownKey, key)
+	}
+	return ids[0], nil
+}
+
+// ResolveFieldKey resolves the key a request field holds, like ResolveKey,
+// but reports an unknown key as a ValidationError naming the field
+func ResolveFieldKey(db *gorm.DB, model interface{}, field, key string) (uint, error) {
+	id, err := ResolveKey(db, model, key)
+	if errors.Is(err, ErrUnknownKey) {
+		return 0, &ValidationError{
+			Message: "the request references unknown keys",
+			Fields:  []FieldError{{Field: field, Message: fmt.Sprintf("%q does not exist", key)}},
+		}
+	}
+	return id, err
+}
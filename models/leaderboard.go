@@ -1,22 +1,51 @@
 package models
 
 import (
+	"base/core/app/users"
+	"base/core/types"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-// Leaderboard represents a leaderboard entity
+// Leaderboard scopes
+const (
+	LeaderboardScopeUser = "user"
+	LeaderboardScopeTeam = "team"
+)
+
+// Leaderboard reset frequencies. Any other value never resets.
+const (
+	LeaderboardResetDaily   = "daily"
+	LeaderboardResetWeekly  = "weekly"
+	LeaderboardResetMonthly = "monthly"
+	LeaderboardResetYearly  = "yearly"
+)
+
+// Bounds of the single period of a leaderboard that never resets
+var (
+	LeaderboardEpoch = time.Unix(0, 0).UTC()
+	LeaderboardEnd   = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// Leaderboard represents a leaderboard entity. Its scope decides whether
+// entries rank users or teams. SegmentBy, when set, names the dimension
+// rankings can be split by, such as country, cohort or friends.
 type Leaderboard struct {
-	Id             uint           `json:"id" gorm:"primarykey"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
-	Name           string         `json:"name"`
-	Type           string         `json:"type"`
-	Period         string         `json:"period"`
-	ResetFrequency string         `json:"reset_frequency"`
-	IsActive       bool           `json:"is_active"`
+	Id             uint                 `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt       `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId       uint                 `json:"-" gorm:"uniqueIndex:idx_leaderboards_tenant_live_key,where:deleted_at IS NULL;not null;default:0"`
+	Key            string               `json:"key" gorm:"uniqueIndex:idx_leaderboards_tenant_live_key,where:deleted_at IS NULL;size:191"`
+	Name           string               `json:"name"`
+	Type           string               `json:"type"`
+	Period         string               `json:"period"`
+	ResetFrequency string               `json:"reset_frequency"`
+	IsActive       bool                 `json:"is_active"`
+	Scope          string               `json:"scope" gorm:"size:16;default:user"`
+	SegmentBy      string               `json:"segment_by" gorm:"size:32"`
+	Rewards        []*LeaderboardReward `json:"rewards,omitempty" gorm:"foreignKey:LeaderboardId"`
 }
 
 // TableName returns the table name for the Leaderboard model
@@ -39,42 +68,129 @@ type LeaderboardListResponse struct {
 	Id             uint      `json:"id"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
+	Key            string    `json:"key"`
 	Name           string    `json:"name"`
 	Type           string    `json:"type"`
 	Period         string    `json:"period"`
 	ResetFrequency string    `json:"reset_frequency"`
 	IsActive       bool      `json:"is_active"`
+	Scope          string    `json:"scope"`
+	SegmentBy      string    `json:"segment_by"`
 }
 
 // LeaderboardResponse represents the detailed view response
 type LeaderboardResponse struct {
-	Id             uint           `json:"id"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty"`
-	Name           string         `json:"name"`
-	Type           string         `json:"type"`
-	Period         string         `json:"period"`
-	ResetFrequency string         `json:"reset_frequency"`
-	IsActive       bool           `json:"is_active"`
+	Id             uint                 `json:"id"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt       `json:"deleted_at,omitempty"`
+	Key            string               `json:"key"`
+	Name           string               `json:"name"`
+	Type           string               `json:"type"`
+	Period         string               `json:"period"`
+	ResetFrequency string               `json:"reset_frequency"`
+	IsActive       bool                 `json:"is_active"`
+	Scope          string               `json:"scope"`
+	SegmentBy      string               `json:"segment_by"`
+	Rewards        []*LeaderboardReward `json:"rewards,omitempty"`
 }
 
 // CreateLeaderboardRequest represents the request payload for creating a Leaderboard
 type CreateLeaderboardRequest struct {
-	Name           string `json:"name" binding:"required"`
-	Type           string `json:"type" binding:"required"`
-	Period         string `json:"period" binding:"required"`
-	ResetFrequency string `json:"reset_frequency" binding:"required"`
-	IsActive       bool   `json:"is_active" binding:"required"`
+	Key            string                     `json:"key,omitempty"`
+	Name           string                     `json:"name" binding:"required"`
+	Type           string                     `json:"type" binding:"required"`
+	Period         string                     `json:"period" binding:"required"`
+	ResetFrequency string                     `json:"reset_frequency" binding:"required"`
+	IsActive       bool                       `json:"is_active" binding:"required"`
+	Scope          string                     `json:"scope,omitempty" binding:"omitempty,oneof=user team"`
+	SegmentBy      string                     `json:"segment_by,omitempty" binding:"omitempty,max=32,excludes=:"`
+	Rewards        []LeaderboardRewardRequest `json:"rewards,omitempty" binding:"omitempty,dive"`
 }
 
 // UpdateLeaderboardRequest represents the request payload for updating a Leaderboard
 type UpdateLeaderboardRequest struct {
-	Name           string `json:"name,omitempty"`
-	Type           string `json:"type,omitempty"`
-	Period         string `json:"period,omitempty"`
-	ResetFrequency string `json:"reset_frequency,omitempty"`
-	IsActive       string `json:"is_active,omitempty"`
+	Name           string                     `json:"name,omitempty"`
+	Type           string                     `json:"type,omitempty"`
+	Period         string                     `json:"period,omitempty"`
+	ResetFrequency string                     `json:"reset_frequency,omitempty"`
+	IsActive       string                     `json:"is_active,omitempty"`
+	Scope          string                     `json:"scope,omitempty" binding:"omitempty,oneof=user team"`
+	SegmentBy      string                     `json:"segment_by,omitempty" binding:"omitempty,max=32,excludes=:"`
+	Rewards        []LeaderboardRewardRequest `json:"rewards,omitempty" binding:"omitempty,dive"`
+}
+
+// SubmitLeaderboardScoreRequest represents the request payload for recording a
+// live score. Increment adds Score to the current score instead of replacing it.
+// Segment is the subject's value of the leaderboard's segment dimension; the
+// segment resolver is asked when it is empty.
+type SubmitLeaderboardScoreRequest struct {
+	UserId    uint   `json:"user_id,omitempty"`
+	TeamId    uint   `json:"team_id,omitempty"`
+	Score     int    `json:"score"`
+	Increment bool   `json:"increment,omitempty"`
+	Segment   string `json:"segment,omitempty"`
+}
+
+// LeaderboardStanding is the position of one user or team in a leaderboard
+// period. Subjects with the same score share the same rank.
+type LeaderboardStanding struct {
+	Rank   int         `json:"rank"`
+	Score  int         `json:"score"`
+	UserId *uint       `json:"user_id,omitempty"`
+	User   *users.User `json:"user,omitempty"`
+	TeamId *uint       `json:"team_id,omitempty"`
+	Team   *Team       `json:"team,omitempty"`
+}
+
+// LeaderboardStandingsResponse represents a slice of the standings of a leaderboard period
+type LeaderboardStandingsResponse struct {
+	LeaderboardId uint                   `json:"leaderboard_id"`
+	Segment       string                 `json:"segment,omitempty"`
+	PeriodStart   types.DateTime         `json:"period_start"`
+	PeriodEnd     types.DateTime         `json:"period_end"`
+	Participants  int                    `json:"participants"`
+	Standings     []*LeaderboardStanding `json:"standings"`
+}
+
+// LeaderboardRankResponse represents the standing of a single user in a leaderboard period
+type LeaderboardRankResponse struct {
+	LeaderboardId uint                 `json:"leaderboard_id"`
+	Segment       string               `json:"segment,omitempty"`
+	PeriodStart   types.DateTime       `json:"period_start"`
+	PeriodEnd     types.DateTime       `json:"period_end"`
+	Participants  int                  `json:"participants"`
+	Standing      *LeaderboardStanding `json:"standing"`
+}
+
+// PeriodAt returns the bounds of the period running at t, in UTC. Weeks
+// start on Monday.
+func (item *Leaderboard) PeriodAt(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch item.ResetFrequency {
+	case LeaderboardResetDaily:
+		return day, day.AddDate(0, 0, 1)
+	case LeaderboardResetWeekly:
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case LeaderboardResetMonthly:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	case LeaderboardResetYearly:
+		start := time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, 0)
+	}
+	return LeaderboardEpoch, LeaderboardEnd
+}
+
+// Resets reports whether the leaderboard starts over every period
+func (item *Leaderboard) Resets() bool {
+	switch item.ResetFrequency {
+	case LeaderboardResetDaily, LeaderboardResetWeekly, LeaderboardResetMonthly, LeaderboardResetYearly:
+		return true
+	}
+	return false
 }
 
 // ToListResponse converts the model to a list response
@@ -86,11 +202,14 @@ func (item *Leaderboard) ToListResponse() *LeaderboardListResponse {
 		Id:             item.Id,
 		CreatedAt:      item.CreatedAt,
 		UpdatedAt:      item.UpdatedAt,
+		Key:            item.Key,
 		Name:           item.Name,
 		Type:           item.Type,
 		Period:         item.Period,
 		ResetFrequency: item.ResetFrequency,
 		IsActive:       item.IsActive,
+		Scope:          item.Scope,
+		SegmentBy:      item.SegmentBy,
 	}
 }
 
@@ -104,16 +223,22 @@ func (item *Leaderboard) ToResponse() *LeaderboardResponse {
 		CreatedAt:      item.CreatedAt,
 		UpdatedAt:      item.UpdatedAt,
 		DeletedAt:      item.DeletedAt,
+		Key:            item.Key,
 		Name:           item.Name,
 		Type:           item.Type,
 		Period:         item.Period,
 		ResetFrequency: item.ResetFrequency,
 		IsActive:       item.IsActive,
+		Scope:          item.Scope,
+		SegmentBy:      item.SegmentBy,
+		Rewards:        item.Rewards,
 	}
 }
 
 // Preload preloads all the model's relationships
 func (item *Leaderboard) Preload(db *gorm.DB) *gorm.DB {
 	query := db
+	query = query.Preload("Rewards.PointType")
+	query = query.Preload("Rewards.Achievement")
 	return query
 }
@@ -8,20 +8,26 @@ import (
 	"gorm.io/gorm"
 )
 
-// LeaderboardEntry represents a leaderboardentry entity
+// LeaderboardEntry represents a leaderboardentry entity. It ranks either a
+// user or a team, depending on the scope of its leaderboard. Entries are the
+// periodic snapshots of the live scores kept by the leaderboard store.
 type LeaderboardEntry struct {
 	Id            uint           `json:"id" gorm:"primarykey"`
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId      uint           `json:"-" gorm:"index;not null;default:0"`
 	LeaderboardId uint           `json:"leaderboard_id"`
 	Leaderboard   *Leaderboard   `json:"leaderboard,omitempty"`
-	UserId        uint           `json:"user_id"`
+	UserId        *uint          `json:"user_id"`
 	User          *users.User    `json:"user,omitempty"`
+	TeamId        *uint          `json:"team_id"`
+	Team          *Team          `json:"team,omitempty"`
 	Score         int            `json:"score"`
 	Rank          int            `json:"rank"`
 	PeriodStart   types.DateTime `json:"period_start"`
 	PeriodEnd     types.DateTime `json:"period_end"`
+	Segment       string         `json:"segment" gorm:"size:128;not null;default:''"`
 }
 
 // TableName returns the table name for the LeaderboardEntry model
@@ -45,11 +51,13 @@ type LeaderboardEntryListResponse struct {
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	LeaderboardId uint           `json:"leaderboard_id"`
-	UserId        uint           `json:"user_id"`
+	UserId        *uint          `json:"user_id"`
+	TeamId        *uint          `json:"team_id"`
 	Score         int            `json:"score"`
 	Rank          int            `json:"rank"`
 	PeriodStart   types.DateTime `json:"period_start"`
 	PeriodEnd     types.DateTime `json:"period_end"`
+	Segment       string         `json:"segment,omitempty"`
 }
 
 // LeaderboardEntryResponse represents the detailed view response
@@ -60,28 +68,35 @@ type LeaderboardEntryResponse struct {
 	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty"`
 	LeaderboardId uint           `json:"leaderboard_id"`
 	Leaderboard   *Leaderboard   `json:"leaderboard,omitempty"`
-	UserId        uint           `json:"user_id"`
+	UserId        *uint          `json:"user_id"`
 	User          *users.User    `json:"user,omitempty"`
+	TeamId        *uint          `json:"team_id"`
+	Team          *Team          `json:"team,omitempty"`
 	Score         int            `json:"score"`
 	Rank          int            `json:"rank"`
 	PeriodStart   types.DateTime `json:"period_start"`
 	PeriodEnd     types.DateTime `json:"period_end"`
+	Segment       string         `json:"segment,omitempty"`
 }
 
 // CreateLeaderboardEntryRequest represents the request payload for creating a LeaderboardEntry
 type CreateLeaderboardEntryRequest struct {
-	LeaderboardId uint           `json:"leaderboard_id" binding:"required"`
-	UserId        uint           `json:"user_id" binding:"required"`
-	Score         int            `json:"score" binding:"required"`
-	Rank          int            `json:"rank" binding:"required"`
-	PeriodStart   types.DateTime `json:"period_start" binding:"required"`
-	PeriodEnd     types.DateTime `json:"period_end" binding:"required"`
+	LeaderboardId  uint           `json:"leaderboard_id" binding:"required_without=LeaderboardKey"`
+	LeaderboardKey string         `json:"leaderboard_key,omitempty"`
+	UserId         uint           `json:"user_id" binding:"required_without=TeamId"`
+	TeamId         uint           `json:"team_id,omitempty"`
+	Score          int            `json:"score" binding:"required"`
+	Rank           int            `json:"rank" binding:"required"`
+	PeriodStart    types.DateTime `json:"period_start" binding:"required"`
+	PeriodEnd      types.DateTime `json:"period_end" binding:"required"`
+	Segment        string         `json:"segment,omitempty" binding:"omitempty,max=128"`
 }
 
 // UpdateLeaderboardEntryRequest represents the request payload for updating a LeaderboardEntry
 type UpdateLeaderboardEntryRequest struct {
 	LeaderboardId uint   `json:"leaderboard_id,omitempty"`
 	UserId        uint   `json:"user_id,omitempty"`
+	TeamId        uint   `json:"team_id,omitempty"`
 	Score         string `json:"score,omitempty"`
 	Rank          string `json:"rank,omitempty"`
 	PeriodStart   string `json:"period_start,omitempty"`
@@ -99,10 +114,12 @@ func (item *LeaderboardEntry) ToListResponse() *LeaderboardEntryListResponse {
 		UpdatedAt:     item.UpdatedAt,
 		LeaderboardId: item.LeaderboardId,
 		UserId:        item.UserId,
+		TeamId:        item.TeamId,
 		Score:         item.Score,
 		Rank:          item.Rank,
 		PeriodStart:   item.PeriodStart,
 		PeriodEnd:     item.PeriodEnd,
+		Segment:       item.Segment,
 	}
 }
 
@@ -120,10 +137,13 @@ func (item *LeaderboardEntry) ToResponse() *LeaderboardEntryResponse {
 		Leaderboard:   item.Leaderboard,
 		UserId:        item.UserId,
 		User:          item.User,
+		TeamId:        item.TeamId,
+		Team:          item.Team,
 		Score:         item.Score,
 		Rank:          item.Rank,
 		PeriodStart:   item.PeriodStart,
 		PeriodEnd:     item.PeriodEnd,
+		Segment:       item.Segment,
 	}
 }
 
@@ -132,5 +152,6 @@ func (item *LeaderboardEntry) Preload(db *gorm.DB) *gorm.DB {
 	query := db
 	query = query.Preload("Leaderboard")
 	query = query.Preload("User")
+	query = query.Preload("Team", Archived)
 	return query
 }
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"base/core/types"
+	"time"
+)
+
+// Leaderboard period statuses. A closing period takes no more scores while
+// its final standings are frozen; a close that fails midway leaves it
+// closing, for the next close to finish.
+const (
+	LeaderboardPeriodOpen    = "open"
+	LeaderboardPeriodClosing = "closing"
+	LeaderboardPeriodClosed  = "closed"
+)
+
+// LeaderboardPeriod represents one period of a leaderboard. Scores are taken
+// while it is open; closing it freezes its final standings and awards the
+// leaderboard's rewards.
+type LeaderboardPeriod struct {
+	Id            uint           `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	TenantId      uint           `json:"-" gorm:"index;not null;default:0"`
+	LeaderboardId uint           `json:"leaderboard_id" gorm:"uniqueIndex:idx_leaderboard_period"`
+	PeriodStart   types.DateTime `json:"period_start" gorm:"uniqueIndex:idx_leaderboard_period"`
+	PeriodEnd     types.DateTime `json:"period_end"`
+	Status        string         `json:"status" gorm:"size:16;index;default:open"`
+	ClosedAt      *time.Time     `json:"closed_at"`
+}
+
+// TableName returns the table name for the LeaderboardPeriod model
+func (item *LeaderboardPeriod) TableName() string {
+	return "leaderboardperiods"
+}
+
+// GetId returns the Id of the model
+func (item *LeaderboardPeriod) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *LeaderboardPeriod) GetModelName() string {
+	return "leaderboardperiod"
+}
+
+// LeaderboardPeriodResponse represents the view response
+type LeaderboardPeriodResponse struct {
+	Id            uint           `json:"id"`
+	LeaderboardId uint           `json:"leaderboard_id"`
+	PeriodStart   types.DateTime `json:"period_start"`
+	PeriodEnd     types.DateTime `json:"period_end"`
+	Status        string         `json:"status"`
+	ClosedAt      *time.Time     `json:"closed_at"`
+}
+
+// ToResponse converts the model to a response
+func (item *LeaderboardPeriod) ToResponse() *LeaderboardPeriodResponse {
+	if item == nil {
+		return nil
+	}
+	return &LeaderboardPeriodResponse{
+		Id:            item.Id,
+		LeaderboardId: item.LeaderboardId,
+		PeriodStart:   item.PeriodStart,
+		PeriodEnd:     item.PeriodEnd,
+		Status:        item.Status,
+		ClosedAt:      item.ClosedAt,
+	}
+}
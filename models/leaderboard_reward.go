@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LeaderboardReward represents what the finishers ranked 1 to MaxRank of a
+// leaderboard period receive when it closes: an amount of a point type, an
+// achievement, or both. On a team leaderboard every member of a team receives it.
+type LeaderboardReward struct {
+	Id            uint           `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId      uint           `json:"-" gorm:"index;not null;default:0"`
+	LeaderboardId uint           `json:"leaderboard_id" gorm:"index"`
+	MaxRank       int            `json:"max_rank"`
+	PointTypeId   *uint          `json:"point_type_id"`
+	PointType     *PointType     `json:"point_type,omitempty"`
+	Amount        int            `json:"amount"`
+	AchievementId *uint          `json:"achievement_id"`
+	Achievement   *Achievement   `json:"achievement,omitempty"`
+}
+
+// TableName returns the table name for the LeaderboardReward model
+func (item *LeaderboardReward) TableName() string {
+	return "leaderboardrewards"
+}
+
+// GetId returns the Id of the model
+func (item *LeaderboardReward) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *LeaderboardReward) GetModelName() string {
+	return "leaderboardreward"
+}
+
+// LeaderboardRewardRequest represents one reward in a Leaderboard create or update payload
+type LeaderboardRewardRequest struct {
+	MaxRank        int    `json:"max_rank" binding:"required,min=1"`
+	PointTypeId    uint   `json:"point_type_id" binding:"required_without_all=PointTypeKey AchievementId AchievementKey"`
+	PointTypeKey   string `json:"point_type_key,omitempty"`
+	Amount         int    `json:"amount,omitempty" binding:"omitempty,min=1"`
+	AchievementId  uint   `json:"achievement_id,omitempty"`
+	AchievementKey string `json:"achievement_key,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// LeaderboardScore is a live score kept by the SQL leaderboard store. The
+// board key already names the tenant, so rows carry no tenant of their own.
+type LeaderboardScore struct {
+	Id        uint   `json:"id" gorm:"primarykey"`
+	BoardKey  string `json:"board_key" gorm:"size:191;uniqueIndex:idx_leaderboard_score_subject;index:idx_leaderboard_score_order,priority:1"`
+	SubjectId uint   `json:"subject_id" gorm:"uniqueIndex:idx_leaderboard_score_subject"`
+	Score     int    `json:"score" gorm:"index:idx_leaderboard_score_order,priority:2"`
+}
+
+// TableName returns the table name for the LeaderboardScore model
+func (item *LeaderboardScore) TableName() string {
+	return "leaderboardscores"
+}
+
+// GetId returns the Id of the model
+func (item *LeaderboardScore) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *LeaderboardScore) GetModelName() string {
+	return "leaderboardscore"
+}
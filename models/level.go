@@ -13,6 +13,8 @@ type Level struct {
 	CreatedAt   time.Time           `json:"created_at"`
 	UpdatedAt   time.Time           `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt      `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId    uint                `json:"-" gorm:"uniqueIndex:idx_levels_tenant_live_key,where:deleted_at IS NULL;not null;default:0"`
+	Key         string              `json:"key" gorm:"uniqueIndex:idx_levels_tenant_live_key,where:deleted_at IS NULL;size:191"`
 	LevelNumber int                 `json:"level_number"`
 	XpRequired  int                 `json:"xp_required"`
 	Title       string              `json:"title"`
@@ -40,6 +42,7 @@ type LevelListResponse struct {
 	Id          uint                `json:"id"`
 	CreatedAt   time.Time           `json:"created_at"`
 	UpdatedAt   time.Time           `json:"updated_at"`
+	Key         string              `json:"key"`
 	LevelNumber int                 `json:"level_number"`
 	XpRequired  int                 `json:"xp_required"`
 	Title       string              `json:"title"`
@@ -53,6 +56,7 @@ type LevelResponse struct {
 	CreatedAt   time.Time           `json:"created_at"`
 	UpdatedAt   time.Time           `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt      `json:"deleted_at,omitempty"`
+	Key         string              `json:"key"`
 	LevelNumber int                 `json:"level_number"`
 	XpRequired  int                 `json:"xp_required"`
 	Title       string              `json:"title"`
@@ -62,6 +66,7 @@ type LevelResponse struct {
 
 // CreateLevelRequest represents the request payload for creating a Level
 type CreateLevelRequest struct {
+	Key         string              `json:"key,omitempty"`
 	LevelNumber int                 `json:"level_number" binding:"required"`
 	XpRequired  int                 `json:"xp_required" binding:"required"`
 	Title       string              `json:"title" binding:"required"`
@@ -87,6 +92,7 @@ func (item *Level) ToListResponse() *LevelListResponse {
 		Id:          item.Id,
 		CreatedAt:   item.CreatedAt,
 		UpdatedAt:   item.UpdatedAt,
+		Key:         item.Key,
 		LevelNumber: item.LevelNumber,
 		XpRequired:  item.XpRequired,
 		Title:       item.Title,
@@ -105,6 +111,7 @@ func (item *Level) ToResponse() *LevelResponse {
 		CreatedAt:   item.CreatedAt,
 		UpdatedAt:   item.UpdatedAt,
 		DeletedAt:   item.DeletedAt,
+		Key:         item.Key,
 		LevelNumber: item.LevelNumber,
 		XpRequired:  item.XpRequired,
 		Title:       item.Title,
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PointLot represents a batch of points earned at once. Lots are consumed
+// oldest first and expire as a whole once ExpiresAt has passed.
+type PointLot struct {
+	Id          uint           `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId    uint           `json:"-" gorm:"index;not null;default:0"`
+	UserId      uint           `json:"user_id" gorm:"index:idx_pointlots_user_type"`
+	PointTypeId uint           `json:"point_type_id" gorm:"index:idx_pointlots_user_type"`
+	PointType   *PointType     `json:"point_type,omitempty"`
+	Amount      int            `json:"amount"`
+	Remaining   int            `json:"remaining"`
+	EarnedAt    time.Time      `json:"earned_at"`
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty" gorm:"index"`
+}
+
+// TableName returns the table name for the PointLot model
+func (item *PointLot) TableName() string {
+	return "pointlots"
+}
+
+// GetId returns the Id of the model
+func (item *PointLot) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *PointLot) GetModelName() string {
+	return "pointlot"
+}
+
+// ExpiringPointsResponse represents the points of a user that expire soon
+type ExpiringPointsResponse struct {
+	UserId uint        `json:"user_id"`
+	Until  time.Time   `json:"until"`
+	Total  int         `json:"total"`
+	Lots   []*PointLot `json:"lots"`
+}
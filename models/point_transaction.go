@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Point transaction kinds
+const (
+	PointTransactionEarn   = "earn"
+	PointTransactionSpend  = "spend"
+	PointTransactionExpire = "expire"
+	PointTransactionDecay  = "decay"
+	// Transfers between users
+	PointTransactionTransferIn  = "transfer_in"
+	PointTransactionTransferOut = "transfer_out"
+	// Direct edits of a balance
+	PointTransactionAdjust = "adjust"
+	// Duel stakes taken into escrow, and paid back or won
+	PointTransactionStake  = "stake"
+	PointTransactionRefund = "refund"
+	PointTransactionPayout = "payout"
+)
+
+// PointTransaction represents one change of a user's balance. Amount is
+// positive for credits and negative for debits.
+type PointTransaction struct {
+	Id          uint           `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId    uint           `json:"-" gorm:"index;not null;default:0"`
+	UserId      uint           `json:"user_id" gorm:"index:idx_pointtransactions_user_type"`
+	PointTypeId uint           `json:"point_type_id" gorm:"index:idx_pointtransactions_user_type"`
+	PointType   *PointType     `json:"point_type,omitempty"`
+	PointLotId  *uint          `json:"point_lot_id,omitempty"`
+	Kind        string         `json:"kind" gorm:"index"`
+	Amount      int            `json:"amount"`
+	Reference   string         `json:"reference"`
+}
+
+// TableName returns the table name for the PointTransaction model
+func (item *PointTransaction) TableName() string {
+	return "pointtransactions"
+}
+
+// GetId returns the Id of the model
+func (item *PointTransaction) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *PointTransaction) GetModelName() string {
+	return "pointtransaction"
+}
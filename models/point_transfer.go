@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PointTransfer represents points moved from one user to another. The two
+// matching ledger entries reference it as "pointtransfer:<id>".
+type PointTransfer struct {
+	Id          uint           `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time      `json:"created_at" gorm:"index"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId    uint           `json:"-" gorm:"index;not null;default:0"`
+	FromUserId  uint           `json:"from_user_id" gorm:"index"`
+	ToUserId    uint           `json:"to_user_id" gorm:"index"`
+	PointTypeId uint           `json:"point_type_id"`
+	PointType   *PointType     `json:"point_type,omitempty"`
+	Amount      int            `json:"amount"`
+	Note        string         `json:"note"`
+}
+
+// TableName returns the table name for the PointTransfer model
+func (item *PointTransfer) TableName() string {
+	return "pointtransfers"
+}
+
+// GetId returns the Id of the model
+func (item *PointTransfer) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *PointTransfer) GetModelName() string {
+	return "pointtransfer"
+}
+
+// TransferUserPointsRequest represents the request payload for moving points between users
+type TransferUserPointsRequest struct {
+	FromUserId   uint   `json:"from_user_id" binding:"required"`
+	ToUserId     uint   `json:"to_user_id" binding:"required,nefield=FromUserId"`
+	PointTypeId  uint   `json:"point_type_id" binding:"required_without=PointTypeKey"`
+	PointTypeKey string `json:"point_type_key,omitempty"`
+	Amount       int    `json:"amount" binding:"required,min=1"`
+	Note         string `json:"note,omitempty"`
+}
+
+// PointTransferResponse represents the result of a transfer
+type PointTransferResponse struct {
+	Transfer *PointTransfer     `json:"transfer"`
+	From     *UserPointResponse `json:"from"`
+	To       *UserPointResponse `json:"to"`
+}
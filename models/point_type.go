@@ -6,15 +6,26 @@ import (
 	"gorm.io/gorm"
 )
 
-// PointType represents a pointtype entity
+// PointType represents a pointtype entity. Admin grants of more than
+// ApprovalThreshold points wait for a second admin's approval; 0 never waits.
 type PointType struct {
-	Id          uint           `json:"id" gorm:"primarykey"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
-	Name        string         `json:"name"`
-	Description string         `json:"description"`
-	Icon        string         `json:"icon"`
+	Id                 uint           `json:"id" gorm:"primarykey"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId           uint           `json:"-" gorm:"uniqueIndex:idx_pointtypes_tenant_live_key,where:deleted_at IS NULL;not null;default:0"`
+	Key                string         `json:"key" gorm:"uniqueIndex:idx_pointtypes_tenant_live_key,where:deleted_at IS NULL;size:191"`
+	Name               string         `json:"name"`
+	Description        string         `json:"description"`
+	Icon               string         `json:"icon"`
+	IsXpSource         bool           `json:"is_xp_source"`
+	ExpiresAfterDays   int            `json:"expires_after_days"`
+	DecayPercent       int            `json:"decay_percent"`
+	DecayPeriodDays    int            `json:"decay_period_days"`
+	IsTransferable     bool           `json:"is_transferable"`
+	DailyTransferLimit int            `json:"daily_transfer_limit"`
+	OverdraftLimit     int            `json:"overdraft_limit"`
+	ApprovalThreshold  int            `json:"approval_threshold"`
 }
 
 // TableName returns the table name for the PointType model
@@ -34,37 +45,72 @@ func (item *PointType) GetModelName() string {
 
 // PointTypeListResponse represents the list view response
 type PointTypeListResponse struct {
-	Id          uint      `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Icon        string    `json:"icon"`
+	Id                 uint      `json:"id"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	Key                string    `json:"key"`
+	Name               string    `json:"name"`
+	Description        string    `json:"description"`
+	Icon               string    `json:"icon"`
+	IsXpSource         bool      `json:"is_xp_source"`
+	ExpiresAfterDays   int       `json:"expires_after_days"`
+	DecayPercent       int       `json:"decay_percent"`
+	DecayPeriodDays    int       `json:"decay_period_days"`
+	IsTransferable     bool      `json:"is_transferable"`
+	DailyTransferLimit int       `json:"daily_transfer_limit"`
+	OverdraftLimit     int       `json:"overdraft_limit"`
+	ApprovalThreshold  int       `json:"approval_threshold"`
 }
 
 // PointTypeResponse represents the detailed view response
 type PointTypeResponse struct {
-	Id          uint           `json:"id"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty"`
-	Name        string         `json:"name"`
-	Description string         `json:"description"`
-	Icon        string         `json:"icon"`
+	Id                 uint           `json:"id"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"deleted_at,omitempty"`
+	Key                string         `json:"key"`
+	Name               string         `json:"name"`
+	Description        string         `json:"description"`
+	Icon               string         `json:"icon"`
+	IsXpSource         bool           `json:"is_xp_source"`
+	ExpiresAfterDays   int            `json:"expires_after_days"`
+	DecayPercent       int            `json:"decay_percent"`
+	DecayPeriodDays    int            `json:"decay_period_days"`
+	IsTransferable     bool           `json:"is_transferable"`
+	DailyTransferLimit int            `json:"daily_transfer_limit"`
+	OverdraftLimit     int            `json:"overdraft_limit"`
+	ApprovalThreshold  int            `json:"approval_threshold"`
 }
 
 // CreatePointTypeRequest represents the request payload for creating a PointType
 type CreatePointTypeRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description" binding:"required"`
-	Icon        string `json:"icon" binding:"required"`
+	Key                string `json:"key,omitempty"`
+	Name               string `json:"name" binding:"required"`
+	Description        string `json:"description" binding:"required"`
+	Icon               string `json:"icon" binding:"required"`
+	IsXpSource         bool   `json:"is_xp_source"`
+	ExpiresAfterDays   int    `json:"expires_after_days" binding:"min=0"`
+	DecayPercent       int    `json:"decay_percent" binding:"min=0,max=100"`
+	DecayPeriodDays    int    `json:"decay_period_days" binding:"min=0"`
+	IsTransferable     bool   `json:"is_transferable"`
+	DailyTransferLimit int    `json:"daily_transfer_limit" binding:"min=0"`
+	OverdraftLimit     int    `json:"overdraft_limit" binding:"min=0"`
+	ApprovalThreshold  int    `json:"approval_threshold" binding:"min=0"`
 }
 
 // UpdatePointTypeRequest represents the request payload for updating a PointType
 type UpdatePointTypeRequest struct {
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
-	Icon        string `json:"icon,omitempty"`
+	Name               string `json:"name,omitempty"`
+	Description        string `json:"description,omitempty"`
+	Icon               string `json:"icon,omitempty"`
+	IsXpSource         *bool  `json:"is_xp_source,omitempty"`
+	ExpiresAfterDays   *int   `json:"expires_after_days,omitempty" binding:"omitempty,min=0"`
+	DecayPercent       *int   `json:"decay_percent,omitempty" binding:"omitempty,min=0,max=100"`
+	DecayPeriodDays    *int   `json:"decay_period_days,omitempty" binding:"omitempty,min=0"`
+	IsTransferable     *bool  `json:"is_transferable,omitempty"`
+	DailyTransferLimit *int   `json:"daily_transfer_limit,omitempty" binding:"omitempty,min=0"`
+	OverdraftLimit     *int   `json:"overdraft_limit,omitempty" binding:"omitempty,min=0"`
+	ApprovalThreshold  *int   `json:"approval_threshold,omitempty" binding:"omitempty,min=0"`
 }
 
 // ToListResponse converts the model to a list response
@@ -73,12 +119,21 @@ func (item *PointType) ToListResponse() *PointTypeListResponse {
 		return nil
 	}
 	return &PointTypeListResponse{
-		Id:          item.Id,
-		CreatedAt:   item.CreatedAt,
-		UpdatedAt:   item.UpdatedAt,
-		Name:        item.Name,
-		Description: item.Description,
-		Icon:        item.Icon,
+		Id:                 item.Id,
+		CreatedAt:          item.CreatedAt,
+		UpdatedAt:          item.UpdatedAt,
+		Key:                item.Key,
+		Name:               item.Name,
+		Description:        item.Description,
+		Icon:               item.Icon,
+		IsXpSource:         item.IsXpSource,
+		ExpiresAfterDays:   item.ExpiresAfterDays,
+		DecayPercent:       item.DecayPercent,
+		DecayPeriodDays:    item.DecayPeriodDays,
+		IsTransferable:     item.IsTransferable,
+		DailyTransferLimit: item.DailyTransferLimit,
+		OverdraftLimit:     item.OverdraftLimit,
+		ApprovalThreshold:  item.ApprovalThreshold,
 	}
 }
 
@@ -88,13 +143,22 @@ func (item *PointType) ToResponse() *PointTypeResponse {
 		return nil
 	}
 	return &PointTypeResponse{
-		Id:          item.Id,
-		CreatedAt:   item.CreatedAt,
-		UpdatedAt:   item.UpdatedAt,
-		DeletedAt:   item.DeletedAt,
-		Name:        item.Name,
-		Description: item.Description,
-		Icon:        item.Icon,
+		Id:                 item.Id,
+		CreatedAt:          item.CreatedAt,
+		UpdatedAt:          item.UpdatedAt,
+		DeletedAt:          item.DeletedAt,
+		Key:                item.Key,
+		Name:               item.Name,
+		Description:        item.Description,
+		Icon:               item.Icon,
+		IsXpSource:         item.IsXpSource,
+		ExpiresAfterDays:   item.ExpiresAfterDays,
+		DecayPercent:       item.DecayPercent,
+		DecayPeriodDays:    item.DecayPeriodDays,
+		IsTransferable:     item.IsTransferable,
+		DailyTransferLimit: item.DailyTransferLimit,
+		OverdraftLimit:     item.OverdraftLimit,
+		ApprovalThreshold:  item.ApprovalThreshold,
 	}
 }
 
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Quest represents a quest entity: a journey of steps that a user completes
+// one after the other, or in any order, for a completion reward
+type Quest struct {
+	Id                  uint           `json:"id" gorm:"primarykey"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId            uint           `json:"-" gorm:"uniqueIndex:idx_quests_tenant_live_key,where:deleted_at IS NULL;not null;default:0"`
+	Key                 string         `json:"key" gorm:"uniqueIndex:idx_quests_tenant_live_key,where:deleted_at IS NULL;size:191"`
+	Name                string         `json:"name"`
+	Description         string         `json:"description"`
+	Ordered             bool           `json:"ordered"`
+	IsActive            bool           `json:"is_active"`
+	RewardPointTypeId   *uint          `json:"reward_point_type_id"`
+	RewardPointType     *PointType     `json:"reward_point_type,omitempty"`
+	RewardAmount        int            `json:"reward_amount"`
+	RewardAchievementId *uint          `json:"reward_achievement_id"`
+	RewardAchievement   *Achievement   `json:"reward_achievement,omitempty"`
+	Steps               []*QuestStep   `json:"steps,omitempty" gorm:"foreignKey:QuestId"`
+}
+
+// TableName returns the table name for the Quest model
+func (item *Quest) TableName() string {
+	return "quests"
+}
+
+// GetId returns the Id of the model
+func (item *Quest) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *Quest) GetModelName() string {
+	return "quest"
+}
+
+// QuestListResponse represents the list view response
+type QuestListResponse struct {
+	Id                  uint      `json:"id"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+	Key                 string    `json:"key"`
+	Name                string    `json:"name"`
+	Description         string    `json:"description"`
+	Ordered             bool      `json:"ordered"`
+	IsActive            bool      `json:"is_active"`
+	RewardPointTypeId   *uint     `json:"reward_point_type_id"`
+	RewardAmount        int       `json:"reward_amount"`
+	RewardAchievementId *uint     `json:"reward_achievement_id"`
+}
+
+// QuestResponse represents the detailed view response
+type QuestResponse struct {
+	Id                  uint           `json:"id"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `json:"deleted_at,omitempty"`
+	Key                 string         `json:"key"`
+	Name                string         `json:"name"`
+	Description         string         `json:"description"`
+	Ordered             bool           `json:"ordered"`
+	IsActive            bool           `json:"is_active"`
+	RewardPointTypeId   *uint          `json:"reward_point_type_id"`
+	RewardPointType     *PointType     `json:"reward_point_type,omitempty"`
+	RewardAmount        int            `json:"reward_amount"`
+	RewardAchievementId *uint          `json:"reward_achievement_id"`
+	RewardAchievement   *Achievement   `json:"reward_achievement,omitempty"`
+	Steps               []*QuestStep   `json:"steps"`
+}
+
+// CreateQuestRequest represents the request payload for creating a Quest
+type CreateQuestRequest struct {
+	Key                  string `json:"key,omitempty"`
+	Name                 string `json:"name" binding:"required"`
+	Description          string `json:"description"`
+	Ordered              bool   `json:"ordered"`
+	IsActive             bool   `json:"is_active"`
+	RewardPointTypeId    uint   `json:"reward_point_type_id,omitempty"`
+	RewardPointTypeKey   string `json:"reward_point_type_key,omitempty"`
+	RewardAmount         int    `json:"reward_amount,omitempty" binding:"omitempty,min=1"`
+	RewardAchievementId  uint   `json:"reward_achievement_id,omitempty"`
+	RewardAchievementKey string `json:"reward_achievement_key,omitempty"`
+}
+
+// UpdateQuestRequest represents the request payload for updating a Quest
+type UpdateQuestRequest struct {
+	Name                 string `json:"name,omitempty"`
+	Description          string `json:"description,omitempty"`
+	Ordered              *bool  `json:"ordered,omitempty"`
+	IsActive             *bool  `json:"is_active,omitempty"`
+	RewardPointTypeId    uint   `json:"reward_point_type_id,omitempty"`
+	RewardPointTypeKey   string `json:"reward_point_type_key,omitempty"`
+	RewardAmount         int    `json:"reward_amount,omitempty" binding:"omitempty,min=1"`
+	RewardAchievementId  uint   `json:"reward_achievement_id,omitempty"`
+	RewardAchievementKey string `json:"reward_achievement_key,omitempty"`
+}
+
+// ToListResponse converts the model to a list response
+func (item *Quest) ToListResponse() *QuestListResponse {
+	if item == nil {
+		return nil
+	}
+	return &QuestListResponse{
+		Id:                  item.Id,
+		CreatedAt:           item.CreatedAt,
+		UpdatedAt:           item.UpdatedAt,
+		Key:                 item.Key,
+		Name:                item.Name,
+		Description:         item.Description,
+		Ordered:             item.Ordered,
+		IsActive:            item.IsActive,
+		RewardPointTypeId:   item.RewardPointTypeId,
+		RewardAmount:        item.RewardAmount,
+		RewardAchievementId: item.RewardAchievementId,
+	}
+}
+
+// ToResponse converts the model to a detailed response
+func (item *Quest) ToResponse() *QuestResponse {
+	if item == nil {
+		return nil
+	}
+	return &QuestResponse{
+		Id:                  item.Id,
+		CreatedAt:           item.CreatedAt,
+		UpdatedAt:           item.UpdatedAt,
+		DeletedAt:           item.DeletedAt,
+		Key:                 item.Key,
+		Name:                item.Name,
+		Description:         item.Description,
+		Ordered:             item.Ordered,
+		IsActive:            item.IsActive,
+		RewardPointTypeId:   item.RewardPointTypeId,
+		RewardPointType:     item.RewardPointType,
+		RewardAmount:        item.RewardAmount,
+		RewardAchievementId: item.RewardAchievementId,
+		RewardAchievement:   item.RewardAchievement,
+		Steps:               item.Steps,
+	}
+}
+
+// Preload preloads all the model's relationships
+func (item *Quest) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	query = query.Preload("RewardPointType")
+	query = query.Preload("RewardAchievement")
+	query = query.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position").Order("id")
+	})
+	query = query.Preload("Steps.ActivityType")
+	query = query.Preload("Steps.Achievement")
+	return query
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Quest step kinds
+const (
+	QuestStepActivity    = "activity"
+	QuestStepAchievement = "achievement"
+)
+
+// QuestStep represents a queststep entity: recording RequiredCount
+// activities of an activity type, or unlocking an achievement. Steps are
+// taken in Position order on an ordered quest.
+type QuestStep struct {
+	Id             uint           `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId       uint           `json:"-" gorm:"index;not null;default:0"`
+	QuestId        uint           `json:"quest_id" gorm:"index"`
+	Quest          *Quest         `json:"quest,omitempty"`
+	Position       int            `json:"position"`
+	Name           string         `json:"name"`
+	Description    string         `json:"description"`
+	Kind           string         `json:"kind" gorm:"size:16"`
+	ActivityTypeId *uint          `json:"activity_type_id"`
+	ActivityType   *ActivityType  `json:"activity_type,omitempty"`
+	RequiredCount  int            `json:"required_count"`
+	AchievementId  *uint          `json:"achievement_id"`
+	Achievement    *Achievement   `json:"achievement,omitempty"`
+}
+
+// TableName returns the table name for the QuestStep model
+func (item *QuestStep) TableName() string {
+	return "queststeps"
+}
+
+// GetId returns the Id of the model
+func (item *QuestStep) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *QuestStep) GetModelName() string {
+	return "queststep"
+}
+
+// Required returns how many occurrences complete the step
+func (item *QuestStep) Required() int {
+	if item.Kind != QuestStepActivity || item.RequiredCount < 1 {
+		return 1
+	}
+	return item.RequiredCount
+}
+
+// QuestStepListResponse represents the list view response
+type QuestStepListResponse struct {
+	Id             uint      `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	QuestId        uint      `json:"quest_id"`
+	Position       int       `json:"position"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	Kind           string    `json:"kind"`
+	ActivityTypeId *uint     `json:"activity_type_id"`
+	RequiredCount  int       `json:"required_count"`
+	AchievementId  *uint     `json:"achievement_id"`
+}
+
+// QuestStepResponse represents the detailed view response
+type QuestStepResponse struct {
+	Id             uint           `json:"id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty"`
+	QuestId        uint           `json:"quest_id"`
+	Quest          *Quest         `json:"quest,omitempty"`
+	Position       int            `json:"position"`
+	Name           string         `json:"name"`
+	Description    string         `json:"description"`
+	Kind           string         `json:"kind"`
+	ActivityTypeId *uint          `json:"activity_type_id"`
+	ActivityType   *ActivityType  `json:"activity_type,omitempty"`
+	RequiredCount  int            `json:"required_count"`
+	AchievementId  *uint          `json:"achievement_id"`
+	Achievement    *Achievement   `json:"achievement,omitempty"`
+}
+
+// CreateQuestStepRequest represents the request payload for creating a QuestStep.
+// Without a position the step is appended to the quest.
+type CreateQuestStepRequest struct {
+	QuestId         uint   `json:"quest_id" binding:"required_without=QuestKey"`
+	QuestKey        string `json:"quest_key,omitempty"`
+	Position        int    `json:"position,omitempty" binding:"omitempty,min=1"`
+	Name            string `json:"name" binding:"required"`
+	Description     string `json:"description"`
+	Kind            string `json:"kind" binding:"required,oneof=activity achievement"`
+	ActivityTypeId  uint   `json:"activity_type_id,omitempty"`
+	ActivityTypeKey string `json:"activity_type_key,omitempty"`
+	RequiredCount   int    `json:"required_count,omitempty" binding:"omitempty,min=1"`
+	AchievementId   uint   `json:"achievement_id,omitempty"`
+	AchievementKey  string `json:"achievement_key,omitempty"`
+}
+
+// UpdateQuestStepRequest represents the request payload for updating a QuestStep
+type UpdateQuestStepRequest struct {
+	Position      int    `json:"position,omitempty" binding:"omitempty,min=1"`
+	Name          string `json:"name,omitempty"`
+	Description   string `json:"description,omitempty"`
+	RequiredCount int    `json:"required_count,omitempty" binding:"omitempty,min=1"`
+}
+
+// ToListResponse converts the model to a list response
+func (item *QuestStep) ToListResponse() *QuestStepListResponse {
+	if item == nil {
+		return nil
+	}
+	return &QuestStepListResponse{
+		Id:             item.Id,
+		CreatedAt:      item.CreatedAt,
+		UpdatedAt:      item.UpdatedAt,
+		QuestId:        item.QuestId,
+		Position:       item.Position,
+		Name:           item.Name,
+		Description:    item.Description,
+		Kind:           item.Kind,
+		ActivityTypeId: item.ActivityTypeId,
+		RequiredCount:  item.RequiredCount,
+		AchievementId:  item.AchievementId,
+	}
+}
+
+// ToResponse converts the model to a detailed response
+func (item *QuestStep) ToResponse() *QuestStepResponse {
+	if item == nil {
+		return nil
+	}
+	return &QuestStepResponse{
+		Id:             item.Id,
+		CreatedAt:      item.CreatedAt,
+		UpdatedAt:      item.UpdatedAt,
+		DeletedAt:      item.DeletedAt,
+		QuestId:        item.QuestId,
+		Quest:          item.Quest,
+		Position:       item.Position,
+		Name:           item.Name,
+		Description:    item.Description,
+		Kind:           item.Kind,
+		ActivityTypeId: item.ActivityTypeId,
+		ActivityType:   item.ActivityType,
+		RequiredCount:  item.RequiredCount,
+		AchievementId:  item.AchievementId,
+		Achievement:    item.Achievement,
+	}
+}
+
+// Preload preloads all the model's relationships
+func (item *QuestStep) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	query = query.Preload("Quest")
+	query = query.Preload("ActivityType")
+	query = query.Preload("Achievement")
+	return query
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reference names the rows of a table that block a write through one of
+// its columns
+type Reference struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	Ids    []uint `json:"ids"`
+}
+
+// ReferenceError is returned when a row cannot be written because of rows it
+// references or that reference it. It is also the body of the 409 responses
+// it leads to.
+type ReferenceError struct {
+	Message    string      `json:"error"`
+	References []Reference `json:"references"`
+}
+
+func (e *ReferenceError) Error() string {
+	names := make([]string, len(e.References))
+	for i, reference := range e.References {
+		names[i] = fmt.Sprintf("%s.%s %v", reference.Table, reference.Column, reference.Ids)
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(names, ", "))
+}
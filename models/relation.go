@@ -0,0 +1,297 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Delete policies of a relation, applied to the rows referencing a row that
+// is deleted
+const (
+	// OnDeleteRestrict keeps a row from being deleted while live rows
+	// reference it
+	OnDeleteRestrict = "restrict"
+	// OnDeleteCascade deletes the referencing rows along with the row
+	OnDeleteCascade = "cascade"
+	// OnDeleteArchive keeps the referencing rows as history of the row, which
+	// stays in the trash until they are purged
+	OnDeleteArchive = "archive"
+)
+
+// Relation is a column of Model referencing the rows of Parent by id, with
+// the policy applied when one of them is deleted
+type Relation struct {
+	Model    interface{}
+	Column   string
+	Parent   interface{}
+	OnDelete string
+}
+
+// Relations are the relations between the gamification tables. Catalog rows
+// that others are configured with are restricted, rows owned by their parent
+// are cascaded, and progress and ledger rows are archived.
+var Relations = []*Relation{
+	{Model: &AchievementCriteria{}, Column: "activity_type_id", Parent: &ActivityType{}, OnDelete: OnDeleteRestrict},
+	{Model: &ActivityTypeReward{}, Column: "activity_type_id", Parent: &ActivityType{}, OnDelete: OnDeleteCascade},
+	{Model: &QuestStep{}, Column: "activity_type_id", Parent: &ActivityType{}, OnDelete: OnDeleteRestrict},
+	{Model: &ChallengeTemplate{}, Column: "goal_activity_type_id", Parent: &ActivityType{}, OnDelete: OnDeleteRestrict},
+	{Model: &Challenge{}, Column: "goal_activity_type_id", Parent: &ActivityType{}, OnDelete: OnDeleteArchive},
+	{Model: &Duel{}, Column: "activity_type_id", Parent: &ActivityType{}, OnDelete: OnDeleteArchive},
+	{Model: &UserActivity{}, Column: "activity_type_id", Parent: &ActivityType{}, OnDelete: OnDeleteArchive},
+	{Model: &UserActivityCounter{}, Column: "activity_type_id", Parent: &ActivityType{}, OnDelete: OnDeleteCascade},
+
+	{Model: &ActivityTypeReward{}, Column: "point_type_id", Parent: &PointType{}, OnDelete: OnDeleteRestrict},
+	{Model: &LeaderboardReward{}, Column: "point_type_id", Parent: &PointType{}, OnDelete: OnDeleteRestrict},
+	{Model: &Quest{}, Column: "reward_point_type_id", Parent: &PointType{}, OnDelete: OnDeleteRestrict},
+	{Model: &ChallengeTemplate{}, Column: "goal_point_type_id", Parent: &PointType{}, OnDelete: OnDeleteRestrict},
+	{Model: &UserPoint{}, Column: "point_type_id", Parent: &PointType{}, OnDelete: OnDeleteRestrict},
+	{Model: &Challenge{}, Column: "goal_point_type_id", Parent: &PointType{}, OnDelete: OnDeleteArchive},
+	{Model: &Duel{}, Column: "point_type_id", Parent: &PointType{}, OnDelete: OnDeleteArchive},
+	{Model: &PointLot{}, Column: "point_type_id", Parent: &PointType{}, OnDelete: OnDeleteArchive},
+	{Model: &PointTransaction{}, Column: "point_type_id", Parent: &PointType{}, OnDelete: OnDeleteArchive},
+	{Model: &PointTransfer{}, Column: "point_type_id", Parent: &PointType{}, OnDelete: OnDeleteArchive},
+	{Model: &UserActivityPoint{}, Column: "point_type_id", Parent: &PointType{}, OnDelete: OnDeleteArchive},
+	{Model: &Adjustment{}, Column: "point_type_id", Parent: &PointType{}, OnDelete: OnDeleteArchive},
+
+	{Model: &AchievementCriteria{}, Column: "achievement_id", Parent: &Achievement{}, OnDelete: OnDeleteCascade},
+	{Model: &LeaderboardReward{}, Column: "achievement_id", Parent: &Achievement{}, OnDelete: OnDeleteRestrict},
+	{Model: &Quest{}, Column: "reward_achievement_id", Parent: &Achievement{}, OnDelete: OnDeleteRestrict},
+	{Model: &QuestStep{}, Column: "achievement_id", Parent: &Achievement{}, OnDelete: OnDeleteRestrict},
+	{Model: &UserAchievement{}, Column: "achievement_id", Parent: &Achievement{}, OnDelete: OnDeleteArchive},
+	{Model: &Adjustment{}, Column: "achievement_id", Parent: &Achievement{}, OnDelete: OnDeleteArchive},
+
+	{Model: &UserLevel{}, Column: "current_level_id", Parent: &Level{}, OnDelete: OnDeleteRestrict},
+	{Model: &Adjustment{}, Column: "level_id", Parent: &Level{}, OnDelete: OnDeleteArchive},
+
+	{Model: &Challenge{}, Column: "template_id", Parent: &ChallengeTemplate{}, OnDelete: OnDeleteArchive},
+	{Model: &UserChallenge{}, Column: "challenge_id", Parent: &Challenge{}, OnDelete: OnDeleteArchive},
+	{Model: &Adjustment{}, Column: "challenge_id", Parent: &Challenge{}, OnDelete: OnDeleteArchive},
+
+	{Model: &LeaderboardReward{}, Column: "leaderboard_id", Parent: &Leaderboard{}, OnDelete: OnDeleteCascade},
+	{Model: &LeaderboardEntry{}, Column: "leaderboard_id", Parent: &Leaderboard{}, OnDelete: OnDeleteCascade},
+	{Model: &LeaderboardPeriod{}, Column: "leaderboard_id", Parent: &Leaderboard{}, OnDelete: OnDeleteArchive},
+
+	{Model: &QuestStep{}, Column: "quest_id", Parent: &Quest{}, OnDelete: OnDeleteCascade},
+	{Model: &UserQuest{}, Column: "quest_id", Parent: &Quest{}, OnDelete: OnDeleteArchive},
+	{Model: &UserQuestStep{}, Column: "quest_step_id", Parent: &QuestStep{}, OnDelete: OnDeleteArchive},
+	{Model: &UserQuestStep{}, Column: "user_quest_id", Parent: &UserQuest{}, OnDelete: OnDeleteCascade},
+
+	{Model: &TeamMember{}, Column: "team_id", Parent: &Team{}, OnDelete: OnDeleteCascade},
+	{Model: &LeaderboardEntry{}, Column: "team_id", Parent: &Team{}, OnDelete: OnDeleteArchive},
+
+	{Model: &UserActivityPoint{}, Column: "user_activity_id", Parent: &UserActivity{}, OnDelete: OnDeleteCascade},
+	{Model: &PointTransaction{}, Column: "point_lot_id", Parent: &PointLot{}, OnDelete: OnDeleteArchive},
+}
+
+// Archived scopes the preload of a parent that archived rows may outlive
+func Archived(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// Delete deletes item under the delete policies of the relations referencing
+// it: live rows of restricted relations fail the delete with a
+// ReferenceError, rows of cascaded relations are deleted along with it, and
+// rows of archived relations are kept.
+func Delete(db *gorm.DB, item interface{}) error {
+	return remove(db, item, false)
+}
+
+// Purge deletes item for good. Rows of restricted and archived relations,
+// deleted or not, fail the purge with a ReferenceError, and rows of cascaded
+// relations are purged along with it.
+func Purge(db *gorm.DB, item interface{}) error {
+	return remove(db, item, true)
+}
+
+func remove(db *gorm.DB, item interface{}, purge bool) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(item); err != nil {
+			return fmt.Errorf("failed to parse model: %w", err)
+		}
+		id, _ := stmt.Schema.PrioritizedPrimaryField.ValueOf(tx.Statement.Context, reflect.Indirect(reflect.ValueOf(item)))
+		key, ok := id.(uint)
+		if !ok || key == 0 {
+			return fmt.Errorf("failed to delete %s: the item has no id", stmt.Schema.Table)
+		}
+		if err := removeReferencing(tx, stmt.Schema.Table, []uint{key}, purge); err != nil {
+			return err
+		}
+		return scoped(tx, purge).Delete(item).Error
+	})
+}
+
+// removeReferencing applies the delete policies of the relations referencing
+// the rows of table with the given ids. Every blocking reference is gathered
+// before any cascaded row is deleted, so that a failed delete names them all.
+func removeReferencing(tx *gorm.DB, table string, ids []uint, purge bool) error {
+	type cascade struct {
+		relation *Relation
+		ids      []uint
+	}
+	var blocking []Reference
+	var cascades []cascade
+	for _, relation := range Relations {
+		parent, err := tableOf(tx, relation.Parent)
+		if err != nil {
+			return err
+		}
+		if parent != table {
+			continue
+		}
+		child, err := tableOf(tx, relation.Model)
+		if err != nil {
+			return err
+		}
+
+		var referencing []uint
+		if err := scoped(tx, purge).Model(newModel(relation.Model)).
+			Where(relation.Column+" IN ?", ids).
+			Order("id").
+			Pluck("id", &referencing).Error; err != nil {
+			return fmt.Errorf("failed to find %s: %w", child, err)
+		}
+		if len(referencing) == 0 {
+			continue
+		}
+
+		switch {
+		case relation.OnDelete == OnDeleteCascade:
+			cascades = append(cascades, cascade{relation, referencing})
+		case relation.OnDelete == OnDeleteRestrict || purge:
+			blocking = append(blocking, Reference{Table: child, Column: relation.Column, Ids: referencing})
+		}
+	}
+	if len(blocking) > 0 {
+		return &ReferenceError{Message: "the item is still referenced", References: blocking}
+	}
+
+	for _, cascade := range cascades {
+		child, err := tableOf(tx, cascade.relation.Model)
+		if err != nil {
+			return err
+		}
+		if err := removeReferencing(tx, child, cascade.ids, purge); err != nil {
+			return err
+		}
+		if err := scoped(tx, purge).Delete(newModel(cascade.relation.Model), cascade.ids).Error; err != nil {
+			return fmt.Errorf("failed to delete %s: %w", child, err)
+		}
+	}
+	return nil
+}
+
+// danglingLimit caps the ids a DanglingError names per relation
+const danglingLimit = 100
+
+// DanglingError is returned by MigrateRelations when rows reference ids
+// that do not exist. The foreign keys of their relations are left out until
+// the rows are fixed, while the others are created.
+type DanglingError struct {
+	References []Reference
+}
+
+func (e *DanglingError) Error() string {
+	names := make([]string, len(e.References))
+	for i, reference := range e.References {
+		names[i] = fmt.Sprintf("%s.%s %v", reference.Table, reference.Column, reference.Ids)
+	}
+	return "foreign keys left out over dangling references: " + strings.Join(names, ", ")
+}
+
+// MigrateRelations creates the foreign keys of the relations the given models
+// are part of, on either side, once both of their tables exist. Restricted
+// and archived relations keep the rows they reference from being purged, and
+// cascaded ones are purged with them. A relation whose rows reference missing
+// ids gets no foreign key, and is named by the DanglingError returned once
+// the others are created. SQLite cannot add foreign keys to existing tables,
+// so there the policies rest on Delete and Purge alone.
+func MigrateRelations(db *gorm.DB, values ...interface{}) error {
+	if db.Dialector.Name() == "sqlite" {
+		return nil
+	}
+
+	tables := map[string]bool{}
+	for _, value := range values {
+		table, err := tableOf(db, value)
+		if err != nil {
+			return err
+		}
+		tables[table] = true
+	}
+
+	var dangling []Reference
+	migrator := db.Migrator()
+	for _, relation := range Relations {
+		child, err := tableOf(db, relation.Model)
+		if err != nil {
+			return err
+		}
+		parent, err := tableOf(db, relation.Parent)
+		if err != nil {
+			return err
+		}
+		if !tables[child] && !tables[parent] {
+			continue
+		}
+		if !migrator.HasTable(child) || !migrator.HasTable(parent) {
+			continue
+		}
+		name := "fk_" + child + "_" + relation.Column
+		if migrator.HasConstraint(relation.Model, name) {
+			continue
+		}
+		var ids []uint
+		if err := db.Table(child).
+			Where("? IS NOT NULL", clause.Column{Name: relation.Column}).
+			Where("NOT EXISTS (SELECT 1 FROM ? WHERE ? = ?)",
+				clause.Table{Name: parent}, clause.Column{Table: parent, Name: "id"}, clause.Column{Table: child, Name: relation.Column}).
+			Distinct(relation.Column).Limit(danglingLimit).
+			Pluck(relation.Column, &ids).Error; err != nil {
+			return fmt.Errorf("failed to find dangling %s.%s: %w", child, relation.Column, err)
+		}
+		if len(ids) > 0 {
+			dangling = append(dangling, Reference{Table: child, Column: relation.Column, Ids: ids})
+			continue
+		}
+		action := "RESTRICT"
+		if relation.OnDelete == OnDeleteCascade {
+			action = "CASCADE"
+		}
+		if err := db.Exec("ALTER TABLE ? ADD CONSTRAINT ? FOREIGN KEY (?) REFERENCES ? (?) ON DELETE "+action,
+			clause.Table{Name: child}, clause.Column{Name: name}, clause.Column{Name: relation.Column},
+			clause.Table{Name: parent}, clause.Column{Name: "id"}).Error; err != nil {
+			return fmt.Errorf("failed to create foreign key %s: %w", name, err)
+		}
+	}
+	if len(dangling) > 0 {
+		return &DanglingError{References: dangling}
+	}
+	return nil
+}
+
+// scoped returns db, seeing deleted rows when purging
+func scoped(db *gorm.DB, purge bool) *gorm.DB {
+	if purge {
+		return db.Unscoped()
+	}
+	return db
+}
+
+// newModel returns a new zero value of the model model points to
+func newModel(model interface{}) interface{} {
+	return reflect.New(reflect.TypeOf(model).Elem()).Interface()
+}
+
+// tableOf returns the table of a model
+func tableOf(db *gorm.DB, model interface{}) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", fmt.Errorf("failed to parse model: %w", err)
+	}
+	return stmt.Schema.Table, nil
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"base/core/app/users"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Team member roles
+const (
+	TeamRoleOwner  = "owner"
+	TeamRoleAdmin  = "admin"
+	TeamRoleMember = "member"
+)
+
+// Team represents a team entity, a group of users that compete together
+type Team struct {
+	Id          uint           `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId    uint           `json:"-" gorm:"index;not null;default:0"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Members     []*TeamMember  `json:"members,omitempty" gorm:"foreignKey:TeamId"`
+}
+
+// TableName returns the table name for the Team model
+func (item *Team) TableName() string {
+	return "teams"
+}
+
+// GetId returns the Id of the model
+func (item *Team) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *Team) GetModelName() string {
+	return "team"
+}
+
+// TeamMember represents the membership of a user in a team
+type TeamMember struct {
+	Id        uint        `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	TenantId  uint        `json:"-" gorm:"index;not null;default:0"`
+	TeamId    uint        `json:"team_id" gorm:"uniqueIndex:idx_team_member"`
+	UserId    uint        `json:"user_id" gorm:"uniqueIndex:idx_team_member"`
+	User      *users.User `json:"user,omitempty"`
+	Role      string      `json:"role" gorm:"size:16"`
+}
+
+// TableName returns the table name for the TeamMember model
+func (item *TeamMember) TableName() string {
+	return "teammembers"
+}
+
+// GetId returns the Id of the model
+func (item *TeamMember) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *TeamMember) GetModelName() string {
+	return "teammember"
+}
+
+// TeamListResponse represents the list view response
+type TeamListResponse struct {
+	Id          uint      `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+}
+
+// TeamResponse represents the detailed view response
+type TeamResponse struct {
+	Id          uint           `json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Members     []*TeamMember  `json:"members"`
+}
+
+// CreateTeamRequest represents the request payload for creating a Team.
+// OwnerId, when set, becomes the first member with the owner role.
+type CreateTeamRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	OwnerId     uint   `json:"owner_id,omitempty"`
+}
+
+// UpdateTeamRequest represents the request payload for updating a Team
+type UpdateTeamRequest struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// AddTeamMemberRequest represents the request payload for adding a user to a Team
+type AddTeamMemberRequest struct {
+	UserId uint   `json:"user_id" binding:"required"`
+	Role   string `json:"role,omitempty" binding:"omitempty,oneof=owner admin member"`
+}
+
+// UpdateTeamMemberRequest represents the request payload for changing a member's role
+type UpdateTeamMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner admin member"`
+}
+
+// TeamPointTotal is the sum of the members' balances of one point type
+type TeamPointTotal struct {
+	PointTypeId    uint       `json:"point_type_id"`
+	PointType      *PointType `json:"point_type,omitempty"`
+	CurrentBalance int        `json:"current_balance"`
+	LifetimeEarned int        `json:"lifetime_earned"`
+}
+
+// TeamPointsResponse represents the aggregated points of a team
+type TeamPointsResponse struct {
+	TeamId  uint              `json:"team_id"`
+	Members int               `json:"members"`
+	Totals  []*TeamPointTotal `json:"totals"`
+}
+
+// TeamChallengeProgressResponse represents the collective progress of a team
+// on a challenge. On a challenge with a goal, Value is the members' measured
+// values added up and Progress its percentage of the goal's target. Without
+// a goal, Progress is the average progress of the members taking part.
+type TeamChallengeProgressResponse struct {
+	TeamId      uint                         `json:"team_id"`
+	ChallengeId uint                         `json:"challenge_id"`
+	Progress    int                          `json:"progress"`
+	Value       int                          `json:"value"`
+	Members     []*UserChallengeListResponse `json:"members"`
+}
+
+// ToListResponse converts the model to a list response
+func (item *Team) ToListResponse() *TeamListResponse {
+	if item == nil {
+		return nil
+	}
+	return &TeamListResponse{
+		Id:          item.Id,
+		CreatedAt:   item.CreatedAt,
+		UpdatedAt:   item.UpdatedAt,
+		Name:        item.Name,
+		Description: item.Description,
+	}
+}
+
+// ToResponse converts the model to a detailed response
+func (item *Team) ToResponse() *TeamResponse {
+	if item == nil {
+		return nil
+	}
+	members := item.Members
+	if members == nil {
+		members = []*TeamMember{}
+	}
+	return &TeamResponse{
+		Id:          item.Id,
+		CreatedAt:   item.CreatedAt,
+		UpdatedAt:   item.UpdatedAt,
+		DeletedAt:   item.DeletedAt,
+		Name:        item.Name,
+		Description: item.Description,
+		Members:     members,
+	}
+}
+
+// Preload preloads all the model's relationships
+func (item *Team) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	query = query.Preload("Members.User")
+	return query
+}
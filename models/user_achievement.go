@@ -14,6 +14,7 @@ type UserAchievement struct {
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId      uint           `json:"-" gorm:"index;not null;default:0"`
 	UserId        uint           `json:"user_id"`
 	User          *users.User    `json:"user,omitempty"`
 	AchievementId uint           `json:"achievement_id"`
@@ -64,10 +65,11 @@ type UserAchievementResponse struct {
 
 // CreateUserAchievementRequest represents the request payload for creating a UserAchievement
 type CreateUserAchievementRequest struct {
-	UserId        uint           `json:"user_id" binding:"required"`
-	AchievementId uint           `json:"achievement_id" binding:"required"`
-	Progress      int            `json:"progress" binding:"required"`
-	CompletedAt   types.DateTime `json:"completed_at" binding:"required"`
+	UserId         uint           `json:"user_id" binding:"required"`
+	AchievementId  uint           `json:"achievement_id" binding:"required_without=AchievementKey"`
+	AchievementKey string         `json:"achievement_key,omitempty"`
+	Progress       int            `json:"progress" binding:"required"`
+	CompletedAt    types.DateTime `json:"completed_at" binding:"required"`
 }
 
 // UpdateUserAchievementRequest represents the request payload for updating a UserAchievement
@@ -117,6 +119,6 @@ func (item *UserAchievement) ToResponse() *UserAchievementResponse {
 func (item *UserAchievement) Preload(db *gorm.DB) *gorm.DB {
 	query := db
 	query = query.Preload("User")
-	query = query.Preload("Achievement")
+	query = query.Preload("Achievement", Archived)
 	return query
 }
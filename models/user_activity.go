@@ -8,19 +8,38 @@ import (
 	"gorm.io/gorm"
 )
 
-// UserActivity represents a useractivity entity
+// User activity statuses. Only accepted activities award points and count
+// towards quests, challenges and duels; the anomaly detector quarantines
+// suspicious ones until an admin approves or rejects them.
+const (
+	UserActivityAccepted    = "accepted"
+	UserActivityQuarantined = "quarantined"
+	UserActivityRejected    = "rejected"
+)
+
+// UserActivity represents a useractivity entity. PointsEarned is the xp it
+// earned, and Points the points of every type. ClaimedPoints keeps the points
+// the client reported, for review only: awards come from the activity type.
 type UserActivity struct {
-	Id             uint           `json:"id" gorm:"primarykey"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
-	UserId         uint           `json:"user_id"`
-	User           *users.User    `json:"user,omitempty"`
-	ActivityTypeId uint           `json:"activity_type_id"`
-	ActivityType   *ActivityType  `json:"activity_type,omitempty"`
-	PointsEarned   int            `json:"points_earned"`
-	Metadata       string         `json:"metadata"`
-	CompletedAt    types.DateTime `json:"completed_at"`
+	Id             uint                 `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt       `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId       uint                 `json:"-" gorm:"index;not null;default:0"`
+	UserId         uint                 `json:"user_id"`
+	User           *users.User          `json:"user,omitempty"`
+	ActivityTypeId uint                 `json:"activity_type_id"`
+	ActivityType   *ActivityType        `json:"activity_type,omitempty"`
+	PointsEarned   int                  `json:"points_earned"`
+	Metadata       string               `json:"metadata"`
+	CompletedAt    types.DateTime       `json:"completed_at"`
+	Points         []*UserActivityPoint `json:"points,omitempty" gorm:"foreignKey:UserActivityId"`
+	Status         string               `json:"status" gorm:"size:16;index;default:accepted"`
+	ClaimedPoints  int                  `json:"claimed_points"`
+	AnomalyScore   int                  `json:"anomaly_score"`
+	AnomalyReasons []string             `json:"anomaly_reasons,omitempty" gorm:"serializer:json"`
+	ReviewedBy     *uint                `json:"reviewed_by"`
+	ReviewedAt     *time.Time           `json:"reviewed_at"`
 }
 
 // TableName returns the table name for the UserActivity model
@@ -40,47 +59,55 @@ func (item *UserActivity) GetModelName() string {
 
 // UserActivityListResponse represents the list view response
 type UserActivityListResponse struct {
-	Id             uint           `json:"id"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	UserId         uint           `json:"user_id"`
-	ActivityTypeId uint           `json:"activity_type_id"`
-	PointsEarned   int            `json:"points_earned"`
-	Metadata       string         `json:"metadata"`
-	CompletedAt    types.DateTime `json:"completed_at"`
+	Id             uint                 `json:"id"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	UserId         uint                 `json:"user_id"`
+	ActivityTypeId uint                 `json:"activity_type_id"`
+	PointsEarned   int                  `json:"points_earned"`
+	Metadata       string               `json:"metadata"`
+	CompletedAt    types.DateTime       `json:"completed_at"`
+	Points         []*UserActivityPoint `json:"points,omitempty"`
+	Status         string               `json:"status"`
+	AnomalyScore   int                  `json:"anomaly_score"`
+	AnomalyReasons []string             `json:"anomaly_reasons,omitempty"`
 }
 
 // UserActivityResponse represents the detailed view response
 type UserActivityResponse struct {
-	Id             uint           `json:"id"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty"`
-	UserId         uint           `json:"user_id"`
-	User           *users.User    `json:"user,omitempty"`
-	ActivityTypeId uint           `json:"activity_type_id"`
-	ActivityType   *ActivityType  `json:"activity_type,omitempty"`
-	PointsEarned   int            `json:"points_earned"`
-	Metadata       string         `json:"metadata"`
-	CompletedAt    types.DateTime `json:"completed_at"`
+	Id             uint                 `json:"id"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt       `json:"deleted_at,omitempty"`
+	UserId         uint                 `json:"user_id"`
+	User           *users.User          `json:"user,omitempty"`
+	ActivityTypeId uint                 `json:"activity_type_id"`
+	ActivityType   *ActivityType        `json:"activity_type,omitempty"`
+	PointsEarned   int                  `json:"points_earned"`
+	Metadata       string               `json:"metadata"`
+	CompletedAt    types.DateTime       `json:"completed_at"`
+	Points         []*UserActivityPoint `json:"points,omitempty"`
+	Status         string               `json:"status"`
+	AnomalyScore   int                  `json:"anomaly_score"`
+	AnomalyReasons []string             `json:"anomaly_reasons,omitempty"`
 }
 
 // CreateUserActivityRequest represents the request payload for creating a UserActivity
 type CreateUserActivityRequest struct {
-	UserId         uint           `json:"user_id" binding:"required"`
-	ActivityTypeId uint           `json:"activity_type_id" binding:"required"`
-	PointsEarned   int            `json:"points_earned" binding:"required"`
-	Metadata       string         `json:"metadata" binding:"required"`
-	CompletedAt    types.DateTime `json:"completed_at" binding:"required"`
+	UserId          uint           `json:"user_id" binding:"required"`
+	ActivityTypeId  uint           `json:"activity_type_id" binding:"required_without=ActivityTypeKey"`
+	ActivityTypeKey string         `json:"activity_type_key,omitempty"`
+	PointsEarned    int            `json:"points_earned" binding:"required"`
+	Metadata        string         `json:"metadata" binding:"required"`
+	CompletedAt     types.DateTime `json:"completed_at" binding:"required"`
 }
 
-// UpdateUserActivityRequest represents the request payload for updating a UserActivity
+// UpdateUserActivityRequest represents the request payload for updating a
+// UserActivity. The user, the activity type and the points are left out, as
+// they were awarded when the activity was logged.
 type UpdateUserActivityRequest struct {
-	UserId         uint   `json:"user_id,omitempty"`
-	ActivityTypeId uint   `json:"activity_type_id,omitempty"`
-	PointsEarned   string `json:"points_earned,omitempty"`
-	Metadata       string `json:"metadata,omitempty"`
-	CompletedAt    string `json:"completed_at,omitempty"`
+	Metadata    string `json:"metadata,omitempty"`
+	CompletedAt string `json:"completed_at,omitempty"`
 }
 
 // ToListResponse converts the model to a list response
@@ -97,6 +124,10 @@ func (item *UserActivity) ToListResponse() *UserActivityListResponse {
 		PointsEarned:   item.PointsEarned,
 		Metadata:       item.Metadata,
 		CompletedAt:    item.CompletedAt,
+		Points:         item.Points,
+		Status:         item.Status,
+		AnomalyScore:   item.AnomalyScore,
+		AnomalyReasons: item.AnomalyReasons,
 	}
 }
 
@@ -117,6 +148,10 @@ func (item *UserActivity) ToResponse() *UserActivityResponse {
 		PointsEarned:   item.PointsEarned,
 		Metadata:       item.Metadata,
 		CompletedAt:    item.CompletedAt,
+		Points:         item.Points,
+		Status:         item.Status,
+		AnomalyScore:   item.AnomalyScore,
+		AnomalyReasons: item.AnomalyReasons,
 	}
 }
 
@@ -124,6 +159,7 @@ func (item *UserActivity) ToResponse() *UserActivityResponse {
 func (item *UserActivity) Preload(db *gorm.DB) *gorm.DB {
 	query := db
 	query = query.Preload("User")
-	query = query.Preload("ActivityType")
+	query = query.Preload("ActivityType", Archived)
+	query = query.Preload("Points.PointType", Archived)
 	return query
 }
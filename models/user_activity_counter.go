@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// UserActivityCounter counts the activities a user submitted of one activity
+// type. Submissions increment it before they are checked for anomalies, so
+// that concurrent submissions of the same user and type are checked one
+// after the other.
+type UserActivityCounter struct {
+	Id             uint      `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	TenantId       uint      `json:"-" gorm:"index;not null;default:0"`
+	UserId         uint      `json:"user_id" gorm:"uniqueIndex:idx_user_activity_counter"`
+	ActivityTypeId uint      `json:"activity_type_id" gorm:"uniqueIndex:idx_user_activity_counter"`
+	Count          int       `json:"count"`
+}
+
+// TableName returns the table name for the UserActivityCounter model
+func (item *UserActivityCounter) TableName() string {
+	return "useractivitycounters"
+}
+
+// GetId returns the Id of the model
+func (item *UserActivityCounter) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *UserActivityCounter) GetModelName() string {
+	return "useractivitycounter"
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserActivityPoint represents the amount of one point type a user activity earned
+type UserActivityPoint struct {
+	Id             uint           `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId       uint           `json:"-" gorm:"index;not null;default:0"`
+	UserActivityId uint           `json:"user_activity_id" gorm:"index"`
+	PointTypeId    uint           `json:"point_type_id"`
+	PointType      *PointType     `json:"point_type,omitempty"`
+	Amount         int            `json:"amount"`
+}
+
+// TableName returns the table name for the UserActivityPoint model
+func (item *UserActivityPoint) TableName() string {
+	return "useractivitypoints"
+}
+
+// GetId returns the Id of the model
+func (item *UserActivityPoint) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *UserActivityPoint) GetModelName() string {
+	return "useractivitypoint"
+}
@@ -8,17 +8,20 @@ import (
 	"gorm.io/gorm"
 )
 
-// UserChallenge represents a userchallenge entity
+// UserChallenge represents a userchallenge entity. On a challenge with a goal,
+// Value is the measured value and Progress the percentage of the target.
 type UserChallenge struct {
 	Id            uint           `json:"id" gorm:"primarykey"`
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId      uint           `json:"-" gorm:"index;not null;default:0"`
 	UserId        uint           `json:"user_id"`
 	User          *users.User    `json:"user,omitempty"`
 	ChallengeId   uint           `json:"challenge_id"`
 	Challenge     *Challenge     `json:"challenge,omitempty"`
 	Progress      int            `json:"progress"`
+	Value         int            `json:"value"`
 	CompletedAt   types.DateTime `json:"completed_at"`
 	RewardClaimed bool           `json:"reward_claimed"`
 }
@@ -46,6 +49,7 @@ type UserChallengeListResponse struct {
 	UserId        uint           `json:"user_id"`
 	ChallengeId   uint           `json:"challenge_id"`
 	Progress      int            `json:"progress"`
+	Value         int            `json:"value"`
 	CompletedAt   types.DateTime `json:"completed_at"`
 	RewardClaimed bool           `json:"reward_claimed"`
 }
@@ -61,6 +65,7 @@ type UserChallengeResponse struct {
 	ChallengeId   uint           `json:"challenge_id"`
 	Challenge     *Challenge     `json:"challenge,omitempty"`
 	Progress      int            `json:"progress"`
+	Value         int            `json:"value"`
 	CompletedAt   types.DateTime `json:"completed_at"`
 	RewardClaimed bool           `json:"reward_claimed"`
 }
@@ -68,7 +73,8 @@ type UserChallengeResponse struct {
 // CreateUserChallengeRequest represents the request payload for creating a UserChallenge
 type CreateUserChallengeRequest struct {
 	UserId        uint           `json:"user_id" binding:"required"`
-	ChallengeId   uint           `json:"challenge_id" binding:"required"`
+	ChallengeId   uint           `json:"challenge_id" binding:"required_without=ChallengeKey"`
+	ChallengeKey  string         `json:"challenge_key,omitempty"`
 	Progress      int            `json:"progress" binding:"required"`
 	CompletedAt   types.DateTime `json:"completed_at" binding:"required"`
 	RewardClaimed bool           `json:"reward_claimed" binding:"required"`
@@ -95,6 +101,7 @@ func (item *UserChallenge) ToListResponse() *UserChallengeListResponse {
 		UserId:        item.UserId,
 		ChallengeId:   item.ChallengeId,
 		Progress:      item.Progress,
+		Value:         item.Value,
 		CompletedAt:   item.CompletedAt,
 		RewardClaimed: item.RewardClaimed,
 	}
@@ -115,6 +122,7 @@ func (item *UserChallenge) ToResponse() *UserChallengeResponse {
 		ChallengeId:   item.ChallengeId,
 		Challenge:     item.Challenge,
 		Progress:      item.Progress,
+		Value:         item.Value,
 		CompletedAt:   item.CompletedAt,
 		RewardClaimed: item.RewardClaimed,
 	}
@@ -124,6 +132,6 @@ func (item *UserChallenge) ToResponse() *UserChallengeResponse {
 func (item *UserChallenge) Preload(db *gorm.DB) *gorm.DB {
 	query := db
 	query = query.Preload("User")
-	query = query.Preload("Challenge")
+	query = query.Preload("Challenge", Archived)
 	return query
 }
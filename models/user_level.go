@@ -10,13 +10,15 @@ import (
 
 // UserLevel represents a userlevel entity
 type UserLevel struct {
-	Id             uint           `json:"id" gorm:"primarykey"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
-	UserId         uint           `json:"user_id"`
-	User           *users.User    `json:"user,omitempty"`
-	CurrentLevelId uint           `json:"current_level_id"`
+	Id        uint           `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId  uint           `json:"-" gorm:"index;not null;default:0"`
+	UserId    uint           `json:"user_id"`
+	User      *users.User    `json:"user,omitempty"`
+	// CurrentLevelId is nil until the user's xp reaches a level
+	CurrentLevelId *uint          `json:"current_level_id"`
 	CurrentLevel   *Level         `json:"current_level,omitempty"`
 	CurrentXp      int            `json:"current_xp"`
 	LastLeveledUp  types.DateTime `json:"last_leveled_up"`
@@ -43,7 +45,7 @@ type UserLevelListResponse struct {
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	UserId         uint           `json:"user_id"`
-	CurrentLevelId uint           `json:"current_level_id"`
+	CurrentLevelId *uint          `json:"current_level_id"`
 	CurrentXp      int            `json:"current_xp"`
 	LastLeveledUp  types.DateTime `json:"last_leveled_up"`
 }
@@ -56,7 +58,7 @@ type UserLevelResponse struct {
 	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty"`
 	UserId         uint           `json:"user_id"`
 	User           *users.User    `json:"user,omitempty"`
-	CurrentLevelId uint           `json:"current_level_id"`
+	CurrentLevelId *uint          `json:"current_level_id"`
 	CurrentLevel   *Level         `json:"current_level,omitempty"`
 	CurrentXp      int            `json:"current_xp"`
 	LastLeveledUp  types.DateTime `json:"last_leveled_up"`
@@ -64,10 +66,11 @@ type UserLevelResponse struct {
 
 // CreateUserLevelRequest represents the request payload for creating a UserLevel
 type CreateUserLevelRequest struct {
-	UserId         uint           `json:"user_id" binding:"required"`
-	CurrentLevelId uint           `json:"current_level_id" binding:"required"`
-	CurrentXp      int            `json:"current_xp" binding:"required"`
-	LastLeveledUp  types.DateTime `json:"last_leveled_up" binding:"required"`
+	UserId          uint           `json:"user_id" binding:"required"`
+	CurrentLevelId  uint           `json:"current_level_id" binding:"required_without=CurrentLevelKey"`
+	CurrentLevelKey string         `json:"current_level_key,omitempty"`
+	CurrentXp       int            `json:"current_xp" binding:"required"`
+	LastLeveledUp   types.DateTime `json:"last_leveled_up" binding:"required"`
 }
 
 // UpdateUserLevelRequest represents the request payload for updating a UserLevel
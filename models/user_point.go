@@ -13,12 +13,17 @@ type UserPoint struct {
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	TenantId       uint           `json:"-" gorm:"index;not null;default:0"`
 	UserId         uint           `json:"user_id"`
 	User           *users.User    `json:"user,omitempty"`
 	PointTypeId    uint           `json:"point_type_id"`
 	PointType      *PointType     `json:"point_type,omitempty"`
 	CurrentBalance int            `json:"current_balance"`
 	LifetimeEarned int            `json:"lifetime_earned"`
+	LastDecayedAt  *time.Time     `json:"last_decayed_at,omitempty"`
+	// OverdraftLimit mirrors the point type's limit so the database can
+	// enforce the balance floor on its own
+	OverdraftLimit int `json:"overdraft_limit" gorm:"not null;default:0;check:chk_userpoints_balance,current_balance + overdraft_limit >= 0"`
 }
 
 // TableName returns the table name for the UserPoint model
@@ -59,14 +64,17 @@ type UserPointResponse struct {
 	PointType      *PointType     `json:"point_type,omitempty"`
 	CurrentBalance int            `json:"current_balance"`
 	LifetimeEarned int            `json:"lifetime_earned"`
+	LastDecayedAt  *time.Time     `json:"last_decayed_at,omitempty"`
+	OverdraftLimit int            `json:"overdraft_limit"`
 }
 
 // CreateUserPointRequest represents the request payload for creating a UserPoint
 type CreateUserPointRequest struct {
-	UserId         uint `json:"user_id" binding:"required"`
-	PointTypeId    uint `json:"point_type_id" binding:"required"`
-	CurrentBalance int  `json:"current_balance" binding:"required"`
-	LifetimeEarned int  `json:"lifetime_earned" binding:"required"`
+	UserId         uint   `json:"user_id" binding:"required"`
+	PointTypeId    uint   `json:"point_type_id" binding:"required_without=PointTypeKey"`
+	PointTypeKey   string `json:"point_type_key,omitempty"`
+	CurrentBalance int    `json:"current_balance" binding:"required"`
+	LifetimeEarned int    `json:"lifetime_earned" binding:"required"`
 }
 
 // UpdateUserPointRequest represents the request payload for updating a UserPoint
@@ -109,6 +117,8 @@ func (item *UserPoint) ToResponse() *UserPointResponse {
 		PointType:      item.PointType,
 		CurrentBalance: item.CurrentBalance,
 		LifetimeEarned: item.LifetimeEarned,
+		LastDecayedAt:  item.LastDecayedAt,
+		OverdraftLimit: item.OverdraftLimit,
 	}
 }
 
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"base/core/app/users"
+	"time"
+)
+
+// User quest statuses
+const (
+	UserQuestActive    = "active"
+	UserQuestCompleted = "completed"
+)
+
+// User quest step statuses. A locked step waits for the previous step of an
+// ordered quest; only active steps make progress.
+const (
+	UserQuestStepLocked    = "locked"
+	UserQuestStepActive    = "active"
+	UserQuestStepCompleted = "completed"
+)
+
+// UserQuest represents the state of a user on a quest
+type UserQuest struct {
+	Id          uint             `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	TenantId    uint             `json:"-" gorm:"index;not null;default:0"`
+	UserId      uint             `json:"user_id" gorm:"uniqueIndex:idx_user_quest"`
+	User        *users.User      `json:"user,omitempty"`
+	QuestId     uint             `json:"quest_id" gorm:"uniqueIndex:idx_user_quest"`
+	Quest       *Quest           `json:"quest,omitempty"`
+	Status      string           `json:"status" gorm:"size:16;index"`
+	CompletedAt *time.Time       `json:"completed_at"`
+	Steps       []*UserQuestStep `json:"steps,omitempty" gorm:"foreignKey:UserQuestId"`
+}
+
+// TableName returns the table name for the UserQuest model
+func (item *UserQuest) TableName() string {
+	return "userquests"
+}
+
+// GetId returns the Id of the model
+func (item *UserQuest) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *UserQuest) GetModelName() string {
+	return "userquest"
+}
+
+// UserQuestStep represents the state of a user on one step of a quest
+type UserQuestStep struct {
+	Id          uint       `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	TenantId    uint       `json:"-" gorm:"index;not null;default:0"`
+	UserQuestId uint       `json:"user_quest_id" gorm:"uniqueIndex:idx_user_quest_step"`
+	QuestStepId uint       `json:"quest_step_id" gorm:"uniqueIndex:idx_user_quest_step"`
+	QuestStep   *QuestStep `json:"quest_step,omitempty"`
+	Status      string     `json:"status" gorm:"size:16"`
+	Progress    int        `json:"progress"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// TableName returns the table name for the UserQuestStep model
+func (item *UserQuestStep) TableName() string {
+	return "userqueststeps"
+}
+
+// GetId returns the Id of the model
+func (item *UserQuestStep) GetId() uint {
+	return item.Id
+}
+
+// GetModelName returns the model name
+func (item *UserQuestStep) GetModelName() string {
+	return "userqueststep"
+}
+
+// UserQuestStepResponse represents the state of a user on one quest step
+type UserQuestStepResponse struct {
+	QuestStepId   uint       `json:"quest_step_id"`
+	Position      int        `json:"position"`
+	Name          string     `json:"name"`
+	Kind          string     `json:"kind"`
+	Status        string     `json:"status"`
+	Progress      int        `json:"progress"`
+	RequiredCount int        `json:"required_count"`
+	CompletedAt   *time.Time `json:"completed_at"`
+}
+
+// UserQuestResponse represents the state of a user on a quest, its steps in order
+type UserQuestResponse struct {
+	Id          uint                     `json:"id"`
+	CreatedAt   time.Time                `json:"created_at"`
+	UserId      uint                     `json:"user_id"`
+	QuestId     uint                     `json:"quest_id"`
+	Quest       *QuestListResponse       `json:"quest,omitempty"`
+	Status      string                   `json:"status"`
+	CompletedAt *time.Time               `json:"completed_at"`
+	Steps       []*UserQuestStepResponse `json:"steps"`
+}
+
+// ToResponse converts the model to a response. Steps are listed in the order
+// of the quest's steps when the quest is loaded with them.
+func (item *UserQuest) ToResponse() *UserQuestResponse {
+	if item == nil {
+		return nil
+	}
+	result := &UserQuestResponse{
+		Id:          item.Id,
+		CreatedAt:   item.CreatedAt,
+		UserId:      item.UserId,
+		QuestId:     item.QuestId,
+		Status:      item.Status,
+		CompletedAt: item.CompletedAt,
+		Steps:       []*UserQuestStepResponse{},
+	}
+	if item.Quest == nil {
+		return result
+	}
+	result.Quest = item.Quest.ToListResponse()
+
+	byStep := make(map[uint]*UserQuestStep, len(item.Steps))
+	for _, state := range item.Steps {
+		byStep[state.QuestStepId] = state
+	}
+	for _, step := range item.Quest.Steps {
+		state, ok := byStep[step.Id]
+		if !ok {
+			state = &UserQuestStep{Status: UserQuestStepLocked}
+		}
+		result.Steps = append(result.Steps, &UserQuestStepResponse{
+			QuestStepId:   step.Id,
+			Position:      step.Position,
+			Name:          step.Name,
+			Kind:          step.Kind,
+			Status:        state.Status,
+			Progress:      state.Progress,
+			RequiredCount: step.Required(),
+			CompletedAt:   state.CompletedAt,
+		})
+	}
+	return result
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// FieldError is a request field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when request fields fail validation. It is
+// also the body of the 400 responses it leads to.
+type ValidationError struct {
+	Message string       `json:"error"`
+	Fields  []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		fields[i] = field.Field + " " + field.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(fields, ", "))
+}
+
+// ReferenceCheck checks the rows a request references by id. Every field is
+// checked, so that a failed check names all the fields at fault.
+type ReferenceCheck struct {
+	db     *gorm.DB
+	err    error
+	fields []FieldError
+}
+
+// CheckReferences starts a reference check on the connection and context of
+// db, whose own conditions are left out. The rows of other tenants are not
+// found, like missing ones.
+func CheckReferences(db *gorm.DB) *ReferenceCheck {
+	return &ReferenceCheck{db: db.Session(&gorm.Session{NewDB: true})}
+}
+
+// Exists checks that id is a row of model. Ids of 0 are unset and pass.
+func (c *ReferenceCheck) Exists(field string, model interface{}, id uint) *ReferenceCheck {
+	return c.check(field, model, id, false)
+}
+
+// Active checks that id is an active row of model, for references that
+// would have the row used. Ids of 0 are unset and pass.
+func (c *ReferenceCheck) Active(field string, model interface{}, id uint) *ReferenceCheck {
+	return c.check(field, model, id, true)
+}
+
+func (c *ReferenceCheck) check(field string, model interface{}, id uint, active bool) *ReferenceCheck {
+	if c.err != nil || id == 0 {
+		return c
+	}
+
+	columns := "id"
+	if active {
+		columns = "id, is_active"
+	}
+	var rows []struct {
+		Id       uint
+		IsActive bool
+	}
+	if err := c.db.Model(newModel(model)).Select(columns).Where("id = ?", id).Limit(1).Find(&rows).Error; err != nil {
+		table, _ := tableOf(c.db, model)
+		c.err = fmt.Errorf("failed to find %s: %w", table, err)
+		return c
+	}
+
+	switch {
+	case len(rows) == 0:
+		c.fields = append(c.fields, FieldError{Field: field, Message: fmt.Sprintf("%d does not exist", id)})
+	case active && !rows[0].IsActive:
+		c.fields = append(c.fields, FieldError{Field: field, Message: fmt.Sprintf("%d is inactive", id)})
+	}
+	return c
+}
+
+// Err returns the error the check failed with: a query error, or a
+// ValidationError naming the fields at fault. It returns nil when every
+// referenced row passed.
+func (c *ReferenceCheck) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	if len(c.fields) > 0 {
+		return &ValidationError{Message: "the request references missing or inactive rows", Fields: c.fields}
+	}
+	return nil
+}
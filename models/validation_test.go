@@ -0,0 +1,71 @@
+package models_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"base/packages/gamification/internal/testdb"
+	"base/packages/gamification/models"
+)
+
+func TestCheckReferences(t *testing.T) {
+	db := testdb.ForTenant(testdb.Open(t, &models.ActivityType{}, &models.PointType{}), 1)
+	active := &models.ActivityType{Key: "run-logged", Name: "Run logged", IsActive: true}
+	inactive := &models.ActivityType{Key: "walk-logged", Name: "Walk logged"}
+	deleted := &models.PointType{Key: "gems", Name: "Gems"}
+	for _, item := range []interface{}{active, inactive, deleted} {
+		if err := db.Create(item).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", item, err)
+		}
+	}
+	if err := db.Delete(deleted).Error; err != nil {
+		t.Fatalf("failed to delete point type: %v", err)
+	}
+
+	// Unset ids and live rows pass
+	if err := models.CheckReferences(db).
+		Active("activity_type_id", &models.ActivityType{}, active.Id).
+		Exists("other_activity_type_id", &models.ActivityType{}, inactive.Id).
+		Exists("point_type_id", &models.PointType{}, 0).
+		Err(); err != nil {
+		t.Errorf("err = %v, want nil", err)
+	}
+
+	err := models.CheckReferences(db.Where("1 = 0")).
+		Active("activity_type_id", &models.ActivityType{}, inactive.Id).
+		Exists("point_type_id", &models.PointType{}, deleted.Id).
+		Exists("other_point_type_id", &models.PointType{}, 999).
+		Err()
+	var validation *models.ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("err = %v, want a validation error", err)
+	}
+	var fields []string
+	for _, field := range validation.Fields {
+		fields = append(fields, field.Field)
+	}
+	// Every field at fault is named, whatever the conditions of db
+	if want := []string{"activity_type_id", "point_type_id", "other_point_type_id"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+}
+
+func TestResolveFieldKey(t *testing.T) {
+	db := testdb.ForTenant(testdb.Open(t, &models.PointType{}), 1)
+	coins := &models.PointType{Key: "coins", Name: "Coins"}
+	if err := db.Create(coins).Error; err != nil {
+		t.Fatalf("failed to create point type: %v", err)
+	}
+
+	id, err := models.ResolveFieldKey(db, &models.PointType{}, "point_type_key", "coins")
+	if err != nil || id != coins.Id {
+		t.Errorf("resolving coins = %d, %v, want %d, nil", id, err, coins.Id)
+	}
+
+	_, err = models.ResolveFieldKey(db, &models.PointType{}, "point_type_key", "gems")
+	var validation *models.ValidationError
+	if !errors.As(err, &validation) || len(validation.Fields) != 1 || validation.Fields[0].Field != "point_type_key" {
+		t.Errorf("resolving an unknown key: err = %v, want a validation error on point_type_key", err)
+	}
+}
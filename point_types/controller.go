@@ -1,10 +1,12 @@
 package point_types
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"base/core/storage"
+	"base/packages/gamification/access"
 	"base/packages/gamification/models"
 
 	"github.com/gin-gonic/gin"
@@ -27,9 +29,10 @@ func (c *PointTypeController) Routes(router *gin.RouterGroup) {
 	router.GET("/point-types", c.List)        // Paginated list
 	router.GET("/point-types/all", c.ListAll) // Unpaginated list
 	router.GET("/point-types/:id", c.Get)
-	router.POST("/point-types", c.Create)
-	router.PUT("/point-types/:id", c.Update)
-	router.DELETE("/point-types/:id", c.Delete)
+	router.GET("/point-types/by-key/:key", c.GetByKey)
+	router.POST("/point-types", access.Require(access.RoleAdmin), c.Create)
+	router.PUT("/point-types/:id", access.Require(access.RoleAdmin), c.Update)
+	router.DELETE("/point-types/:id", access.Require(access.RoleAdmin), c.Delete)
 
 	// File/Image attachment endpoints
 
@@ -47,6 +50,8 @@ func (c *PointTypeController) Routes(router *gin.RouterGroup) {
 // @Param point-types body models.CreatePointTypeRequest true "Create PointType request"
 // @Success 201 {object} models.PointTypeResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /point-types [post]
 func (c *PointTypeController) Create(ctx *gin.Context) {
@@ -56,8 +61,16 @@ func (c *PointTypeController) Create(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Create(&req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidKey) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, models.ErrDuplicateKey) {
+			ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
 		return
 	}
@@ -76,6 +89,7 @@ func (c *PointTypeController) Create(ctx *gin.Context) {
 // @Param id path int true "PointType id"
 // @Success 200 {object} models.PointTypeResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /point-types/{id} [get]
 func (c *PointTypeController) Get(ctx *gin.Context) {
@@ -85,7 +99,30 @@ func (c *PointTypeController) Get(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// GetPointTypeByKey godoc
+// @Summary Get a PointType by key
+// @Description Get a PointType by its stable key
+// @Tags PointType
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key path string true "PointType key"
+// @Success 200 {object} models.PointTypeResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /point-types/by-key/{key} [get]
+func (c *PointTypeController) GetByKey(ctx *gin.Context) {
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetByKey(ctx.Param("key"))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -106,6 +143,7 @@ func (c *PointTypeController) Get(ctx *gin.Context) {
 // @Param limit query int false "Number of items per page"
 // @Success 200 {object} types.PaginatedResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /point-types [get]
 func (c *PointTypeController) List(ctx *gin.Context) {
@@ -129,7 +167,7 @@ func (c *PointTypeController) List(ctx *gin.Context) {
 		}
 	}
 
-	paginatedResponse, err := c.Service.GetAll(page, limit)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(page, limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
 		return
@@ -147,10 +185,11 @@ func (c *PointTypeController) List(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /point-types/all [get]
 func (c *PointTypeController) ListAll(ctx *gin.Context) {
-	paginatedResponse, err := c.Service.GetAll(nil, nil)
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(nil, nil)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
 		return
@@ -171,7 +210,9 @@ func (c *PointTypeController) ListAll(ctx *gin.Context) {
 // @Param point-types body models.UpdatePointTypeRequest true "Update PointType request"
 // @Success 200 {object} models.PointTypeResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /point-types/{id} [put]
 func (c *PointTypeController) Update(ctx *gin.Context) {
@@ -187,8 +228,12 @@ func (c *PointTypeController) Update(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Update(uint(id), &req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
 	if err != nil {
+		if errors.Is(err, ErrOverdraftInUse) {
+			ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
 	}
@@ -207,6 +252,8 @@ func (c *PointTypeController) Update(ctx *gin.Context) {
 // @Param id path int true "PointType id"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
 // @Failure 500 {object} ErrorResponse
 // @Router /point-types/{id} [delete]
 func (c *PointTypeController) Delete(ctx *gin.Context) {
@@ -216,7 +263,12 @@ func (c *PointTypeController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.Delete(uint(id)); err != nil {
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
 		return
 	}
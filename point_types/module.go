@@ -41,7 +41,13 @@ func (m *Module) Routes(router *gin.RouterGroup) {
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.PointType{})
+	if err := models.MigrateKey(m.DB, &models.PointType{}, "name"); err != nil {
+		return err
+	}
+	if err := m.DB.AutoMigrate(&models.PointType{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.PointType{})
 }
 
 func (m *Module) GetModels() []interface{} {
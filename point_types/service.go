@@ -1,6 +1,8 @@
 package point_types
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 
@@ -19,6 +21,9 @@ const (
 	DeletePointTypeEvent = "pointtypes.delete"
 )
 
+// ErrOverdraftInUse is returned when lowering the overdraft limit would leave balances below the new floor
+var ErrOverdraftInUse = errors.New("balances are overdrawn beyond the new overdraft limit")
+
 type PointTypeService struct {
 	DB      *gorm.DB
 	Emitter *emitter.Emitter
@@ -35,14 +40,46 @@ func NewPointTypeService(db *gorm.DB, emitter *emitter.Emitter, storage *storage
 	}
 }
 
+func (s *PointTypeService) WithContext(ctx context.Context) *PointTypeService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
 func (s *PointTypeService) Create(req *models.CreatePointTypeRequest) (*models.PointType, error) {
+	key, err := models.NewKey(s.DB, &models.PointType{}, req.Key, req.Name)
+	if err != nil {
+		s.Logger.Error("failed to assign pointtype key", logger.String("error", err.Error()))
+		return nil, err
+	}
+
 	item := &models.PointType{
+		Key:         key,
 		Name:        req.Name,
 		Description: req.Description,
 		Icon:        req.Icon,
+		IsXpSource:  req.IsXpSource,
+		// Expiration and decay policy
+		ExpiresAfterDays: req.ExpiresAfterDays,
+		DecayPercent:     req.DecayPercent,
+		DecayPeriodDays:  req.DecayPeriodDays,
+		// Transfer policy
+		IsTransferable:     req.IsTransferable,
+		DailyTransferLimit: req.DailyTransferLimit,
+		OverdraftLimit:     req.OverdraftLimit,
+		// Admin adjustment policy
+		ApprovalThreshold: req.ApprovalThreshold,
 	}
 
-	if err := s.DB.Create(item).Error; err != nil {
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if item.IsXpSource {
+			if err := clearXpSource(tx); err != nil {
+				return err
+			}
+		}
+		return tx.Create(item).Error
+	})
+	if err != nil {
 		s.Logger.Error("failed to create pointtype", logger.String("error", err.Error()))
 		return nil, fmt.Errorf("failed to create pointtype: %w", err)
 	}
@@ -73,8 +110,49 @@ func (s *PointTypeService) Update(id uint, req *models.UpdatePointTypeRequest) (
 	if req.Icon != "" {
 		updates["icon"] = req.Icon
 	}
+	if req.IsXpSource != nil {
+		updates["is_xp_source"] = *req.IsXpSource
+	}
+	if req.ExpiresAfterDays != nil {
+		updates["expires_after_days"] = *req.ExpiresAfterDays
+	}
+	if req.DecayPercent != nil {
+		updates["decay_percent"] = *req.DecayPercent
+	}
+	if req.DecayPeriodDays != nil {
+		updates["decay_period_days"] = *req.DecayPeriodDays
+	}
+	if req.IsTransferable != nil {
+		updates["is_transferable"] = *req.IsTransferable
+	}
+	if req.DailyTransferLimit != nil {
+		updates["daily_transfer_limit"] = *req.DailyTransferLimit
+	}
+	if req.OverdraftLimit != nil {
+		updates["overdraft_limit"] = *req.OverdraftLimit
+	}
+	if req.ApprovalThreshold != nil {
+		updates["approval_threshold"] = *req.ApprovalThreshold
+	}
 
-	if err := s.DB.Model(item).Updates(updates).Error; err != nil {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if req.IsXpSource != nil && *req.IsXpSource {
+			if err := clearXpSource(tx); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(item).Updates(updates).Error; err != nil {
+			return err
+		}
+		if req.OverdraftLimit != nil {
+			return applyOverdraftLimit(tx, item.Id, *req.OverdraftLimit)
+		}
+		return nil
+	})
+	if errors.Is(err, ErrOverdraftInUse) {
+		return nil, err
+	}
+	if err != nil {
 		s.Logger.Error("failed to update pointtype",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -106,7 +184,11 @@ func (s *PointTypeService) Delete(id uint) error {
 
 	// Delete file attachments if any
 
-	if err := s.DB.Delete(item).Error; err != nil {
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
 		s.Logger.Error("failed to delete pointtype",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -134,6 +216,22 @@ func (s *PointTypeService) GetById(id uint) (*models.PointType, error) {
 	return item, nil
 }
 
+// GetByKey returns the pointtype identified by its key
+func (s *PointTypeService) GetByKey(key string) (*models.PointType, error) {
+	item := &models.PointType{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.Where(&models.PointType{Key: key}).First(item).Error; err != nil {
+		s.Logger.Error("failed to get pointtype by key",
+			logger.String("error", err.Error()),
+			logger.String("key", key))
+		return nil, fmt.Errorf("failed to get pointtype: %w", err)
+	}
+
+	return item, nil
+}
+
 func (s *PointTypeService) GetAll(page *int, limit *int) (*types.PaginatedResponse, error) {
 	var items []*models.PointType
 	var total int64
@@ -193,3 +291,28 @@ func (s *PointTypeService) GetAll(page *int, limit *int) (*types.PaginatedRespon
 		},
 	}, nil
 }
+
+// clearXpSource unsets the xp flag on every point type, so that the one
+// being saved is the only currency feeding user levels
+func clearXpSource(tx *gorm.DB) error {
+	return tx.Model(&models.PointType{}).
+		Where("is_xp_source = ?", true).
+		Update("is_xp_source", false).Error
+}
+
+// applyOverdraftLimit copies a point type's overdraft limit onto its balances,
+// which carry it for the balance check constraint
+func applyOverdraftLimit(tx *gorm.DB, pointTypeId uint, limit int) error {
+	var overdrawn int64
+	if err := tx.Model(&models.UserPoint{}).
+		Where("point_type_id = ? AND current_balance < ?", pointTypeId, -limit).
+		Count(&overdrawn).Error; err != nil {
+		return err
+	}
+	if overdrawn > 0 {
+		return ErrOverdraftInUse
+	}
+	return tx.Model(&models.UserPoint{}).
+		Where("point_type_id = ?", pointTypeId).
+		Update("overdraft_limit", limit).Error
+}
@@ -0,0 +1,265 @@
+package quest_steps
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"base/core/storage"
+	"base/packages/gamification/access"
+	"base/packages/gamification/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type QuestStepController struct {
+	Service *QuestStepService
+	Storage *storage.ActiveStorage
+}
+
+func NewQuestStepController(service *QuestStepService, storage *storage.ActiveStorage) *QuestStepController {
+	return &QuestStepController{
+		Service: service,
+		Storage: storage,
+	}
+}
+
+func (c *QuestStepController) Routes(router *gin.RouterGroup) {
+	// Main CRUD endpoints
+	router.GET("/quest-steps", c.List)        // Paginated list
+	router.GET("/quest-steps/all", c.ListAll) // Unpaginated list
+	router.GET("/quest-steps/:id", c.Get)
+	router.POST("/quest-steps", access.Require(access.RoleAdmin), c.Create)
+	router.PUT("/quest-steps/:id", access.Require(access.RoleAdmin), c.Update)
+	router.DELETE("/quest-steps/:id", access.Require(access.RoleAdmin), c.Delete)
+
+	// File/Image attachment endpoints
+
+	// HasMany relation endpoints
+}
+
+// CreateQuestStep godoc
+// @Summary Create a new QuestStep
+// @Description Create a new QuestStep with the input payload
+// @Tags QuestStep
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param quest-steps body models.CreateQuestStepRequest true "Create QuestStep request"
+// @Success 201 {object} models.QuestStepResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quest-steps [post]
+func (c *QuestStepController) Create(ctx *gin.Context) {
+	var req models.CreateQuestStepRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
+	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, ErrInvalidStep) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, item.ToResponse())
+}
+
+// GetQuestStep godoc
+// @Summary Get a QuestStep
+// @Description Get a QuestStep by its id
+// @Tags QuestStep
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "QuestStep id"
+// @Success 200 {object} models.QuestStepResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /quest-steps/{id} [get]
+func (c *QuestStepController) Get(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// ListQuestSteps godoc
+// @Summary List quest-steps
+// @Description Get a list of quest-steps
+// @Tags QuestStep
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quest-steps [get]
+func (c *QuestStepController) List(ctx *gin.Context) {
+	var page, limit *int
+
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid page number"})
+			return
+		}
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit number"})
+			return
+		}
+	}
+
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// ListAllQuestSteps godoc
+// @Summary List all quest-steps without pagination
+// @Description Get a list of all quest-steps without pagination
+// @Tags QuestStep
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quest-steps/all [get]
+func (c *QuestStepController) ListAll(ctx *gin.Context) {
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(nil, nil)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// UpdateQuestStep godoc
+// @Summary Update a QuestStep
+// @Description Update a QuestStep by its id
+// @Tags QuestStep
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "QuestStep id"
+// @Param quest-steps body models.UpdateQuestStepRequest true "Update QuestStep request"
+// @Success 200 {object} models.QuestStepResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quest-steps/{id} [put]
+func (c *QuestStepController) Update(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	var req models.UpdateQuestStepRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
+	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, ErrInvalidStep) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// DeleteQuestStep godoc
+// @Summary Delete a QuestStep
+// @Description Delete a QuestStep by its id
+// @Tags QuestStep
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "QuestStep id"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
+// @Failure 500 {object} ErrorResponse
+// @Router /quest-steps/{id} [delete]
+func (c *QuestStepController) Delete(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, SuccessResponse{Message: "Item deleted successfully"})
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,280 @@
+package quest_steps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+	"base/core/types"
+	"base/packages/gamification/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CreateQuestStepEvent = "queststeps.create"
+	UpdateQuestStepEvent = "queststeps.update"
+	DeleteQuestStepEvent = "queststeps.delete"
+)
+
+// ErrInvalidStep is returned when a step isn't bound to exactly what its kind
+// needs: an activity type, or an achievement
+var ErrInvalidStep = errors.New("an activity step needs an activity type and an achievement step an achievement")
+
+type QuestStepService struct {
+	DB      *gorm.DB
+	Emitter *emitter.Emitter
+	Storage *storage.ActiveStorage
+	Logger  logger.Logger
+}
+
+func NewQuestStepService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *QuestStepService {
+	return &QuestStepService{
+		DB:      db,
+		Emitter: emitter,
+		Storage: storage,
+		Logger:  logger,
+	}
+}
+
+func (s *QuestStepService) WithContext(ctx context.Context) *QuestStepService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
+func (s *QuestStepService) Create(req *models.CreateQuestStepRequest) (*models.QuestStep, error) {
+	if req.QuestKey != "" {
+		questId, err := models.ResolveFieldKey(s.DB, &models.Quest{}, "quest_key", req.QuestKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve quest key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve quest: %w", err)
+		}
+		req.QuestId = questId
+	}
+	if req.ActivityTypeKey != "" {
+		activityTypeId, err := models.ResolveFieldKey(s.DB, &models.ActivityType{}, "activity_type_key", req.ActivityTypeKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve activity type key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve activity type: %w", err)
+		}
+		req.ActivityTypeId = activityTypeId
+	}
+	if req.AchievementKey != "" {
+		achievementId, err := models.ResolveFieldKey(s.DB, &models.Achievement{}, "achievement_key", req.AchievementKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve achievement key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve achievement: %w", err)
+		}
+		req.AchievementId = achievementId
+	}
+	if err := models.CheckReferences(s.DB).
+		Exists("quest_id", &models.Quest{}, req.QuestId).
+		Exists("activity_type_id", &models.ActivityType{}, req.ActivityTypeId).
+		Exists("achievement_id", &models.Achievement{}, req.AchievementId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check queststep references", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	item := &models.QuestStep{
+		QuestId:     req.QuestId,
+		Position:    req.Position,
+		Name:        req.Name,
+		Description: req.Description,
+		Kind:        req.Kind,
+	}
+	switch req.Kind {
+	case models.QuestStepActivity:
+		if req.ActivityTypeId == 0 || req.AchievementId != 0 {
+			return nil, ErrInvalidStep
+		}
+		item.ActivityTypeId = &req.ActivityTypeId
+		item.RequiredCount = req.RequiredCount
+		if item.RequiredCount == 0 {
+			item.RequiredCount = 1
+		}
+	case models.QuestStepAchievement:
+		if req.AchievementId == 0 || req.ActivityTypeId != 0 {
+			return nil, ErrInvalidStep
+		}
+		item.AchievementId = &req.AchievementId
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		// Without a position the step goes last
+		if item.Position == 0 {
+			var last int
+			if err := tx.Model(&models.QuestStep{}).Where("quest_id = ?", req.QuestId).
+				Select("COALESCE(MAX(position), 0)").Scan(&last).Error; err != nil {
+				return err
+			}
+			item.Position = last + 1
+		}
+		return tx.Create(item).Error
+	})
+	if err != nil {
+		s.Logger.Error("failed to create queststep", logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to create queststep: %w", err)
+	}
+
+	// Emit create event
+	s.Emitter.Emit(CreateQuestStepEvent, item)
+
+	return s.GetById(item.Id)
+}
+
+func (s *QuestStepService) Update(id uint, req *models.UpdateQuestStepRequest) (*models.QuestStep, error) {
+	item := &models.QuestStep{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to find queststep for update",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to find queststep: %w", err)
+	}
+
+	// Build updates map
+	updates := make(map[string]interface{})
+	if req.Position != 0 {
+		updates["position"] = req.Position
+	}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != "" {
+		updates["description"] = req.Description
+	}
+	if req.RequiredCount != 0 {
+		if item.Kind != models.QuestStepActivity {
+			return nil, ErrInvalidStep
+		}
+		updates["required_count"] = req.RequiredCount
+	}
+
+	if err := s.DB.Model(item).Updates(updates).Error; err != nil {
+		s.Logger.Error("failed to update queststep",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to update queststep: %w", err)
+	}
+
+	result, err := s.GetById(item.Id)
+	if err != nil {
+		s.Logger.Error("failed to get updated queststep",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to get updated queststep: %w", err)
+	}
+
+	// Emit update event
+	s.Emitter.Emit(UpdateQuestStepEvent, result)
+
+	return result, nil
+}
+
+func (s *QuestStepService) Delete(id uint) error {
+	item := &models.QuestStep{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to find queststep for deletion",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return fmt.Errorf("failed to find queststep: %w", err)
+	}
+
+	// Delete file attachments if any
+
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
+		s.Logger.Error("failed to delete queststep",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return fmt.Errorf("failed to delete queststep: %w", err)
+	}
+
+	// Emit delete event
+	s.Emitter.Emit(DeleteQuestStepEvent, item)
+
+	return nil
+}
+
+func (s *QuestStepService) GetById(id uint) (*models.QuestStep, error) {
+	item := &models.QuestStep{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to get queststep",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to get queststep: %w", err)
+	}
+
+	return item, nil
+}
+
+func (s *QuestStepService) GetAll(page *int, limit *int) (*types.PaginatedResponse, error) {
+	var items []*models.QuestStep
+	var total int64
+	query := s.DB.Model(&models.QuestStep{})
+	// Set default values if nil
+	defaultPage := 1
+	defaultLimit := 10
+	if page == nil {
+		page = &defaultPage
+	}
+	if limit == nil {
+		limit = &defaultLimit
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count queststeps",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to count queststeps: %w", err)
+	}
+
+	// Apply pagination if provided
+	if page != nil && limit != nil {
+		offset := (*page - 1) * *limit
+		query = query.Offset(offset).Limit(*limit)
+	}
+
+	// Preload relationships
+	query = (&models.QuestStep{}).Preload(query).Order("quest_id").Order("position").Order("id")
+
+	// Execute query
+	if err := query.Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get queststeps",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to get queststeps: %w", err)
+	}
+
+	// Convert to response type
+	responses := make([]*models.QuestStepListResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToListResponse()
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(total) / float64(*limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return &types.PaginatedResponse{
+		Data: responses,
+		Pagination: types.Pagination{
+			Total:      int(total),
+			Page:       *page,
+			PageSize:   *limit,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
@@ -0,0 +1,388 @@
+package quests
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"base/core/storage"
+	"base/packages/gamification/access"
+	"base/packages/gamification/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type QuestController struct {
+	Service *QuestService
+	Storage *storage.ActiveStorage
+}
+
+func NewQuestController(service *QuestService, storage *storage.ActiveStorage) *QuestController {
+	return &QuestController{
+		Service: service,
+		Storage: storage,
+	}
+}
+
+func (c *QuestController) Routes(router *gin.RouterGroup) {
+	// Main CRUD endpoints
+	router.GET("/quests", c.List)        // Paginated list
+	router.GET("/quests/all", c.ListAll) // Unpaginated list
+	router.GET("/quests/:id", c.Get)
+	router.GET("/quests/by-key/:key", c.GetByKey)
+	router.POST("/quests", access.Require(access.RoleAdmin), c.Create)
+	router.PUT("/quests/:id", access.Require(access.RoleAdmin), c.Update)
+	router.DELETE("/quests/:id", access.Require(access.RoleAdmin), c.Delete)
+
+	// Progress of a user
+	router.GET("/quests/:id/users/:user_id", c.Progress)
+	router.POST("/quests/:id/users/:user_id/start", c.Start)
+
+	// File/Image attachment endpoints
+
+	// HasMany relation endpoints
+}
+
+// CreateQuest godoc
+// @Summary Create a new Quest
+// @Description Create a new Quest with the input payload
+// @Tags Quest
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param quests body models.CreateQuestRequest true "Create Quest request"
+// @Success 201 {object} models.QuestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quests [post]
+func (c *QuestController) Create(ctx *gin.Context) {
+	var req models.CreateQuestRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
+	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, models.ErrDuplicateKey) {
+			ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, ErrInvalidReward) || errors.Is(err, models.ErrInvalidKey) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, item.ToResponse())
+}
+
+// GetQuest godoc
+// @Summary Get a Quest
+// @Description Get a Quest by its id
+// @Tags Quest
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Quest id"
+// @Success 200 {object} models.QuestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /quests/{id} [get]
+func (c *QuestController) Get(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// GetQuestByKey godoc
+// @Summary Get a Quest by key
+// @Description Get a Quest by its stable key
+// @Tags Quest
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key path string true "Quest key"
+// @Success 200 {object} models.QuestResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /quests/by-key/{key} [get]
+func (c *QuestController) GetByKey(ctx *gin.Context) {
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetByKey(ctx.Param("key"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// ListQuests godoc
+// @Summary List quests
+// @Description Get a list of quests
+// @Tags Quest
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quests [get]
+func (c *QuestController) List(ctx *gin.Context) {
+	var page, limit *int
+
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid page number"})
+			return
+		}
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit number"})
+			return
+		}
+	}
+
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// ListAllQuests godoc
+// @Summary List all quests without pagination
+// @Description Get a list of all quests without pagination
+// @Tags Quest
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quests/all [get]
+func (c *QuestController) ListAll(ctx *gin.Context) {
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(nil, nil)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// UpdateQuest godoc
+// @Summary Update a Quest
+// @Description Update a Quest by its id
+// @Tags Quest
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Quest id"
+// @Param quests body models.UpdateQuestRequest true "Update Quest request"
+// @Success 200 {object} models.QuestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quests/{id} [put]
+func (c *QuestController) Update(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	var req models.UpdateQuestRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
+	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, ErrInvalidReward) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// DeleteQuest godoc
+// @Summary Delete a Quest
+// @Description Delete a Quest by its id
+// @Tags Quest
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Quest id"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
+// @Failure 500 {object} ErrorResponse
+// @Router /quests/{id} [delete]
+func (c *QuestController) Delete(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, SuccessResponse{Message: "Item deleted successfully"})
+}
+
+// GetQuestProgress godoc
+// @Summary Get the progress of a user on a Quest
+// @Description Get the state of a user on a quest and on each of its steps, in order
+// @Tags Quest
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Quest id"
+// @Param user_id path int true "User id"
+// @Success 200 {object} models.UserQuestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /quests/{id}/users/{user_id} [get]
+func (c *QuestController) Progress(ctx *gin.Context) {
+	id, userId, ok := questUser(ctx)
+	if !ok {
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).Progress(id, userId)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// StartQuest godoc
+// @Summary Start a Quest for a user
+// @Description Put a user on a quest without waiting for their first step. Starting a quest twice returns its state.
+// @Tags Quest
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Quest id"
+// @Param user_id path int true "User id"
+// @Success 200 {object} models.UserQuestResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /quests/{id}/users/{user_id}/start [post]
+func (c *QuestController) Start(ctx *gin.Context) {
+	id, userId, ok := questUser(ctx)
+	if !ok {
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).Start(id, userId)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrQuestInactive):
+			ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to start quest: " + err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// questUser parses the quest and user ids of a progress route. End users may
+// only name themselves.
+func questUser(ctx *gin.Context) (uint, uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return 0, 0, false
+	}
+	userId, err := strconv.ParseUint(ctx.Param("user_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user id format"})
+		return 0, 0, false
+	}
+	if own, restricted := access.RestrictedTo(ctx); restricted && own != uint(userId) {
+		access.Forbid(ctx)
+		return 0, 0, false
+	}
+	return uint(id), uint(userId), true
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
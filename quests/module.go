@@ -0,0 +1,61 @@
+package quests
+
+import (
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/module"
+	"base/core/storage"
+	"base/packages/gamification/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Module struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Controller *QuestController
+	Service    *QuestService
+	Logger     *logger.Logger
+	Storage    *storage.ActiveStorage
+}
+
+func NewQuestModule(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, storage *storage.ActiveStorage) module.Module {
+
+	service := NewQuestService(db, emitter, storage, log)
+	controller := NewQuestController(service, storage)
+
+	m := &Module{
+		DB:         db,
+		Service:    service,
+		Controller: controller,
+		Logger:     &log,
+		Storage:    storage,
+	}
+
+	return m
+}
+
+// Init subscribes quest progress to activities and achievements
+func (m *Module) Init() error {
+	m.Service.Listen()
+	return nil
+}
+
+func (m *Module) Routes(router *gin.RouterGroup) {
+	m.Controller.Routes(router)
+}
+
+func (m *Module) Migrate() error {
+	if err := models.MigrateKey(m.DB, &models.Quest{}, "name"); err != nil {
+		return err
+	}
+	if err := m.DB.AutoMigrate(&models.Quest{}, &models.UserQuest{}, &models.UserQuestStep{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.Quest{}, &models.UserQuest{}, &models.UserQuestStep{})
+}
+
+func (m *Module) GetModels() []interface{} {
+	return []interface{}{&models.Quest{}, &models.UserQuest{}, &models.UserQuestStep{}}
+}
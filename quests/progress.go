@@ -0,0 +1,442 @@
+package quests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"base/core/logger"
+	"base/core/types"
+	"base/packages/gamification/models"
+	"base/packages/gamification/tenancy"
+	"base/packages/gamification/user_achievements"
+	"base/packages/gamification/user_activities"
+	"base/packages/gamification/user_levels"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	StartQuestEvent        = "userquests.start"
+	CompleteQuestStepEvent = "userquests.step"
+	CompleteQuestEvent     = "userquests.complete"
+)
+
+// ErrQuestInactive is returned when a user starts an inactive quest
+var ErrQuestInactive = errors.New("quest is not active")
+
+// outcome collects what advancing a user's quest changed, to be announced
+// once it is committed
+type outcome struct {
+	started      bool
+	steps        []*models.UserQuestStep
+	completed    bool
+	levels       []*models.UserLevel
+	achievements []*models.UserAchievement
+}
+
+// Listen advances the quests of users as their activities are recorded and
+// their achievements unlocked
+func (s *QuestService) Listen() {
+	s.Emitter.On(user_activities.CreateUserActivityEvent, func(data any) {
+		item, ok := data.(*models.UserActivity)
+		if !ok {
+			return
+		}
+		s.forTenant(item.TenantId).record(item.UserId, models.QuestStepActivity, item.ActivityTypeId)
+	})
+	s.Emitter.On(user_achievements.CreateUserAchievementEvent, func(data any) {
+		item, ok := data.(*models.UserAchievement)
+		if !ok {
+			return
+		}
+		s.forTenant(item.TenantId).record(item.UserId, models.QuestStepAchievement, item.AchievementId)
+	})
+}
+
+// forTenant returns a copy of the service scoped to a tenant, for work done
+// outside of a request
+func (s *QuestService) forTenant(tenantId uint) *QuestService {
+	return s.WithContext(tenancy.WithTenant(context.Background(), tenantId))
+}
+
+// record counts one occurrence of an activity type or achievement on the
+// active quests with a step bound to it
+func (s *QuestService) record(userId uint, kind string, refId uint) {
+	column := "queststeps.activity_type_id"
+	if kind == models.QuestStepAchievement {
+		column = "queststeps.achievement_id"
+	}
+
+	var questIds []uint
+	if err := s.DB.Model(&models.QuestStep{}).
+		Joins("JOIN quests ON quests.id = queststeps.quest_id AND quests.deleted_at IS NULL").
+		Where("quests.is_active = ? AND queststeps.kind = ? AND "+column+" = ?", true, kind, refId).
+		Distinct("queststeps.quest_id").
+		Pluck("queststeps.quest_id", &questIds).Error; err != nil {
+		s.Logger.Error("failed to find quests for progress",
+			logger.String("error", err.Error()),
+			logger.String("kind", kind),
+			logger.Int("ref_id", int(refId)))
+		return
+	}
+
+	match := func(step *models.QuestStep) bool {
+		if step.Kind != kind {
+			return false
+		}
+		if kind == models.QuestStepAchievement {
+			return step.AchievementId != nil && *step.AchievementId == refId
+		}
+		return step.ActivityTypeId != nil && *step.ActivityTypeId == refId
+	}
+	for _, questId := range questIds {
+		if _, err := s.progress(questId, userId, match, false); err != nil {
+			s.Logger.Error("failed to advance quest",
+				logger.String("error", err.Error()),
+				logger.Int("quest_id", int(questId)),
+				logger.Int("user_id", int(userId)))
+		}
+	}
+}
+
+// Start puts a user on a quest, or returns their state when they already are
+func (s *QuestService) Start(questId uint, userId uint) (*models.UserQuest, error) {
+	quest := &models.Quest{}
+	if err := s.DB.First(quest, questId).Error; err != nil {
+		s.Logger.Error("failed to find quest to start",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(questId)))
+		return nil, fmt.Errorf("failed to find quest: %w", err)
+	}
+	if !quest.IsActive {
+		return nil, ErrQuestInactive
+	}
+
+	if _, err := s.progress(questId, userId, nil, true); err != nil {
+		s.Logger.Error("failed to start quest",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(questId)),
+			logger.Int("user_id", int(userId)))
+		return nil, fmt.Errorf("failed to start quest: %w", err)
+	}
+	return s.Progress(questId, userId)
+}
+
+// Progress returns the state of a user on a quest
+func (s *QuestService) Progress(questId uint, userId uint) (*models.UserQuest, error) {
+	item := &models.UserQuest{}
+	if err := userQuests(s.DB).Where("quest_id = ? AND user_id = ?", questId, userId).Take(item).Error; err != nil {
+		s.Logger.Error("failed to get userquest",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(questId)),
+			logger.Int("user_id", int(userId)))
+		return nil, fmt.Errorf("failed to get userquest: %w", err)
+	}
+	return item, nil
+}
+
+// UserQuests returns the quests a user started, latest first
+func (s *QuestService) UserQuests(userId uint, page *int, limit *int) (*types.PaginatedResponse, error) {
+	var items []*models.UserQuest
+	var total int64
+	query := s.DB.Model(&models.UserQuest{}).Where("user_id = ?", userId)
+	// Set default values if nil
+	defaultPage := 1
+	defaultLimit := 10
+	if page == nil {
+		page = &defaultPage
+	}
+	if limit == nil {
+		limit = &defaultLimit
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count userquests",
+			logger.String("error", err.Error()),
+			logger.Int("user_id", int(userId)))
+		return nil, fmt.Errorf("failed to count userquests: %w", err)
+	}
+
+	offset := (*page - 1) * *limit
+	if err := userQuests(query).Order("id DESC").Offset(offset).Limit(*limit).Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get userquests",
+			logger.String("error", err.Error()),
+			logger.Int("user_id", int(userId)))
+		return nil, fmt.Errorf("failed to get userquests: %w", err)
+	}
+
+	responses := make([]*models.UserQuestResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToResponse()
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(*limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return &types.PaginatedResponse{
+		Data: responses,
+		Pagination: types.Pagination{
+			Total:      int(total),
+			Page:       *page,
+			PageSize:   *limit,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// userQuests preloads what a user quest response shows
+func userQuests(db *gorm.DB) *gorm.DB {
+	return db.Preload("Steps").Preload("Quest").Preload("Quest.Steps", byPosition)
+}
+
+// byPosition orders quest steps
+func byPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position").Order("id")
+}
+
+// progress advances a user's quest by one occurrence of the steps match
+// selects, starting the quest when it is new to the user and either start is
+// set or the occurrence counts on a step that is open from the start
+func (s *QuestService) progress(questId uint, userId uint, match func(*models.QuestStep) bool, start bool) (*models.UserQuest, error) {
+	var item *models.UserQuest
+	o := &outcome{}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		quest := &models.Quest{}
+		if err := tx.Preload("Steps", byPosition).First(quest, questId).Error; err != nil {
+			return err
+		}
+		if !quest.IsActive {
+			return nil
+		}
+
+		var err error
+		item, err = s.lock(tx, quest, userId, start || opens(quest, match), o)
+		if err != nil || item == nil || item.Status == models.UserQuestCompleted {
+			return err
+		}
+		return s.advance(tx, quest, item, match, o)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.announce(item, o)
+	return item, nil
+}
+
+// opens reports whether an occurrence counts on a step that is open as soon
+// as the quest starts: the first one of an ordered quest, any other one
+func opens(quest *models.Quest, match func(*models.QuestStep) bool) bool {
+	if match == nil {
+		return false
+	}
+	for i, step := range quest.Steps {
+		if quest.Ordered && i > 0 {
+			return false
+		}
+		if match(step) {
+			return true
+		}
+	}
+	return false
+}
+
+// lock returns the user's state on a quest locked for update, creating it
+// when create is set. It returns nil when the user hasn't started the quest.
+func (s *QuestService) lock(tx *gorm.DB, quest *models.Quest, userId uint, create bool, o *outcome) (*models.UserQuest, error) {
+	find := func() (*models.UserQuest, error) {
+		item := &models.UserQuest{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Steps").
+			Where("user_id = ? AND quest_id = ?", userId, quest.Id).Take(item).Error
+		return item, err
+	}
+
+	item, err := find()
+	if err == nil {
+		return item, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if !create {
+		return nil, nil
+	}
+
+	item = &models.UserQuest{UserId: userId, QuestId: quest.Id, Status: models.UserQuestActive}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(item).Error; err != nil {
+		return nil, err
+	}
+	// Started at the same time by another event
+	if item.Id == 0 {
+		return find()
+	}
+	o.started = true
+	return item, nil
+}
+
+// advance counts one occurrence on the user's open steps that match, then
+// completes the steps that reached their count, opens the next ones and
+// completes the quest once every step is done
+func (s *QuestService) advance(tx *gorm.DB, quest *models.Quest, item *models.UserQuest, match func(*models.QuestStep) bool, o *outcome) error {
+	states := make(map[uint]*models.UserQuestStep, len(item.Steps))
+	for _, state := range item.Steps {
+		states[state.QuestStepId] = state
+	}
+	// Steps added to the quest after the user started it
+	for _, step := range quest.Steps {
+		if states[step.Id] != nil {
+			continue
+		}
+		state := &models.UserQuestStep{UserQuestId: item.Id, QuestStepId: step.Id, Status: models.UserQuestStepLocked}
+		if err := tx.Create(state).Error; err != nil {
+			return err
+		}
+		states[step.Id] = state
+		item.Steps = append(item.Steps, state)
+	}
+
+	if err := s.unlock(tx, quest, item, states, o); err != nil {
+		return err
+	}
+	if match != nil {
+		for _, step := range quest.Steps {
+			state := states[step.Id]
+			if state.Status != models.UserQuestStepActive || !match(step) {
+				continue
+			}
+			state.Progress++
+			if err := s.saveStep(tx, step, state, o); err != nil {
+				return err
+			}
+		}
+		if err := s.unlock(tx, quest, item, states, o); err != nil {
+			return err
+		}
+	}
+
+	if len(quest.Steps) == 0 {
+		return nil
+	}
+	for _, step := range quest.Steps {
+		if states[step.Id].Status != models.UserQuestStepCompleted {
+			return nil
+		}
+	}
+	return s.complete(tx, quest, item, o)
+}
+
+// unlock opens the locked steps the user may take: the step after the last
+// completed one on an ordered quest, every step otherwise. An achievement
+// step whose achievement the user already has completes at once, which may
+// open the next step in turn.
+func (s *QuestService) unlock(tx *gorm.DB, quest *models.Quest, item *models.UserQuest, states map[uint]*models.UserQuestStep, o *outcome) error {
+	for changed := true; changed; {
+		changed = false
+		for i, step := range quest.Steps {
+			state := states[step.Id]
+			if state.Status != models.UserQuestStepLocked {
+				continue
+			}
+			if quest.Ordered && i > 0 && states[quest.Steps[i-1].Id].Status != models.UserQuestStepCompleted {
+				continue
+			}
+
+			state.Status = models.UserQuestStepActive
+			changed = true
+			if step.Kind == models.QuestStepAchievement && step.AchievementId != nil {
+				var owned int64
+				if err := tx.Model(&models.UserAchievement{}).
+					Where("user_id = ? AND achievement_id = ?", item.UserId, *step.AchievementId).
+					Count(&owned).Error; err != nil {
+					return err
+				}
+				if owned > 0 {
+					state.Progress = 1
+				}
+			}
+			if err := s.saveStep(tx, step, state, o); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// saveStep saves the state of a step, completing it when it reached its count
+func (s *QuestService) saveStep(tx *gorm.DB, step *models.QuestStep, state *models.UserQuestStep, o *outcome) error {
+	if state.Status == models.UserQuestStepActive && state.Progress >= step.Required() {
+		now := time.Now()
+		state.Status, state.CompletedAt = models.UserQuestStepCompleted, &now
+		o.steps = append(o.steps, state)
+	}
+	return tx.Save(state).Error
+}
+
+// complete marks a user's quest completed and grants its reward
+func (s *QuestService) complete(tx *gorm.DB, quest *models.Quest, item *models.UserQuest, o *outcome) error {
+	now := time.Now()
+	if err := tx.Model(item).Updates(map[string]interface{}{
+		"status":       models.UserQuestCompleted,
+		"completed_at": now,
+	}).Error; err != nil {
+		return err
+	}
+	item.Status, item.CompletedAt = models.UserQuestCompleted, &now
+	o.completed = true
+
+	if quest.RewardPointTypeId != nil && quest.RewardAmount > 0 {
+		reference := fmt.Sprintf("userquest:%d", item.Id)
+		if _, err := s.Points.Credit(tx, item.UserId, *quest.RewardPointTypeId, quest.RewardAmount, models.PointTransactionEarn, reference); err != nil {
+			return err
+		}
+		xpPointTypeId, err := s.Points.XpPointTypeId(tx)
+		if err != nil {
+			return err
+		}
+		if *quest.RewardPointTypeId == xpPointTypeId {
+			level, changed, err := s.Levels.AddXp(tx, item.UserId, quest.RewardAmount)
+			if err != nil {
+				return err
+			}
+			if changed {
+				o.levels = append(o.levels, level)
+			}
+		}
+	}
+	if quest.RewardAchievementId != nil {
+		achievement, granted, err := s.Achievements.Grant(tx, item.UserId, *quest.RewardAchievementId)
+		if err != nil {
+			return err
+		}
+		if granted {
+			o.achievements = append(o.achievements, achievement)
+		}
+	}
+	return nil
+}
+
+// announce emits the events of a committed quest change
+func (s *QuestService) announce(item *models.UserQuest, o *outcome) {
+	if o.started {
+		s.Emitter.Emit(StartQuestEvent, item)
+	}
+	for _, state := range o.steps {
+		s.Emitter.Emit(CompleteQuestStepEvent, state)
+	}
+	if o.completed {
+		s.Emitter.Emit(CompleteQuestEvent, item)
+	}
+	for _, level := range o.levels {
+		s.Emitter.Emit(user_levels.LevelUpEvent, level)
+	}
+	// An achievement reward may complete a step of another quest
+	for _, achievement := range o.achievements {
+		s.Emitter.Emit(user_achievements.CreateUserAchievementEvent, achievement)
+	}
+}
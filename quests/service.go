@@ -0,0 +1,313 @@
+package quests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+	"base/core/types"
+	"base/packages/gamification/models"
+	"base/packages/gamification/user_achievements"
+	"base/packages/gamification/user_levels"
+	"base/packages/gamification/user_points"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CreateQuestEvent = "quests.create"
+	UpdateQuestEvent = "quests.update"
+	DeleteQuestEvent = "quests.delete"
+)
+
+// ErrInvalidReward is returned when a reward gives a point type without an
+// amount, or an amount without a point type
+var ErrInvalidReward = errors.New("reward amount and point type go together")
+
+type QuestService struct {
+	DB           *gorm.DB
+	Emitter      *emitter.Emitter
+	Storage      *storage.ActiveStorage
+	Logger       logger.Logger
+	Points       *user_points.UserPointService
+	Levels       *user_levels.UserLevelService
+	Achievements *user_achievements.UserAchievementService
+}
+
+func NewQuestService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *QuestService {
+	return &QuestService{
+		DB:           db,
+		Emitter:      emitter,
+		Storage:      storage,
+		Logger:       logger,
+		Points:       user_points.NewUserPointService(db, emitter, storage, logger),
+		Levels:       user_levels.NewUserLevelService(db, emitter, storage, logger),
+		Achievements: user_achievements.NewUserAchievementService(db, emitter, storage, logger),
+	}
+}
+
+func (s *QuestService) WithContext(ctx context.Context) *QuestService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	clone.Points = s.Points.WithContext(ctx)
+	clone.Levels = s.Levels.WithContext(ctx)
+	clone.Achievements = s.Achievements.WithContext(ctx)
+	return &clone
+}
+
+func (s *QuestService) Create(req *models.CreateQuestRequest) (*models.Quest, error) {
+	key, err := models.NewKey(s.DB, &models.Quest{}, req.Key, req.Name)
+	if err != nil {
+		s.Logger.Error("failed to assign quest key", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	item := &models.Quest{
+		Key:         key,
+		Name:        req.Name,
+		Description: req.Description,
+		Ordered:     req.Ordered,
+		IsActive:    req.IsActive,
+	}
+	if err := s.reward(item, req.RewardPointTypeId, req.RewardPointTypeKey, req.RewardAmount, req.RewardAchievementId, req.RewardAchievementKey); err != nil {
+		s.Logger.Error("failed to build quest reward", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	if err := s.DB.Create(item).Error; err != nil {
+		s.Logger.Error("failed to create quest", logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to create quest: %w", err)
+	}
+
+	// Emit create event
+	s.Emitter.Emit(CreateQuestEvent, item)
+
+	return s.GetById(item.Id)
+}
+
+func (s *QuestService) Update(id uint, req *models.UpdateQuestRequest) (*models.Quest, error) {
+	item := &models.Quest{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to find quest for update",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to find quest: %w", err)
+	}
+
+	// Build updates map
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != "" {
+		updates["description"] = req.Description
+	}
+	if req.Ordered != nil {
+		updates["ordered"] = *req.Ordered
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	if req.RewardPointTypeId != 0 || req.RewardPointTypeKey != "" || req.RewardAmount != 0 ||
+		req.RewardAchievementId != 0 || req.RewardAchievementKey != "" {
+		reward := *item
+		if req.RewardAmount == 0 {
+			req.RewardAmount = item.RewardAmount
+		}
+		if req.RewardPointTypeId == 0 && req.RewardPointTypeKey == "" && item.RewardPointTypeId != nil {
+			req.RewardPointTypeId = *item.RewardPointTypeId
+		}
+		if req.RewardAchievementId == 0 && req.RewardAchievementKey == "" && item.RewardAchievementId != nil {
+			req.RewardAchievementId = *item.RewardAchievementId
+		}
+		if err := s.reward(&reward, req.RewardPointTypeId, req.RewardPointTypeKey, req.RewardAmount, req.RewardAchievementId, req.RewardAchievementKey); err != nil {
+			s.Logger.Error("failed to build quest reward",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(id)))
+			return nil, err
+		}
+		updates["reward_point_type_id"] = reward.RewardPointTypeId
+		updates["reward_amount"] = reward.RewardAmount
+		updates["reward_achievement_id"] = reward.RewardAchievementId
+	}
+
+	if err := s.DB.Model(item).Updates(updates).Error; err != nil {
+		s.Logger.Error("failed to update quest",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to update quest: %w", err)
+	}
+
+	result, err := s.GetById(item.Id)
+	if err != nil {
+		s.Logger.Error("failed to get updated quest",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to get updated quest: %w", err)
+	}
+
+	// Emit update event
+	s.Emitter.Emit(UpdateQuestEvent, result)
+
+	return result, nil
+}
+
+// reward sets the completion reward of a quest, resolving point type and
+// achievement keys and checking that they exist
+func (s *QuestService) reward(item *models.Quest, pointTypeId uint, pointTypeKey string, amount int, achievementId uint, achievementKey string) error {
+	if pointTypeKey != "" {
+		id, err := models.ResolveFieldKey(s.DB, &models.PointType{}, "reward_point_type_key", pointTypeKey)
+		if err != nil {
+			return fmt.Errorf("failed to resolve point type: %w", err)
+		}
+		pointTypeId = id
+	}
+	if (pointTypeId != 0) != (amount > 0) {
+		return ErrInvalidReward
+	}
+	if achievementKey != "" {
+		id, err := models.ResolveFieldKey(s.DB, &models.Achievement{}, "reward_achievement_key", achievementKey)
+		if err != nil {
+			return fmt.Errorf("failed to resolve achievement: %w", err)
+		}
+		achievementId = id
+	}
+	if err := models.CheckReferences(s.DB).
+		Exists("reward_point_type_id", &models.PointType{}, pointTypeId).
+		Exists("reward_achievement_id", &models.Achievement{}, achievementId).
+		Err(); err != nil {
+		return err
+	}
+
+	item.RewardPointTypeId, item.RewardAmount, item.RewardAchievementId = nil, amount, nil
+	if pointTypeId != 0 {
+		item.RewardPointTypeId = &pointTypeId
+	}
+	if achievementId != 0 {
+		item.RewardAchievementId = &achievementId
+	}
+	return nil
+}
+
+func (s *QuestService) Delete(id uint) error {
+	item := &models.Quest{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to find quest for deletion",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return fmt.Errorf("failed to find quest: %w", err)
+	}
+
+	// Delete file attachments if any
+
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
+		s.Logger.Error("failed to delete quest",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return fmt.Errorf("failed to delete quest: %w", err)
+	}
+
+	// Emit delete event
+	s.Emitter.Emit(DeleteQuestEvent, item)
+
+	return nil
+}
+
+func (s *QuestService) GetById(id uint) (*models.Quest, error) {
+	item := &models.Quest{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to get quest",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to get quest: %w", err)
+	}
+
+	return item, nil
+}
+
+// GetByKey returns the quest identified by its key
+func (s *QuestService) GetByKey(key string) (*models.Quest, error) {
+	item := &models.Quest{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.Where(&models.Quest{Key: key}).First(item).Error; err != nil {
+		s.Logger.Error("failed to get quest by key",
+			logger.String("error", err.Error()),
+			logger.String("key", key))
+		return nil, fmt.Errorf("failed to get quest: %w", err)
+	}
+
+	return item, nil
+}
+
+func (s *QuestService) GetAll(page *int, limit *int) (*types.PaginatedResponse, error) {
+	var items []*models.Quest
+	var total int64
+	query := s.DB.Model(&models.Quest{})
+	// Set default values if nil
+	defaultPage := 1
+	defaultLimit := 10
+	if page == nil {
+		page = &defaultPage
+	}
+	if limit == nil {
+		limit = &defaultLimit
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count quests",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to count quests: %w", err)
+	}
+
+	// Apply pagination if provided
+	if page != nil && limit != nil {
+		offset := (*page - 1) * *limit
+		query = query.Offset(offset).Limit(*limit)
+	}
+
+	// Preload relationships
+	query = (&models.Quest{}).Preload(query)
+
+	// Execute query
+	if err := query.Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get quests",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to get quests: %w", err)
+	}
+
+	// Convert to response type
+	responses := make([]*models.QuestListResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToListResponse()
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(total) / float64(*limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return &types.PaginatedResponse{
+		Data: responses,
+		Pagination: types.Pagination{
+			Total:      int(total),
+			Page:       *page,
+			PageSize:   *limit,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
@@ -0,0 +1,91 @@
+package gamification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+
+	"base/core/logger"
+	"base/packages/gamification/catalog"
+	"base/packages/gamification/models"
+	"base/packages/gamification/tenancy"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultSeedFile is loaded at startup when it exists in the working directory
+	DefaultSeedFile = "gamification.yaml"
+
+	// SeedFileEnv overrides the seed file location. A file named here must exist.
+	SeedFileEnv = "GAMIFICATION_SEED_FILE"
+
+	// SeedTenantEnv names the tenant whose catalog the seed file fills
+	SeedTenantEnv = "GAMIFICATION_SEED_TENANT"
+)
+
+// Seed upserts the catalog definitions of a YAML seed file by key into the
+// catalog of a tenant. Seeding is idempotent: unchanged definitions are not
+// written, and only catalog tables are touched, so user points, levels,
+// achievements and activities survive.
+func (app *Gamification) Seed(path string, tenantId uint) (*models.CatalogImportResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed file: %w", err)
+	}
+
+	bundle := &models.CatalogBundle{}
+	if err := yaml.Unmarshal(data, bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse seed file %s: %w", path, err)
+	}
+
+	service := catalog.NewCatalogService(app.DB, app.Emitter, app.Storage, app.Log)
+	return service.WithContext(tenancy.WithTenant(context.Background(), tenantId)).Import(bundle, false)
+}
+
+// seedCatalog runs Seed on the configured seed file, if there is one
+func (app *Gamification) seedCatalog() error {
+	path, required := os.LookupEnv(SeedFileEnv)
+	if !required {
+		path = DefaultSeedFile
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) && !required {
+		return nil
+	}
+
+	tenant := os.Getenv(SeedTenantEnv)
+	if tenant == "" {
+		if required {
+			return fmt.Errorf("%s is set but %s is not", SeedFileEnv, SeedTenantEnv)
+		}
+		app.Log.Info("Skipped catalog seed without a tenant",
+			logger.String("file", path),
+			logger.String("env", SeedTenantEnv))
+		return nil
+	}
+	tenantId, err := strconv.ParseUint(tenant, 10, 32)
+	if err != nil || tenantId == 0 {
+		return fmt.Errorf("invalid %s: %s", SeedTenantEnv, tenant)
+	}
+
+	result, err := app.Seed(path, uint(tenantId))
+	if err != nil {
+		app.Log.Error("Failed to seed catalog",
+			logger.String("file", path),
+			logger.String("error", err.Error()))
+		return err
+	}
+
+	app.Log.Info("Seeded catalog",
+		logger.String("file", path),
+		logger.Int("tenant_id", int(tenantId)),
+		logger.Int("created", result.Created),
+		logger.Int("updated", result.Updated),
+		logger.Int("deleted", result.Deleted),
+		logger.Int("unchanged", result.Unchanged))
+	return nil
+}
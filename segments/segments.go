@@ -0,0 +1,95 @@
+// Package segments splits leaderboard rankings by a dimension such as
+// country, cohort or friends. The host app supplies the data the package
+// doesn't own through a Resolver.
+package segments
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// DimensionFriends ranks a user among their friends, whose ids the resolver
+// returns. Every other dimension is an attribute with one value per subject.
+const DimensionFriends = "friends"
+
+// MaxFriends bounds how many friends are ranked together
+const MaxFriends = 1000
+
+// ErrInvalidSegment is returned when a segment is malformed or doesn't match
+// the dimension of the leaderboard
+var ErrInvalidSegment = errors.New("segment must be <dimension>:<value> for the leaderboard's segment dimension")
+
+// Resolver supplies segment data from the host app
+type Resolver interface {
+	// Value returns the value of dimension for a user or team, "" when it has none
+	Value(ctx context.Context, dimension string, subjectId uint) (string, error)
+	// Friends returns the ids of the friends of a user
+	Friends(ctx context.Context, userId uint) ([]uint, error)
+}
+
+var (
+	mu       sync.RWMutex
+	resolver Resolver
+)
+
+// SetResolver installs the host app's resolver
+func SetResolver(r Resolver) {
+	mu.Lock()
+	defer mu.Unlock()
+	resolver = r
+}
+
+// Value asks the resolver for the value of dimension, "" without a resolver
+func Value(ctx context.Context, dimension string, subjectId uint) (string, error) {
+	mu.RLock()
+	r := resolver
+	mu.RUnlock()
+	if r == nil {
+		return "", nil
+	}
+	return r.Value(ctx, dimension, subjectId)
+}
+
+// Friends asks the resolver for the friends of a user, none without a resolver
+func Friends(ctx context.Context, userId uint) ([]uint, error) {
+	mu.RLock()
+	r := resolver
+	mu.RUnlock()
+	if r == nil {
+		return nil, nil
+	}
+	return r.Friends(ctx, userId)
+}
+
+// Segment is one value of a segment dimension, written dimension:value
+type Segment struct {
+	Dimension string
+	Value     string
+}
+
+// Parse reads a dimension:value segment. The value of a friends segment may
+// be left out to mean the friends of the user being ranked.
+func Parse(raw string) (Segment, error) {
+	if raw == "" {
+		return Segment{}, nil
+	}
+	dimension, value, _ := strings.Cut(raw, ":")
+	if dimension == "" || (value == "" && dimension != DimensionFriends) {
+		return Segment{}, ErrInvalidSegment
+	}
+	return Segment{Dimension: dimension, Value: value}, nil
+}
+
+// IsZero reports whether the segment is the whole leaderboard
+func (s Segment) IsZero() bool {
+	return s.Dimension == ""
+}
+
+func (s Segment) String() string {
+	if s.IsZero() {
+		return ""
+	}
+	return s.Dimension + ":" + s.Value
+}
@@ -0,0 +1,487 @@
+package teams
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"base/core/storage"
+	"base/packages/gamification/access"
+	"base/packages/gamification/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TeamController struct {
+	Service *TeamService
+	Storage *storage.ActiveStorage
+}
+
+func NewTeamController(service *TeamService, storage *storage.ActiveStorage) *TeamController {
+	return &TeamController{
+		Service: service,
+		Storage: storage,
+	}
+}
+
+func (c *TeamController) Routes(router *gin.RouterGroup) {
+	// Main CRUD endpoints
+	router.GET("/teams", c.List)        // Paginated list
+	router.GET("/teams/all", c.ListAll) // Unpaginated list
+	router.GET("/teams/:id", c.Get)
+	router.POST("/teams", access.Require(access.RoleAdmin, access.RoleService), c.Create)
+	router.PUT("/teams/:id", access.Require(access.RoleAdmin, access.RoleService), c.Update)
+	router.DELETE("/teams/:id", access.Require(access.RoleAdmin, access.RoleService), c.Delete)
+
+	// Membership endpoints
+	router.GET("/teams/:id/members", c.ListMembers)
+	router.POST("/teams/:id/members", access.Require(access.RoleAdmin, access.RoleService), c.AddMember)
+	router.PUT("/teams/:id/members/:user_id", access.Require(access.RoleAdmin, access.RoleService), c.UpdateMember)
+	router.DELETE("/teams/:id/members/:user_id", access.Require(access.RoleAdmin, access.RoleService), c.RemoveMember)
+
+	// Aggregates over the members
+	router.GET("/teams/:id/points", c.Points)
+	router.GET("/teams/:id/challenges/:challenge_id", c.ChallengeProgress)
+}
+
+// CreateTeam godoc
+// @Summary Create a new Team
+// @Description Create a new Team with the input payload. The optional owner joins it with the owner role.
+// @Tags Team
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param teams body models.CreateTeamRequest true "Create Team request"
+// @Success 201 {object} models.TeamResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /teams [post]
+func (c *TeamController) Create(ctx *gin.Context) {
+	var req models.CreateTeamRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, item.ToResponse())
+}
+
+// GetTeam godoc
+// @Summary Get a Team
+// @Description Get a Team and its members by its id
+// @Tags Team
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Team id"
+// @Success 200 {object} models.TeamResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /teams/{id} [get]
+func (c *TeamController) Get(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).GetById(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// ListTeams godoc
+// @Summary List teams
+// @Description Get a list of teams
+// @Tags Team
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /teams [get]
+func (c *TeamController) List(ctx *gin.Context) {
+	var page, limit *int
+
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid page number"})
+			return
+		}
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit number"})
+			return
+		}
+	}
+
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// ListAllTeams godoc
+// @Summary List all teams without pagination
+// @Description Get a list of all teams without pagination
+// @Tags Team
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /teams/all [get]
+func (c *TeamController) ListAll(ctx *gin.Context) {
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(nil, nil)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// UpdateTeam godoc
+// @Summary Update a Team
+// @Description Update a Team by its id
+// @Tags Team
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Team id"
+// @Param teams body models.UpdateTeamRequest true "Update Team request"
+// @Success 200 {object} models.TeamResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /teams/{id} [put]
+func (c *TeamController) Update(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	var req models.UpdateTeamRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// DeleteTeam godoc
+// @Summary Delete a Team
+// @Description Delete a Team and its memberships by its id
+// @Tags Team
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Team id"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
+// @Failure 500 {object} ErrorResponse
+// @Router /teams/{id} [delete]
+func (c *TeamController) Delete(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, SuccessResponse{Message: "Item deleted successfully"})
+}
+
+// ListTeamMembers godoc
+// @Summary List team members
+// @Description Get the members of a Team with their roles
+// @Tags Team
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Team id"
+// @Success 200 {array} models.TeamMember
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /teams/{id}/members [get]
+func (c *TeamController) ListMembers(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	members, err := c.Service.WithContext(ctx.Request.Context()).GetMembers(uint(id))
+	if err != nil {
+		c.memberError(ctx, err, "Failed to fetch members: ")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, members)
+}
+
+// AddTeamMember godoc
+// @Summary Add a team member
+// @Description Add a user to a Team, with the member role unless another role is given
+// @Tags Team
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Team id"
+// @Param member body models.AddTeamMemberRequest true "Add member request"
+// @Success 201 {object} models.TeamMember
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /teams/{id}/members [post]
+func (c *TeamController) AddMember(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	var req models.AddTeamMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	member, err := c.Service.WithContext(ctx.Request.Context()).AddMember(uint(id), &req)
+	if err != nil {
+		c.memberError(ctx, err, "Failed to add member: ")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, member)
+}
+
+// UpdateTeamMember godoc
+// @Summary Change a team member's role
+// @Description Change the role of a user in a Team
+// @Tags Team
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Team id"
+// @Param user_id path int true "User id"
+// @Param member body models.UpdateTeamMemberRequest true "Update member request"
+// @Success 200 {object} models.TeamMember
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /teams/{id}/members/{user_id} [put]
+func (c *TeamController) UpdateMember(ctx *gin.Context) {
+	id, userId, ok := memberParams(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateTeamMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	member, err := c.Service.WithContext(ctx.Request.Context()).UpdateMember(id, userId, &req)
+	if err != nil {
+		c.memberError(ctx, err, "Failed to update member: ")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, member)
+}
+
+// RemoveTeamMember godoc
+// @Summary Remove a team member
+// @Description Remove a user from a Team
+// @Tags Team
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Team id"
+// @Param user_id path int true "User id"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /teams/{id}/members/{user_id} [delete]
+func (c *TeamController) RemoveMember(ctx *gin.Context) {
+	id, userId, ok := memberParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.Service.WithContext(ctx.Request.Context()).RemoveMember(id, userId); err != nil {
+		c.memberError(ctx, err, "Failed to remove member: ")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, SuccessResponse{Message: "Member removed successfully"})
+}
+
+// TeamPoints godoc
+// @Summary Get team points
+// @Description Get the balances of all team members summed per point type
+// @Tags Team
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Team id"
+// @Success 200 {object} models.TeamPointsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /teams/{id}/points [get]
+func (c *TeamController) Points(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	result, err := c.Service.WithContext(ctx.Request.Context()).Points(uint(id))
+	if err != nil {
+		c.memberError(ctx, err, "Failed to fetch team points: ")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// TeamChallengeProgress godoc
+// @Summary Get team challenge progress
+// @Description Get the collective progress of a Team on a team challenge, summed over its members
+// @Tags Team
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Team id"
+// @Param challenge_id path int true "Challenge id"
+// @Success 200 {object} models.TeamChallengeProgressResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /teams/{id}/challenges/{challenge_id} [get]
+func (c *TeamController) ChallengeProgress(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+	challengeId, err := strconv.ParseUint(ctx.Param("challenge_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid challenge_id format"})
+		return
+	}
+
+	result, err := c.Service.WithContext(ctx.Request.Context()).ChallengeProgress(uint(id), uint(challengeId))
+	if err != nil {
+		c.memberError(ctx, err, "Failed to fetch team progress: ")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (c *TeamController) memberError(ctx *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, ErrMemberNotFound):
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrAlreadyMember):
+		ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrNotTeamChallenge):
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: message + err.Error()})
+	}
+}
+
+func memberParams(ctx *gin.Context) (uint, uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return 0, 0, false
+	}
+	userId, err := strconv.ParseUint(ctx.Param("user_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user_id format"})
+		return 0, 0, false
+	}
+	return uint(id), uint(userId), true
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,52 @@
+package teams
+
+import (
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/module"
+	"base/core/storage"
+	"base/packages/gamification/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Module struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Controller *TeamController
+	Service    *TeamService
+	Logger     *logger.Logger
+	Storage    *storage.ActiveStorage
+}
+
+func NewTeamModule(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, storage *storage.ActiveStorage) module.Module {
+
+	service := NewTeamService(db, emitter, storage, log)
+	controller := NewTeamController(service, storage)
+
+	m := &Module{
+		DB:         db,
+		Service:    service,
+		Controller: controller,
+		Logger:     &log,
+		Storage:    storage,
+	}
+
+	return m
+}
+
+func (m *Module) Routes(router *gin.RouterGroup) {
+	m.Controller.Routes(router)
+}
+
+func (m *Module) Migrate() error {
+	if err := m.DB.AutoMigrate(&models.Team{}, &models.TeamMember{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.Team{}, &models.TeamMember{})
+}
+
+func (m *Module) GetModels() []interface{} {
+	return []interface{}{&models.Team{}, &models.TeamMember{}}
+}
@@ -0,0 +1,424 @@
+package teams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+	"base/core/types"
+	"base/packages/gamification/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CreateTeamEvent = "teams.create"
+	UpdateTeamEvent = "teams.update"
+	DeleteTeamEvent = "teams.delete"
+
+	AddTeamMemberEvent    = "teammembers.create"
+	UpdateTeamMemberEvent = "teammembers.update"
+	RemoveTeamMemberEvent = "teammembers.delete"
+)
+
+var (
+	// ErrAlreadyMember is returned when adding a user that already belongs to the team
+	ErrAlreadyMember = errors.New("user is already a member of the team")
+
+	// ErrMemberNotFound is returned when the user does not belong to the team
+	ErrMemberNotFound = errors.New("user is not a member of the team")
+
+	// ErrNotTeamChallenge is returned when asking for team progress on an individual challenge
+	ErrNotTeamChallenge = errors.New("challenge is not a team challenge")
+)
+
+type TeamService struct {
+	DB      *gorm.DB
+	Emitter *emitter.Emitter
+	Storage *storage.ActiveStorage
+	Logger  logger.Logger
+}
+
+func NewTeamService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *TeamService {
+	return &TeamService{
+		DB:      db,
+		Emitter: emitter,
+		Storage: storage,
+		Logger:  logger,
+	}
+}
+
+func (s *TeamService) WithContext(ctx context.Context) *TeamService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
+func (s *TeamService) Create(req *models.CreateTeamRequest) (*models.Team, error) {
+	item := &models.Team{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		if req.OwnerId == 0 {
+			return nil
+		}
+		return tx.Create(&models.TeamMember{
+			TeamId: item.Id,
+			UserId: req.OwnerId,
+			Role:   models.TeamRoleOwner,
+		}).Error
+	})
+	if err != nil {
+		s.Logger.Error("failed to create team", logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to create team: %w", err)
+	}
+
+	// Emit create event
+	s.Emitter.Emit(CreateTeamEvent, item)
+
+	return s.GetById(item.Id)
+}
+
+func (s *TeamService) Update(id uint, req *models.UpdateTeamRequest) (*models.Team, error) {
+	item := &models.Team{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to find team for update",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to find team: %w", err)
+	}
+
+	// Build updates map
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != "" {
+		updates["description"] = req.Description
+	}
+
+	if err := s.DB.Model(item).Updates(updates).Error; err != nil {
+		s.Logger.Error("failed to update team",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to update team: %w", err)
+	}
+
+	result, err := s.GetById(item.Id)
+	if err != nil {
+		s.Logger.Error("failed to get updated team",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to get updated team: %w", err)
+	}
+
+	// Emit update event
+	s.Emitter.Emit(UpdateTeamEvent, result)
+
+	return result, nil
+}
+
+func (s *TeamService) Delete(id uint) error {
+	item := &models.Team{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to find team for deletion",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return fmt.Errorf("failed to find team: %w", err)
+	}
+
+	// Members go with the team, as its relation policy cascades
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
+		s.Logger.Error("failed to delete team",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return fmt.Errorf("failed to delete team: %w", err)
+	}
+
+	// Emit delete event
+	s.Emitter.Emit(DeleteTeamEvent, item)
+
+	return nil
+}
+
+func (s *TeamService) GetById(id uint) (*models.Team, error) {
+	item := &models.Team{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to get team",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+
+	return item, nil
+}
+
+func (s *TeamService) GetAll(page *int, limit *int) (*types.PaginatedResponse, error) {
+	var items []*models.Team
+	var total int64
+	query := s.DB.Model(&models.Team{})
+	// Set default values if nil
+	defaultPage := 1
+	defaultLimit := 10
+	if page == nil {
+		page = &defaultPage
+	}
+	if limit == nil {
+		limit = &defaultLimit
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count teams",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to count teams: %w", err)
+	}
+
+	// Apply pagination if provided
+	if page != nil && limit != nil {
+		offset := (*page - 1) * *limit
+		query = query.Offset(offset).Limit(*limit)
+	}
+
+	// Execute query
+	if err := query.Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get teams",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to get teams: %w", err)
+	}
+
+	// Convert to response type
+	responses := make([]*models.TeamListResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToListResponse()
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(total) / float64(*limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return &types.PaginatedResponse{
+		Data: responses,
+		Pagination: types.Pagination{
+			Total:      int(total),
+			Page:       *page,
+			PageSize:   *limit,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// GetMembers returns the members of a team
+func (s *TeamService) GetMembers(teamId uint) ([]*models.TeamMember, error) {
+	if err := s.DB.First(&models.Team{}, teamId).Error; err != nil {
+		return nil, fmt.Errorf("failed to find team: %w", err)
+	}
+
+	members := []*models.TeamMember{}
+	if err := s.DB.Preload("User").Where("team_id = ?", teamId).Order("id").Find(&members).Error; err != nil {
+		s.Logger.Error("failed to get team members",
+			logger.String("error", err.Error()),
+			logger.Int("team_id", int(teamId)))
+		return nil, fmt.Errorf("failed to get team members: %w", err)
+	}
+
+	return members, nil
+}
+
+// AddMember adds a user to a team, as a plain member unless a role is given
+func (s *TeamService) AddMember(teamId uint, req *models.AddTeamMemberRequest) (*models.TeamMember, error) {
+	if err := s.DB.First(&models.Team{}, teamId).Error; err != nil {
+		return nil, fmt.Errorf("failed to find team: %w", err)
+	}
+
+	var existing int64
+	if err := s.DB.Model(&models.TeamMember{}).
+		Where("team_id = ? AND user_id = ?", teamId, req.UserId).
+		Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check team membership: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrAlreadyMember
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.TeamRoleMember
+	}
+	member := &models.TeamMember{
+		TeamId: teamId,
+		UserId: req.UserId,
+		Role:   role,
+	}
+	if err := s.DB.Create(member).Error; err != nil {
+		s.Logger.Error("failed to add team member",
+			logger.String("error", err.Error()),
+			logger.Int("team_id", int(teamId)))
+		return nil, fmt.Errorf("failed to add team member: %w", err)
+	}
+
+	// Emit member event
+	s.Emitter.Emit(AddTeamMemberEvent, member)
+
+	return member, nil
+}
+
+// UpdateMember changes the role of a team member
+func (s *TeamService) UpdateMember(teamId uint, userId uint, req *models.UpdateTeamMemberRequest) (*models.TeamMember, error) {
+	member, err := s.findMember(teamId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.Model(member).Update("role", req.Role).Error; err != nil {
+		s.Logger.Error("failed to update team member",
+			logger.String("error", err.Error()),
+			logger.Int("team_id", int(teamId)))
+		return nil, fmt.Errorf("failed to update team member: %w", err)
+	}
+
+	// Emit member event
+	s.Emitter.Emit(UpdateTeamMemberEvent, member)
+
+	return member, nil
+}
+
+// RemoveMember removes a user from a team
+func (s *TeamService) RemoveMember(teamId uint, userId uint) error {
+	member, err := s.findMember(teamId, userId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.DB.Delete(member).Error; err != nil {
+		s.Logger.Error("failed to remove team member",
+			logger.String("error", err.Error()),
+			logger.Int("team_id", int(teamId)))
+		return fmt.Errorf("failed to remove team member: %w", err)
+	}
+
+	// Emit member event
+	s.Emitter.Emit(RemoveTeamMemberEvent, member)
+
+	return nil
+}
+
+// Points sums the balances the members of a team hold in its tenant, per
+// point type
+func (s *TeamService) Points(teamId uint) (*models.TeamPointsResponse, error) {
+	if err := s.DB.First(&models.Team{}, teamId).Error; err != nil {
+		return nil, fmt.Errorf("failed to find team: %w", err)
+	}
+
+	result := &models.TeamPointsResponse{
+		TeamId: teamId,
+		Totals: []*models.TeamPointTotal{},
+	}
+
+	var members int64
+	if err := s.DB.Model(&models.TeamMember{}).Where("team_id = ?", teamId).Count(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to count team members: %w", err)
+	}
+	result.Members = int(members)
+
+	if err := s.DB.Model(&models.UserPoint{}).
+		Select("point_type_id, SUM(current_balance) AS current_balance, SUM(lifetime_earned) AS lifetime_earned").
+		Where("user_id IN (?)", s.memberIds(teamId)).
+		Group("point_type_id").
+		Order("point_type_id").
+		Scan(&result.Totals).Error; err != nil {
+		s.Logger.Error("failed to sum team points",
+			logger.String("error", err.Error()),
+			logger.Int("team_id", int(teamId)))
+		return nil, fmt.Errorf("failed to sum team points: %w", err)
+	}
+
+	for _, total := range result.Totals {
+		pointType := &models.PointType{}
+		if err := s.DB.First(pointType, total.PointTypeId).Error; err == nil {
+			total.PointType = pointType
+		}
+	}
+
+	return result, nil
+}
+
+// ChallengeProgress returns the collective progress of a team on a team
+// challenge. With a goal, the members' values are added up and measured
+// against its target; without one, their progress is added up.
+func (s *TeamService) ChallengeProgress(teamId uint, challengeId uint) (*models.TeamChallengeProgressResponse, error) {
+	if err := s.DB.First(&models.Team{}, teamId).Error; err != nil {
+		return nil, fmt.Errorf("failed to find team: %w", err)
+	}
+	challenge := &models.Challenge{}
+	if err := s.DB.First(challenge, challengeId).Error; err != nil {
+		return nil, fmt.Errorf("failed to find challenge: %w", err)
+	}
+	if !challenge.IsTeam {
+		return nil, ErrNotTeamChallenge
+	}
+
+	var items []*models.UserChallenge
+	if err := s.DB.Where("challenge_id = ? AND user_id IN (?)", challengeId, s.memberIds(teamId)).
+		Order("user_id").
+		Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get team challenge progress",
+			logger.String("error", err.Error()),
+			logger.Int("team_id", int(teamId)))
+		return nil, fmt.Errorf("failed to get team challenge progress: %w", err)
+	}
+
+	result := &models.TeamChallengeProgressResponse{
+		TeamId:      teamId,
+		ChallengeId: challengeId,
+		Members:     make([]*models.UserChallengeListResponse, len(items)),
+	}
+	for i, item := range items {
+		result.Value += item.Value
+		result.Progress += item.Progress
+		result.Members[i] = item.ToListResponse()
+	}
+	switch {
+	case challenge.Goal.IsSet():
+		result.Progress = challenge.Goal.Percent(result.Value)
+	case len(items) > 0:
+		result.Progress /= len(items)
+	}
+
+	return result, nil
+}
+
+func (s *TeamService) findMember(teamId uint, userId uint) (*models.TeamMember, error) {
+	member := &models.TeamMember{}
+	err := s.DB.Where("team_id = ? AND user_id = ?", teamId, userId).First(member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMemberNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find team member: %w", err)
+	}
+	return member, nil
+}
+
+// memberIds is a subquery selecting the user ids of a team's members
+func (s *TeamService) memberIds(teamId uint) *gorm.DB {
+	return s.DB.Model(&models.TeamMember{}).Select("user_id").Where("team_id = ?", teamId)
+}
@@ -0,0 +1,81 @@
+package teams_test
+
+import (
+	"testing"
+
+	"base/core/emitter"
+	"base/packages/gamification/internal/testdb"
+	"base/packages/gamification/models"
+	"base/packages/gamification/teams"
+)
+
+func TestChallengeProgressAveragesMembers(t *testing.T) {
+	db := testdb.ForTenant(testdb.Open(t, &models.Team{}, &models.TeamMember{}, &models.Challenge{}, &models.UserChallenge{}), 1)
+	team := &models.Team{Name: "Runners"}
+	if err := db.Create(team).Error; err != nil {
+		t.Fatalf("failed to create team: %v", err)
+	}
+	withoutGoal := &models.Challenge{Key: "open", Name: "Open", IsTeam: true}
+	withGoal := &models.Challenge{Key: "goal", Name: "Goal", IsTeam: true,
+		Goal: models.ChallengeGoal{Kind: models.ChallengeGoalActivityCount, Target: 10}}
+	for _, challenge := range []*models.Challenge{withoutGoal, withGoal} {
+		if err := db.Create(challenge).Error; err != nil {
+			t.Fatalf("failed to create challenge: %v", err)
+		}
+	}
+	for userId, progress := range map[uint]int{7: 80, 8: 60} {
+		if err := db.Create(&models.TeamMember{TeamId: team.Id, UserId: userId, Role: models.TeamRoleMember}).Error; err != nil {
+			t.Fatalf("failed to add member: %v", err)
+		}
+		for _, challenge := range []*models.Challenge{withoutGoal, withGoal} {
+			item := &models.UserChallenge{UserId: userId, ChallengeId: challenge.Id, Progress: progress, Value: 4}
+			if err := db.Create(item).Error; err != nil {
+				t.Fatalf("failed to create user challenge: %v", err)
+			}
+		}
+	}
+	service := teams.NewTeamService(db, &emitter.Emitter{}, nil, testdb.Logger{})
+
+	result, err := service.ChallengeProgress(team.Id, withoutGoal.Id)
+	if err != nil {
+		t.Fatalf("failed to get progress: %v", err)
+	}
+	if result.Progress != 70 {
+		t.Errorf("progress without a goal = %d, want the average 70", result.Progress)
+	}
+
+	result, err = service.ChallengeProgress(team.Id, withGoal.Id)
+	if err != nil {
+		t.Fatalf("failed to get progress: %v", err)
+	}
+	if result.Value != 8 || result.Progress != 80 {
+		t.Errorf("goal progress = %d of value %d, want 80 of 8", result.Progress, result.Value)
+	}
+}
+
+func TestPointsHidesOtherTenants(t *testing.T) {
+	base := testdb.Open(t, &models.Team{}, &models.TeamMember{}, &models.PointType{}, &models.UserPoint{})
+	db := testdb.ForTenant(base, 1)
+	team := &models.Team{Name: "Runners"}
+	if err := db.Create(team).Error; err != nil {
+		t.Fatalf("failed to create team: %v", err)
+	}
+	if err := db.Create(&models.TeamMember{TeamId: team.Id, UserId: 7, Role: models.TeamRoleMember}).Error; err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+	// The member also holds points in another tenant
+	for tenantId, balance := range map[uint]int{1: 5, 2: 7} {
+		item := &models.UserPoint{UserId: 7, PointTypeId: 1, CurrentBalance: balance, LifetimeEarned: balance}
+		if err := testdb.ForTenant(base, tenantId).Create(item).Error; err != nil {
+			t.Fatalf("failed to create user point: %v", err)
+		}
+	}
+
+	result, err := teams.NewTeamService(db, &emitter.Emitter{}, nil, testdb.Logger{}).Points(team.Id)
+	if err != nil {
+		t.Fatalf("failed to sum points: %v", err)
+	}
+	if len(result.Totals) != 1 || result.Totals[0].CurrentBalance != 5 {
+		t.Errorf("totals = %+v, want a balance of 5 from tenant 1 only", result.Totals)
+	}
+}
@@ -0,0 +1,66 @@
+package tenancy
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FieldName is the model field holding the tenant id
+const FieldName = "TenantId"
+
+// Plugin scopes the queries of models with a TenantId field to the tenant of
+// the statement context. Creates are stamped with the tenant, and queries,
+// scans, updates and deletes get a tenant_id condition. Statements whose context
+// carries no tenant, such as migrations and background jobs, are left alone.
+type Plugin struct{}
+
+// Name returns the plugin name
+func (Plugin) Name() string {
+	return "gamification:tenancy"
+}
+
+// Initialize registers the tenant callbacks
+func (Plugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("tenancy:create", stampTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("tenancy:query", scopeTenant); err != nil {
+		return err
+	}
+	// Scan, Row and Rows run through the row callbacks rather than the query ones
+	if err := callbacks.Row().Before("gorm:row").Register("tenancy:row", scopeTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenancy:update", scopeTenant); err != nil {
+		return err
+	}
+	return callbacks.Delete().Before("gorm:delete").Register("tenancy:delete", scopeTenant)
+}
+
+func stampTenant(db *gorm.DB) {
+	tenantId, ok := tenantOf(db)
+	if !ok {
+		return
+	}
+	db.Statement.SetColumn(FieldName, tenantId, true)
+}
+
+func scopeTenant(db *gorm.DB) {
+	tenantId, ok := tenantOf(db)
+	if !ok {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(FieldName)
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantId},
+	}})
+}
+
+// tenantOf returns the tenant of a statement on a tenant-aware model
+func tenantOf(db *gorm.DB) (uint, bool) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Schema.LookUpField(FieldName) == nil {
+		return 0, false
+	}
+	return FromContext(db.Statement.Context)
+}
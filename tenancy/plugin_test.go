@@ -0,0 +1,235 @@
+package tenancy_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"base/core/logger"
+	"base/packages/gamification/achievements"
+	"base/packages/gamification/models"
+	"base/packages/gamification/tenancy"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// note is a tenant-aware model of the tests
+type note struct {
+	Id       uint `gorm:"primarykey"`
+	TenantId uint
+	Body     string
+}
+
+// quietLogger drops the errors services log for the lookups that are meant to fail
+type quietLogger struct {
+	logger.Logger
+}
+
+func (quietLogger) Error(string, ...logger.Field) {}
+
+func newDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	// Every connection to an in-memory database gets a database of its own
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get connection pool: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.Use(tenancy.Plugin{}); err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+	if err := db.AutoMigrate(&note{}, &models.Achievement{}, &models.PointType{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+func forTenant(db *gorm.DB, tenantId uint) *gorm.DB {
+	return db.WithContext(tenancy.WithTenant(context.Background(), tenantId))
+}
+
+func TestPluginStampsCreates(t *testing.T) {
+	db := newDB(t)
+
+	item := &note{Body: "mine"}
+	if err := forTenant(db, 1).Create(item).Error; err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	if item.TenantId != 1 {
+		t.Errorf("tenant id = %d, want 1", item.TenantId)
+	}
+
+	// A create cannot write into another tenant
+	foreign := &note{TenantId: 2, Body: "theirs"}
+	if err := forTenant(db, 1).Create(foreign).Error; err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	stored := &note{}
+	if err := db.First(stored, foreign.Id).Error; err != nil {
+		t.Fatalf("failed to find: %v", err)
+	}
+	if stored.TenantId != 1 {
+		t.Errorf("tenant id = %d, want 1", stored.TenantId)
+	}
+}
+
+func TestPluginScopesQueries(t *testing.T) {
+	db := newDB(t)
+	mine := &note{Body: "mine"}
+	theirs := &note{Body: "theirs"}
+	if err := forTenant(db, 1).Create(mine).Error; err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	if err := forTenant(db, 2).Create(theirs).Error; err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	var items []*note
+	if err := forTenant(db, 1).Find(&items).Error; err != nil {
+		t.Fatalf("failed to find: %v", err)
+	}
+	if len(items) != 1 || items[0].Id != mine.Id {
+		t.Errorf("tenant 1 sees %d notes, want only its own", len(items))
+	}
+
+	err := forTenant(db, 1).First(&note{}, theirs.Id).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("reading another tenant's note: err = %v, want record not found", err)
+	}
+
+	// Statements without a tenant, such as migrations, see every row
+	var total int64
+	if err := db.Model(&note{}).Count(&total).Error; err != nil {
+		t.Fatalf("failed to count: %v", err)
+	}
+	if total != 2 {
+		t.Errorf("unscoped count = %d, want 2", total)
+	}
+}
+
+func TestPluginScopesScans(t *testing.T) {
+	db := newDB(t)
+	for tenantId, body := range map[uint]string{1: "mine", 2: "theirs"} {
+		if err := forTenant(db, tenantId).Create(&note{Body: body}).Error; err != nil {
+			t.Fatalf("failed to create: %v", err)
+		}
+	}
+
+	// Aggregates are scanned through the row callbacks
+	var total int64
+	if err := forTenant(db, 1).Model(&note{}).Select("COUNT(*)").Scan(&total).Error; err != nil {
+		t.Fatalf("failed to scan: %v", err)
+	}
+	if total != 1 {
+		t.Errorf("tenant 1 scans %d notes, want only its own", total)
+	}
+
+	var bodies []string
+	rows, err := forTenant(db, 1).Model(&note{}).Select("body").Rows()
+	if err != nil {
+		t.Fatalf("failed to query rows: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var body string
+		if err := rows.Scan(&body); err != nil {
+			t.Fatalf("failed to scan row: %v", err)
+		}
+		bodies = append(bodies, body)
+	}
+	if len(bodies) != 1 || bodies[0] != "mine" {
+		t.Errorf("tenant 1 reads rows %v, want only its own", bodies)
+	}
+}
+
+func TestPluginScopesUpdatesAndDeletes(t *testing.T) {
+	db := newDB(t)
+	theirs := &note{Body: "theirs"}
+	if err := forTenant(db, 2).Create(theirs).Error; err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	result := forTenant(db, 1).Model(&note{}).Where("id = ?", theirs.Id).Update("body", "overwritten")
+	if result.Error != nil {
+		t.Fatalf("failed to update: %v", result.Error)
+	}
+	if result.RowsAffected != 0 {
+		t.Errorf("update of another tenant's note affected %d rows, want 0", result.RowsAffected)
+	}
+
+	result = forTenant(db, 1).Delete(&note{}, theirs.Id)
+	if result.Error != nil {
+		t.Fatalf("failed to delete: %v", result.Error)
+	}
+	if result.RowsAffected != 0 {
+		t.Errorf("delete of another tenant's note affected %d rows, want 0", result.RowsAffected)
+	}
+
+	stored := &note{}
+	if err := db.First(stored, theirs.Id).Error; err != nil {
+		t.Fatalf("another tenant deleted the note: %v", err)
+	}
+	if stored.Body != "theirs" {
+		t.Errorf("body = %q, want it untouched", stored.Body)
+	}
+}
+
+func TestServiceHidesOtherTenants(t *testing.T) {
+	db := newDB(t)
+	theirs := &models.Achievement{Key: "first-run", Name: "First run"}
+	if err := forTenant(db, 2).Create(theirs).Error; err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	service := achievements.NewAchievementService(db, nil, nil, quietLogger{}).
+		WithContext(tenancy.WithTenant(context.Background(), 1))
+
+	if _, err := service.GetById(theirs.Id); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetById of another tenant's achievement: err = %v, want record not found", err)
+	}
+
+	_, err := service.Update(theirs.Id, &models.UpdateAchievementRequest{Name: "Taken over"})
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Update of another tenant's achievement: err = %v, want record not found", err)
+	}
+
+	stored := &models.Achievement{}
+	if err := db.First(stored, theirs.Id).Error; err != nil {
+		t.Fatalf("failed to find: %v", err)
+	}
+	if stored.Name != "First run" {
+		t.Errorf("name = %q, want it untouched", stored.Name)
+	}
+}
+
+func TestReferenceCheckHidesOtherTenants(t *testing.T) {
+	db := newDB(t)
+	theirs := &models.PointType{Key: "coins", Name: "Coins"}
+	if err := forTenant(db, 2).Create(theirs).Error; err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	err := models.CheckReferences(forTenant(db, 1)).
+		Exists("point_type_id", &models.PointType{}, theirs.Id).
+		Err()
+	var invalid *models.ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("referencing another tenant's point type: err = %v, want a validation error", err)
+	}
+	if len(invalid.Fields) != 1 || invalid.Fields[0].Field != "point_type_id" {
+		t.Errorf("fields = %v, want point_type_id", invalid.Fields)
+	}
+
+	if err := models.CheckReferences(forTenant(db, 2)).
+		Exists("point_type_id", &models.PointType{}, theirs.Id).
+		Err(); err != nil {
+		t.Errorf("referencing an own point type: err = %v, want nil", err)
+	}
+}
@@ -0,0 +1,133 @@
+// Package tenancy isolates the data of the workspaces that share one
+// database. Every gamification table carries a tenant id; the middleware
+// resolves the tenant of a request and the GORM plugin scopes every query
+// made with that request's context to it.
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// HeaderName is the request header carrying the tenant id
+	HeaderName = "X-Tenant-ID"
+
+	// ContextKey is the gin context key under which authentication
+	// middleware may store the tenant id of the caller, and under which the
+	// resolved tenant id is exposed to handlers
+	ContextKey = "tenant_id"
+)
+
+var (
+	// ErrMissingTenant is returned when a request names no tenant
+	ErrMissingTenant = errors.New("missing tenant")
+
+	// ErrInvalidTenant is returned when the tenant id is not a positive integer
+	ErrInvalidTenant = errors.New("invalid tenant id")
+
+	// ErrTenantMismatch is returned when the header names another tenant than the authenticated one
+	ErrTenantMismatch = errors.New("tenant header does not match the authenticated tenant")
+)
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx that scopes queries to tenantId
+func WithTenant(ctx context.Context, tenantId uint) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantId)
+}
+
+// FromContext returns the tenant id carried by ctx, if any
+func FromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	tenantId, ok := ctx.Value(tenantKey{}).(uint)
+	return tenantId, ok
+}
+
+// Middleware resolves the tenant of every request and stores it on the
+// request context. The tenant set by authentication wins; the header is only
+// used when authentication did not set one, and must agree with it when both
+// are present. Requests without a tenant are rejected.
+func Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tenantId, err := Resolve(ctx)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrTenantMismatch) {
+				status = http.StatusForbidden
+			}
+			ctx.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.Set(ContextKey, tenantId)
+		ctx.Request = ctx.Request.WithContext(WithTenant(ctx.Request.Context(), tenantId))
+		ctx.Next()
+	}
+}
+
+// Resolve returns the tenant id of a request from the auth context or the header
+func Resolve(ctx *gin.Context) (uint, error) {
+	var fromAuth uint
+	if value, ok := ctx.Get(ContextKey); ok {
+		tenantId, err := parse(value)
+		if err != nil {
+			return 0, err
+		}
+		fromAuth = tenantId
+	}
+
+	header := ctx.GetHeader(HeaderName)
+	if header == "" {
+		if fromAuth == 0 {
+			return 0, ErrMissingTenant
+		}
+		return fromAuth, nil
+	}
+
+	fromHeader, err := parse(header)
+	if err != nil {
+		return 0, err
+	}
+	if fromAuth != 0 && fromAuth != fromHeader {
+		return 0, ErrTenantMismatch
+	}
+	return fromHeader, nil
+}
+
+func parse(value interface{}) (uint, error) {
+	var tenantId uint64
+	switch v := value.(type) {
+	case uint:
+		tenantId = uint64(v)
+	case uint64:
+		tenantId = v
+	case int:
+		if v > 0 {
+			tenantId = uint64(v)
+		}
+	case int64:
+		if v > 0 {
+			tenantId = uint64(v)
+		}
+	case string:
+		parsed, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidTenant, v)
+		}
+		tenantId = parsed
+	default:
+		return 0, fmt.Errorf("%w: %v", ErrInvalidTenant, value)
+	}
+	if tenantId == 0 {
+		return 0, ErrInvalidTenant
+	}
+	return uint(tenantId), nil
+}
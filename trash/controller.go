@@ -0,0 +1,170 @@
+package trash
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"base/core/storage"
+	"base/packages/gamification/access"
+	"base/packages/gamification/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrashController struct {
+	Service *TrashService
+	Storage *storage.ActiveStorage
+}
+
+func NewTrashController(service *TrashService, storage *storage.ActiveStorage) *TrashController {
+	return &TrashController{
+		Service: service,
+		Storage: storage,
+	}
+}
+
+func (c *TrashController) Routes(router *gin.RouterGroup) {
+	for _, resource := range Resources {
+		resource := resource
+		require := access.Require(resource.Roles...)
+		router.GET("/"+resource.Path+"/trash", require, func(ctx *gin.Context) { c.List(ctx, resource) })
+		router.POST("/"+resource.Path+"/:id/restore", require, func(ctx *gin.Context) { c.Restore(ctx, resource) })
+		router.DELETE("/"+resource.Path+"/:id/purge", require, func(ctx *gin.Context) { c.Purge(ctx, resource) })
+	}
+}
+
+// ListTrash godoc
+// @Summary List the trash of a resource
+// @Description Get a list of the soft deleted items of a resource, latest deleted first
+// @Tags Trash
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param resource path string true "Resource, such as achievements or user-points"
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /{resource}/trash [get]
+func (c *TrashController) List(ctx *gin.Context, resource *Resource) {
+	var page, limit *int
+
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid page number"})
+			return
+		}
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit number"})
+			return
+		}
+	}
+
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).GetAll(resource, page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// RestoreTrash godoc
+// @Summary Restore an item from the trash
+// @Description Take a soft deleted item of a resource out of the trash. Items whose parents are deleted cannot be restored before them, nor items whose key or user and subject are taken by a live item.
+// @Tags Trash
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param resource path string true "Resource, such as achievements or user-points"
+// @Param id path int true "Item id"
+// @Success 200 {object} object
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
+// @Failure 500 {object} ErrorResponse
+// @Router /{resource}/{id}/restore [post]
+func (c *TrashController) Restore(ctx *gin.Context, resource *Resource) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	item, err := c.Service.WithContext(ctx.Request.Context()).Restore(resource, uint(id))
+	if err != nil {
+		var references *models.ReferenceError
+		switch {
+		case errors.Is(err, ErrNotInTrash):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		case errors.As(err, &references):
+			ctx.JSON(http.StatusConflict, references)
+		default:
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to restore item: " + err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item)
+}
+
+// PurgeTrash godoc
+// @Summary Purge an item from the trash
+// @Description Delete a soft deleted item of a resource for good, along with its file attachments and the rows its relations cascade to. Items still referenced cannot be purged.
+// @Tags Trash
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param resource path string true "Resource, such as achievements or user-points"
+// @Param id path int true "Item id"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
+// @Failure 500 {object} ErrorResponse
+// @Router /{resource}/{id}/purge [delete]
+func (c *TrashController) Purge(ctx *gin.Context, resource *Resource) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	if err := c.Service.WithContext(ctx.Request.Context()).Purge(resource, uint(id)); err != nil {
+		var references *models.ReferenceError
+		switch {
+		case errors.Is(err, ErrNotInTrash):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		case errors.As(err, &references):
+			ctx.JSON(http.StatusConflict, references)
+		default:
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to purge item: " + err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, SuccessResponse{Message: "Item purged successfully"})
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,49 @@
+package trash
+
+import (
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/module"
+	"base/core/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Module struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Controller *TrashController
+	Service    *TrashService
+	Logger     *logger.Logger
+	Storage    *storage.ActiveStorage
+}
+
+func NewTrashModule(db *gorm.DB, router *gin.RouterGroup, log logger.Logger, emitter *emitter.Emitter, storage *storage.ActiveStorage) module.Module {
+
+	service := NewTrashService(db, emitter, storage, log)
+	controller := NewTrashController(service, storage)
+
+	m := &Module{
+		DB:         db,
+		Service:    service,
+		Controller: controller,
+		Logger:     &log,
+		Storage:    storage,
+	}
+
+	return m
+}
+
+func (m *Module) Routes(router *gin.RouterGroup) {
+	m.Controller.Routes(router)
+}
+
+// Migrate has nothing to do, since the trash lives in the tables of its resources
+func (m *Module) Migrate() error {
+	return nil
+}
+
+func (m *Module) GetModels() []interface{} {
+	return []interface{}{}
+}
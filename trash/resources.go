@@ -0,0 +1,45 @@
+package trash
+
+import (
+	"base/packages/gamification/access"
+	"base/packages/gamification/models"
+)
+
+// Resource is a soft deleted model exposed through the trash routes under
+// its route Path. Event prefixes the restore and purge events, and Roles may
+// use the routes, as they may delete the resource. Unique are the columns
+// whose values no two live rows share.
+type Resource struct {
+	Path   string
+	Event  string
+	Model  interface{}
+	Roles  []string
+	Unique []string
+}
+
+var (
+	catalogRoles = []string{access.RoleAdmin}
+	userRoles    = []string{access.RoleService}
+	keyColumns   = []string{"key"}
+)
+
+// Resources are the resources with a trash
+var Resources = []*Resource{
+	{Path: "activity-types", Event: "activitytypes", Model: &models.ActivityType{}, Roles: catalogRoles, Unique: keyColumns},
+	{Path: "point-types", Event: "pointtypes", Model: &models.PointType{}, Roles: catalogRoles, Unique: keyColumns},
+	{Path: "achievements", Event: "achievements", Model: &models.Achievement{}, Roles: catalogRoles, Unique: keyColumns},
+	{Path: "achievement-criteria", Event: "achievementcriteria", Model: &models.AchievementCriteria{}, Roles: catalogRoles},
+	{Path: "levels", Event: "levels", Model: &models.Level{}, Roles: catalogRoles, Unique: keyColumns},
+	{Path: "challenges", Event: "challenges", Model: &models.Challenge{}, Roles: catalogRoles, Unique: keyColumns},
+	{Path: "challenge-templates", Event: "challengetemplates", Model: &models.ChallengeTemplate{}, Roles: catalogRoles, Unique: keyColumns},
+	{Path: "leaderboards", Event: "leaderboards", Model: &models.Leaderboard{}, Roles: catalogRoles, Unique: keyColumns},
+	{Path: "quests", Event: "quests", Model: &models.Quest{}, Roles: catalogRoles, Unique: keyColumns},
+	{Path: "quest-steps", Event: "queststeps", Model: &models.QuestStep{}, Roles: catalogRoles},
+	{Path: "teams", Event: "teams", Model: &models.Team{}, Roles: []string{access.RoleAdmin, access.RoleService}},
+	{Path: "user-activities", Event: "useractivities", Model: &models.UserActivity{}, Roles: userRoles},
+	{Path: "user-points", Event: "userpoints", Model: &models.UserPoint{}, Roles: userRoles, Unique: []string{"user_id", "point_type_id"}},
+	{Path: "user-achievements", Event: "userachievements", Model: &models.UserAchievement{}, Roles: userRoles, Unique: []string{"user_id", "achievement_id"}},
+	{Path: "user-levels", Event: "userlevels", Model: &models.UserLevel{}, Roles: userRoles, Unique: []string{"user_id"}},
+	{Path: "user-challenges", Event: "userchallenges", Model: &models.UserChallenge{}, Roles: userRoles},
+	{Path: "leaderboard-entries", Event: "leaderboardentries", Model: &models.LeaderboardEntry{}, Roles: userRoles},
+}
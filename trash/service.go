@@ -0,0 +1,313 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+	"base/core/types"
+	"base/packages/gamification/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrNotInTrash is returned when restoring or purging a row that is not soft deleted
+var ErrNotInTrash = errors.New("item is not in the trash")
+
+type TrashService struct {
+	DB      *gorm.DB
+	Emitter *emitter.Emitter
+	Storage *storage.ActiveStorage
+	Logger  logger.Logger
+}
+
+func NewTrashService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *TrashService {
+	return &TrashService{
+		DB:      db,
+		Emitter: emitter,
+		Storage: storage,
+		Logger:  logger,
+	}
+}
+
+func (s *TrashService) WithContext(ctx context.Context) *TrashService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
+// GetAll returns the soft deleted rows of a resource, latest deleted first
+func (s *TrashService) GetAll(resource *Resource, page *int, limit *int) (*types.PaginatedResponse, error) {
+	items := reflect.New(reflect.SliceOf(reflect.TypeOf(resource.Model)))
+	var total int64
+	query := s.DB.Unscoped().Model(resource.Model).Where("deleted_at IS NOT NULL")
+	// Set default values if nil
+	defaultPage := 1
+	defaultLimit := 10
+	if page == nil {
+		page = &defaultPage
+	}
+	if limit == nil {
+		limit = &defaultLimit
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count trash",
+			logger.String("error", err.Error()),
+			logger.String("resource", resource.Path))
+		return nil, fmt.Errorf("failed to count trash: %w", err)
+	}
+
+	offset := (*page - 1) * *limit
+	query = query.Order("deleted_at DESC").Offset(offset).Limit(*limit)
+
+	// Execute query
+	if err := query.Find(items.Interface()).Error; err != nil {
+		s.Logger.Error("failed to get trash",
+			logger.String("error", err.Error()),
+			logger.String("resource", resource.Path))
+		return nil, fmt.Errorf("failed to get trash: %w", err)
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(total) / float64(*limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return &types.PaginatedResponse{
+		Data: items.Elem().Interface(),
+		Pagination: types.Pagination{
+			Total:      int(total),
+			Page:       *page,
+			PageSize:   *limit,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// Restore takes a row of a resource out of the trash. Rows whose parents
+// are deleted, or whose unique columns are taken by a live row, stay in the
+// trash, with a ReferenceError naming the parents or the live rows.
+func (s *TrashService) Restore(resource *Resource, id uint) (interface{}, error) {
+	item := reflect.New(reflect.TypeOf(resource.Model).Elem()).Interface()
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := find(tx, item, id); err != nil {
+			return err
+		}
+		missing, err := missingParents(tx, item)
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return &models.ReferenceError{Message: "restore the deleted parents first", References: missing}
+		}
+		taken, err := liveDuplicates(tx, resource, item, id)
+		if err != nil {
+			return err
+		}
+		if len(taken) > 0 {
+			return &models.ReferenceError{
+				Message:    "delete the live items with the same " + strings.Join(resource.Unique, " and ") + " first",
+				References: taken,
+			}
+		}
+		return tx.Unscoped().Model(item).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		var references *models.ReferenceError
+		if !errors.Is(err, ErrNotInTrash) && !errors.As(err, &references) {
+			s.Logger.Error("failed to restore item",
+				logger.String("error", err.Error()),
+				logger.String("resource", resource.Path),
+				logger.Int("id", int(id)))
+		}
+		return nil, err
+	}
+
+	// Emit restore event
+	s.Emitter.Emit(resource.Event+".restore", item)
+
+	return item, nil
+}
+
+// Purge deletes a row of a resource in the trash for good, along with its
+// storage attachments and the rows its relations cascade to. Rows still
+// referenced fail with a ReferenceError. Attachments are deleted once the
+// row is gone, and one that fails to delete is logged and left behind.
+func (s *TrashService) Purge(resource *Resource, id uint) error {
+	item := reflect.New(reflect.TypeOf(resource.Model).Elem()).Interface()
+	query := s.DB
+	attachments, err := attachmentFields(s.DB, item)
+	if err != nil {
+		return err
+	}
+	for _, name := range attachments {
+		query = query.Preload(name)
+	}
+	if err := find(query, item, id); err != nil {
+		if !errors.Is(err, ErrNotInTrash) {
+			s.Logger.Error("failed to find item for purge",
+				logger.String("error", err.Error()),
+				logger.String("resource", resource.Path),
+				logger.Int("id", int(id)))
+		}
+		return err
+	}
+
+	if err := models.Purge(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
+		s.Logger.Error("failed to purge item",
+			logger.String("error", err.Error()),
+			logger.String("resource", resource.Path),
+			logger.Int("id", int(id)))
+		return fmt.Errorf("failed to purge item: %w", err)
+	}
+
+	// Delete file attachments if any
+	for _, name := range attachments {
+		attachment, ok := reflect.ValueOf(item).Elem().FieldByName(name).Interface().(*storage.Attachment)
+		if !ok || attachment == nil {
+			continue
+		}
+		if err := s.Storage.Delete(attachment); err != nil {
+			s.Logger.Error("failed to delete attachment of purged item",
+				logger.String("error", err.Error()),
+				logger.String("resource", resource.Path),
+				logger.String("attachment", name),
+				logger.Int("id", int(id)))
+		}
+	}
+
+	// Emit purge event
+	s.Emitter.Emit(resource.Event+".purge", item)
+
+	return nil
+}
+
+// find loads a soft deleted row into item
+func find(db *gorm.DB, item interface{}, id uint) error {
+	err := db.Unscoped().Where("deleted_at IS NOT NULL").First(item, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotInTrash
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find item: %w", err)
+	}
+	return nil
+}
+
+// missingParents returns the parents item belongs to that are deleted or
+// gone
+func missingParents(tx *gorm.DB, item interface{}) ([]models.Reference, error) {
+	itemSchema, err := parse(tx, item)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []models.Reference
+	value := reflect.ValueOf(item).Elem()
+	for _, relationship := range itemSchema.Relationships.BelongsTo {
+		for _, reference := range relationship.References {
+			if reference.OwnPrimaryKey || reference.PrimaryKey == nil {
+				continue
+			}
+			parentId, zero := reference.ForeignKey.ValueOf(tx.Statement.Context, value)
+			if zero {
+				continue
+			}
+			var count int64
+			parent := reflect.New(relationship.FieldSchema.ModelType).Interface()
+			if err := tx.Model(parent).Where(reference.PrimaryKey.DBName+" = ?", parentId).Count(&count).Error; err != nil {
+				return nil, fmt.Errorf("failed to find %s: %w", relationship.FieldSchema.Table, err)
+			}
+			if count > 0 {
+				continue
+			}
+			id, _ := parentId.(uint)
+			if pointer, ok := parentId.(*uint); ok && pointer != nil {
+				id = *pointer
+			}
+			missing = append(missing, models.Reference{
+				Table:  relationship.FieldSchema.Table,
+				Column: reference.PrimaryKey.DBName,
+				Ids:    []uint{id},
+			})
+		}
+	}
+	return missing, nil
+}
+
+// liveDuplicates returns the live rows of a resource other than item that
+// share the values of its unique columns
+func liveDuplicates(tx *gorm.DB, resource *Resource, item interface{}, id uint) ([]models.Reference, error) {
+	if len(resource.Unique) == 0 {
+		return nil, nil
+	}
+	itemSchema, err := parse(tx, item)
+	if err != nil {
+		return nil, err
+	}
+
+	query := tx.Model(resource.Model).Where("id <> ?", id)
+	value := reflect.ValueOf(item).Elem()
+	for _, column := range resource.Unique {
+		field := itemSchema.LookUpField(column)
+		if field == nil {
+			return nil, fmt.Errorf("failed to find column %s of %s", column, itemSchema.Table)
+		}
+		columnValue, _ := field.ValueOf(tx.Statement.Context, value)
+		query = query.Where(column+" = ?", columnValue)
+	}
+
+	var ids []uint
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to find live %s: %w", itemSchema.Table, err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return []models.Reference{{
+		Table:  itemSchema.Table,
+		Column: strings.Join(resource.Unique, ","),
+		Ids:    ids,
+	}}, nil
+}
+
+// attachmentFields returns the names of the storage attachment fields of item
+func attachmentFields(db *gorm.DB, item interface{}) ([]string, error) {
+	itemSchema, err := parse(db, item)
+	if err != nil {
+		return nil, err
+	}
+	attachmentType := reflect.TypeOf(storage.Attachment{})
+	var names []string
+	for _, relationship := range itemSchema.Relationships.Relations {
+		if relationship.FieldSchema.ModelType == attachmentType {
+			names = append(names, relationship.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// parse returns the schema of a model
+func parse(db *gorm.DB, item interface{}) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(item); err != nil {
+		return nil, fmt.Errorf("failed to parse model: %w", err)
+	}
+	return stmt.Schema, nil
+}
@@ -0,0 +1,148 @@
+package trash_test
+
+import (
+	"errors"
+	"testing"
+
+	"base/core/emitter"
+	"base/core/storage"
+	"base/packages/gamification/internal/testdb"
+	"base/packages/gamification/models"
+	"base/packages/gamification/trash"
+
+	"gorm.io/gorm"
+)
+
+// newTrash returns the database of tenant 1, migrated for every table of
+// the relations and for attachments, and a service on it
+func newTrash(t *testing.T) (*gorm.DB, *trash.TrashService) {
+	t.Helper()
+	values := []interface{}{&storage.Attachment{}}
+	for _, relation := range models.Relations {
+		values = append(values, relation.Model, relation.Parent)
+	}
+	db := testdb.ForTenant(testdb.Open(t, values...), 1)
+	return db, trash.NewTrashService(db, &emitter.Emitter{}, nil, testdb.Logger{})
+}
+
+func resource(t *testing.T, path string) *trash.Resource {
+	t.Helper()
+	for _, item := range trash.Resources {
+		if item.Path == path {
+			return item
+		}
+	}
+	t.Fatalf("no resource %s", path)
+	return nil
+}
+
+func create(t *testing.T, db *gorm.DB, items ...interface{}) {
+	t.Helper()
+	for _, item := range items {
+		if err := db.Create(item).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", item, err)
+		}
+	}
+}
+
+func count(t *testing.T, db *gorm.DB, model interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Unscoped().Model(model).Count(&n).Error; err != nil {
+		t.Fatalf("failed to count %T: %v", model, err)
+	}
+	return n
+}
+
+func TestRestoreNeedsParents(t *testing.T) {
+	db, service := newTrash(t)
+	achievement := &models.Achievement{Key: "marathon", Name: "Marathon"}
+	activityType := &models.ActivityType{Key: "run-logged", Name: "Run logged"}
+	create(t, db, achievement, activityType)
+	criteria := &models.AchievementCriteria{AchievementId: achievement.Id, ActivityTypeId: activityType.Id, RequiredCount: 3}
+	create(t, db, criteria)
+	// The criteria is deleted along with its achievement
+	if err := models.Delete(db, achievement); err != nil {
+		t.Fatalf("failed to delete achievement: %v", err)
+	}
+
+	var references *models.ReferenceError
+	_, err := service.Restore(resource(t, "achievement-criteria"), criteria.Id)
+	if !errors.As(err, &references) {
+		t.Fatalf("restoring before the achievement: err = %v, want a reference error", err)
+	}
+	if len(references.References) != 1 || references.References[0].Table != "achievements" {
+		t.Errorf("references = %+v, want the achievement", references.References)
+	}
+
+	if _, err := service.Restore(resource(t, "achievements"), achievement.Id); err != nil {
+		t.Fatalf("failed to restore achievement: %v", err)
+	}
+	if _, err := service.Restore(resource(t, "achievement-criteria"), criteria.Id); err != nil {
+		t.Fatalf("failed to restore criteria: %v", err)
+	}
+	if _, err := service.Restore(resource(t, "achievement-criteria"), criteria.Id); !errors.Is(err, trash.ErrNotInTrash) {
+		t.Errorf("restoring again: err = %v, want %v", err, trash.ErrNotInTrash)
+	}
+}
+
+func TestRestoreRefusesTakenKey(t *testing.T) {
+	db, service := newTrash(t)
+	deleted := &models.PointType{Key: "coins", Name: "Coins"}
+	create(t, db, deleted)
+	if err := models.Delete(db, deleted); err != nil {
+		t.Fatalf("failed to delete point type: %v", err)
+	}
+	live := &models.PointType{Key: "coins", Name: "New coins"}
+	create(t, db, live)
+
+	var references *models.ReferenceError
+	_, err := service.Restore(resource(t, "point-types"), deleted.Id)
+	if !errors.As(err, &references) {
+		t.Fatalf("err = %v, want a reference error", err)
+	}
+	if len(references.References) != 1 || len(references.References[0].Ids) != 1 || references.References[0].Ids[0] != live.Id {
+		t.Errorf("references = %+v, want point type %d", references.References, live.Id)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	db, service := newTrash(t)
+	achievement := &models.Achievement{Key: "marathon", Name: "Marathon"}
+	activityType := &models.ActivityType{Key: "run-logged", Name: "Run logged"}
+	create(t, db, achievement, activityType)
+	create(t, db,
+		&models.AchievementCriteria{AchievementId: achievement.Id, ActivityTypeId: activityType.Id, RequiredCount: 3},
+		&models.UserAchievement{UserId: 7, AchievementId: achievement.Id},
+	)
+
+	if err := service.Purge(resource(t, "achievements"), achievement.Id); !errors.Is(err, trash.ErrNotInTrash) {
+		t.Errorf("purging a live row: err = %v, want %v", err, trash.ErrNotInTrash)
+	}
+
+	// Archived rows keep a row from being purged
+	if err := models.Delete(db, achievement); err != nil {
+		t.Fatalf("failed to delete achievement: %v", err)
+	}
+	var references *models.ReferenceError
+	if err := service.Purge(resource(t, "achievements"), achievement.Id); !errors.As(err, &references) {
+		t.Fatalf("purging with user achievements: err = %v, want a reference error", err)
+	}
+	if references.References[0].Table != "userachievements" {
+		t.Errorf("references = %+v, want the user achievements", references.References)
+	}
+
+	if err := db.Unscoped().Where("achievement_id = ?", achievement.Id).Delete(&models.UserAchievement{}).Error; err != nil {
+		t.Fatalf("failed to delete user achievements: %v", err)
+	}
+	if err := service.Purge(resource(t, "achievements"), achievement.Id); err != nil {
+		t.Fatalf("failed to purge achievement: %v", err)
+	}
+	// The criteria go with it, deleted or not
+	if n := count(t, db, &models.Achievement{}); n != 0 {
+		t.Errorf("%d achievements left, want 0", n)
+	}
+	if n := count(t, db, &models.AchievementCriteria{}); n != 0 {
+		t.Errorf("%d criteria left, want 0", n)
+	}
+}
@@ -41,7 +41,10 @@ func (m *Module) Routes(router *gin.RouterGroup) {
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.UserAchievement{})
+	if err := m.DB.AutoMigrate(&models.UserAchievement{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.UserAchievement{})
 }
 
 func (m *Module) GetModels() []interface{} {
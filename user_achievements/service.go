@@ -1,8 +1,11 @@
 package user_achievements
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
+	"time"
 
 	"base/core/emitter"
 	"base/core/logger"
@@ -35,7 +38,35 @@ func NewUserAchievementService(db *gorm.DB, emitter *emitter.Emitter, storage *s
 	}
 }
 
+func (s *UserAchievementService) WithContext(ctx context.Context) *UserAchievementService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
+// ForUser returns a copy of the service that only sees the rows of userId
+func (s *UserAchievementService) ForUser(userId uint) *UserAchievementService {
+	clone := *s
+	clone.DB = s.DB.Where("user_id = ?", userId).Session(&gorm.Session{})
+	return &clone
+}
+
 func (s *UserAchievementService) Create(req *models.CreateUserAchievementRequest) (*models.UserAchievement, error) {
+	if req.AchievementKey != "" {
+		achievementId, err := models.ResolveFieldKey(s.DB, &models.Achievement{}, "achievement_key", req.AchievementKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve achievement key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve achievement: %w", err)
+		}
+		req.AchievementId = achievementId
+	}
+	if err := models.CheckReferences(s.DB).
+		Active("achievement_id", &models.Achievement{}, req.AchievementId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check userachievement references", logger.String("error", err.Error()))
+		return nil, err
+	}
+
 	item := &models.UserAchievement{
 		UserId:        req.UserId,
 		AchievementId: req.AchievementId,
@@ -62,6 +93,14 @@ func (s *UserAchievementService) Update(id uint, req *models.UpdateUserAchieveme
 			logger.Int("id", int(id)))
 		return nil, fmt.Errorf("failed to find userachievement: %w", err)
 	}
+	if err := models.CheckReferences(s.DB).
+		Active("achievement_id", &models.Achievement{}, req.AchievementId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check userachievement references",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
 
 	// Build updates map
 	updates := make(map[string]interface{})
@@ -110,7 +149,11 @@ func (s *UserAchievementService) Delete(id uint) error {
 
 	// Delete file attachments if any
 
-	if err := s.DB.Delete(item).Error; err != nil {
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
 		s.Logger.Error("failed to delete userachievement",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -197,3 +240,27 @@ func (s *UserAchievementService) GetAll(page *int, limit *int) (*types.Paginated
 		},
 	}, nil
 }
+
+// Grant completes an achievement for a user within tx. It reports false,
+// leaving the row as it is, when the user already has the achievement.
+func (s *UserAchievementService) Grant(tx *gorm.DB, userId uint, achievementId uint) (*models.UserAchievement, bool, error) {
+	item := &models.UserAchievement{}
+	err := tx.Where("user_id = ? AND achievement_id = ?", userId, achievementId).Take(item).Error
+	if err == nil {
+		return item, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("failed to find userachievement: %w", err)
+	}
+
+	item = &models.UserAchievement{
+		UserId:        userId,
+		AchievementId: achievementId,
+		Progress:      100,
+		CompletedAt:   types.DateTime{Time: time.Now()},
+	}
+	if err := tx.Create(item).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to create userachievement: %w", err)
+	}
+	return item, true, nil
+}
@@ -0,0 +1,156 @@
+package user_activities
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"base/packages/gamification/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Weights of the anomaly checks. An activity whose score reaches
+// QuarantineScore is quarantined; scores are capped at 100.
+const (
+	burstWeight     = 60
+	rateWeight      = 50
+	metadataWeight  = 100
+	QuarantineScore = 50
+)
+
+// rateHistory is how far back a user's usual hourly rate is measured
+const rateHistory = 30 * 24 * time.Hour
+
+// Anomaly reasons
+const (
+	AnomalyBurst    = "burst"
+	AnomalyRate     = "rate"
+	AnomalyMetadata = "metadata"
+)
+
+// detect scores an incoming activity of a user against the limits of its
+// type and returns the score with the reasons behind it. The activity itself
+// is not stored yet, so it is added to every count. The user's counter of the
+// type is incremented first, which holds concurrent submissions of the same
+// user and type until tx ends, so that each one counts the ones before it.
+func detect(tx *gorm.DB, activityType *models.ActivityType, userId uint, metadata string, now time.Time) (int, []string, error) {
+	if err := countActivity(tx, userId, activityType.Id); err != nil {
+		return 0, nil, err
+	}
+
+	limits := activityType.Limits
+	score := 0
+	var reasons []string
+
+	count := func(from, to time.Time) (int64, error) {
+		var n int64
+		err := tx.Model(&models.UserActivity{}).
+			Where("user_id = ? AND activity_type_id = ? AND created_at > ? AND created_at <= ?", userId, activityType.Id, from, to).
+			Count(&n).Error
+		if err != nil {
+			return 0, fmt.Errorf("failed to count activities for anomaly detection: %w", err)
+		}
+		return n, nil
+	}
+
+	if limits.BurstCount > 0 && limits.BurstWindow > 0 {
+		recent, err := count(now.Add(-time.Duration(limits.BurstWindow)*time.Second), now)
+		if err != nil {
+			return 0, nil, err
+		}
+		if recent+1 > int64(limits.BurstCount) {
+			score += burstWeight
+			reasons = append(reasons, AnomalyBurst)
+		}
+	}
+
+	if limits.RateFactor > 0 {
+		hourAgo := now.Add(-time.Hour)
+		recent, err := count(hourAgo, now)
+		if err != nil {
+			return 0, nil, err
+		}
+		earlier, err := count(now.Add(-rateHistory), hourAgo)
+		if err != nil {
+			return 0, nil, err
+		}
+		// New users have no history, so the usual rate is at least one an hour
+		usual := math.Max(float64(earlier)/(rateHistory.Hours()-1), 1)
+		if float64(recent+1) > float64(limits.RateFactor)*usual {
+			score += rateWeight
+			reasons = append(reasons, AnomalyRate)
+		}
+	}
+
+	if len(limits.Metadata) > 0 {
+		for _, name := range outOfBounds(limits.Metadata, metadata) {
+			score += metadataWeight
+			reasons = append(reasons, AnomalyMetadata+":"+name)
+		}
+	}
+
+	if score > 100 {
+		score = 100
+	}
+	return score, reasons, nil
+}
+
+// countActivity increments the user's counter of an activity type, creating
+// it on first use. The update locks the counter row until tx ends.
+func countActivity(tx *gorm.DB, userId, activityTypeId uint) error {
+	counter := &models.UserActivityCounter{UserId: userId, ActivityTypeId: activityTypeId}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(counter).Error; err != nil {
+		return fmt.Errorf("failed to create activity counter: %w", err)
+	}
+	err := tx.Model(&models.UserActivityCounter{}).
+		Where("user_id = ? AND activity_type_id = ?", userId, activityTypeId).
+		UpdateColumn("count", gorm.Expr("count + 1")).Error
+	if err != nil {
+		return fmt.Errorf("failed to increment activity counter: %w", err)
+	}
+	return nil
+}
+
+// outOfBounds returns the names of the bounded metadata fields whose value is
+// not a number within its bounds. Missing fields are not checked.
+func outOfBounds(bounds map[string]models.MetadataLimit, metadata string) []string {
+	values := make(map[string]interface{})
+	if metadata != "" {
+		if err := json.Unmarshal([]byte(metadata), &values); err != nil {
+			return nil
+		}
+	}
+
+	var names []string
+	for name, limit := range bounds {
+		raw, ok := values[name]
+		if !ok {
+			continue
+		}
+		var value float64
+		switch typed := raw.(type) {
+		case float64:
+			value = typed
+		case string:
+			parsed, err := strconv.ParseFloat(typed, 64)
+			if err != nil {
+				names = append(names, name)
+				continue
+			}
+			value = parsed
+		default:
+			names = append(names, name)
+			continue
+		}
+		if (limit.Min != nil && value < *limit.Min) || (limit.Max != nil && value > *limit.Max) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
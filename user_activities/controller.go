@@ -1,10 +1,12 @@
 package user_activities
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"base/core/storage"
+	"base/packages/gamification/access"
 	"base/packages/gamification/models"
 
 	"github.com/gin-gonic/gin"
@@ -26,10 +28,15 @@ func (c *UserActivityController) Routes(router *gin.RouterGroup) {
 	// Main CRUD endpoints
 	router.GET("/user-activities", c.List)        // Paginated list
 	router.GET("/user-activities/all", c.ListAll) // Unpaginated list
+	router.GET("/user-activities/quarantined", access.Require(access.RoleAdmin), c.ListQuarantined)
 	router.GET("/user-activities/:id", c.Get)
-	router.POST("/user-activities", c.Create)
-	router.PUT("/user-activities/:id", c.Update)
-	router.DELETE("/user-activities/:id", c.Delete)
+	router.POST("/user-activities", access.Require(access.RoleService), c.Create)
+	router.PUT("/user-activities/:id", access.Require(access.RoleService), c.Update)
+	router.DELETE("/user-activities/:id", access.Require(access.RoleService), c.Delete)
+
+	// Anomaly review endpoints
+	router.POST("/user-activities/:id/approve", access.Require(access.RoleAdmin), c.Approve)
+	router.POST("/user-activities/:id/reject", access.Require(access.RoleAdmin), c.Reject)
 
 	// File/Image attachment endpoints
 
@@ -47,6 +54,7 @@ func (c *UserActivityController) Routes(router *gin.RouterGroup) {
 // @Param user-activities body models.CreateUserActivityRequest true "Create UserActivity request"
 // @Success 201 {object} models.UserActivityResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /user-activities [post]
 func (c *UserActivityController) Create(ctx *gin.Context) {
@@ -56,8 +64,13 @@ func (c *UserActivityController) Create(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Create(&req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
 		return
 	}
@@ -76,6 +89,7 @@ func (c *UserActivityController) Create(ctx *gin.Context) {
 // @Param id path int true "UserActivity id"
 // @Success 200 {object} models.UserActivityResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /user-activities/{id} [get]
 func (c *UserActivityController) Get(ctx *gin.Context) {
@@ -85,7 +99,7 @@ func (c *UserActivityController) Get(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.service(ctx).GetById(uint(id))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -106,6 +120,7 @@ func (c *UserActivityController) Get(ctx *gin.Context) {
 // @Param limit query int false "Number of items per page"
 // @Success 200 {object} types.PaginatedResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /user-activities [get]
 func (c *UserActivityController) List(ctx *gin.Context) {
@@ -129,7 +144,7 @@ func (c *UserActivityController) List(ctx *gin.Context) {
 		}
 	}
 
-	paginatedResponse, err := c.Service.GetAll(page, limit)
+	paginatedResponse, err := c.service(ctx).GetAll(page, limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
 		return
@@ -147,10 +162,11 @@ func (c *UserActivityController) List(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /user-activities/all [get]
 func (c *UserActivityController) ListAll(ctx *gin.Context) {
-	paginatedResponse, err := c.Service.GetAll(nil, nil)
+	paginatedResponse, err := c.service(ctx).GetAll(nil, nil)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
 		return
@@ -161,7 +177,7 @@ func (c *UserActivityController) ListAll(ctx *gin.Context) {
 
 // UpdateUserActivity godoc
 // @Summary Update a UserActivity
-// @Description Update a UserActivity by its id
+// @Description Update the metadata and completion time of a UserActivity by its id. The user, the activity type and the points awarded cannot change.
 // @Tags UserActivity
 // @Security ApiKeyAuth
 // @Security BearerAuth
@@ -171,6 +187,7 @@ func (c *UserActivityController) ListAll(ctx *gin.Context) {
 // @Param user-activities body models.UpdateUserActivityRequest true "Update UserActivity request"
 // @Success 200 {object} models.UserActivityResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /user-activities/{id} [put]
@@ -187,8 +204,13 @@ func (c *UserActivityController) Update(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Update(uint(id), &req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
 	}
@@ -207,6 +229,8 @@ func (c *UserActivityController) Update(ctx *gin.Context) {
 // @Param id path int true "UserActivity id"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
 // @Failure 500 {object} ErrorResponse
 // @Router /user-activities/{id} [delete]
 func (c *UserActivityController) Delete(ctx *gin.Context) {
@@ -216,7 +240,12 @@ func (c *UserActivityController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.Delete(uint(id)); err != nil {
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
 		return
 	}
@@ -224,6 +253,120 @@ func (c *UserActivityController) Delete(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, SuccessResponse{Message: "Item deleted successfully"})
 }
 
+// ListQuarantinedUserActivities godoc
+// @Summary List quarantined user-activities
+// @Description Get a list of the user-activities held back for review by anomaly detection
+// @Tags UserActivity
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /user-activities/quarantined [get]
+func (c *UserActivityController) ListQuarantined(ctx *gin.Context) {
+	var page, limit *int
+
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid page number"})
+			return
+		}
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit number"})
+			return
+		}
+	}
+
+	paginatedResponse, err := c.Service.WithContext(ctx.Request.Context()).Quarantined().GetAll(page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// ApproveUserActivity godoc
+// @Summary Approve a quarantined UserActivity
+// @Description Accept a quarantined UserActivity and award the points it held back
+// @Tags UserActivity
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "UserActivity id"
+// @Success 200 {object} models.UserActivityResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /user-activities/{id}/approve [post]
+func (c *UserActivityController) Approve(ctx *gin.Context) {
+	c.review(ctx, (*UserActivityService).Approve)
+}
+
+// RejectUserActivity godoc
+// @Summary Reject a quarantined UserActivity
+// @Description Reject a quarantined UserActivity; it never awards points
+// @Tags UserActivity
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "UserActivity id"
+// @Success 200 {object} models.UserActivityResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /user-activities/{id}/reject [post]
+func (c *UserActivityController) Reject(ctx *gin.Context) {
+	c.review(ctx, (*UserActivityService).Reject)
+}
+
+// review applies an admin's verdict to a quarantined activity
+func (c *UserActivityController) review(ctx *gin.Context, apply func(*UserActivityService, uint, uint) (*models.UserActivity, error)) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+		return
+	}
+
+	reviewerId, _ := access.UserId(ctx)
+	item, err := apply(c.Service.WithContext(ctx.Request.Context()), uint(id), reviewerId)
+	if err != nil {
+		if errors.Is(err, ErrNotQuarantined) {
+			ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to review item: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// service scopes the service to the request's tenant and, for end users, to their own rows
+func (c *UserActivityController) service(ctx *gin.Context) *UserActivityService {
+	service := c.Service.WithContext(ctx.Request.Context())
+	if userId, restricted := access.RestrictedTo(ctx); restricted {
+		service = service.ForUser(userId)
+	}
+	return service
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
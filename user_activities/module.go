@@ -41,9 +41,12 @@ func (m *Module) Routes(router *gin.RouterGroup) {
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.UserActivity{})
+	if err := m.DB.AutoMigrate(&models.UserActivity{}, &models.UserActivityPoint{}, &models.UserActivityCounter{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.UserActivity{}, &models.UserActivityPoint{}, &models.UserActivityCounter{})
 }
 
 func (m *Module) GetModels() []interface{} {
-	return []interface{}{&models.UserActivity{}}
+	return []interface{}{&models.UserActivity{}, &models.UserActivityPoint{}, &models.UserActivityCounter{}}
 }
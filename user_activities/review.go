@@ -0,0 +1,111 @@
+package user_activities
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"base/core/logger"
+	"base/packages/gamification/models"
+	"base/packages/gamification/user_levels"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	QuarantineUserActivityEvent = "useractivities.quarantine"
+	ApproveUserActivityEvent    = "useractivities.approve"
+	RejectUserActivityEvent     = "useractivities.reject"
+)
+
+// ErrNotQuarantined is returned when reviewing an activity that is not in quarantine
+var ErrNotQuarantined = errors.New("useractivity is not quarantined")
+
+// Quarantined returns a copy of the service that only sees quarantined activities
+func (s *UserActivityService) Quarantined() *UserActivityService {
+	clone := *s
+	clone.DB = s.DB.Where("status = ?", models.UserActivityQuarantined).Session(&gorm.Session{})
+	return &clone
+}
+
+// Approve accepts a quarantined activity and awards the points it held back.
+// reviewerId is the admin reviewing it, or 0 when unknown.
+func (s *UserActivityService) Approve(id uint, reviewerId uint) (*models.UserActivity, error) {
+	var level *models.UserLevel
+	leveledUp := false
+	item, err := s.review(id, reviewerId, models.UserActivityAccepted, func(tx *gorm.DB, item *models.UserActivity) error {
+		activityType := &models.ActivityType{}
+		if err := tx.Preload("Rewards").First(activityType, item.ActivityTypeId).Error; err != nil {
+			return fmt.Errorf("failed to find activity type: %w", err)
+		}
+		var err error
+		level, leveledUp, err = s.award(tx, activityType, item)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.Emitter.Emit(ApproveUserActivityEvent, item)
+	// Only now does the activity count towards progress
+	s.Emitter.Emit(CreateUserActivityEvent, item)
+	if leveledUp {
+		s.Emitter.Emit(user_levels.LevelUpEvent, level)
+	}
+
+	return s.GetById(item.Id)
+}
+
+// Reject rejects a quarantined activity for good; it never awards points.
+// reviewerId is the admin reviewing it, or 0 when unknown.
+func (s *UserActivityService) Reject(id uint, reviewerId uint) (*models.UserActivity, error) {
+	item, err := s.review(id, reviewerId, models.UserActivityRejected, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Emitter.Emit(RejectUserActivityEvent, item)
+
+	return s.GetById(item.Id)
+}
+
+// review moves a quarantined activity to status, with the activity locked.
+// apply, when set, runs before the status is stored.
+func (s *UserActivityService) review(id uint, reviewerId uint, status string, apply func(tx *gorm.DB, item *models.UserActivity) error) (*models.UserActivity, error) {
+	item := &models.UserActivity{}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(item, id).Error; err != nil {
+			return fmt.Errorf("failed to find useractivity: %w", err)
+		}
+		if item.Status != models.UserActivityQuarantined {
+			return ErrNotQuarantined
+		}
+		if apply != nil {
+			if err := apply(tx, item); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		item.Status, item.ReviewedAt = status, &now
+		if reviewerId != 0 {
+			item.ReviewedBy = &reviewerId
+		}
+		return tx.Model(item).Updates(map[string]interface{}{
+			"status":      item.Status,
+			"reviewed_by": item.ReviewedBy,
+			"reviewed_at": now,
+		}).Error
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotQuarantined) {
+			s.Logger.Error("failed to review useractivity",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(id)))
+		}
+		return nil, err
+	}
+
+	return item, nil
+}
@@ -0,0 +1,137 @@
+package user_activities_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"base/core/app/users"
+	"base/core/emitter"
+	"base/core/types"
+	"base/packages/gamification/internal/testdb"
+	"base/packages/gamification/models"
+	"base/packages/gamification/user_activities"
+
+	"gorm.io/gorm"
+)
+
+// newActivities returns the database of tenant 1, a service on it and an
+// activity type worth 10 xp whose steps metadata may not exceed 100000
+func newActivities(t *testing.T) (*gorm.DB, *user_activities.UserActivityService, *models.ActivityType) {
+	t.Helper()
+	base := testdb.Open(t, &users.User{}, &models.ActivityType{}, &models.ActivityTypeReward{}, &models.UserActivity{},
+		&models.UserActivityPoint{}, &models.UserActivityCounter{}, &models.PointType{}, &models.UserPoint{},
+		&models.PointLot{}, &models.PointTransaction{}, &models.Level{}, &models.UserLevel{})
+	db := testdb.ForTenant(base, 1)
+	if err := db.Create(&models.PointType{Key: "xp", Name: "XP", IsXpSource: true}).Error; err != nil {
+		t.Fatalf("failed to create point type: %v", err)
+	}
+	max := 100000.0
+	activityType := &models.ActivityType{
+		Key:         "walk",
+		Name:        "Walk",
+		PointsValue: 10,
+		IsActive:    true,
+		Limits:      models.ActivityLimits{Metadata: map[string]models.MetadataLimit{"steps": {Max: &max}}},
+	}
+	if err := db.Create(activityType).Error; err != nil {
+		t.Fatalf("failed to create activity type: %v", err)
+	}
+	return db, user_activities.NewUserActivityService(db, &emitter.Emitter{}, nil, testdb.Logger{}), activityType
+}
+
+func walk(t *testing.T, service *user_activities.UserActivityService, activityType *models.ActivityType, steps string) *models.UserActivity {
+	t.Helper()
+	item, err := service.Create(&models.CreateUserActivityRequest{
+		UserId:         7,
+		ActivityTypeId: activityType.Id,
+		PointsEarned:   10,
+		Metadata:       `{"steps": ` + steps + `}`,
+		CompletedAt:    types.DateTime{Time: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("failed to create activity: %v", err)
+	}
+	return item
+}
+
+func xpOf(t *testing.T, db *gorm.DB) int {
+	t.Helper()
+	var balances []int
+	if err := db.Model(&models.UserPoint{}).Where("user_id = ?", 7).Pluck("current_balance", &balances).Error; err != nil {
+		t.Fatalf("failed to find balance: %v", err)
+	}
+	if len(balances) == 0 {
+		return 0
+	}
+	return balances[0]
+}
+
+func TestQuarantineAndApprove(t *testing.T) {
+	db, service, activityType := newActivities(t)
+
+	accepted := walk(t, service, activityType, "5000")
+	if accepted.Status != models.UserActivityAccepted || accepted.PointsEarned != 10 {
+		t.Errorf("activity within limits: status = %s, points = %d, want %s and 10", accepted.Status, accepted.PointsEarned, models.UserActivityAccepted)
+	}
+
+	item := walk(t, service, activityType, "500000")
+	if item.Status != models.UserActivityQuarantined {
+		t.Fatalf("status = %s, want %s", item.Status, models.UserActivityQuarantined)
+	}
+	want := user_activities.AnomalyMetadata + ":steps"
+	if item.AnomalyScore < user_activities.QuarantineScore || len(item.AnomalyReasons) != 1 || item.AnomalyReasons[0] != want {
+		t.Errorf("anomaly = %d %v, want %s", item.AnomalyScore, item.AnomalyReasons, want)
+	}
+	// A quarantined activity holds its points back
+	if item.PointsEarned != 0 || xpOf(t, db) != 10 {
+		t.Errorf("quarantined: points = %d, xp = %d, want 0 and 10", item.PointsEarned, xpOf(t, db))
+	}
+
+	if _, err := service.Approve(accepted.Id, 2); !errors.Is(err, user_activities.ErrNotQuarantined) {
+		t.Errorf("approving an accepted activity: err = %v, want %v", err, user_activities.ErrNotQuarantined)
+	}
+
+	approved, err := service.Approve(item.Id, 2)
+	if err != nil {
+		t.Fatalf("failed to approve activity: %v", err)
+	}
+	if approved.Status != models.UserActivityAccepted || approved.PointsEarned != 10 {
+		t.Errorf("approved: status = %s, points = %d, want %s and 10", approved.Status, approved.PointsEarned, models.UserActivityAccepted)
+	}
+	if approved.ReviewedBy == nil || approved.ReviewedAt == nil {
+		t.Errorf("approved activity has no reviewer")
+	} else if *approved.ReviewedBy != 2 {
+		t.Errorf("reviewed by %d, want 2", *approved.ReviewedBy)
+	}
+	if got := xpOf(t, db); got != 20 {
+		t.Errorf("xp = %d, want 20", got)
+	}
+
+	// The points are awarded once
+	if _, err := service.Approve(item.Id, 2); !errors.Is(err, user_activities.ErrNotQuarantined) {
+		t.Errorf("approving again: err = %v, want %v", err, user_activities.ErrNotQuarantined)
+	}
+	if got := xpOf(t, db); got != 20 {
+		t.Errorf("xp = %d after approving again, want 20", got)
+	}
+}
+
+func TestReject(t *testing.T) {
+	db, service, activityType := newActivities(t)
+	item := walk(t, service, activityType, "500000")
+
+	rejected, err := service.Reject(item.Id, 2)
+	if err != nil {
+		t.Fatalf("failed to reject activity: %v", err)
+	}
+	if rejected.Status != models.UserActivityRejected {
+		t.Errorf("status = %s, want %s", rejected.Status, models.UserActivityRejected)
+	}
+	if _, err := service.Approve(item.Id, 2); !errors.Is(err, user_activities.ErrNotQuarantined) {
+		t.Errorf("approving a rejected activity: err = %v, want %v", err, user_activities.ErrNotQuarantined)
+	}
+	if got := xpOf(t, db); got != 0 {
+		t.Errorf("xp = %d, want 0", got)
+	}
+}
@@ -1,14 +1,19 @@
 package user_activities
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
+	"time"
 
 	"base/core/emitter"
 	"base/core/logger"
 	"base/core/storage"
 	"base/core/types"
 	"base/packages/gamification/models"
+	"base/packages/gamification/user_levels"
+	"base/packages/gamification/user_points"
 
 	"gorm.io/gorm"
 )
@@ -24,6 +29,8 @@ type UserActivityService struct {
 	Emitter *emitter.Emitter
 	Storage *storage.ActiveStorage
 	Logger  logger.Logger
+	Points  *user_points.UserPointService
+	Levels  *user_levels.UserLevelService
 }
 
 func NewUserActivityService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *UserActivityService {
@@ -32,25 +39,95 @@ func NewUserActivityService(db *gorm.DB, emitter *emitter.Emitter, storage *stor
 		Emitter: emitter,
 		Storage: storage,
 		Logger:  logger,
+		Points:  user_points.NewUserPointService(db, emitter, storage, logger),
+		Levels:  user_levels.NewUserLevelService(db, emitter, storage, logger),
 	}
 }
 
+func (s *UserActivityService) WithContext(ctx context.Context) *UserActivityService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	clone.Points = s.Points.WithContext(ctx)
+	clone.Levels = s.Levels.WithContext(ctx)
+	return &clone
+}
+
+// ForUser returns a copy of the service that only sees the rows of userId
+func (s *UserActivityService) ForUser(userId uint) *UserActivityService {
+	clone := *s
+	clone.DB = s.DB.Where("user_id = ?", userId).Session(&gorm.Session{})
+	return &clone
+}
+
 func (s *UserActivityService) Create(req *models.CreateUserActivityRequest) (*models.UserActivity, error) {
+	if req.ActivityTypeKey != "" {
+		activityTypeId, err := models.ResolveFieldKey(s.DB, &models.ActivityType{}, "activity_type_key", req.ActivityTypeKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve activity type key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve activity type: %w", err)
+		}
+		req.ActivityTypeId = activityTypeId
+	}
+	if err := models.CheckReferences(s.DB).
+		Active("activity_type_id", &models.ActivityType{}, req.ActivityTypeId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check useractivity references", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	activityType := &models.ActivityType{}
+	if err := s.DB.Preload("Rewards").First(activityType, req.ActivityTypeId).Error; err != nil {
+		s.Logger.Error("failed to find activity type for useractivity", logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to find activity type: %w", err)
+	}
+
 	item := &models.UserActivity{
 		UserId:         req.UserId,
 		ActivityTypeId: req.ActivityTypeId,
-		PointsEarned:   req.PointsEarned,
 		Metadata:       req.Metadata,
 		CompletedAt:    req.CompletedAt,
+		Status:         models.UserActivityAccepted,
+		ClaimedPoints:  req.PointsEarned,
 	}
 
-	if err := s.DB.Create(item).Error; err != nil {
+	var level *models.UserLevel
+	leveledUp := false
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		score, reasons, err := detect(tx, activityType, item.UserId, item.Metadata, time.Now())
+		if err != nil {
+			return err
+		}
+		item.AnomalyScore, item.AnomalyReasons = score, reasons
+		if score >= QuarantineScore {
+			item.Status = models.UserActivityQuarantined
+		}
+
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		// Quarantined activities award nothing until they are approved
+		if item.Status != models.UserActivityAccepted {
+			return nil
+		}
+
+		level, leveledUp, err = s.award(tx, activityType, item)
+		return err
+	})
+	if err != nil {
 		s.Logger.Error("failed to create useractivity", logger.String("error", err.Error()))
 		return nil, fmt.Errorf("failed to create useractivity: %w", err)
 	}
 
+	if item.Status == models.UserActivityQuarantined {
+		s.Emitter.Emit(QuarantineUserActivityEvent, item)
+		return s.GetById(item.Id)
+	}
+
 	// Emit create event
 	s.Emitter.Emit(CreateUserActivityEvent, item)
+	if leveledUp {
+		s.Emitter.Emit(user_levels.LevelUpEvent, level)
+	}
 
 	return s.GetById(item.Id)
 }
@@ -66,15 +143,6 @@ func (s *UserActivityService) Update(id uint, req *models.UpdateUserActivityRequ
 
 	// Build updates map
 	updates := make(map[string]interface{})
-	if req.UserId != 0 {
-		updates["user_id"] = req.UserId
-	}
-	if req.ActivityTypeId != 0 {
-		updates["activity_type_id"] = req.ActivityTypeId
-	}
-	if req.PointsEarned != "" {
-		updates["points_earned"] = req.PointsEarned
-	}
 	if req.Metadata != "" {
 		updates["metadata"] = req.Metadata
 	}
@@ -114,7 +182,11 @@ func (s *UserActivityService) Delete(id uint) error {
 
 	// Delete file attachments if any
 
-	if err := s.DB.Delete(item).Error; err != nil {
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
 		s.Logger.Error("failed to delete useractivity",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -201,3 +273,63 @@ func (s *UserActivityService) GetAll(page *int, limit *int) (*types.PaginatedRes
 		},
 	}, nil
 }
+
+// award credits the points of an activity and the xp they bring, and stores
+// the xp it earned as PointsEarned, so that the total never mixes point
+// types. It returns the user's level and whether it changed.
+func (s *UserActivityService) award(tx *gorm.DB, activityType *models.ActivityType, item *models.UserActivity) (*models.UserLevel, bool, error) {
+	xpPointTypeId, err := s.Points.XpPointTypeId(tx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var level *models.UserLevel
+	leveledUp := false
+	for _, award := range awards(activityType, xpPointTypeId) {
+		award.UserActivityId = item.Id
+		if err := tx.Create(award).Error; err != nil {
+			return nil, false, err
+		}
+		if _, err := s.Points.Credit(tx, item.UserId, award.PointTypeId, award.Amount, models.PointTransactionEarn, fmt.Sprintf("useractivity:%d", item.Id)); err != nil {
+			return nil, false, err
+		}
+		if award.PointTypeId == xpPointTypeId {
+			var changed bool
+			level, changed, err = s.Levels.AddXp(tx, item.UserId, award.Amount)
+			if err != nil {
+				return nil, false, err
+			}
+			leveledUp = leveledUp || changed
+			item.PointsEarned += award.Amount
+		}
+	}
+
+	if err := tx.Model(item).Update("points_earned", item.PointsEarned).Error; err != nil {
+		return nil, false, err
+	}
+	return level, leveledUp, nil
+}
+
+// awards returns the per point type breakdown an activity of activityType
+// earns. Activity types without rewards fall back to a single award of their
+// PointsValue in the xp point type. The points a client claims never count.
+func awards(activityType *models.ActivityType, xpPointTypeId uint) []*models.UserActivityPoint {
+	var result []*models.UserActivityPoint
+	for _, reward := range activityType.Rewards {
+		if reward.Amount == 0 {
+			continue
+		}
+		result = append(result, &models.UserActivityPoint{
+			PointTypeId: reward.PointTypeId,
+			Amount:      reward.Amount,
+		})
+	}
+	if len(activityType.Rewards) > 0 || xpPointTypeId == 0 {
+		return result
+	}
+
+	if activityType.PointsValue == 0 {
+		return nil
+	}
+	return []*models.UserActivityPoint{{PointTypeId: xpPointTypeId, Amount: activityType.PointsValue}}
+}
@@ -0,0 +1,199 @@
+package user_challenges
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"base/core/logger"
+	"base/core/types"
+	"base/packages/gamification/models"
+	"base/packages/gamification/tenancy"
+	"base/packages/gamification/user_activities"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ChallengeMilestoneEvent announces a models.ChallengeMilestone
+const ChallengeMilestoneEvent = "userchallenges.milestone"
+
+// Listen measures the goal challenges of users as their activities are recorded
+func (s *UserChallengeService) Listen() {
+	s.Emitter.On(user_activities.CreateUserActivityEvent, func(data any) {
+		item, ok := data.(*models.UserActivity)
+		if !ok {
+			return
+		}
+		s.forTenant(item.TenantId).record(item)
+	})
+}
+
+// forTenant returns a copy of the service scoped to a tenant, for work done
+// outside of a request
+func (s *UserChallengeService) forTenant(tenantId uint) *UserChallengeService {
+	return s.WithContext(tenancy.WithTenant(context.Background(), tenantId))
+}
+
+// record measures again every goal challenge the user of an activity is
+// enrolled in and whose window holds the activity
+func (s *UserChallengeService) record(activity *models.UserActivity) {
+	var items []*models.UserChallenge
+	if err := s.DB.Joins("JOIN challenges ON challenges.id = userchallenges.challenge_id AND challenges.deleted_at IS NULL").
+		Where("userchallenges.user_id = ? AND challenges.is_active = ? AND challenges.goal_kind <> ''", activity.UserId, true).
+		Where("challenges.start_date <= ? AND challenges.end_date > ?", activity.CreatedAt, activity.CreatedAt).
+		Where("(challenges.goal_activity_type_id IS NULL OR challenges.goal_activity_type_id = ?)", activity.ActivityTypeId).
+		Find(&items).Error; err != nil {
+		s.Logger.Error("failed to find challenges for progress",
+			logger.String("error", err.Error()),
+			logger.Int("user_id", int(activity.UserId)))
+		return
+	}
+
+	for _, item := range items {
+		if _, err := s.Measure(item.Id); err != nil {
+			s.Logger.Error("failed to measure challenge progress",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(item.Id)))
+		}
+	}
+}
+
+// Measure computes a user's value on the goal of their challenge from the
+// activities in its window, stores it with its percentage of the target and
+// announces every milestone it reaches. A user completes the challenge at 100%.
+func (s *UserChallengeService) Measure(id uint) (*models.UserChallenge, error) {
+	item := &models.UserChallenge{}
+	var reached []int
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(item, id).Error; err != nil {
+			return fmt.Errorf("failed to lock userchallenge: %w", err)
+		}
+		challenge := &models.Challenge{}
+		if err := tx.First(challenge, item.ChallengeId).Error; err != nil {
+			return fmt.Errorf("failed to find challenge: %w", err)
+		}
+		if !challenge.Goal.IsSet() {
+			return nil
+		}
+
+		value, err := goalValue(tx, challenge, item.UserId)
+		if err != nil {
+			return err
+		}
+		percent := challenge.Goal.Percent(value)
+		for _, milestone := range models.ChallengeMilestones {
+			if item.Progress < milestone && percent >= milestone {
+				reached = append(reached, milestone)
+			}
+		}
+
+		updates := map[string]interface{}{"value": value, "progress": percent}
+		if percent >= 100 && item.CompletedAt.IsZero() {
+			item.CompletedAt = types.DateTime{Time: time.Now()}
+			updates["completed_at"] = item.CompletedAt
+		}
+		item.Value, item.Progress = value, percent
+		return tx.Model(item).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, milestone := range reached {
+		s.Emitter.Emit(ChallengeMilestoneEvent, &models.ChallengeMilestone{UserChallenge: item, Milestone: milestone})
+	}
+
+	return item, nil
+}
+
+// goalValue measures a user's value on the goal of a challenge from their
+// activities in its window
+func goalValue(tx *gorm.DB, challenge *models.Challenge, userId uint) (int, error) {
+	goal := challenge.Goal
+	query := tx.Model(&models.UserActivity{}).
+		Select("id", "created_at", "points_earned", "metadata").
+		Where("user_id = ? AND created_at >= ? AND created_at < ?", userId, challenge.StartDate.Time, challenge.EndDate.Time).
+		Where("status = ?", models.UserActivityAccepted)
+	if goal.ActivityTypeId != nil {
+		query = query.Where("activity_type_id = ?", *goal.ActivityTypeId)
+	}
+	var activities []*models.UserActivity
+	if err := query.Order("created_at").Find(&activities).Error; err != nil {
+		return 0, fmt.Errorf("failed to find activities for goal: %w", err)
+	}
+
+	var matched []*models.UserActivity
+	for _, activity := range activities {
+		if goal.Matches(activity.Metadata) {
+			matched = append(matched, activity)
+		}
+	}
+
+	switch goal.Kind {
+	case models.ChallengeGoalActivityCount:
+		return len(matched), nil
+
+	case models.ChallengeGoalPointsSum:
+		if goal.PointTypeId == nil {
+			sum := 0
+			for _, activity := range matched {
+				sum += activity.PointsEarned
+			}
+			return sum, nil
+		}
+		if len(matched) == 0 {
+			return 0, nil
+		}
+		ids := make([]uint, len(matched))
+		for i, activity := range matched {
+			ids[i] = activity.Id
+		}
+		var sum int
+		if err := tx.Model(&models.UserActivityPoint{}).
+			Select("COALESCE(SUM(amount), 0)").
+			Where("user_activity_id IN ? AND point_type_id = ?", ids, *goal.PointTypeId).
+			Scan(&sum).Error; err != nil {
+			return 0, fmt.Errorf("failed to sum activity points: %w", err)
+		}
+		return sum, nil
+
+	case models.ChallengeGoalActiveDays, models.ChallengeGoalStreak:
+		days := activeDays(matched)
+		if goal.Kind == models.ChallengeGoalActiveDays {
+			return len(days), nil
+		}
+		return longestStreak(days), nil
+	}
+
+	return 0, nil
+}
+
+// activeDays returns the distinct UTC days of activities ordered by time
+func activeDays(activities []*models.UserActivity) []time.Time {
+	var days []time.Time
+	for _, activity := range activities {
+		at := activity.CreatedAt.UTC()
+		day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+		if len(days) == 0 || !days[len(days)-1].Equal(day) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// longestStreak returns the longest run of consecutive days in ordered days
+func longestStreak(days []time.Time) int {
+	longest, current := 0, 0
+	for i, day := range days {
+		if i > 0 && day.Equal(days[i-1].AddDate(0, 0, 1)) {
+			current++
+		} else {
+			current = 1
+		}
+		if current > longest {
+			longest = current
+		}
+	}
+	return longest
+}
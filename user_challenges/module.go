@@ -36,12 +36,21 @@ func NewUserChallengeModule(db *gorm.DB, router *gin.RouterGroup, log logger.Log
 	return m
 }
 
+// Init subscribes goal progress to activities
+func (m *Module) Init() error {
+	m.Service.Listen()
+	return nil
+}
+
 func (m *Module) Routes(router *gin.RouterGroup) {
 	m.Controller.Routes(router)
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.UserChallenge{})
+	if err := m.DB.AutoMigrate(&models.UserChallenge{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.UserChallenge{})
 }
 
 func (m *Module) GetModels() []interface{} {
@@ -1,6 +1,8 @@
 package user_challenges
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 
@@ -35,7 +37,35 @@ func NewUserChallengeService(db *gorm.DB, emitter *emitter.Emitter, storage *sto
 	}
 }
 
+func (s *UserChallengeService) WithContext(ctx context.Context) *UserChallengeService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
+// ForUser returns a copy of the service that only sees the rows of userId
+func (s *UserChallengeService) ForUser(userId uint) *UserChallengeService {
+	clone := *s
+	clone.DB = s.DB.Where("user_id = ?", userId).Session(&gorm.Session{})
+	return &clone
+}
+
 func (s *UserChallengeService) Create(req *models.CreateUserChallengeRequest) (*models.UserChallenge, error) {
+	if req.ChallengeKey != "" {
+		challengeId, err := models.ResolveFieldKey(s.DB, &models.Challenge{}, "challenge_key", req.ChallengeKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve challenge key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve challenge: %w", err)
+		}
+		req.ChallengeId = challengeId
+	}
+	if err := models.CheckReferences(s.DB).
+		Active("challenge_id", &models.Challenge{}, req.ChallengeId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check userchallenge references", logger.String("error", err.Error()))
+		return nil, err
+	}
+
 	item := &models.UserChallenge{
 		UserId:        req.UserId,
 		ChallengeId:   req.ChallengeId,
@@ -63,6 +93,14 @@ func (s *UserChallengeService) Update(id uint, req *models.UpdateUserChallengeRe
 			logger.Int("id", int(id)))
 		return nil, fmt.Errorf("failed to find userchallenge: %w", err)
 	}
+	if err := models.CheckReferences(s.DB).
+		Active("challenge_id", &models.Challenge{}, req.ChallengeId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check userchallenge references",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
 
 	// Build updates map
 	updates := make(map[string]interface{})
@@ -114,7 +152,11 @@ func (s *UserChallengeService) Delete(id uint) error {
 
 	// Delete file attachments if any
 
-	if err := s.DB.Delete(item).Error; err != nil {
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
 		s.Logger.Error("failed to delete userchallenge",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
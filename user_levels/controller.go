@@ -1,10 +1,12 @@
 package user_levels
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"base/core/storage"
+	"base/packages/gamification/access"
 	"base/packages/gamification/models"
 
 	"github.com/gin-gonic/gin"
@@ -27,9 +29,9 @@ func (c *UserLevelController) Routes(router *gin.RouterGroup) {
 	router.GET("/user-levels", c.List)        // Paginated list
 	router.GET("/user-levels/all", c.ListAll) // Unpaginated list
 	router.GET("/user-levels/:id", c.Get)
-	router.POST("/user-levels", c.Create)
-	router.PUT("/user-levels/:id", c.Update)
-	router.DELETE("/user-levels/:id", c.Delete)
+	router.POST("/user-levels", access.Require(access.RoleService), c.Create)
+	router.PUT("/user-levels/:id", access.Require(access.RoleService), c.Update)
+	router.DELETE("/user-levels/:id", access.Require(access.RoleService), c.Delete)
 
 	// File/Image attachment endpoints
 
@@ -47,6 +49,7 @@ func (c *UserLevelController) Routes(router *gin.RouterGroup) {
 // @Param user-levels body models.CreateUserLevelRequest true "Create UserLevel request"
 // @Success 201 {object} models.UserLevelResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /user-levels [post]
 func (c *UserLevelController) Create(ctx *gin.Context) {
@@ -56,8 +59,13 @@ func (c *UserLevelController) Create(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Create(&req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
 		return
 	}
@@ -76,6 +84,7 @@ func (c *UserLevelController) Create(ctx *gin.Context) {
 // @Param id path int true "UserLevel id"
 // @Success 200 {object} models.UserLevelResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /user-levels/{id} [get]
 func (c *UserLevelController) Get(ctx *gin.Context) {
@@ -85,7 +94,7 @@ func (c *UserLevelController) Get(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.service(ctx).GetById(uint(id))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -106,6 +115,7 @@ func (c *UserLevelController) Get(ctx *gin.Context) {
 // @Param limit query int false "Number of items per page"
 // @Success 200 {object} types.PaginatedResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /user-levels [get]
 func (c *UserLevelController) List(ctx *gin.Context) {
@@ -129,7 +139,7 @@ func (c *UserLevelController) List(ctx *gin.Context) {
 		}
 	}
 
-	paginatedResponse, err := c.Service.GetAll(page, limit)
+	paginatedResponse, err := c.service(ctx).GetAll(page, limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
 		return
@@ -147,10 +157,11 @@ func (c *UserLevelController) List(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /user-levels/all [get]
 func (c *UserLevelController) ListAll(ctx *gin.Context) {
-	paginatedResponse, err := c.Service.GetAll(nil, nil)
+	paginatedResponse, err := c.service(ctx).GetAll(nil, nil)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
 		return
@@ -171,6 +182,7 @@ func (c *UserLevelController) ListAll(ctx *gin.Context) {
 // @Param user-levels body models.UpdateUserLevelRequest true "Update UserLevel request"
 // @Success 200 {object} models.UserLevelResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /user-levels/{id} [put]
@@ -187,8 +199,13 @@ func (c *UserLevelController) Update(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Update(uint(id), &req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
 	}
@@ -207,6 +224,8 @@ func (c *UserLevelController) Update(ctx *gin.Context) {
 // @Param id path int true "UserLevel id"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
 // @Failure 500 {object} ErrorResponse
 // @Router /user-levels/{id} [delete]
 func (c *UserLevelController) Delete(ctx *gin.Context) {
@@ -216,7 +235,12 @@ func (c *UserLevelController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.Delete(uint(id)); err != nil {
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
 		return
 	}
@@ -224,6 +248,15 @@ func (c *UserLevelController) Delete(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, SuccessResponse{Message: "Item deleted successfully"})
 }
 
+// service scopes the service to the request's tenant and, for end users, to their own rows
+func (c *UserLevelController) service(ctx *gin.Context) *UserLevelService {
+	service := c.Service.WithContext(ctx.Request.Context())
+	if userId, restricted := access.RestrictedTo(ctx); restricted {
+		service = service.ForUser(userId)
+	}
+	return service
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
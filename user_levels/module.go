@@ -41,7 +41,17 @@ func (m *Module) Routes(router *gin.RouterGroup) {
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.UserLevel{})
+	if err := m.DB.AutoMigrate(&models.UserLevel{}); err != nil {
+		return err
+	}
+	// Users below every level used to be stored at level 0, which the
+	// foreign key on current_level_id rejects
+	if err := m.DB.Unscoped().Model(&models.UserLevel{}).
+		Where("current_level_id = ?", 0).
+		Update("current_level_id", nil).Error; err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.UserLevel{})
 }
 
 func (m *Module) GetModels() []interface{} {
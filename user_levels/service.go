@@ -1,8 +1,11 @@
 package user_levels
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
+	"time"
 
 	"base/core/emitter"
 	"base/core/logger"
@@ -11,12 +14,14 @@ import (
 	"base/packages/gamification/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
 	CreateUserLevelEvent = "userlevels.create"
 	UpdateUserLevelEvent = "userlevels.update"
 	DeleteUserLevelEvent = "userlevels.delete"
+	LevelUpEvent         = "userlevels.levelup"
 )
 
 type UserLevelService struct {
@@ -35,10 +40,38 @@ func NewUserLevelService(db *gorm.DB, emitter *emitter.Emitter, storage *storage
 	}
 }
 
+func (s *UserLevelService) WithContext(ctx context.Context) *UserLevelService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
+// ForUser returns a copy of the service that only sees the rows of userId
+func (s *UserLevelService) ForUser(userId uint) *UserLevelService {
+	clone := *s
+	clone.DB = s.DB.Where("user_id = ?", userId).Session(&gorm.Session{})
+	return &clone
+}
+
 func (s *UserLevelService) Create(req *models.CreateUserLevelRequest) (*models.UserLevel, error) {
+	if req.CurrentLevelKey != "" {
+		currentLevelId, err := models.ResolveFieldKey(s.DB, &models.Level{}, "current_level_key", req.CurrentLevelKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve level key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve level: %w", err)
+		}
+		req.CurrentLevelId = currentLevelId
+	}
+	if err := models.CheckReferences(s.DB).
+		Exists("current_level_id", &models.Level{}, req.CurrentLevelId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check userlevel references", logger.String("error", err.Error()))
+		return nil, err
+	}
+
 	item := &models.UserLevel{
 		UserId:         req.UserId,
-		CurrentLevelId: req.CurrentLevelId,
+		CurrentLevelId: &req.CurrentLevelId,
 		CurrentXp:      req.CurrentXp,
 		LastLeveledUp:  req.LastLeveledUp,
 	}
@@ -62,6 +95,14 @@ func (s *UserLevelService) Update(id uint, req *models.UpdateUserLevelRequest) (
 			logger.Int("id", int(id)))
 		return nil, fmt.Errorf("failed to find userlevel: %w", err)
 	}
+	if err := models.CheckReferences(s.DB).
+		Exists("current_level_id", &models.Level{}, req.CurrentLevelId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check userlevel references",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
 
 	// Build updates map
 	updates := make(map[string]interface{})
@@ -110,7 +151,11 @@ func (s *UserLevelService) Delete(id uint) error {
 
 	// Delete file attachments if any
 
-	if err := s.DB.Delete(item).Error; err != nil {
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
 		s.Logger.Error("failed to delete userlevel",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -138,6 +183,21 @@ func (s *UserLevelService) GetById(id uint) (*models.UserLevel, error) {
 	return item, nil
 }
 
+// Current returns the level of the user the service was scoped to with ForUser
+func (s *UserLevelService) Current() (*models.UserLevel, error) {
+	item := &models.UserLevel{}
+
+	query := item.Preload(s.DB)
+
+	if err := query.First(item).Error; err != nil {
+		s.Logger.Error("failed to get current userlevel",
+			logger.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to get current userlevel: %w", err)
+	}
+
+	return item, nil
+}
+
 func (s *UserLevelService) GetAll(page *int, limit *int) (*types.PaginatedResponse, error) {
 	var items []*models.UserLevel
 	var total int64
@@ -197,3 +257,36 @@ func (s *UserLevelService) GetAll(page *int, limit *int) (*types.PaginatedRespon
 		},
 	}, nil
 }
+
+// AddXp adds amount to the user's xp within tx, creating the level row on
+// first use, and moves the user to the highest level whose XpRequired is
+// reached. It reports whether the user changed level.
+func (s *UserLevelService) AddXp(tx *gorm.DB, userId uint, amount int) (*models.UserLevel, bool, error) {
+	item := &models.UserLevel{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userId).
+		First(item).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("failed to find userlevel: %w", err)
+	}
+	item.UserId = userId
+	item.CurrentXp += amount
+
+	level := &models.Level{}
+	err = tx.Where("xp_required <= ?", item.CurrentXp).Order("xp_required DESC").First(level).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("failed to find level: %w", err)
+	}
+
+	leveledUp := level.Id != 0 && (item.CurrentLevelId == nil || level.Id != *item.CurrentLevelId)
+	if leveledUp {
+		item.CurrentLevelId = &level.Id
+		item.LastLeveledUp = types.DateTime{Time: time.Now()}
+	}
+
+	if err := tx.Save(item).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to save userlevel: %w", err)
+	}
+
+	return item, leveledUp, nil
+}
@@ -1,10 +1,13 @@
 package user_points
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"base/core/storage"
+	"base/packages/gamification/access"
 	"base/packages/gamification/models"
 
 	"github.com/gin-gonic/gin"
@@ -26,10 +29,12 @@ func (c *UserPointController) Routes(router *gin.RouterGroup) {
 	// Main CRUD endpoints
 	router.GET("/user-points", c.List)        // Paginated list
 	router.GET("/user-points/all", c.ListAll) // Unpaginated list
+	router.GET("/user-points/expiring", c.Expiring)
+	router.POST("/user-points/transfer", access.Require(access.RoleService, access.RoleUser), c.Transfer)
 	router.GET("/user-points/:id", c.Get)
-	router.POST("/user-points", c.Create)
-	router.PUT("/user-points/:id", c.Update)
-	router.DELETE("/user-points/:id", c.Delete)
+	router.POST("/user-points", access.Require(access.RoleService), c.Create)
+	router.PUT("/user-points/:id", access.Require(access.RoleService), c.Update)
+	router.DELETE("/user-points/:id", access.Require(access.RoleService), c.Delete)
 
 	// File/Image attachment endpoints
 
@@ -47,6 +52,8 @@ func (c *UserPointController) Routes(router *gin.RouterGroup) {
 // @Param user-points body models.CreateUserPointRequest true "Create UserPoint request"
 // @Success 201 {object} models.UserPointResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 402 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /user-points [post]
 func (c *UserPointController) Create(ctx *gin.Context) {
@@ -56,8 +63,17 @@ func (c *UserPointController) Create(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Create(&req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Create(&req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, ErrInsufficientBalance) {
+			ctx.JSON(http.StatusPaymentRequired, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create item: " + err.Error()})
 		return
 	}
@@ -76,6 +92,7 @@ func (c *UserPointController) Create(ctx *gin.Context) {
 // @Param id path int true "UserPoint id"
 // @Success 200 {object} models.UserPointResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /user-points/{id} [get]
 func (c *UserPointController) Get(ctx *gin.Context) {
@@ -85,7 +102,7 @@ func (c *UserPointController) Get(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.service(ctx).GetById(uint(id))
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
 		return
@@ -106,6 +123,7 @@ func (c *UserPointController) Get(ctx *gin.Context) {
 // @Param limit query int false "Number of items per page"
 // @Success 200 {object} types.PaginatedResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /user-points [get]
 func (c *UserPointController) List(ctx *gin.Context) {
@@ -129,7 +147,7 @@ func (c *UserPointController) List(ctx *gin.Context) {
 		}
 	}
 
-	paginatedResponse, err := c.Service.GetAll(page, limit)
+	paginatedResponse, err := c.service(ctx).GetAll(page, limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
 		return
@@ -147,10 +165,11 @@ func (c *UserPointController) List(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {object} types.PaginatedResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /user-points/all [get]
 func (c *UserPointController) ListAll(ctx *gin.Context) {
-	paginatedResponse, err := c.Service.GetAll(nil, nil)
+	paginatedResponse, err := c.service(ctx).GetAll(nil, nil)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch all items: " + err.Error()})
 		return
@@ -159,6 +178,102 @@ func (c *UserPointController) ListAll(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, paginatedResponse)
 }
 
+// ExpiringUserPoints godoc
+// @Summary List points expiring soon
+// @Description Get the point lots of a user that expire within the given number of days
+// @Tags UserPoint
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param user_id query int true "User id"
+// @Param days query int false "Number of days to look ahead, 7 by default"
+// @Success 200 {object} models.ExpiringPointsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /user-points/expiring [get]
+func (c *UserPointController) Expiring(ctx *gin.Context) {
+	userId, err := strconv.ParseUint(ctx.Query("user_id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user_id format"})
+		return
+	}
+
+	days := 7
+	if daysStr := ctx.Query("days"); daysStr != "" {
+		if days, err = strconv.Atoi(daysStr); err != nil || days <= 0 {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid days number"})
+			return
+		}
+	}
+
+	if own, restricted := access.RestrictedTo(ctx); restricted && own != uint(userId) {
+		access.Forbid(ctx)
+		return
+	}
+
+	result, err := c.service(ctx).ExpiringSoon(uint(userId), time.Duration(days)*24*time.Hour)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch expiring points: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// TransferUserPoints godoc
+// @Summary Transfer points between users
+// @Description Move an amount of a transferable point type from one user to another in one transaction
+// @Tags UserPoint
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param transfer body models.TransferUserPointsRequest true "Transfer request"
+// @Success 200 {object} models.PointTransferResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 402 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /user-points/transfer [post]
+func (c *UserPointController) Transfer(ctx *gin.Context) {
+	var req models.TransferUserPointsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// End users may only give away their own points
+	if own, restricted := access.RestrictedTo(ctx); restricted && own != req.FromUserId {
+		access.Forbid(ctx)
+		return
+	}
+
+	result, err := c.Service.WithContext(ctx.Request.Context()).Transfer(&req)
+	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		switch {
+		case errors.Is(err, ErrInsufficientBalance):
+			ctx.JSON(http.StatusPaymentRequired, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrNotTransferable):
+			ctx.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrTransferLimitExceeded):
+			ctx.JSON(http.StatusTooManyRequests, ErrorResponse{Error: err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to transfer points: " + err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
 // UpdateUserPoint godoc
 // @Summary Update a UserPoint
 // @Description Update a UserPoint by its id
@@ -171,6 +286,8 @@ func (c *UserPointController) ListAll(ctx *gin.Context) {
 // @Param user-points body models.UpdateUserPointRequest true "Update UserPoint request"
 // @Success 200 {object} models.UserPointResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 402 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /user-points/{id} [put]
@@ -187,8 +304,17 @@ func (c *UserPointController) Update(ctx *gin.Context) {
 		return
 	}
 
-	item, err := c.Service.Update(uint(id), &req)
+	item, err := c.Service.WithContext(ctx.Request.Context()).Update(uint(id), &req)
 	if err != nil {
+		var invalid *models.ValidationError
+		if errors.As(err, &invalid) {
+			ctx.JSON(http.StatusBadRequest, invalid)
+			return
+		}
+		if errors.Is(err, ErrInsufficientBalance) {
+			ctx.JSON(http.StatusPaymentRequired, ErrorResponse{Error: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update item: " + err.Error()})
 		return
 	}
@@ -207,6 +333,8 @@ func (c *UserPointController) Update(ctx *gin.Context) {
 // @Param id path int true "UserPoint id"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} models.ReferenceError
 // @Failure 500 {object} ErrorResponse
 // @Router /user-points/{id} [delete]
 func (c *UserPointController) Delete(ctx *gin.Context) {
@@ -216,7 +344,12 @@ func (c *UserPointController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Service.Delete(uint(id)); err != nil {
+	if err := c.Service.WithContext(ctx.Request.Context()).Delete(uint(id)); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			ctx.JSON(http.StatusConflict, references)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete item: " + err.Error()})
 		return
 	}
@@ -224,6 +357,15 @@ func (c *UserPointController) Delete(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, SuccessResponse{Message: "Item deleted successfully"})
 }
 
+// service scopes the service to the request's tenant and, for end users, to their own rows
+func (c *UserPointController) service(ctx *gin.Context) *UserPointService {
+	service := c.Service.WithContext(ctx.Request.Context())
+	if userId, restricted := access.RestrictedTo(ctx); restricted {
+		service = service.ForUser(userId)
+	}
+	return service
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
@@ -0,0 +1,266 @@
+package user_points
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"base/core/logger"
+	"base/packages/gamification/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientBalance is returned when a debit would take a balance below
+// zero, or below minus the overdraft limit of its point type
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// OverdraftReference is the reference of the adjustments that raise balances
+// found below their floor when migrating
+const OverdraftReference = "migration:overdraft"
+
+// SettleOverdrafts raises the balances that are below their floor, zero or
+// minus the overdraft limit, to the floor, since they would keep the balance
+// check constraint from being created. Every raise is recorded as an adjust
+// transaction and logged. It runs across tenants, from migrations.
+func (s *UserPointService) SettleOverdrafts() error {
+	migrator := s.DB.Migrator()
+	if !migrator.HasTable(&models.UserPoint{}) {
+		return nil
+	}
+	floor := "current_balance < 0"
+	if migrator.HasColumn(&models.UserPoint{}, "OverdraftLimit") {
+		floor = "current_balance + overdraft_limit < 0"
+	}
+
+	var items []*models.UserPoint
+	if err := s.DB.Unscoped().Where(floor).Find(&items).Error; err != nil {
+		return fmt.Errorf("failed to find overdrawn userpoints: %w", err)
+	}
+
+	for _, item := range items {
+		amount := -(item.CurrentBalance + item.OverdraftLimit)
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.UserPoint{}).Unscoped().
+				Where("id = ?", item.Id).
+				Update("current_balance", gorm.Expr("current_balance + ?", amount)).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.PointTransaction{
+				TenantId:    item.TenantId,
+				UserId:      item.UserId,
+				PointTypeId: item.PointTypeId,
+				Kind:        models.PointTransactionAdjust,
+				Amount:      amount,
+				Reference:   OverdraftReference,
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to settle overdrawn userpoint %d: %w", item.Id, err)
+		}
+		s.Logger.Warn("raised overdrawn userpoint balance to its floor",
+			logger.Int("id", int(item.Id)),
+			logger.Int("user_id", int(item.UserId)),
+			logger.Int("point_type_id", int(item.PointTypeId)),
+			logger.Int("amount", amount))
+	}
+	return nil
+}
+
+// Credit adds amount to the user's balance of a point type within tx and
+// records it as a new lot, which expires according to the point type's
+// policy. The balance row is created on first use and locked for the update
+// so concurrent credits do not lose increments.
+func (s *UserPointService) Credit(tx *gorm.DB, userId uint, pointTypeId uint, amount int, kind string, reference string) (*models.UserPoint, error) {
+	pointType := &models.PointType{}
+	if err := tx.First(pointType, pointTypeId).Error; err != nil {
+		return nil, fmt.Errorf("failed to find point type: %w", err)
+	}
+
+	item, err := lockBalance(tx, userId, pointTypeId, true)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"current_balance": gorm.Expr("current_balance + ?", amount),
+	}
+	// Transfers and adjustments are not earned by the receiver
+	if kind == models.PointTransactionEarn {
+		updates["lifetime_earned"] = gorm.Expr("lifetime_earned + ?", amount)
+		item.LifetimeEarned += amount
+	}
+	if err := tx.Model(item).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to credit userpoint: %w", err)
+	}
+	item.CurrentBalance += amount
+
+	now := time.Now()
+	lot := &models.PointLot{
+		UserId:      userId,
+		PointTypeId: pointTypeId,
+		Amount:      amount,
+		Remaining:   amount,
+		EarnedAt:    now,
+	}
+	if pointType.ExpiresAfterDays > 0 {
+		expiresAt := now.AddDate(0, 0, pointType.ExpiresAfterDays)
+		lot.ExpiresAt = &expiresAt
+	}
+	if err := tx.Create(lot).Error; err != nil {
+		return nil, fmt.Errorf("failed to create point lot: %w", err)
+	}
+
+	if err := recordTransaction(tx, userId, pointTypeId, &lot.Id, kind, amount, reference); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// Debit removes amount from the user's balance of a point type within tx,
+// consuming lots soonest expiring first. It fails with ErrInsufficientBalance
+// when the balance plus the overdraft limit does not cover the amount.
+func (s *UserPointService) Debit(tx *gorm.DB, userId uint, pointTypeId uint, amount int, kind string, reference string) (*models.UserPoint, error) {
+	item, err := lockBalance(tx, userId, pointTypeId, true)
+	if err != nil {
+		return nil, err
+	}
+	if item.CurrentBalance+item.OverdraftLimit < amount {
+		return nil, ErrInsufficientBalance
+	}
+
+	if err := consumeLots(tx, userId, pointTypeId, amount); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(item).Update("current_balance", gorm.Expr("current_balance - ?", amount)).Error; err != nil {
+		return nil, fmt.Errorf("failed to debit userpoint: %w", err)
+	}
+	item.CurrentBalance -= amount
+
+	if err := recordTransaction(tx, userId, pointTypeId, nil, kind, -amount, reference); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// XpPointTypeId returns the id of the point type that feeds user levels, or 0 if none is designated
+func (s *UserPointService) XpPointTypeId(tx *gorm.DB) (uint, error) {
+	var ids []uint
+	if err := tx.Model(&models.PointType{}).Where("is_xp_source = ?", true).Limit(1).Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to find xp point type: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return ids[0], nil
+}
+
+// ExpiringSoon returns the lots of a user that expire within the given duration
+func (s *UserPointService) ExpiringSoon(userId uint, within time.Duration) (*models.ExpiringPointsResponse, error) {
+	now := time.Now()
+	result := &models.ExpiringPointsResponse{
+		UserId: userId,
+		Until:  now.Add(within),
+		Lots:   []*models.PointLot{},
+	}
+
+	if err := s.DB.Preload("PointType").
+		Where("user_id = ? AND remaining > 0 AND expires_at > ? AND expires_at <= ?", userId, now, result.Until).
+		Order("expires_at").
+		Find(&result.Lots).Error; err != nil {
+		return nil, fmt.Errorf("failed to get expiring points: %w", err)
+	}
+
+	for _, lot := range result.Lots {
+		result.Total += lot.Remaining
+	}
+
+	return result, nil
+}
+
+// lockBalance loads the balance row of a user and point type for update,
+// creating it with the point type's overdraft limit when create is set
+func lockBalance(tx *gorm.DB, userId uint, pointTypeId uint, create bool) (*models.UserPoint, error) {
+	item := &models.UserPoint{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND point_type_id = ?", userId, pointTypeId).
+		First(item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) && create {
+		overdraftLimit, err := pointTypeOverdraftLimit(tx, pointTypeId)
+		if err != nil {
+			return nil, err
+		}
+		item = &models.UserPoint{
+			UserId:         userId,
+			PointTypeId:    pointTypeId,
+			OverdraftLimit: overdraftLimit,
+		}
+		if err := tx.Create(item).Error; err != nil {
+			return nil, fmt.Errorf("failed to create userpoint: %w", err)
+		}
+		return item, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func pointTypeOverdraftLimit(tx *gorm.DB, pointTypeId uint) (int, error) {
+	var limits []int
+	if err := tx.Model(&models.PointType{}).Where("id = ?", pointTypeId).Limit(1).Pluck("overdraft_limit", &limits).Error; err != nil {
+		return 0, fmt.Errorf("failed to find point type overdraft limit: %w", err)
+	}
+	if len(limits) == 0 {
+		return 0, nil
+	}
+	return limits[0], nil
+}
+
+// consumeLots takes amount out of the user's open lots, soonest expiring
+// first and lots without expiry last. Balances predating lots may not be
+// fully covered by lots, in which case the remainder is simply untracked.
+func consumeLots(tx *gorm.DB, userId uint, pointTypeId uint, amount int) error {
+	var lots []*models.PointLot
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND point_type_id = ? AND remaining > 0", userId, pointTypeId).
+		Order("expires_at IS NULL, expires_at, earned_at, id").
+		Find(&lots).Error; err != nil {
+		return fmt.Errorf("failed to find point lots: %w", err)
+	}
+
+	for _, lot := range lots {
+		if amount == 0 {
+			break
+		}
+		taken := lot.Remaining
+		if taken > amount {
+			taken = amount
+		}
+		if err := tx.Model(lot).Update("remaining", lot.Remaining-taken).Error; err != nil {
+			return fmt.Errorf("failed to consume point lot: %w", err)
+		}
+		amount -= taken
+	}
+
+	return nil
+}
+
+func recordTransaction(tx *gorm.DB, userId uint, pointTypeId uint, lotId *uint, kind string, amount int, reference string) error {
+	transaction := &models.PointTransaction{
+		UserId:      userId,
+		PointTypeId: pointTypeId,
+		PointLotId:  lotId,
+		Kind:        kind,
+		Amount:      amount,
+		Reference:   reference,
+	}
+	if err := tx.Create(transaction).Error; err != nil {
+		return fmt.Errorf("failed to record point transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package user_points_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"base/core/emitter"
+	"base/packages/gamification/internal/testdb"
+	"base/packages/gamification/models"
+	"base/packages/gamification/user_points"
+
+	"gorm.io/gorm"
+)
+
+// newLedger returns the unscoped database the schedulers run on, the
+// database of tenant 1 and a service on the unscoped one
+func newLedger(t *testing.T) (*gorm.DB, *gorm.DB, *user_points.UserPointService) {
+	t.Helper()
+	base := testdb.Open(t, &models.PointType{}, &models.UserPoint{}, &models.PointLot{}, &models.PointTransaction{}, &models.PointTransfer{})
+	return base, testdb.ForTenant(base, 1), user_points.NewUserPointService(base, &emitter.Emitter{}, nil, testdb.Logger{})
+}
+
+func newPointType(t *testing.T, db *gorm.DB, pointType *models.PointType) *models.PointType {
+	t.Helper()
+	if err := db.Create(pointType).Error; err != nil {
+		t.Fatalf("failed to create point type: %v", err)
+	}
+	return pointType
+}
+
+func credit(t *testing.T, db *gorm.DB, service *user_points.UserPointService, userId, pointTypeId uint, amount int) {
+	t.Helper()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		_, err := service.Credit(tx, userId, pointTypeId, amount, models.PointTransactionEarn, "test")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to credit: %v", err)
+	}
+}
+
+func debit(db *gorm.DB, service *user_points.UserPointService, userId, pointTypeId uint, amount int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		_, err := service.Debit(tx, userId, pointTypeId, amount, models.PointTransactionSpend, "test")
+		return err
+	})
+}
+
+func balanceOf(t *testing.T, db *gorm.DB, userId, pointTypeId uint) *models.UserPoint {
+	t.Helper()
+	item := &models.UserPoint{}
+	if err := db.Where("user_id = ? AND point_type_id = ?", userId, pointTypeId).Take(item).Error; err != nil {
+		t.Fatalf("failed to find balance: %v", err)
+	}
+	return item
+}
+
+// ledgerSum adds up the transactions of a user's balance
+func ledgerSum(t *testing.T, db *gorm.DB, userId, pointTypeId uint) int {
+	t.Helper()
+	var sum int
+	if err := db.Model(&models.PointTransaction{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("user_id = ? AND point_type_id = ?", userId, pointTypeId).
+		Scan(&sum).Error; err != nil {
+		t.Fatalf("failed to sum transactions: %v", err)
+	}
+	return sum
+}
+
+func TestDebitConsumesSoonestExpiringLots(t *testing.T) {
+	_, db, service := newLedger(t)
+	coins := newPointType(t, db, &models.PointType{Key: "coins", Name: "Coins"})
+	credit(t, db, service, 7, coins.Id, 10)
+	if err := db.Model(coins).Update("expires_after_days", 5).Error; err != nil {
+		t.Fatalf("failed to update point type: %v", err)
+	}
+	credit(t, db, service, 7, coins.Id, 10)
+
+	if err := debit(db, service, 7, coins.Id, 15); err != nil {
+		t.Fatalf("failed to debit: %v", err)
+	}
+
+	var lots []*models.PointLot
+	if err := db.Order("id").Find(&lots).Error; err != nil {
+		t.Fatalf("failed to find lots: %v", err)
+	}
+	if len(lots) != 2 || lots[0].Remaining != 5 || lots[1].Remaining != 0 {
+		t.Errorf("lots = %+v, want the expiring lot used up first", lots)
+	}
+	if balance := balanceOf(t, db, 7, coins.Id); balance.CurrentBalance != 5 || balance.LifetimeEarned != 20 {
+		t.Errorf("balance = %d earned %d, want 5 earned 20", balance.CurrentBalance, balance.LifetimeEarned)
+	}
+	if sum := ledgerSum(t, db, 7, coins.Id); sum != 5 {
+		t.Errorf("transactions add up to %d, want the balance 5", sum)
+	}
+}
+
+func TestDebitOverdraft(t *testing.T) {
+	_, db, service := newLedger(t)
+	coins := newPointType(t, db, &models.PointType{Key: "coins", Name: "Coins", OverdraftLimit: 20})
+
+	if err := debit(db, service, 7, coins.Id, 15); err != nil {
+		t.Fatalf("debit within the overdraft: %v", err)
+	}
+	if err := debit(db, service, 7, coins.Id, 10); !errors.Is(err, user_points.ErrInsufficientBalance) {
+		t.Fatalf("debit past the overdraft: err = %v, want %v", err, user_points.ErrInsufficientBalance)
+	}
+
+	if balance := balanceOf(t, db, 7, coins.Id); balance.CurrentBalance != -15 {
+		t.Errorf("balance = %d, want -15", balance.CurrentBalance)
+	}
+	if sum := ledgerSum(t, db, 7, coins.Id); sum != -15 {
+		t.Errorf("transactions add up to %d, want -15", sum)
+	}
+}
+
+func TestExpireLots(t *testing.T) {
+	base, db, service := newLedger(t)
+	coins := newPointType(t, db, &models.PointType{Key: "coins", Name: "Coins", ExpiresAfterDays: 5})
+	// The balance of the first lot is deleted, which must not hold up the next
+	credit(t, db, service, 8, coins.Id, 10)
+	if err := db.Delete(balanceOf(t, db, 8, coins.Id)).Error; err != nil {
+		t.Fatalf("failed to delete balance: %v", err)
+	}
+	credit(t, db, service, 7, coins.Id, 10)
+	if err := debit(db, service, 7, coins.Id, 4); err != nil {
+		t.Fatalf("failed to debit: %v", err)
+	}
+
+	if expired, err := service.ExpireLots(time.Now().AddDate(0, 0, 4)); err != nil || expired != 0 {
+		t.Fatalf("expired %d lots before their expiry, err = %v", expired, err)
+	}
+	expired, err := service.ExpireLots(time.Now().AddDate(0, 0, 6))
+	if err != nil {
+		t.Fatalf("failed to expire lots: %v", err)
+	}
+	if expired != 1 {
+		t.Errorf("expired %d lots, want 1", expired)
+	}
+
+	if balance := balanceOf(t, db, 7, coins.Id); balance.CurrentBalance != 0 {
+		t.Errorf("balance = %d, want 0", balance.CurrentBalance)
+	}
+	if sum := ledgerSum(t, db, 7, coins.Id); sum != 0 {
+		t.Errorf("transactions add up to %d, want 0", sum)
+	}
+	var open int64
+	if err := base.Model(&models.PointLot{}).Where("remaining > 0").Count(&open).Error; err != nil {
+		t.Fatalf("failed to count lots: %v", err)
+	}
+	if open != 0 {
+		t.Errorf("%d lots left open, want the lot of the deleted balance closed too", open)
+	}
+}
+
+func TestDecayBalances(t *testing.T) {
+	_, db, service := newLedger(t)
+	coins := newPointType(t, db, &models.PointType{Key: "coins", Name: "Coins", DecayPercent: 10, DecayPeriodDays: 7})
+	credit(t, db, service, 7, coins.Id, 100)
+
+	now := time.Now().AddDate(0, 0, 8)
+	decayed, err := service.DecayBalances(now)
+	if err != nil {
+		t.Fatalf("failed to decay balances: %v", err)
+	}
+	if decayed != 1 {
+		t.Errorf("decayed %d balances, want 1", decayed)
+	}
+	// The next decay waits for another period
+	if decayed, err := service.DecayBalances(now.AddDate(0, 0, 1)); err != nil || decayed != 0 {
+		t.Errorf("decayed %d balances again within the period, err = %v", decayed, err)
+	}
+
+	if balance := balanceOf(t, db, 7, coins.Id); balance.CurrentBalance != 90 {
+		t.Errorf("balance = %d, want 90", balance.CurrentBalance)
+	}
+	if sum := ledgerSum(t, db, 7, coins.Id); sum != 90 {
+		t.Errorf("transactions add up to %d, want 90", sum)
+	}
+}
@@ -36,14 +36,31 @@ func NewUserPointModule(db *gorm.DB, router *gin.RouterGroup, log logger.Logger,
 	return m
 }
 
+// Init starts the background job that expires and decays points
+func (m *Module) Init() error {
+	go m.Service.RunScheduler(SchedulerInterval)
+	return nil
+}
+
 func (m *Module) Routes(router *gin.RouterGroup) {
 	m.Controller.Routes(router)
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.UserPoint{})
+	// Balances below their floor would keep the balance check constraint of
+	// userpoints from being created, so they are settled in the ledger first
+	if err := m.DB.AutoMigrate(&models.PointLot{}, &models.PointTransaction{}, &models.PointTransfer{}); err != nil {
+		return err
+	}
+	if err := m.Service.SettleOverdrafts(); err != nil {
+		return err
+	}
+	if err := m.DB.AutoMigrate(&models.UserPoint{}); err != nil {
+		return err
+	}
+	return models.MigrateRelations(m.DB, &models.UserPoint{}, &models.PointLot{}, &models.PointTransaction{}, &models.PointTransfer{})
 }
 
 func (m *Module) GetModels() []interface{} {
-	return []interface{}{&models.UserPoint{}}
+	return []interface{}{&models.UserPoint{}, &models.PointLot{}, &models.PointTransaction{}, &models.PointTransfer{}}
 }
@@ -0,0 +1,230 @@
+package user_points
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"base/core/logger"
+	"base/packages/gamification/models"
+	"base/packages/gamification/tenancy"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ExpirePointsEvent = "userpoints.expire"
+	DecayPointsEvent  = "userpoints.decay"
+)
+
+// SchedulerInterval is how often expired lots and decaying balances are processed
+const SchedulerInterval = time.Hour
+
+// expireBatchSize bounds how many lots are loaded per expiration round
+const expireBatchSize = 500
+
+// RunScheduler expires lots and applies decay every interval. It blocks, so
+// callers run it in its own goroutine.
+func (s *UserPointService) RunScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if _, err := s.ExpireLots(now); err != nil {
+			s.Logger.Error("failed to expire point lots", logger.String("error", err.Error()))
+		}
+		if _, err := s.DecayBalances(now); err != nil {
+			s.Logger.Error("failed to decay point balances", logger.String("error", err.Error()))
+		}
+	}
+}
+
+// ExpireLots removes what is left of every lot that expired at now from its
+// balance and writes a compensating expire transaction. A lot that fails does
+// not hold up the others; their errors are returned together. It returns the
+// number of lots expired.
+func (s *UserPointService) ExpireLots(now time.Time) (int, error) {
+	expired := 0
+	lastId := uint(0)
+	var failures []error
+	for {
+		var lots []*models.PointLot
+		if err := s.DB.Where("id > ? AND remaining > 0 AND expires_at <= ?", lastId, now).
+			Order("id").
+			Limit(expireBatchSize).
+			Find(&lots).Error; err != nil {
+			failures = append(failures, fmt.Errorf("failed to find expired point lots: %w", err))
+			return expired, errors.Join(failures...)
+		}
+		if len(lots) == 0 {
+			return expired, errors.Join(failures...)
+		}
+
+		for _, lot := range lots {
+			lastId = lot.Id
+			var transaction *models.PointTransaction
+			err := s.forTenant(lot.TenantId).Transaction(func(tx *gorm.DB) error {
+				var err error
+				transaction, err = expireLot(tx, lot.Id)
+				return err
+			})
+			if err != nil {
+				s.Logger.Error("failed to expire point lot",
+					logger.String("error", err.Error()),
+					logger.Int("id", int(lot.Id)))
+				failures = append(failures, fmt.Errorf("point lot %d: %w", lot.Id, err))
+				continue
+			}
+			if transaction != nil {
+				expired++
+				s.Emitter.Emit(ExpirePointsEvent, transaction)
+			}
+		}
+	}
+}
+
+// DecayBalances takes DecayPercent off every balance of a decaying point type
+// whose last decay is at least DecayPeriodDays before now. A balance that
+// fails does not hold up the others; their errors are returned together. It
+// returns the number of balances decayed.
+func (s *UserPointService) DecayBalances(now time.Time) (int, error) {
+	var pointTypes []*models.PointType
+	if err := s.DB.Where("decay_percent > 0 AND decay_period_days > 0").Find(&pointTypes).Error; err != nil {
+		return 0, fmt.Errorf("failed to find decaying point types: %w", err)
+	}
+
+	decayed := 0
+	var failures []error
+	for _, pointType := range pointTypes {
+		cutoff := now.AddDate(0, 0, -pointType.DecayPeriodDays)
+
+		var balances []*models.UserPoint
+		if err := s.DB.Where("point_type_id = ? AND COALESCE(last_decayed_at, created_at) <= ?", pointType.Id, cutoff).
+			Find(&balances).Error; err != nil {
+			failures = append(failures, fmt.Errorf("failed to find balances to decay: %w", err))
+			continue
+		}
+
+		for _, balance := range balances {
+			var transaction *models.PointTransaction
+			err := s.forTenant(balance.TenantId).Transaction(func(tx *gorm.DB) error {
+				var err error
+				transaction, err = decayBalance(tx, balance.Id, pointType.DecayPercent, now)
+				return err
+			})
+			if err != nil {
+				s.Logger.Error("failed to decay point balance",
+					logger.String("error", err.Error()),
+					logger.Int("id", int(balance.Id)))
+				failures = append(failures, fmt.Errorf("userpoint %d: %w", balance.Id, err))
+				continue
+			}
+			if transaction != nil {
+				decayed++
+				s.Emitter.Emit(DecayPointsEvent, transaction)
+			}
+		}
+	}
+
+	return decayed, errors.Join(failures...)
+}
+
+// forTenant scopes the writes for one row to the row's tenant, since the
+// scheduler itself runs across all tenants
+func (s *UserPointService) forTenant(tenantId uint) *gorm.DB {
+	return s.DB.WithContext(tenancy.WithTenant(context.Background(), tenantId))
+}
+
+// expireLot zeroes a lot and debits what was left of it. It returns nil when
+// the lot was consumed in the meantime, or when its balance was deleted, in
+// which case the lot is closed with nothing left to debit.
+func expireLot(tx *gorm.DB, lotId uint) (*models.PointTransaction, error) {
+	lot := &models.PointLot{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(lot, lotId).Error; err != nil {
+		return nil, fmt.Errorf("failed to lock point lot: %w", err)
+	}
+	if lot.Remaining <= 0 {
+		return nil, nil
+	}
+
+	balance, err := lockBalance(tx, lot.UserId, lot.PointTypeId, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := tx.Model(lot).Update("remaining", 0).Error; err != nil {
+			return nil, fmt.Errorf("failed to close point lot: %w", err)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock userpoint: %w", err)
+	}
+
+	// Never expire more than the balance still holds
+	amount := lot.Remaining
+	if amount > balance.CurrentBalance {
+		amount = balance.CurrentBalance
+	}
+	if amount < 0 {
+		amount = 0
+	}
+
+	if err := tx.Model(lot).Update("remaining", 0).Error; err != nil {
+		return nil, fmt.Errorf("failed to expire point lot: %w", err)
+	}
+	if err := tx.Model(balance).Update("current_balance", gorm.Expr("current_balance - ?", amount)).Error; err != nil {
+		return nil, fmt.Errorf("failed to debit expired points: %w", err)
+	}
+
+	transaction := &models.PointTransaction{
+		UserId:      lot.UserId,
+		PointTypeId: lot.PointTypeId,
+		PointLotId:  &lot.Id,
+		Kind:        models.PointTransactionExpire,
+		Amount:      -amount,
+		Reference:   fmt.Sprintf("pointlot:%d", lot.Id),
+	}
+	if err := tx.Create(transaction).Error; err != nil {
+		return nil, fmt.Errorf("failed to record point transaction: %w", err)
+	}
+
+	return transaction, nil
+}
+
+// decayBalance takes percent of a balance off and marks it decayed at now.
+// It returns nil when the decayed amount rounds down to zero.
+func decayBalance(tx *gorm.DB, balanceId uint, percent int, now time.Time) (*models.PointTransaction, error) {
+	balance := &models.UserPoint{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(balance, balanceId).Error; err != nil {
+		return nil, fmt.Errorf("failed to lock userpoint: %w", err)
+	}
+
+	if err := tx.Model(balance).Update("last_decayed_at", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to mark userpoint decayed: %w", err)
+	}
+
+	amount := balance.CurrentBalance * percent / 100
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	if err := consumeLots(tx, balance.UserId, balance.PointTypeId, amount); err != nil {
+		return nil, err
+	}
+	if err := tx.Model(balance).Update("current_balance", gorm.Expr("current_balance - ?", amount)).Error; err != nil {
+		return nil, fmt.Errorf("failed to debit decayed points: %w", err)
+	}
+
+	transaction := &models.PointTransaction{
+		UserId:      balance.UserId,
+		PointTypeId: balance.PointTypeId,
+		Kind:        models.PointTransactionDecay,
+		Amount:      -amount,
+		Reference:   fmt.Sprintf("userpoint:%d", balance.Id),
+	}
+	if err := tx.Create(transaction).Error; err != nil {
+		return nil, fmt.Errorf("failed to record point transaction: %w", err)
+	}
+
+	return transaction, nil
+}
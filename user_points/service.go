@@ -1,8 +1,11 @@
 package user_points
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
+	"strconv"
 
 	"base/core/emitter"
 	"base/core/logger"
@@ -35,12 +38,50 @@ func NewUserPointService(db *gorm.DB, emitter *emitter.Emitter, storage *storage
 	}
 }
 
+func (s *UserPointService) WithContext(ctx context.Context) *UserPointService {
+	clone := *s
+	clone.DB = s.DB.WithContext(ctx)
+	return &clone
+}
+
+// ForUser returns a copy of the service that only sees the rows of userId
+func (s *UserPointService) ForUser(userId uint) *UserPointService {
+	clone := *s
+	clone.DB = s.DB.Where("user_id = ?", userId).Session(&gorm.Session{})
+	return &clone
+}
+
 func (s *UserPointService) Create(req *models.CreateUserPointRequest) (*models.UserPoint, error) {
+	if req.PointTypeKey != "" {
+		pointTypeId, err := models.ResolveFieldKey(s.DB, &models.PointType{}, "point_type_key", req.PointTypeKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve point type key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve point type: %w", err)
+		}
+		req.PointTypeId = pointTypeId
+	}
+	if err := models.CheckReferences(s.DB).
+		Exists("point_type_id", &models.PointType{}, req.PointTypeId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check userpoint references", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	overdraftLimit, err := pointTypeOverdraftLimit(s.DB, req.PointTypeId)
+	if err != nil {
+		s.Logger.Error("failed to create userpoint", logger.String("error", err.Error()))
+		return nil, err
+	}
+	if req.CurrentBalance < -overdraftLimit {
+		return nil, ErrInsufficientBalance
+	}
+
 	item := &models.UserPoint{
 		UserId:         req.UserId,
 		PointTypeId:    req.PointTypeId,
 		CurrentBalance: req.CurrentBalance,
 		LifetimeEarned: req.LifetimeEarned,
+		OverdraftLimit: overdraftLimit,
 	}
 
 	if err := s.DB.Create(item).Error; err != nil {
@@ -62,6 +103,14 @@ func (s *UserPointService) Update(id uint, req *models.UpdateUserPointRequest) (
 			logger.Int("id", int(id)))
 		return nil, fmt.Errorf("failed to find userpoint: %w", err)
 	}
+	if err := models.CheckReferences(s.DB).
+		Exists("point_type_id", &models.PointType{}, req.PointTypeId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check userpoint references",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
 
 	// Build updates map
 	updates := make(map[string]interface{})
@@ -71,14 +120,43 @@ func (s *UserPointService) Update(id uint, req *models.UpdateUserPointRequest) (
 	if req.PointTypeId != 0 {
 		updates["point_type_id"] = req.PointTypeId
 	}
-	if req.CurrentBalance != "" {
-		updates["current_balance"] = req.CurrentBalance
-	}
 	if req.LifetimeEarned != "" {
 		updates["lifetime_earned"] = req.LifetimeEarned
 	}
 
-	if err := s.DB.Model(item).Updates(updates).Error; err != nil {
+	// The balance itself only changes through the ledger, so the change is
+	// checked against the overdraft limit and recorded as an adjustment
+	var balance *int
+	if req.CurrentBalance != "" {
+		value, err := strconv.Atoi(req.CurrentBalance)
+		if err != nil {
+			return nil, fmt.Errorf("invalid current_balance: %w", err)
+		}
+		balance = &value
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if balance != nil {
+			if err := s.adjust(tx, item, *balance); err != nil {
+				return err
+			}
+		}
+		if req.PointTypeId != 0 && req.PointTypeId != item.PointTypeId {
+			overdraftLimit, err := pointTypeOverdraftLimit(tx, req.PointTypeId)
+			if err != nil {
+				return err
+			}
+			updates["overdraft_limit"] = overdraftLimit
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(item).Updates(updates).Error
+	})
+	if errors.Is(err, ErrInsufficientBalance) {
+		return nil, err
+	}
+	if err != nil {
 		s.Logger.Error("failed to update userpoint",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
@@ -99,6 +177,20 @@ func (s *UserPointService) Update(id uint, req *models.UpdateUserPointRequest) (
 	return result, nil
 }
 
+// adjust credits or debits the difference between a balance and its new value
+func (s *UserPointService) adjust(tx *gorm.DB, item *models.UserPoint, balance int) error {
+	reference := fmt.Sprintf("userpoint:%d", item.Id)
+	delta := balance - item.CurrentBalance
+	var err error
+	switch {
+	case delta > 0:
+		_, err = s.Credit(tx, item.UserId, item.PointTypeId, delta, models.PointTransactionAdjust, reference)
+	case delta < 0:
+		_, err = s.Debit(tx, item.UserId, item.PointTypeId, -delta, models.PointTransactionAdjust, reference)
+	}
+	return err
+}
+
 func (s *UserPointService) Delete(id uint) error {
 	item := &models.UserPoint{}
 	if err := s.DB.First(item, id).Error; err != nil {
@@ -110,7 +202,11 @@ func (s *UserPointService) Delete(id uint) error {
 
 	// Delete file attachments if any
 
-	if err := s.DB.Delete(item).Error; err != nil {
+	if err := models.Delete(s.DB, item); err != nil {
+		var references *models.ReferenceError
+		if errors.As(err, &references) {
+			return err
+		}
 		s.Logger.Error("failed to delete userpoint",
 			logger.String("error", err.Error()),
 			logger.Int("id", int(id)))
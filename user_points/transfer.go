@@ -0,0 +1,133 @@
+package user_points
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"base/core/logger"
+	"base/packages/gamification/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TransferPointsEvent = "points.transferred"
+)
+
+var (
+	// ErrNotTransferable is returned when the point type does not allow transfers
+	ErrNotTransferable = errors.New("point type is not transferable")
+
+	// ErrTransferLimitExceeded is returned when a transfer exceeds the sender's daily limit
+	ErrTransferLimitExceeded = errors.New("daily transfer limit exceeded")
+)
+
+// Transfer moves points of one point type from one user to another in a
+// single transaction. Both balance changes reference the stored transfer.
+func (s *UserPointService) Transfer(req *models.TransferUserPointsRequest) (*models.PointTransferResponse, error) {
+	if req.PointTypeKey != "" {
+		pointTypeId, err := models.ResolveFieldKey(s.DB, &models.PointType{}, "point_type_key", req.PointTypeKey)
+		if err != nil {
+			s.Logger.Error("failed to resolve point type key", logger.String("error", err.Error()))
+			return nil, fmt.Errorf("failed to resolve point type: %w", err)
+		}
+		req.PointTypeId = pointTypeId
+	}
+	if err := models.CheckReferences(s.DB).
+		Exists("point_type_id", &models.PointType{}, req.PointTypeId).
+		Err(); err != nil {
+		s.Logger.Error("failed to check transfer references", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	pointType := &models.PointType{}
+	if err := s.DB.First(pointType, req.PointTypeId).Error; err != nil {
+		s.Logger.Error("failed to find point type for transfer",
+			logger.String("error", err.Error()),
+			logger.Int("point_type_id", int(req.PointTypeId)))
+		return nil, fmt.Errorf("failed to find point type: %w", err)
+	}
+	if !pointType.IsTransferable {
+		return nil, ErrNotTransferable
+	}
+
+	transfer := &models.PointTransfer{
+		FromUserId:  req.FromUserId,
+		ToUserId:    req.ToUserId,
+		PointTypeId: req.PointTypeId,
+		Amount:      req.Amount,
+		Note:        req.Note,
+	}
+	var from, to *models.UserPoint
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock both balances in user id order so crossing transfers cannot
+		// deadlock, and so that concurrent transfers of the sender read the
+		// daily limit one after the other
+		userIds := []uint{req.FromUserId, req.ToUserId}
+		if req.ToUserId < req.FromUserId {
+			userIds = []uint{req.ToUserId, req.FromUserId}
+		}
+		for _, userId := range userIds {
+			if _, err := lockBalance(tx, userId, req.PointTypeId, true); err != nil {
+				return fmt.Errorf("failed to lock userpoint: %w", err)
+			}
+		}
+
+		if pointType.DailyTransferLimit > 0 {
+			sent, err := s.sentToday(tx, req.FromUserId, req.PointTypeId)
+			if err != nil {
+				return err
+			}
+			if sent+req.Amount > pointType.DailyTransferLimit {
+				return ErrTransferLimitExceeded
+			}
+		}
+
+		if err := tx.Create(transfer).Error; err != nil {
+			return err
+		}
+		reference := fmt.Sprintf("pointtransfer:%d", transfer.Id)
+
+		var err error
+		if from, err = s.Debit(tx, req.FromUserId, req.PointTypeId, req.Amount, models.PointTransactionTransferOut, reference); err != nil {
+			return err
+		}
+		to, err = s.Credit(tx, req.ToUserId, req.PointTypeId, req.Amount, models.PointTransactionTransferIn, reference)
+		return err
+	})
+	if err != nil {
+		s.Logger.Error("failed to transfer points",
+			logger.String("error", err.Error()),
+			logger.Int("from_user_id", int(req.FromUserId)),
+			logger.Int("to_user_id", int(req.ToUserId)))
+		return nil, fmt.Errorf("failed to transfer points: %w", err)
+	}
+
+	result := &models.PointTransferResponse{
+		Transfer: transfer,
+		From:     from.ToResponse(),
+		To:       to.ToResponse(),
+	}
+
+	// Emit transfer event
+	s.Emitter.Emit(TransferPointsEvent, result)
+
+	return result, nil
+}
+
+// sentToday returns how many points of a type the user transferred since the start of the day
+func (s *UserPointService) sentToday(tx *gorm.DB, userId uint, pointTypeId uint) (int, error) {
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var sent int
+	if err := tx.Model(&models.PointTransfer{}).
+		Where("from_user_id = ? AND point_type_id = ? AND created_at >= ?", userId, pointTypeId, startOfDay).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&sent).Error; err != nil {
+		return 0, fmt.Errorf("failed to sum today's transfers: %w", err)
+	}
+	return sent, nil
+}
@@ -0,0 +1,67 @@
+package user_points_test
+
+import (
+	"errors"
+	"testing"
+
+	"base/packages/gamification/models"
+	"base/packages/gamification/user_points"
+)
+
+func TestTransfer(t *testing.T) {
+	_, db, service := newLedger(t)
+	service = service.WithContext(db.Statement.Context)
+	coins := newPointType(t, db, &models.PointType{Key: "coins", Name: "Coins", IsTransferable: true, DailyTransferLimit: 50})
+	credit(t, db, service, 7, coins.Id, 100)
+
+	result, err := service.Transfer(&models.TransferUserPointsRequest{FromUserId: 7, ToUserId: 3, PointTypeKey: "coins", Amount: 30})
+	if err != nil {
+		t.Fatalf("failed to transfer: %v", err)
+	}
+	if result.From.CurrentBalance != 70 || result.To.CurrentBalance != 30 {
+		t.Errorf("balances = %d and %d, want 70 and 30", result.From.CurrentBalance, result.To.CurrentBalance)
+	}
+	// Transfers are not earned by the receiver
+	if result.To.LifetimeEarned != 0 {
+		t.Errorf("receiver earned %d, want 0", result.To.LifetimeEarned)
+	}
+
+	// The day's transfers count towards the limit
+	_, err = service.Transfer(&models.TransferUserPointsRequest{FromUserId: 7, ToUserId: 3, PointTypeId: coins.Id, Amount: 21})
+	if !errors.Is(err, user_points.ErrTransferLimitExceeded) {
+		t.Errorf("transfer past the daily limit: err = %v, want %v", err, user_points.ErrTransferLimitExceeded)
+	}
+	// A failed debit leaves no transfer behind
+	_, err = service.Transfer(&models.TransferUserPointsRequest{FromUserId: 3, ToUserId: 7, PointTypeId: coins.Id, Amount: 40})
+	if !errors.Is(err, user_points.ErrInsufficientBalance) {
+		t.Errorf("transfer past the balance: err = %v, want %v", err, user_points.ErrInsufficientBalance)
+	}
+
+	var transfers int64
+	if err := db.Model(&models.PointTransfer{}).Count(&transfers).Error; err != nil {
+		t.Fatalf("failed to count transfers: %v", err)
+	}
+	if transfers != 1 {
+		t.Errorf("%d transfers stored, want 1", transfers)
+	}
+	for userId, want := range map[uint]int{7: 70, 3: 30} {
+		if balance := balanceOf(t, db, userId, coins.Id); balance.CurrentBalance != want {
+			t.Errorf("balance of %d = %d, want %d", userId, balance.CurrentBalance, want)
+		}
+		if sum := ledgerSum(t, db, userId, coins.Id); sum != want {
+			t.Errorf("transactions of %d add up to %d, want %d", userId, sum, want)
+		}
+	}
+}
+
+func TestTransferNotTransferable(t *testing.T) {
+	_, db, service := newLedger(t)
+	service = service.WithContext(db.Statement.Context)
+	xp := newPointType(t, db, &models.PointType{Key: "xp", Name: "XP"})
+	credit(t, db, service, 7, xp.Id, 100)
+
+	_, err := service.Transfer(&models.TransferUserPointsRequest{FromUserId: 7, ToUserId: 3, PointTypeId: xp.Id, Amount: 10})
+	if !errors.Is(err, user_points.ErrNotTransferable) {
+		t.Errorf("err = %v, want %v", err, user_points.ErrNotTransferable)
+	}
+}